@@ -32,6 +32,20 @@ func main() {
 		fmt.Println("pocket-id " + common.Version)
 	case "one-time-access-token":
 		err = cmds.OneTimeAccessToken(args)
+	case "backup":
+		err = cmds.Backup(args)
+	case "restore":
+		err = cmds.Restore(args)
+	case "migrate":
+		err = cmds.Migrate(args)
+	case "apply":
+		err = cmds.Apply(args)
+	case "transfer-database":
+		err = cmds.TransferDatabase(args)
+	case "import-audit-logs":
+		err = cmds.ImportAuditLogs(args)
+	case "verify-audit-logs":
+		err = cmds.VerifyAuditLogs(args)
 	default:
 		// Start the server
 		err = bootstrap.Bootstrap()
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
 	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/job"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
 	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
 )
@@ -18,6 +20,9 @@ func Bootstrap() error {
 	// Get a context that is canceled when the application is stopping
 	ctx := signals.SignalContext(context.Background())
 
+	// Only log the messages of the configured level or above
+	slog.SetLogLoggerLevel(common.EnvConfig.LogLevel)
+
 	initApplicationImages()
 
 	// Initialize the tracer and metrics exporter
@@ -35,6 +40,12 @@ func Bootstrap() error {
 		return fmt.Errorf("failed to initialize services: %w", err)
 	}
 
+	// Apply the declarative configuration before anything can change the resources in it
+	err = applyDeclarativeConfig(ctx, svc)
+	if err != nil {
+		return fmt.Errorf("failed to apply declarative configuration: %w", err)
+	}
+
 	// Init the job scheduler
 	scheduler, err := job.NewScheduler()
 	if err != nil {
@@ -46,12 +57,27 @@ func Bootstrap() error {
 	}
 
 	// Init the router
-	router := initRouter(db, svc)
+	corsMiddleware := middleware.NewCorsMiddleware(common.EnvConfig.CorsAllowedOrigins)
+	router := initRouter(db, svc, corsMiddleware)
+
+	// Reload the settings that can change at runtime on SIGHUP
+	reloader := newConfigReloader(svc.appConfigService, corsMiddleware)
+
+	backgroundServices := []utils.Service{router, scheduler.Run, svc.auditLogSinkService.Run, reloader.Run}
+
+	// Init the LDAP server if it's enabled
+	if common.EnvConfig.LdapServerEnabled {
+		ldapServer, err := initLdapServer(svc)
+		if err != nil {
+			return fmt.Errorf("failed to init LDAP server: %w", err)
+		}
+		backgroundServices = append(backgroundServices, ldapServer)
+	}
 
 	// Run all background services
 	// This call blocks until the context is canceled
 	err = utils.
-		NewServiceRunner(router, scheduler.Run).
+		NewServiceRunner(backgroundServices...).
 		Run(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to run services: %w", err)
@@ -3,6 +3,7 @@ package bootstrap
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/url"
 	"os"
@@ -10,96 +11,336 @@ import (
 	"time"
 
 	"github.com/glebarez/sqlite"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
+	mysqlMigrate "github.com/golang-migrate/migrate/v4/database/mysql"
 	postgresMigrate "github.com/golang-migrate/migrate/v4/database/postgres"
 	sqliteMigrate "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	"github.com/pocket-id/pocket-id/backend/resources"
 )
 
 func NewDatabase() (db *gorm.DB) {
-	db, err := connectDatabase()
+	db, err := ConnectDatabase()
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
+
+	// Run migrations, unless they're run as a separate step with the migrate command
+	if common.EnvConfig.DbAutoMigrateDisabled {
+		if err := checkDatabaseMigrated(db); err != nil {
+			log.Fatalf("database isn't migrated: %v", err)
+		}
+	} else if err := MigrateDatabase(db, 0); err != nil {
+		log.Fatalf("failed to run migrations: %v", err)
+	}
+
+	return db
+}
+
+// checkDatabaseMigrated returns an error if the schema of the database isn't at the version of the latest migration
+func checkDatabaseMigrated(db *gorm.DB) error {
+	status, err := GetDatabaseMigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("the migration to version %d failed, fix the schema and run 'migrate force'", status.Version)
+	}
+	if pending := status.Pending(); len(pending) > 0 {
+		return fmt.Errorf("%d migrations are pending and DB_AUTO_MIGRATE_DISABLED is set, run 'migrate up'", len(pending))
+	}
+	if status.Version != status.LatestVersion() {
+		return fmt.Errorf("the schema version %d is unknown to this version of Pocket ID", status.Version)
+	}
+
+	return nil
+}
+
+// ConnectDatabase connects to the configured database without running the migrations
+func ConnectDatabase() (*gorm.DB, error) {
+	return connectDatabase(common.EnvConfig.DbProvider, common.EnvConfig.DbConnectionString)
+}
+
+// OpenDatabase connects to a database other than the configured one and applies all migrations
+func OpenDatabase(provider common.DbProvider, connectionString string) (*gorm.DB, error) {
+	db, err := connectDatabase(provider, connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrateDatabase(db, provider, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// MigrateDatabase migrates the database up or down to the given version, or applies all migrations if it's 0
+func MigrateDatabase(db *gorm.DB, version uint) error {
+	return migrateDatabase(db, common.EnvConfig.DbProvider, version)
+}
+
+func migrateDatabase(db *gorm.DB, provider common.DbProvider, version uint) error {
+	m, err := newMigrate(db, provider)
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		err = m.Up()
+	} else {
+		err = m.Migrate(version)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return nil
+}
+
+// DatabaseSchemaVersion returns the version of the last migration that was applied to the database, or 0 if none was
+func DatabaseSchemaVersion(db *gorm.DB) (uint, error) {
+	m, err := newMigrate(db, common.EnvConfig.DbProvider)
+	if err != nil {
+		return 0, err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("the database schema is dirty at version %d", version)
+	}
+
+	return version, nil
+}
+
+// DatabaseMigration is a migration that is embedded in Pocket ID
+type DatabaseMigration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// DatabaseMigrationStatus is the state of the migrations of the configured database
+type DatabaseMigrationStatus struct {
+	// Version is the version of the last applied migration, or 0 if none was applied
+	Version uint
+	// Dirty is true if the last migration failed, in which case the schema must be fixed manually
+	Dirty      bool
+	Migrations []DatabaseMigration
+}
+
+// Pending returns the migrations that haven't been applied yet
+func (s DatabaseMigrationStatus) Pending() []DatabaseMigration {
+	pending := make([]DatabaseMigration, 0)
+	for _, migration := range s.Migrations {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Applied returns the migrations that have been applied, starting with the latest one
+func (s DatabaseMigrationStatus) Applied() []DatabaseMigration {
+	applied := make([]DatabaseMigration, 0)
+	for i := len(s.Migrations) - 1; i >= 0; i-- {
+		if s.Migrations[i].Applied {
+			applied = append(applied, s.Migrations[i])
+		}
+	}
+	return applied
+}
+
+// LatestVersion returns the version of the latest embedded migration
+func (s DatabaseMigrationStatus) LatestVersion() uint {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// GetDatabaseMigrationStatus returns the schema version of the configured database and the embedded migrations
+func GetDatabaseMigrationStatus(db *gorm.DB) (DatabaseMigrationStatus, error) {
+	m, err := newMigrate(db, common.EnvConfig.DbProvider)
+	if err != nil {
+		return DatabaseMigrationStatus{}, err
+	}
+
+	var status DatabaseMigrationStatus
+	status.Version, status.Dirty, err = m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return DatabaseMigrationStatus{}, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	status.Migrations, err = listDatabaseMigrations(common.EnvConfig.DbProvider)
+	if err != nil {
+		return DatabaseMigrationStatus{}, err
+	}
+	for i := range status.Migrations {
+		// The migration the database is dirty at hasn't been applied completely
+		status.Migrations[i].Applied = status.Migrations[i].Version < status.Version ||
+			(status.Migrations[i].Version == status.Version && !status.Dirty)
+	}
+
+	return status, nil
+}
+
+// StepDatabaseMigrations applies the next n migrations, or rolls back the last -n migrations if n is negative
+func StepDatabaseMigrations(db *gorm.DB, n int) error {
+	m, err := newMigrate(db, common.EnvConfig.DbProvider)
+	if err != nil {
+		return err
+	}
+
+	err = m.Steps(n)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return nil
+}
+
+// ForceDatabaseSchemaVersion sets the schema version and clears the dirty flag without running any migration.
+// A version of 0 marks the database as not migrated.
+func ForceDatabaseSchemaVersion(db *gorm.DB, version uint) error {
+	m, err := newMigrate(db, common.EnvConfig.DbProvider)
+	if err != nil {
+		return err
+	}
+
+	forcedVersion := int(version) //nolint:gosec // Migration versions are timestamps, which fit into an int
+	if version == 0 {
+		forcedVersion = database.NilVersion
+	}
+	err = m.Force(forcedVersion)
+	if err != nil {
+		return fmt.Errorf("failed to force schema version: %w", err)
+	}
+
+	return nil
+}
+
+func listDatabaseMigrations(provider common.DbProvider) ([]DatabaseMigration, error) {
+	src, err := newMigrationSource(provider)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	migrations := make([]DatabaseMigration, 0)
+	version, err := src.First()
+	for err == nil {
+		r, name, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		r.Close()
+
+		migrations = append(migrations, DatabaseMigration{Version: version, Name: name})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return migrations, nil
+}
+
+func newMigrationSource(provider common.DbProvider) (source.Driver, error) {
+	// Use the embedded migrations
+	src, err := iofs.New(resources.FS, "migrations/"+string(provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedded migration source: %w", err)
+	}
+	return src, nil
+}
+
+func newMigrate(db *gorm.DB, provider common.DbProvider) (*migrate.Migrate, error) {
 	sqlDb, err := db.DB()
 	if err != nil {
-		log.Fatalf("failed to get sql.DB: %v", err)
+		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
 	}
 
 	// Choose the correct driver for the database provider
 	var driver database.Driver
-	switch common.EnvConfig.DbProvider {
+	switch provider {
 	case common.DbProviderSqlite:
 		driver, err = sqliteMigrate.WithInstance(sqlDb, &sqliteMigrate.Config{})
 	case common.DbProviderPostgres:
 		driver, err = postgresMigrate.WithInstance(sqlDb, &postgresMigrate.Config{})
+	case common.DbProviderMysql:
+		driver, err = mysqlMigrate.WithInstance(sqlDb, &mysqlMigrate.Config{})
 	default:
 		// Should never happen at this point
-		log.Fatalf("unsupported database provider: %s", common.EnvConfig.DbProvider)
+		return nil, fmt.Errorf("unsupported database provider: %s", provider)
 	}
 	if err != nil {
-		log.Fatalf("failed to create migration driver: %v", err)
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
-	// Run migrations
-	if err := migrateDatabase(driver); err != nil {
-		log.Fatalf("failed to run migrations: %v", err)
-	}
-
-	return db
-}
-
-func migrateDatabase(driver database.Driver) error {
-	// Use the embedded migrations
-	source, err := iofs.New(resources.FS, "migrations/"+string(common.EnvConfig.DbProvider))
+	src, err := newMigrationSource(provider)
 	if err != nil {
-		return fmt.Errorf("failed to create embedded migration source: %w", err)
+		return nil, err
 	}
 
-	m, err := migrate.NewWithInstance("iofs", source, "pocket-id", driver)
+	m, err := migrate.NewWithInstance("iofs", src, "pocket-id", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
 
-	err = m.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	return nil
+	return m, nil
 }
 
-func connectDatabase() (db *gorm.DB, err error) {
+func connectDatabase(provider common.DbProvider, connectionString string) (db *gorm.DB, err error) {
 	var dialector gorm.Dialector
 
 	// Choose the correct database provider
-	switch common.EnvConfig.DbProvider {
+	switch provider {
 	case common.DbProviderSqlite:
-		if common.EnvConfig.DbConnectionString == "" {
+		if connectionString == "" {
 			return nil, errors.New("missing required env var 'DB_CONNECTION_STRING' for SQLite database")
 		}
-		if !strings.HasPrefix(common.EnvConfig.DbConnectionString, "file:") {
+		if !strings.HasPrefix(connectionString, "file:") {
 			return nil, errors.New("invalid value for env var 'DB_CONNECTION_STRING': does not begin with 'file:'")
 		}
-		connString, err := parseSqliteConnectionString(common.EnvConfig.DbConnectionString)
+		connString, err := parseSqliteConnectionString(connectionString)
 		if err != nil {
 			return nil, err
 		}
 		dialector = sqlite.Open(connString)
 	case common.DbProviderPostgres:
-		if common.EnvConfig.DbConnectionString == "" {
+		if connectionString == "" {
 			return nil, errors.New("missing required env var 'DB_CONNECTION_STRING' for Postgres database")
 		}
-		dialector = postgres.Open(common.EnvConfig.DbConnectionString)
+		dialector = postgres.Open(connectionString)
+	case common.DbProviderMysql:
+		if connectionString == "" {
+			return nil, errors.New("missing required env var 'DB_CONNECTION_STRING' for MySQL database")
+		}
+		connString, err := parseMysqlConnectionString(connectionString)
+		if err != nil {
+			return nil, err
+		}
+		dialector = mysql.Open(connString)
 	default:
-		return nil, fmt.Errorf("unsupported database provider: %s", common.EnvConfig.DbProvider)
+		return nil, fmt.Errorf("unsupported database provider: %s", provider)
 	}
 
 	for i := 1; i <= 3; i++ {
@@ -108,6 +349,8 @@ func connectDatabase() (db *gorm.DB, err error) {
 			Logger:         getLogger(),
 		})
 		if err == nil {
+			// Allows side effects such as streaming audit logs to wait for the transaction to be committed
+			utils.EnableAfterCommitHooks(db)
 			return db, nil
 		}
 
@@ -162,6 +405,28 @@ func parseSqliteConnectionString(connString string) (string, error) {
 	return connStringUrl.String(), nil
 }
 
+// The queries of Pocket ID are written in standard SQL, so MySQL must treat double quotes as quotes of identifiers
+// and "||" as concatenation. Additionally, times must be parsed and the migrations contain multiple statements.
+func parseMysqlConnectionString(connString string) (string, error) {
+	config, err := mysqlDriver.ParseDSN(connString)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse MySQL connection string: %w", err)
+	}
+
+	config.ParseTime = true
+	config.MultiStatements = true
+
+	sqlMode := "@@sql_mode"
+	if config.Params == nil {
+		config.Params = make(map[string]string)
+	} else if mode, ok := config.Params["sql_mode"]; ok {
+		sqlMode = mode
+	}
+	config.Params["sql_mode"] = "CONCAT(" + sqlMode + ", ',ANSI_QUOTES,PIPES_AS_CONCAT')"
+
+	return config.FormatDSN(), nil
+}
+
 func getLogger() logger.Interface {
 	isProduction := common.EnvConfig.AppEnv == "production"
 
@@ -4,8 +4,11 @@ import (
 	"net/url"
 	"testing"
 
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
 )
 
 func TestParseSqliteConnectionString(t *testing.T) {
@@ -143,3 +146,95 @@ func TestParseSqliteConnectionString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMysqlConnectionString(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedSqlMode string
+		expectedError   bool
+	}{
+		{
+			name:            "appends to the sql_mode of the server",
+			input:           "pocketid:secret@tcp(localhost:3306)/pocketid",
+			expectedSqlMode: "CONCAT(@@sql_mode, ',ANSI_QUOTES,PIPES_AS_CONCAT')",
+		},
+		{
+			name:            "appends to a custom sql_mode",
+			input:           "pocketid:secret@tcp(localhost:3306)/pocketid?sql_mode='TRADITIONAL'",
+			expectedSqlMode: "CONCAT('TRADITIONAL', ',ANSI_QUOTES,PIPES_AS_CONCAT')",
+		},
+		{
+			name:          "invalid DSN",
+			input:         "pocketid:secret@tcp(localhost:3306)",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMysqlConnectionString(tt.input)
+
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			config, err := mysqlDriver.ParseDSN(result)
+			require.NoError(t, err)
+			assert.True(t, config.ParseTime)
+			assert.True(t, config.MultiStatements)
+			assert.Equal(t, "pocketid", config.DBName)
+			assert.Equal(t, tt.expectedSqlMode, config.Params["sql_mode"])
+		})
+	}
+}
+
+func TestDatabaseMigrations(t *testing.T) {
+	db, err := connectDatabase(common.DbProviderSqlite, "file:"+t.Name()+"?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	status, err := GetDatabaseMigrationStatus(db)
+	require.NoError(t, err)
+	require.NotEmpty(t, status.Migrations)
+	assert.Equal(t, uint(0), status.Version)
+	assert.Len(t, status.Pending(), len(status.Migrations))
+	require.Error(t, checkDatabaseMigrated(db))
+
+	require.NoError(t, MigrateDatabase(db, 0))
+	status, err = GetDatabaseMigrationStatus(db)
+	require.NoError(t, err)
+	assert.Equal(t, status.LatestVersion(), status.Version)
+	assert.Empty(t, status.Pending())
+	require.NoError(t, checkDatabaseMigrated(db))
+
+	t.Run("rolls back migrations", func(t *testing.T) {
+		applied := status.Applied()
+		require.NoError(t, StepDatabaseMigrations(db, -2))
+
+		rolledBack, err := GetDatabaseMigrationStatus(db)
+		require.NoError(t, err)
+		assert.Equal(t, applied[2].Version, rolledBack.Version)
+		pending := rolledBack.Pending()
+		require.Len(t, pending, 2)
+		assert.Equal(t, applied[1].Version, pending[0].Version)
+		assert.Equal(t, applied[0].Version, pending[1].Version)
+
+		require.NoError(t, StepDatabaseMigrations(db, 2))
+		require.NoError(t, checkDatabaseMigrated(db))
+	})
+
+	t.Run("forces the schema version", func(t *testing.T) {
+		require.NoError(t, ForceDatabaseSchemaVersion(db, status.Migrations[0].Version))
+		forced, err := GetDatabaseMigrationStatus(db)
+		require.NoError(t, err)
+		assert.Equal(t, status.Migrations[0].Version, forced.Version)
+		assert.False(t, forced.Dirty)
+		assert.Len(t, forced.Applied(), 1)
+
+		require.NoError(t, ForceDatabaseSchemaVersion(db, status.LatestVersion()))
+		require.NoError(t, checkDatabaseMigrated(db))
+	})
+}
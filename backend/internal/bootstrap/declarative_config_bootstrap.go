@@ -0,0 +1,44 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+// NewDeclarativeConfigService creates the services that applying the declarative configuration requires, e.g. for the apply command
+func NewDeclarativeConfigService(ctx context.Context, db *gorm.DB) (*service.DeclarativeConfigService, error) {
+	svc, err := initServices(ctx, db, http.DefaultClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize services: %w", err)
+	}
+	return svc.declarativeConfigService, nil
+}
+
+// applyDeclarativeConfig applies the declarative configuration file at startup, if one is configured
+func applyDeclarativeConfig(ctx context.Context, svc *services) error {
+	if common.EnvConfig.DeclarativeConfigPath == "" {
+		return nil
+	}
+
+	config, err := service.LoadDeclarativeConfigFile(common.EnvConfig.DeclarativeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	changes, err := svc.declarativeConfigService.Apply(ctx, config)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		log.Printf("Declarative configuration: %s", change)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/jimlambrt/gldap"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/controller"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// initLdapServer creates the read-only LDAP server, which exposes the users and groups to applications that only support LDAP
+func initLdapServer(svc *services) (utils.Service, error) {
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LDAP mux: %w", err)
+	}
+
+	// The connections are secured with StartTLS, which the env config requires if the LDAP server is enabled
+	certReloader, err := utils.NewCertificateReloader(common.EnvConfig.LdapServerTlsCertFile, common.EnvConfig.LdapServerTlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load LDAP server certificate: %w", err)
+	}
+	startTLSConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certReloader.GetCertificate,
+	}
+
+	ldapServerController, err := controller.NewLdapServerController(mux, svc.ldapServerService, startTLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register LDAP handlers: %w", err)
+	}
+
+	server, err := gldap.NewServer(gldap.WithOnClose(ldapServerController.OnClose))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LDAP server: %w", err)
+	}
+	err = server.Router(mux)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set LDAP router: %w", err)
+	}
+
+	addr := net.JoinHostPort(common.EnvConfig.Host, common.EnvConfig.LdapServerPort)
+
+	runFn := func(ctx context.Context) error {
+		log.Printf("LDAP server listening on %s with base DN %s", addr, common.EnvConfig.LdapServerBaseDN)
+
+		// Reload the certificate when the files change
+		go func() {
+			_ = certReloader.Run(ctx)
+		}()
+
+		// Start the server in a background goroutine
+		// The call blocks until the server is stopped
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- server.Run(addr)
+		}()
+
+		// Block until the context is canceled or the server fails
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return fmt.Errorf("failed to run LDAP server: %w", err)
+			}
+			return nil
+		case <-ctx.Done():
+		}
+
+		err := server.Stop()
+		if err != nil {
+			// Log the error only
+			log.Printf("[WARN] LDAP server shutdown error: %v", err)
+		}
+
+		return nil
+	}
+
+	return runFn, nil
+}
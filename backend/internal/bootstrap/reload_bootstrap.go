@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"reflect"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+// configReloader applies the settings that can change at runtime when the application receives SIGHUP
+type configReloader struct {
+	appConfigService *service.AppConfigService
+	corsMiddleware   *middleware.CorsMiddleware
+}
+
+func newConfigReloader(appConfigService *service.AppConfigService, corsMiddleware *middleware.CorsMiddleware) *configReloader {
+	return &configReloader{appConfigService: appConfigService, corsMiddleware: corsMiddleware}
+}
+
+// Run reloads the configuration whenever the application receives SIGHUP, until the context is canceled
+func (r *configReloader) Run(ctx context.Context) error {
+	for range signals.ReloadSignal(ctx) {
+		log.Println("Received SIGHUP. Reloading configuration…")
+		err := r.reload(ctx)
+		if err != nil {
+			// Keep running with the previous configuration
+			log.Printf("Failed to reload configuration: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *configReloader) reload(ctx context.Context) error {
+	config, err := common.LoadEnvConfig()
+	if err != nil {
+		return err
+	}
+
+	changed := r.applyEnvConfig(config)
+
+	// The app config is only loaded from the environment and the config file if it can't be changed in the UI
+	if common.EnvConfig.UiConfigDisabled {
+		before := r.appConfigService.GetDbConfig().ToAppConfigVariableSlice(true)
+		err = r.appConfigService.LoadDbConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to reload app config: %w", err)
+		}
+		after := r.appConfigService.GetDbConfig().ToAppConfigVariableSlice(true)
+
+		for i := range after {
+			if before[i].Value == after[i].Value {
+				continue
+			}
+			changed = true
+
+			name := utils.CamelCaseToScreamingSnakeCase(after[i].Key)
+			if model.IsSensitiveAppConfigKey(after[i].Key) {
+				log.Printf("Reloaded %s", name)
+			} else {
+				log.Printf("Reloaded %s: '%s' -> '%s'", name, before[i].Value, after[i].Value)
+			}
+		}
+	}
+
+	if !changed {
+		log.Println("The configuration didn't change")
+	}
+	return nil
+}
+
+// applyEnvConfig applies the changed settings that can change at runtime and logs the ones that require a restart.
+// It returns whether any setting changed.
+func (r *configReloader) applyEnvConfig(config *common.EnvConfigSchema) (changed bool) {
+	rt := reflect.TypeFor[common.EnvConfigSchema]()
+	current := reflect.ValueOf(common.EnvConfig).Elem()
+	updated := reflect.ValueOf(config).Elem()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+		changed = true
+
+		name := field.Tag.Get("env")
+		if field.Tag.Get("reload") != "true" {
+			log.Printf("%s changed, restart Pocket ID to apply it", name)
+			continue
+		}
+
+		log.Printf("Reloaded %s: '%v' -> '%v'", name, current.Field(i).Interface(), updated.Field(i).Interface())
+		current.Field(i).Set(updated.Field(i))
+	}
+
+	slog.SetLogLoggerLevel(common.EnvConfig.LogLevel)
+	r.corsMiddleware.SetAllowedOrigins(common.EnvConfig.CorsAllowedOrigins)
+
+	return changed
+}
@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+)
+
+func TestConfigReloader_applyEnvConfig(t *testing.T) {
+	original := *common.EnvConfig
+	t.Cleanup(func() {
+		*common.EnvConfig = original
+		slog.SetLogLoggerLevel(original.LogLevel)
+	})
+
+	reloader := newConfigReloader(nil, middleware.NewCorsMiddleware(nil))
+
+	updated := original
+	assert.False(t, reloader.applyEnvConfig(&updated), "nothing changed")
+
+	updated.LogLevel = slog.LevelDebug
+	updated.CorsAllowedOrigins = []string{"https://app.example.com"}
+	updated.Port = "8080"
+	assert.True(t, reloader.applyEnvConfig(&updated))
+
+	// Only the settings that can change at runtime are applied
+	assert.Equal(t, slog.LevelDebug, common.EnvConfig.LogLevel)
+	assert.Equal(t, []string{"https://app.example.com"}, common.EnvConfig.CorsAllowedOrigins)
+	assert.Equal(t, original.Port, common.EnvConfig.Port)
+	assert.True(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+}
@@ -26,15 +26,15 @@ import (
 // This is used to register additional controllers for tests
 var registerTestControllers []func(apiGroup *gin.RouterGroup, db *gorm.DB, svc *services)
 
-func initRouter(db *gorm.DB, svc *services) utils.Service {
-	runner, err := initRouterInternal(db, svc)
+func initRouter(db *gorm.DB, svc *services, corsMiddleware *middleware.CorsMiddleware) utils.Service {
+	runner, err := initRouterInternal(db, svc, corsMiddleware)
 	if err != nil {
 		log.Fatalf("failed to init router: %v", err)
 	}
 	return runner
 }
 
-func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
+func initRouterInternal(db *gorm.DB, svc *services, corsMiddleware *middleware.CorsMiddleware) (utils.Service, error) {
 	// Set the appropriate Gin mode based on the environment
 	switch common.EnvConfig.AppEnv {
 	case "production":
@@ -59,7 +59,7 @@ func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
 	rateLimitMiddleware := middleware.NewRateLimitMiddleware().Add(rate.Every(time.Second), 60)
 
 	// Setup global middleware
-	r.Use(middleware.NewCorsMiddleware().Add())
+	r.Use(corsMiddleware.Add())
 	r.Use(middleware.NewErrorHandlerMiddleware().Add())
 
 	err := frontend.RegisterFrontend(r)
@@ -70,19 +70,26 @@ func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
 	}
 
 	// Initialize middleware for specific routes
-	authMiddleware := middleware.NewAuthMiddleware(svc.apiKeyService, svc.userService, svc.jwtService)
+	authMiddleware := middleware.NewAuthMiddleware(svc.apiKeyService, svc.userService, svc.jwtService, svc.sessionService)
 	fileSizeLimitMiddleware := middleware.NewFileSizeLimitMiddleware()
 
 	// Set up API routes
 	apiGroup := r.Group("/api", rateLimitMiddleware)
 	controller.NewApiKeyController(apiGroup, authMiddleware, svc.apiKeyService)
-	controller.NewWebauthnController(apiGroup, authMiddleware, middleware.NewRateLimitMiddleware(), svc.webauthnService, svc.appConfigService)
+	controller.NewAppPasswordController(apiGroup, authMiddleware, svc.appPasswordService)
+	controller.NewWebauthnController(apiGroup, authMiddleware, middleware.NewRateLimitMiddleware(), svc.webauthnService, svc.appConfigService, svc.sessionService)
 	controller.NewOidcController(apiGroup, authMiddleware, fileSizeLimitMiddleware, svc.oidcService, svc.jwtService)
 	controller.NewUserController(apiGroup, authMiddleware, middleware.NewRateLimitMiddleware(), svc.userService, svc.appConfigService)
 	controller.NewAppConfigController(apiGroup, authMiddleware, svc.appConfigService, svc.emailService, svc.ldapService)
-	controller.NewAuditLogController(apiGroup, svc.auditLogService, authMiddleware)
+	controller.NewAuditLogController(apiGroup, svc.auditLogService, svc.auditLogChainService, authMiddleware)
 	controller.NewUserGroupController(apiGroup, authMiddleware, svc.userGroupService)
 	controller.NewCustomClaimController(apiGroup, authMiddleware, svc.customClaimService)
+	controller.NewSessionController(apiGroup, authMiddleware, svc.sessionService, svc.auditLogService)
+	controller.NewSamlController(apiGroup, authMiddleware, svc.samlService)
+	controller.NewScimProvisioningController(apiGroup, authMiddleware, svc.scimProvisioningService)
+	controller.NewWebhookController(apiGroup, authMiddleware, svc.webhookService)
+	controller.NewIdentityProviderController(apiGroup, authMiddleware, svc.identityProviderService)
+	controller.NewForwardAuthController(r, apiGroup, authMiddleware, middleware.NewJwtAuthMiddleware(svc.jwtService, svc.userService, svc.sessionService), svc.forwardAuthService)
 
 	// Add test controller in non-production environments
 	if common.EnvConfig.AppEnv != "production" {
@@ -95,6 +102,14 @@ func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
 	baseGroup := r.Group("/", rateLimitMiddleware)
 	controller.NewWellKnownController(baseGroup, svc.jwtService)
 
+	// Set up SCIM routes, which are authenticated with the API key of an admin
+	scimGroup := r.Group("/scim/v2",
+		rateLimitMiddleware,
+		middleware.NewScimErrorHandlerMiddleware().Add(),
+		middleware.NewApiKeyAuthMiddleware(svc.apiKeyService, svc.jwtService).WithBearerToken().Add(true),
+	)
+	controller.NewScimController(scimGroup, svc.scimService)
+
 	// Set up healthcheck routes
 	// These are not rate-limited
 	controller.NewHealthzController(r)
@@ -114,26 +129,76 @@ func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
 		addr = common.EnvConfig.UnixSocket
 	}
 
+	// Serve HTTPS if a certificate is configured
+	var certReloader *utils.CertificateReloader
+	if common.EnvConfig.TlsCertFile != "" {
+		srv.TLSConfig, certReloader, err = initServerTLS()
+		if err != nil {
+			return nil, fmt.Errorf("failed to init TLS: %w", err)
+		}
+	}
+
 	listener, err := net.Listen(network, addr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create %s listener: %w", network, err)
 	}
 
+	// Set up the listener that redirects HTTP to HTTPS if it's enabled
+	var redirectSrv *http.Server
+	var redirectListener net.Listener
+	if common.EnvConfig.TlsRedirectPort != "" {
+		redirectSrv = newTLSRedirectServer()
+		redirectListener, err = net.Listen("tcp", net.JoinHostPort(common.EnvConfig.Host, common.EnvConfig.TlsRedirectPort))
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to create redirect listener: %w", err)
+		}
+	}
+
 	// Service runner function
 	runFn := func(ctx context.Context) error {
-		log.Printf("Server listening on %s", addr)
+		if srv.TLSConfig != nil {
+			log.Printf("Server listening on %s with TLS", addr)
+		} else {
+			log.Printf("Server listening on %s", addr)
+		}
 
 		// Start the server in a background goroutine
 		go func() {
 			defer listener.Close()
 
 			// Next call blocks until the server is shut down
-			srvErr := srv.Serve(listener)
+			var srvErr error
+			if srv.TLSConfig != nil {
+				// The certificate is served by the TLS config
+				srvErr = srv.ServeTLS(listener, "", "")
+			} else {
+				srvErr = srv.Serve(listener)
+			}
 			if srvErr != http.ErrServerClosed {
 				log.Fatalf("Error starting app server: %v", srvErr)
 			}
 		}()
 
+		// Reload the certificate when the files change
+		if certReloader != nil {
+			go func() {
+				_ = certReloader.Run(ctx)
+			}()
+		}
+
+		if redirectSrv != nil {
+			log.Printf("Redirecting HTTP requests on %s to %s", redirectListener.Addr(), common.EnvConfig.AppURL)
+			go func() {
+				defer redirectListener.Close()
+
+				srvErr := redirectSrv.Serve(redirectListener)
+				if srvErr != http.ErrServerClosed {
+					log.Fatalf("Error starting redirect server: %v", srvErr)
+				}
+			}()
+		}
+
 		// Notify systemd that we are ready
 		err = systemd.SdNotifyReady()
 		if err != nil {
@@ -148,11 +213,17 @@ func initRouterInternal(db *gorm.DB, svc *services) (utils.Service, error) {
 		// Note we use the background context here as ctx has been canceled already
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		shutdownErr := srv.Shutdown(shutdownCtx) //nolint:contextcheck
-		shutdownCancel()
 		if shutdownErr != nil {
 			// Log the error only (could be context canceled)
 			log.Printf("[WARN] App server shutdown error: %v", shutdownErr)
 		}
+		if redirectSrv != nil {
+			shutdownErr = redirectSrv.Shutdown(shutdownCtx) //nolint:contextcheck
+			if shutdownErr != nil {
+				log.Printf("[WARN] Redirect server shutdown error: %v", shutdownErr)
+			}
+		}
+		shutdownCancel()
 
 		return nil
 	}
@@ -19,7 +19,7 @@ func registerScheduledJobs(ctx context.Context, db *gorm.DB, svc *services, http
 	if err != nil {
 		return fmt.Errorf("failed to register GeoLite DB update service: %w", err)
 	}
-	err = scheduler.RegisterDbCleanupJobs(ctx, db)
+	err = scheduler.RegisterDbCleanupJobs(ctx, db, svc.auditLogService)
 	if err != nil {
 		return fmt.Errorf("failed to register DB cleanup jobs in scheduler: %w", err)
 	}
@@ -31,6 +31,18 @@ func registerScheduledJobs(ctx context.Context, db *gorm.DB, svc *services, http
 	if err != nil {
 		return fmt.Errorf("failed to register API key expiration jobs in scheduler: %w", err)
 	}
+	err = scheduler.RegisterScimProvisioningJobs(ctx, svc.scimProvisioningService)
+	if err != nil {
+		return fmt.Errorf("failed to register SCIM provisioning jobs in scheduler: %w", err)
+	}
+	err = scheduler.RegisterWebhookJobs(ctx, svc.webhookService)
+	if err != nil {
+		return fmt.Errorf("failed to register webhook jobs in scheduler: %w", err)
+	}
+	err = scheduler.RegisterAuditLogCheckpointJobs(ctx, svc.auditLogChainService)
+	if err != nil {
+		return fmt.Errorf("failed to register audit log checkpoint jobs in scheduler: %w", err)
+	}
 	err = scheduler.RegisterAnalyticsJob(ctx, svc.appConfigService, httpClient)
 	if err != nil {
 		return fmt.Errorf("failed to register analytics job in scheduler: %w", err)
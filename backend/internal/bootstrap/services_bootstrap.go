@@ -7,22 +7,35 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/service"
 )
 
 type services struct {
-	appConfigService   *service.AppConfigService
-	emailService       *service.EmailService
-	geoLiteService     *service.GeoLiteService
-	auditLogService    *service.AuditLogService
-	jwtService         *service.JwtService
-	webauthnService    *service.WebAuthnService
-	userService        *service.UserService
-	customClaimService *service.CustomClaimService
-	oidcService        *service.OidcService
-	userGroupService   *service.UserGroupService
-	ldapService        *service.LdapService
-	apiKeyService      *service.ApiKeyService
+	appConfigService         *service.AppConfigService
+	emailService             *service.EmailService
+	geoLiteService           *service.GeoLiteService
+	auditLogService          *service.AuditLogService
+	auditLogSinkService      *service.AuditLogSinkService
+	auditLogChainService     *service.AuditLogChainService
+	jwtService               *service.JwtService
+	webauthnService          *service.WebAuthnService
+	userService              *service.UserService
+	customClaimService       *service.CustomClaimService
+	oidcService              *service.OidcService
+	userGroupService         *service.UserGroupService
+	ldapService              *service.LdapService
+	apiKeyService            *service.ApiKeyService
+	sessionService           *service.SessionService
+	scimProvisioningService  *service.ScimProvisioningService
+	samlService              *service.SamlService
+	scimService              *service.ScimService
+	appPasswordService       *service.AppPasswordService
+	ldapServerService        *service.LdapServerService
+	forwardAuthService       *service.ForwardAuthService
+	identityProviderService  *service.IdentityProviderService
+	webhookService           *service.WebhookService
+	declarativeConfigService *service.DeclarativeConfigService
 }
 
 // Initializes all services
@@ -37,20 +50,39 @@ func initServices(ctx context.Context, db *gorm.DB, httpClient *http.Client) (sv
 	}
 
 	svc.geoLiteService = service.NewGeoLiteService(httpClient)
-	svc.auditLogService = service.NewAuditLogService(db, svc.appConfigService, svc.emailService, svc.geoLiteService)
+	svc.webhookService = service.NewWebhookService(db, httpClient)
+	svc.auditLogSinkService, err = service.NewAuditLogSinkService(httpClient, common.EnvConfig.AuditLogSinks, common.EnvConfig.AuditLogSinkBufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log sink service: %w", err)
+	}
 	svc.jwtService = service.NewJwtService(svc.appConfigService)
-	svc.userService = service.NewUserService(db, svc.jwtService, svc.auditLogService, svc.emailService, svc.appConfigService)
-	svc.customClaimService = service.NewCustomClaimService(db)
+	svc.auditLogChainService, err = service.NewAuditLogChainService(ctx, db, svc.jwtService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log chain service: %w", err)
+	}
+	svc.auditLogService = service.NewAuditLogService(db, svc.appConfigService, svc.emailService, svc.geoLiteService, svc.webhookService, svc.auditLogSinkService, svc.auditLogChainService)
+	svc.appConfigService.SetAuditLogService(svc.auditLogService)
+	svc.sessionService = service.NewSessionService(db, svc.appConfigService, svc.geoLiteService)
+	svc.scimProvisioningService = service.NewScimProvisioningService(db, httpClient)
+	svc.userService = service.NewUserService(db, svc.jwtService, svc.auditLogService, svc.emailService, svc.appConfigService, svc.sessionService, svc.scimProvisioningService, svc.webhookService)
+	svc.customClaimService = service.NewCustomClaimService(db, svc.auditLogService)
 
-	svc.oidcService, err = service.NewOidcService(ctx, db, svc.jwtService, svc.appConfigService, svc.auditLogService, svc.customClaimService)
+	svc.oidcService, err = service.NewOidcService(ctx, db, svc.jwtService, svc.appConfigService, svc.auditLogService, svc.customClaimService, svc.scimProvisioningService)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OIDC service: %w", err)
 	}
 
-	svc.userGroupService = service.NewUserGroupService(db, svc.appConfigService)
+	svc.samlService = service.NewSamlService(db, svc.jwtService, svc.appConfigService, svc.auditLogService, svc.customClaimService)
+	svc.userGroupService = service.NewUserGroupService(db, svc.appConfigService, svc.scimProvisioningService, svc.webhookService, svc.auditLogService)
+	svc.declarativeConfigService = service.NewDeclarativeConfigService(db, svc.appConfigService, svc.oidcService, svc.auditLogService)
 	svc.ldapService = service.NewLdapService(db, httpClient, svc.appConfigService, svc.userService, svc.userGroupService)
-	svc.apiKeyService = service.NewApiKeyService(db, svc.emailService)
-	svc.webauthnService = service.NewWebAuthnService(db, svc.jwtService, svc.auditLogService, svc.appConfigService)
+	svc.scimService = service.NewScimService(db, svc.userService, svc.userGroupService, svc.auditLogService)
+	svc.apiKeyService = service.NewApiKeyService(db, svc.emailService, svc.auditLogService)
+	svc.appPasswordService = service.NewAppPasswordService(db)
+	svc.ldapServerService = service.NewLdapServerService(db, svc.apiKeyService, svc.appPasswordService, svc.auditLogService, common.EnvConfig.LdapServerBaseDN)
+	svc.forwardAuthService = service.NewForwardAuthService(db, svc.jwtService, svc.oidcService)
+	svc.identityProviderService = service.NewIdentityProviderService(db, httpClient, svc.jwtService, svc.oidcService, svc.userService, svc.sessionService, svc.auditLogService, svc.scimProvisioningService, svc.webhookService)
+	svc.webauthnService = service.NewWebAuthnService(db, svc.jwtService, svc.auditLogService, svc.appConfigService, svc.sessionService)
 
 	return svc, nil
 }
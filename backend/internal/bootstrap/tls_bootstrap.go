@@ -0,0 +1,52 @@
+package bootstrap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// initServerTLS returns the TLS configuration of the server and the reloader that serves its certificate
+func initServerTLS() (*tls.Config, *utils.CertificateReloader, error) {
+	certReloader, err := utils.NewCertificateReloader(common.EnvConfig.TlsCertFile, common.EnvConfig.TlsKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certReloader.GetCertificate,
+	}
+
+	// Request client certificates for mTLS
+	switch common.EnvConfig.TlsClientAuth {
+	case common.TlsClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case common.TlsClientAuthRequired:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if tlsConfig.ClientAuth != tls.NoClientCert {
+		tlsConfig.ClientCAs, err = utils.LoadCertPool(common.EnvConfig.TlsClientCaFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load TLS_CLIENT_CA_FILE: %w", err)
+		}
+	}
+
+	return tlsConfig, certReloader, nil
+}
+
+// newTLSRedirectServer returns a server that redirects all plain HTTP requests to the HTTPS APP_URL.
+// The target doesn't depend on the Host header, so that it can't be used as an open redirect.
+func newTLSRedirectServer() *http.Server {
+	return &http.Server{
+		MaxHeaderBytes:    1 << 20,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, common.EnvConfig.AppURL+r.URL.RequestURI(), http.StatusMovedPermanently)
+		}),
+	}
+}
@@ -0,0 +1,96 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+const applyUsage = "usage: apply [--dry-run] [<file>]"
+
+// Apply applies the declarative configuration file, which defaults to DECLARATIVE_CONFIG_PATH.
+// With --dry-run, it only prints the changes that applying the file would make.
+func Apply(args []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Only print the changes that applying the configuration would make")
+	// Note the first argument is always the command (apply)
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	path := common.EnvConfig.DeclarativeConfigPath
+	switch flags.NArg() {
+	case 0:
+		if path == "" {
+			return errors.New("missing configuration file and DECLARATIVE_CONFIG_PATH isn't set; " + applyUsage)
+		}
+	case 1:
+		path = flags.Arg(0)
+	default:
+		return errors.New("too many arguments; " + applyUsage)
+	}
+
+	config, err := service.LoadDeclarativeConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the database
+	db := bootstrap.NewDatabase()
+
+	declarativeConfigService, err := bootstrap.NewDeclarativeConfigService(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var changes []service.DeclarativeConfigChange
+	if *dryRun {
+		changes, err = declarativeConfigService.Plan(ctx, config)
+	} else {
+		changes, err = declarativeConfigService.Apply(ctx, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("The configuration is up to date")
+		return nil
+	}
+
+	var appConfigChanged bool
+	for _, change := range changes {
+		fmt.Println(change)
+		for _, field := range change.Fields {
+			if change.Action == service.DeclarativeConfigActionCreate {
+				fmt.Printf("    %s: %s\n", field.Field, service.FormatDeclarativeConfigValue(field.After))
+			} else {
+				fmt.Printf("    %s: %s -> %s\n", field.Field, service.FormatDeclarativeConfigValue(field.Before), service.FormatDeclarativeConfigValue(field.After))
+			}
+		}
+		appConfigChanged = appConfigChanged || change.Target.Type == model.AuditLogTargetAppConfig
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d resources would be changed\n", len(changes))
+		return nil
+	}
+
+	fmt.Printf("Changed %d resources\n", len(changes))
+	if appConfigChanged {
+		// Running instances load the application configuration at startup only
+		fmt.Println("Restart Pocket ID to load the changed application configuration")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+// Backup writes a backup of the database, the signing keys and the uploaded files to an archive
+func Backup(args []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	// Note the first argument is always the command (backup)
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	passphraseFile := flags.String("passphrase-file", "", "Encrypt the backup with the passphrase in this file")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("missing archive; usage: backup [--passphrase-file <file>] <archive>")
+	}
+	archivePath := flags.Arg(0)
+
+	passphrase, err := readPassphraseFile(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the database
+	db := bootstrap.NewDatabase()
+	schemaVersion, err := bootstrap.DatabaseSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	// The backup is written to a temporary file first, so that a failed backup doesn't leave a partial archive behind
+	tmpFile, err := os.CreateTemp(filepath.Dir(archivePath), ".pocket-id-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	backupService := service.NewBackupService(db)
+	err = backupService.CreateBackup(ctx, tmpFile, schemaVersion, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	err = os.Rename(tmpFile.Name(), archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if passphrase != "" {
+		fmt.Printf("Created encrypted backup %s\n", archivePath)
+	} else {
+		fmt.Printf("Created backup %s\n", archivePath)
+	}
+	return nil
+}
+
+// Restore restores a backup created by Backup into an empty instance
+func Restore(args []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	// Note the first argument is always the command (restore)
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	passphraseFile := flags.String("passphrase-file", "", "Decrypt the backup with the passphrase in this file")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("missing archive; usage: restore [--passphrase-file <file>] <archive>")
+	}
+
+	passphrase, err := readPassphraseFile(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	// Connect to the database without migrating it, as it's migrated to the schema version of the backup below
+	db, err := bootstrap.ConnectDatabase()
+	if err != nil {
+		return err
+	}
+
+	backupService := service.NewBackupService(db)
+	archive, err := backupService.OpenBackup(file, passphrase)
+	if err != nil {
+		return err
+	}
+
+	empty, err := backupService.IsDatabaseEmpty(ctx)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return errors.New("the database isn't empty, backups can only be restored into a new instance")
+	}
+
+	// The rows are restored with the schema they were backed up with, newer migrations are applied afterwards
+	err = bootstrap.MigrateDatabase(db, archive.Manifest.SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("failed to migrate the database to the schema version of the backup (%d), it may have been created by a newer version of Pocket ID: %w", archive.Manifest.SchemaVersion, err)
+	}
+
+	err = backupService.RestoreBackup(ctx, archive)
+	if err != nil {
+		return err
+	}
+
+	err = bootstrap.MigrateDatabase(db, 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored backup created by Pocket ID %s at %s\n", archive.Manifest.Version, archive.Manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	return nil
+}
+
+func readPassphraseFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	passphrase, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+
+	trimmed := strings.TrimRight(string(passphrase), "\r\n")
+	if trimmed == "" {
+		return "", errors.New("the passphrase file is empty")
+	}
+	return trimmed, nil
+}
@@ -0,0 +1,50 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+// ImportAuditLogs imports archived audit logs, e.g. for an investigation
+// Args must contain the paths of the archives
+func ImportAuditLogs(args []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	// Note the first argument is always the command (import-audit-logs)
+	if len(args) < 2 {
+		return errors.New("missing archive; usage: import-audit-logs <archive>...")
+	}
+
+	// Connect to the database
+	db := bootstrap.NewDatabase()
+
+	// Importing only requires the database
+	auditLogService := service.NewAuditLogService(db, nil, nil, nil, nil, nil, nil)
+
+	for _, path := range args[1:] {
+		imported, err := importAuditLogArchive(ctx, auditLogService, path)
+		if err != nil {
+			return fmt.Errorf("failed to import '%s': %w", path, err)
+		}
+		fmt.Printf("Imported %d audit logs from %s\n", imported, path)
+	}
+
+	return nil
+}
+
+func importAuditLogArchive(ctx context.Context, auditLogService *service.AuditLogService, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return auditLogService.ImportAuditLogArchive(ctx, file)
+}
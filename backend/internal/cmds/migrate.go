@@ -0,0 +1,219 @@
+package cmds
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+)
+
+const migrateUsage = "usage: migrate status | migrate up [--dry-run] | migrate down [--dry-run] <N> | migrate force <version>"
+
+// Migrate shows and changes the schema version of the database. It allows running the migrations as a separate
+// step, e.g. if DB_AUTO_MIGRATE_DISABLED is set, and rolling back migrations.
+func Migrate(args []string) error {
+	// Note the first argument is always the command (migrate)
+	if len(args) < 2 {
+		return errors.New("missing subcommand; " + migrateUsage)
+	}
+
+	switch args[1] {
+	case "status":
+		return migrateStatus(args[1:])
+	case "up":
+		return migrateUp(args[1:])
+	case "down":
+		return migrateDown(args[1:])
+	case "force":
+		return migrateForce(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand '%s'; %s", args[1], migrateUsage)
+	}
+}
+
+func migrateStatus(args []string) error {
+	if len(args) != 1 {
+		return errors.New("too many arguments; usage: migrate status")
+	}
+
+	_, status, err := connectDatabaseForMigration()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database provider: %s\n", common.EnvConfig.DbProvider)
+	fmt.Printf("Schema version:    %s\n", formatSchemaVersion(status.Version, status.Dirty))
+	fmt.Printf("Latest version:    %d\n", status.LatestVersion())
+	fmt.Printf("Pending:           %d\n\n", len(status.Pending()))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		} else if status.Dirty && migration.Version == status.Version {
+			state = "failed"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	return w.Flush()
+}
+
+func migrateUp(args []string) error {
+	flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Only print the migrations that would be applied")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("too many arguments; usage: migrate up [--dry-run]")
+	}
+
+	db, status, err := connectDatabaseForMigration()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return dirtySchemaError(status)
+	}
+
+	pending := status.Pending()
+	if len(pending) == 0 {
+		fmt.Printf("The database is up to date at version %d\n", status.Version)
+		return nil
+	}
+
+	for _, migration := range pending {
+		fmt.Printf("Apply %d %s\n", migration.Version, migration.Name)
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d migrations would be applied\n", len(pending))
+		return nil
+	}
+
+	err = bootstrap.StepDatabaseMigrations(db, len(pending))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Applied %d migrations, the schema version is %d\n", len(pending), pending[len(pending)-1].Version)
+	return nil
+}
+
+func migrateDown(args []string) error {
+	flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Only print the migrations that would be rolled back")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("missing number of migrations; usage: migrate down [--dry-run] <N>")
+	}
+	n, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || n < 1 {
+		return errors.New("the number of migrations to roll back must be a positive integer")
+	}
+
+	db, status, err := connectDatabaseForMigration()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return dirtySchemaError(status)
+	}
+
+	applied := status.Applied()
+	if n > len(applied) {
+		return fmt.Errorf("can't roll back %d migrations, only %d are applied", n, len(applied))
+	}
+
+	for _, migration := range applied[:n] {
+		fmt.Printf("Roll back %d %s\n", migration.Version, migration.Name)
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d migrations would be rolled back\n", n)
+		return nil
+	}
+
+	err = bootstrap.StepDatabaseMigrations(db, -n)
+	if err != nil {
+		return err
+	}
+
+	var version uint
+	if n < len(applied) {
+		version = applied[n].Version
+	}
+	fmt.Printf("Rolled back %d migrations, the schema version is %d\n", n, version)
+	return nil
+}
+
+func migrateForce(args []string) error {
+	if len(args) != 2 {
+		return errors.New("missing version; usage: migrate force <version>")
+	}
+	version, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version '%s'", args[1])
+	}
+
+	db, status, err := connectDatabaseForMigration()
+	if err != nil {
+		return err
+	}
+
+	// Only allow versions of existing migrations, as the next migration would fail otherwise
+	known := version == 0
+	for _, migration := range status.Migrations {
+		if migration.Version == uint(version) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("there is no migration with version %d", version)
+	}
+
+	err = bootstrap.ForceDatabaseSchemaVersion(db, uint(version))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Forced the schema version from %s to %d without running migrations\n", formatSchemaVersion(status.Version, status.Dirty), version)
+	return nil
+}
+
+func connectDatabaseForMigration() (*gorm.DB, bootstrap.DatabaseMigrationStatus, error) {
+	db, err := bootstrap.ConnectDatabase()
+	if err != nil {
+		return nil, bootstrap.DatabaseMigrationStatus{}, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	status, err := bootstrap.GetDatabaseMigrationStatus(db)
+	if err != nil {
+		return nil, bootstrap.DatabaseMigrationStatus{}, err
+	}
+
+	return db, status, nil
+}
+
+func dirtySchemaError(status bootstrap.DatabaseMigrationStatus) error {
+	return fmt.Errorf("the migration to version %d failed; fix the schema manually, then run 'migrate force <version>' with the version the schema matches", status.Version)
+}
+
+func formatSchemaVersion(version uint, dirty bool) string {
+	if dirty {
+		return fmt.Sprintf("%d (dirty)", version)
+	}
+	return strconv.FormatUint(uint64(version), 10)
+}
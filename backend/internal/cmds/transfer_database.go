@@ -0,0 +1,62 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+// TransferDatabase copies all data from the configured database into an empty database, e.g. to move from SQLite
+// to Postgres. The target database is migrated before the data is copied.
+func TransferDatabase(args []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	// Note the first argument is always the command (transfer-database)
+	flags := flag.NewFlagSet("transfer-database", flag.ContinueOnError)
+	targetProvider := flags.String("target-provider", "", "Database provider of the target database (sqlite, postgres or mysql)")
+	targetConnectionString := flags.String("target-connection-string", "", "Connection string of the target database")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *targetProvider == "" || *targetConnectionString == "" || flags.NArg() != 0 {
+		return errors.New("missing target database; usage: transfer-database --target-provider <provider> --target-connection-string <connection string>")
+	}
+
+	provider := common.DbProvider(*targetProvider)
+	if provider != common.DbProviderSqlite && provider != common.DbProviderPostgres && provider != common.DbProviderMysql {
+		return fmt.Errorf("unsupported target database provider: %s", provider)
+	}
+	if provider == common.EnvConfig.DbProvider && *targetConnectionString == common.EnvConfig.DbConnectionString {
+		return errors.New("the target database must be different from the configured database")
+	}
+
+	// Connect to both databases, which also migrates them to the latest schema version
+	source := bootstrap.NewDatabase()
+	target, err := bootstrap.OpenDatabase(provider, *targetConnectionString)
+	if err != nil {
+		return fmt.Errorf("failed to open target database: %w", err)
+	}
+
+	transferService := service.NewDatabaseTransferService(source, common.EnvConfig.DbProvider, target, provider)
+	tables, err := transferService.Transfer(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to transfer database: %w", err)
+	}
+
+	var total int64
+	for _, table := range tables {
+		fmt.Printf("Transferred %d rows of table %s\n", table.Rows, table.Name)
+		total += table.Rows
+	}
+	fmt.Printf("Transferred %d rows from %s to %s, the row counts of all tables match\n", total, common.EnvConfig.DbProvider, provider)
+	fmt.Println("Set DB_PROVIDER and DB_CONNECTION_STRING to the target database to start using it")
+	return nil
+}
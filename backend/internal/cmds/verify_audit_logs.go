@@ -0,0 +1,55 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pocket-id/pocket-id/backend/internal/bootstrap"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/signals"
+)
+
+// VerifyAuditLogs walks the tamper-evident chain of the audit logs and reports the first broken link
+func VerifyAuditLogs(_ []string) error {
+	// Get a context that is canceled when the application is stopping
+	ctx := signals.SignalContext(context.Background())
+
+	// Connect to the database
+	db := bootstrap.NewDatabase()
+
+	// Verifying only requires the database and the key that signs the checkpoints
+	jwtService := service.NewJwtService(nil)
+	auditLogChainService, err := service.NewAuditLogChainService(ctx, db, jwtService)
+	if err != nil {
+		return err
+	}
+
+	verification, err := auditLogChainService.VerifyChain(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify audit logs: %w", err)
+	}
+
+	fmt.Printf("Verified %d links of the audit log chain, %d of them belong to purged audit logs\n", verification.Links, verification.PurgedLinks)
+	fmt.Printf("Verified %d checkpoints", verification.Checkpoints)
+	if verification.SkippedCheckpoints > 0 {
+		fmt.Printf(", skipped %d checkpoints signed with a previous key", verification.SkippedCheckpoints)
+	}
+	fmt.Println()
+	if verification.LastCheckpoint != nil {
+		fmt.Printf("The last checkpoint was created at %s\n", verification.LastCheckpoint.CreatedAt.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+
+	if !verification.Valid {
+		brokenLink := verification.BrokenLink
+		if brokenLink.AuditLogID != "" {
+			return fmt.Errorf("the audit log chain is broken at link %d (audit log %s): %s", brokenLink.Sequence, brokenLink.AuditLogID, brokenLink.Reason)
+		}
+		return fmt.Errorf("the audit log chain is broken at link %d: %s", brokenLink.Sequence, brokenLink.Reason)
+	}
+	if verification.UnsignedLinks > 0 {
+		fmt.Printf("%d links were appended after the last checkpoint and aren't signed yet\n", verification.UnsignedLinks)
+	}
+
+	fmt.Println("The audit log chain is intact")
+	return nil
+}
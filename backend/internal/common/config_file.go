@@ -0,0 +1,93 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileValues contains the values of the config file by the names of the environment variables they set
+var configFileValues atomic.Pointer[map[string]string]
+
+// LookupEnv returns the value of an environment variable, or the value with the same name in the config file.
+// The environment variables take precedence over the config file.
+func LookupEnv(name string) (string, bool) {
+	return lookupEnv(name, configFileValues.Load())
+}
+
+func lookupEnv(name string, fileValues *map[string]string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	if fileValues == nil {
+		return "", false
+	}
+	value, ok := (*fileValues)[name]
+	return value, ok
+}
+
+// readConfigFile reads the config file, which is TOML or YAML depending on its extension.
+// Its keys are the names of the environment variables, e.g. APP_URL or SMTP_HOST.
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(content)).Decode(&raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("the config file '%s' must have the extension .toml, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if key != strings.ToUpper(key) {
+			return nil, fmt.Errorf("invalid key '%s' in config file: the keys are the names of the environment variables, e.g. APP_URL", key)
+		}
+
+		values[key], err = configFileValueToString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s' in config file: %w", key, err)
+		}
+	}
+	return values, nil
+}
+
+// configFileValueToString converts a value of the config file to the format of the environment variables.
+// Lists are joined with commas, like AUDIT_LOG_SINKS.
+func configFileValueToString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			str, err := configFileValueToString(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = str
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
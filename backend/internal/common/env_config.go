@@ -1,8 +1,15 @@
 package common
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/caarlos0/env/v11"
 	_ "github.com/joho/godotenv/autoload"
@@ -10,6 +17,10 @@ import (
 
 type DbProvider string
 
+type TlsClientAuth string
+
+type LdapServerTls string
+
 const (
 	// TracerName should be passed to otel.Tracer, trace.SpanFromContext when creating custom spans.
 	TracerName = "github.com/pocket-id/pocket-id/backend/tracing"
@@ -20,20 +31,38 @@ const (
 const (
 	DbProviderSqlite      DbProvider = "sqlite"
 	DbProviderPostgres    DbProvider = "postgres"
+	DbProviderMysql       DbProvider = "mysql"
 	MaxMindGeoLiteCityUrl string     = "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-City&license_key=%s&suffix=tar.gz"
 )
 
+const (
+	TlsClientAuthNone TlsClientAuth = "none"
+	// TlsClientAuthOptional requests a client certificate and verifies it if the client sends one
+	TlsClientAuthOptional TlsClientAuth = "optional"
+	// TlsClientAuthRequired rejects connections without a valid client certificate
+	TlsClientAuthRequired TlsClientAuth = "required"
+)
+
+const (
+	LdapServerTlsNone LdapServerTls = "none"
+	// LdapServerTlsStartTls upgrades connections with the StartTLS operation and rejects binds on connections without TLS
+	LdapServerTlsStartTls LdapServerTls = "starttls"
+)
+
+// EnvConfigSchema contains the configuration from the environment variables and the config file.
+// Fields with the tag `sensitive:"true"` can also be read from the file in the variable with the suffix _FILE.
+// Fields with the tag `reload:"true"` are applied at runtime when the configuration is reloaded with SIGHUP.
 type EnvConfigSchema struct {
 	AppEnv             string     `env:"APP_ENV"`
 	AppURL             string     `env:"APP_URL"`
 	DbProvider         DbProvider `env:"DB_PROVIDER"`
-	DbConnectionString string     `env:"DB_CONNECTION_STRING"`
+	DbConnectionString string     `env:"DB_CONNECTION_STRING" sensitive:"true"`
 	UploadPath         string     `env:"UPLOAD_PATH"`
 	KeysPath           string     `env:"KEYS_PATH"`
 	Port               string     `env:"PORT"`
 	Host               string     `env:"HOST"`
 	UnixSocket         string     `env:"UNIX_SOCKET"`
-	MaxMindLicenseKey  string     `env:"MAXMIND_LICENSE_KEY"`
+	MaxMindLicenseKey  string     `env:"MAXMIND_LICENSE_KEY" sensitive:"true"`
 	GeoLiteDBPath      string     `env:"GEOLITE_DB_PATH"`
 	GeoLiteDBUrl       string     `env:"GEOLITE_DB_URL"`
 	UiConfigDisabled   bool       `env:"UI_CONFIG_DISABLED"`
@@ -41,52 +70,337 @@ type EnvConfigSchema struct {
 	TracingEnabled     bool       `env:"TRACING_ENABLED"`
 	TrustProxy         bool       `env:"TRUST_PROXY"`
 	AnalyticsDisabled  bool       `env:"ANALYTICS_DISABLED"`
+	LdapServerEnabled  bool       `env:"LDAP_SERVER_ENABLED"`
+	LdapServerPort     string     `env:"LDAP_SERVER_PORT"`
+	LdapServerBaseDN   string     `env:"LDAP_SERVER_BASE_DN"`
+
+	// LdapServerTls secures the LDAP server with the certificate in LdapServerTlsCertFile, or TlsCertFile if it's empty.
+	// It must be StartTLS if the LDAP server is enabled, as the IP addresses of the clients are only known from the StartTLS handshake.
+	LdapServerTls         LdapServerTls `env:"LDAP_SERVER_TLS"`
+	LdapServerTlsCertFile string        `env:"LDAP_SERVER_TLS_CERT_FILE"`
+	LdapServerTlsKeyFile  string        `env:"LDAP_SERVER_TLS_KEY_FILE"`
+
+	// TlsCertFile and TlsKeyFile enable HTTPS, the certificate is reloaded when the files change on disk
+	TlsCertFile string `env:"TLS_CERT_FILE"`
+	TlsKeyFile  string `env:"TLS_KEY_FILE"`
+	// TlsClientAuth requests client certificates for mTLS, which are verified with the CAs in TlsClientCaFile
+	TlsClientAuth   TlsClientAuth `env:"TLS_CLIENT_AUTH"`
+	TlsClientCaFile string        `env:"TLS_CLIENT_CA_FILE"`
+	// TlsRedirectPort is the port of an HTTP listener that redirects all requests to APP_URL, it's disabled if empty
+	TlsRedirectPort string `env:"TLS_REDIRECT_PORT"`
+
+	// ConfigFile is the path of an optional TOML or YAML file whose keys are the names of the environment variables.
+	// The environment variables take precedence over the values in the file.
+	ConfigFile string `env:"CONFIG_FILE"`
+	// LogLevel is the minimum level of the logs, e.g. debug or warn
+	LogLevel slog.Level `env:"LOG_LEVEL" reload:"true"`
+	// CorsAllowedOrigins are the origins that browsers can call the OIDC endpoints from, all origins can if it's empty
+	CorsAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" reload:"true"`
+
+	// DbAutoMigrateDisabled makes the server refuse to start if migrations are pending instead of applying them
+	DbAutoMigrateDisabled bool `env:"DB_AUTO_MIGRATE_DISABLED"`
+
+	// DeclarativeConfigPath is the path of a YAML or JSON file with clients, groups and app config values that is
+	// applied at startup. The resources in it can't be changed in the UI.
+	DeclarativeConfigPath string `env:"DECLARATIVE_CONFIG_PATH"`
+
+	// AuditLogSinks is a comma-separated list of URLs that the audit logs are streamed to. They can contain credentials.
+	AuditLogSinks          []string `env:"AUDIT_LOG_SINKS" sensitive:"true"`
+	AuditLogSinkBufferSize int      `env:"AUDIT_LOG_SINK_BUFFER_SIZE"`
+	// AuditLogRetentionDays is the number of days the audit logs are kept, 0 keeps them forever
+	AuditLogRetentionDays int `env:"AUDIT_LOG_RETENTION_DAYS"`
+	// AuditLogRetentionDaysByEvent overrides the retention for single events, e.g. SIGN_IN:365,TOKEN_SIGN_IN:14
+	AuditLogRetentionDaysByEvent map[string]int `env:"AUDIT_LOG_RETENTION_DAYS_BY_EVENT"`
+	// AuditLogArchivePath is the directory the expired audit logs are archived to before they're deleted
+	AuditLogArchivePath string `env:"AUDIT_LOG_ARCHIVE_PATH"`
 }
 
-var EnvConfig = &EnvConfigSchema{
-	AppEnv:             "production",
-	DbProvider:         "sqlite",
-	DbConnectionString: "file:data/pocket-id.db?_pragma=journal_mode(WAL)&_pragma=busy_timeout(2500)&_txlock=immediate",
-	UploadPath:         "data/uploads",
-	KeysPath:           "data/keys",
-	AppURL:             "http://localhost:1411",
-	Port:               "1411",
-	Host:               "0.0.0.0",
-	UnixSocket:         "",
-	MaxMindLicenseKey:  "",
-	GeoLiteDBPath:      "data/GeoLite2-City.mmdb",
-	GeoLiteDBUrl:       MaxMindGeoLiteCityUrl,
-	UiConfigDisabled:   false,
-	MetricsEnabled:     false,
-	TracingEnabled:     false,
-	TrustProxy:         false,
-	AnalyticsDisabled:  false,
+var EnvConfig = defaultEnvConfig()
+
+// AuditLogEvents are the names of the audit log events that AUDIT_LOG_RETENTION_DAYS_BY_EVENT accepts.
+// They're the events of the model package, which can't be imported here; a test there keeps them in sync.
+var AuditLogEvents = []string{
+	"SIGN_IN",
+	"TOKEN_SIGN_IN",
+	"CLIENT_AUTHORIZATION",
+	"NEW_CLIENT_AUTHORIZATION",
+	"DEVICE_CODE_AUTHORIZATION",
+	"NEW_DEVICE_CODE_AUTHORIZATION",
+	"USER_DISABLED",
+	"SUDO_MODE_ELEVATION",
+	"SAML_SIGN_IN",
+	"IDENTITY_PROVIDER_SIGN_IN",
+	"LDAP_BIND_FAILED",
+	"USER_CREATED",
+	"USER_UPDATED",
+	"USER_DELETED",
+	"USER_GROUPS_UPDATED",
+	"ONE_TIME_ACCESS_TOKEN_CREATED",
+	"USER_GROUP_CREATED",
+	"USER_GROUP_UPDATED",
+	"USER_GROUP_DELETED",
+	"USER_GROUP_MEMBERS_UPDATED",
+	"OIDC_CLIENT_CREATED",
+	"OIDC_CLIENT_UPDATED",
+	"OIDC_CLIENT_DELETED",
+	"OIDC_CLIENT_SECRET_CREATED",
+	"OIDC_CLIENT_ALLOWED_GROUPS_UPDATED",
+	"API_KEY_CREATED",
+	"API_KEY_REVOKED",
+	"CUSTOM_CLAIMS_UPDATED",
+	"APP_CONFIG_UPDATED",
+}
+
+func defaultEnvConfig() *EnvConfigSchema {
+	return &EnvConfigSchema{
+		AppEnv:             "production",
+		DbProvider:         "sqlite",
+		DbConnectionString: "file:data/pocket-id.db?_pragma=journal_mode(WAL)&_pragma=busy_timeout(2500)&_txlock=immediate",
+		UploadPath:         "data/uploads",
+		KeysPath:           "data/keys",
+		AppURL:             "http://localhost:1411",
+		Port:               "1411",
+		Host:               "0.0.0.0",
+		UnixSocket:         "",
+		MaxMindLicenseKey:  "",
+		GeoLiteDBPath:      "data/GeoLite2-City.mmdb",
+		GeoLiteDBUrl:       MaxMindGeoLiteCityUrl,
+		UiConfigDisabled:   false,
+		MetricsEnabled:     false,
+		TracingEnabled:     false,
+		TrustProxy:         false,
+		AnalyticsDisabled:  false,
+		LdapServerEnabled:  false,
+		LdapServerPort:     "3890",
+		LdapServerBaseDN:   "",
+
+		LdapServerTls:         LdapServerTlsNone,
+		LdapServerTlsCertFile: "",
+		LdapServerTlsKeyFile:  "",
+
+		TlsCertFile:     "",
+		TlsKeyFile:      "",
+		TlsClientAuth:   TlsClientAuthNone,
+		TlsClientCaFile: "",
+		TlsRedirectPort: "",
+
+		ConfigFile:         "",
+		LogLevel:           slog.LevelInfo,
+		CorsAllowedOrigins: nil,
+
+		DbAutoMigrateDisabled: false,
+
+		DeclarativeConfigPath: "",
+
+		AuditLogSinks:          nil,
+		AuditLogSinkBufferSize: 1000,
+
+		AuditLogRetentionDays:        90,
+		AuditLogRetentionDaysByEvent: nil,
+		AuditLogArchivePath:          "",
+	}
 }
 
 func init() {
-	if err := env.ParseWithOptions(EnvConfig, env.Options{}); err != nil {
+	config, err := LoadEnvConfig()
+	if err != nil {
 		log.Fatal(err)
 	}
+	EnvConfig = config
+}
 
-	// Validate the environment variables
-	switch EnvConfig.DbProvider {
+// LoadEnvConfig reads the environment variables and the config file. It doesn't change EnvConfig, so that the
+// caller can decide which settings to apply when the configuration is reloaded.
+func LoadEnvConfig() (*EnvConfigSchema, error) {
+	var fileValues *map[string]string
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = &values
+	}
+
+	// The environment variables override the values in the config file
+	environment := make(map[string]string)
+	if fileValues != nil {
+		for key, value := range *fileValues {
+			environment[key] = value
+		}
+	}
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		environment[key] = value
+	}
+
+	config := defaultEnvConfig()
+	if err := env.ParseWithOptions(config, env.Options{Environment: environment}); err != nil {
+		return nil, err
+	}
+	if err := loadSensitiveEnvFromFiles(config, fileValues); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	// Only keep the values of the config file if it's valid, as the app config is also loaded from them
+	configFileValues.Store(fileValues)
+
+	return config, nil
+}
+
+func (config *EnvConfigSchema) validate() error {
+	switch config.DbProvider {
 	case DbProviderSqlite:
-		if EnvConfig.DbConnectionString == "" {
-			log.Fatal("Missing required env var 'DB_CONNECTION_STRING' for SQLite database")
+		if config.DbConnectionString == "" {
+			return errors.New("missing required env var 'DB_CONNECTION_STRING' for SQLite database")
 		}
 	case DbProviderPostgres:
-		if EnvConfig.DbConnectionString == "" {
-			log.Fatal("Missing required env var 'DB_CONNECTION_STRING' for Postgres database")
+		if config.DbConnectionString == "" {
+			return errors.New("missing required env var 'DB_CONNECTION_STRING' for Postgres database")
+		}
+	case DbProviderMysql:
+		if config.DbConnectionString == "" {
+			return errors.New("missing required env var 'DB_CONNECTION_STRING' for MySQL database")
 		}
 	default:
-		log.Fatal("Invalid DB_PROVIDER value. Must be 'sqlite' or 'postgres'")
+		return errors.New("invalid DB_PROVIDER value. Must be 'sqlite', 'postgres' or 'mysql'")
 	}
 
-	parsedAppUrl, err := url.Parse(EnvConfig.AppURL)
+	if config.AuditLogRetentionDays < 0 {
+		return errors.New("AUDIT_LOG_RETENTION_DAYS must not be negative")
+	}
+	for event, days := range config.AuditLogRetentionDaysByEvent {
+		if !slices.Contains(AuditLogEvents, event) {
+			return fmt.Errorf("unknown audit log event '%s' in AUDIT_LOG_RETENTION_DAYS_BY_EVENT", event)
+		}
+		if days < 0 {
+			return fmt.Errorf("the retention of the audit log event '%s' in AUDIT_LOG_RETENTION_DAYS_BY_EVENT must not be negative", event)
+		}
+	}
+
+	parsedAppUrl, err := url.Parse(config.AppURL)
 	if err != nil {
-		log.Fatal("APP_URL is not a valid URL")
+		return errors.New("APP_URL is not a valid URL")
 	}
 	if parsedAppUrl.Path != "" {
-		log.Fatal("APP_URL must not contain a path")
+		return errors.New("APP_URL must not contain a path")
+	}
+
+	if (config.TlsCertFile == "") != (config.TlsKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch config.TlsClientAuth {
+	case TlsClientAuthNone:
+	case TlsClientAuthOptional, TlsClientAuthRequired:
+		if config.TlsCertFile == "" || config.TlsClientCaFile == "" {
+			return errors.New("TLS_CLIENT_AUTH requires TLS_CERT_FILE, TLS_KEY_FILE and TLS_CLIENT_CA_FILE")
+		}
+	default:
+		return errors.New("invalid TLS_CLIENT_AUTH value. Must be 'none', 'optional' or 'required'")
+	}
+	if config.TlsRedirectPort != "" {
+		if config.TlsCertFile == "" {
+			return errors.New("TLS_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		// The redirect listener would redirect to itself otherwise
+		if parsedAppUrl.Scheme != "https" {
+			return errors.New("TLS_REDIRECT_PORT requires an https APP_URL")
+		}
+	}
+
+	if (config.LdapServerTlsCertFile == "") != (config.LdapServerTlsKeyFile == "") {
+		return errors.New("LDAP_SERVER_TLS_CERT_FILE and LDAP_SERVER_TLS_KEY_FILE must be set together")
+	}
+	if config.LdapServerTlsCertFile == "" {
+		config.LdapServerTlsCertFile = config.TlsCertFile
+		config.LdapServerTlsKeyFile = config.TlsKeyFile
+	}
+	switch config.LdapServerTls {
+	case LdapServerTlsNone:
+		// gldap doesn't expose the address of a connection, so failed binds couldn't be throttled per IP address
+		if config.LdapServerEnabled {
+			return errors.New("LDAP_SERVER_TLS must be 'starttls' if the LDAP server is enabled, as the IP addresses of the clients are only known from the StartTLS handshake")
+		}
+	case LdapServerTlsStartTls:
+		if config.LdapServerTlsCertFile == "" {
+			return errors.New("LDAP_SERVER_TLS requires LDAP_SERVER_TLS_CERT_FILE and LDAP_SERVER_TLS_KEY_FILE, or TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+	default:
+		return errors.New("invalid LDAP_SERVER_TLS value. Must be 'none' or 'starttls'")
+	}
+
+	// Derive the base DN of the LDAP server from the domain, e.g. id.example.com becomes dc=id,dc=example,dc=com
+	if config.LdapServerBaseDN == "" {
+		labels := strings.Split(parsedAppUrl.Hostname(), ".")
+		for i, label := range labels {
+			labels[i] = "dc=" + label
+		}
+		config.LdapServerBaseDN = strings.Join(labels, ",")
+	}
+
+	return nil
+}
+
+// LookupEnvOrFile returns the value of an environment variable or the config file. If the variable with the suffix _FILE is set instead,
+// e.g. by Docker or Kubernetes secrets, the value is read from the file it contains.
+func LookupEnvOrFile(name string) (value string, ok bool, err error) {
+	return lookupEnvOrFile(name, configFileValues.Load())
+}
+
+func lookupEnvOrFile(name string, fileValues *map[string]string) (value string, ok bool, err error) {
+	value, ok = lookupEnv(name, fileValues)
+	path, fileOk := lookupEnv(name+"_FILE", fileValues)
+	if !fileOk {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("only one of the environment variables %s and %s_FILE can be set", name, name)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the file in %s_FILE: %w", name, err)
+	}
+
+	// Editors usually add a trailing newline, which isn't part of the secret
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// loadSensitiveEnvFromFiles sets the sensitive fields whose value is in a file
+func loadSensitiveEnvFromFiles(config *EnvConfigSchema, fileValues *map[string]string) error {
+	rt := reflect.TypeOf(config).Elem()
+	rv := reflect.ValueOf(config).Elem()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if field.Tag.Get("sensitive") != "true" {
+			continue
+		}
+
+		value, ok, err := lookupEnvOrFile(field.Tag.Get("env"), fileValues)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		switch fieldValue := rv.Field(i); fieldValue.Kind() {
+		case reflect.String:
+			fieldValue.SetString(value)
+		case reflect.Slice:
+			// Lists are comma-separated, like in the environment variables
+			var values []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+			fieldValue.Set(reflect.ValueOf(values))
+		default:
+			return fmt.Errorf("unsupported type of sensitive field %s", field.Name)
+		}
 	}
+	return nil
 }
@@ -11,6 +11,12 @@ type AppError interface {
 	HttpStatusCode() int
 }
 
+// AppErrorWithCode is implemented by errors that the frontend needs to tell apart from other errors with the same status code
+type AppErrorWithCode interface {
+	AppError
+	ErrorCode() string
+}
+
 // Custom error types for various conditions
 
 type AlreadyInUseError struct {
@@ -196,6 +202,20 @@ func (e *LdapUserGroupUpdateError) Error() string {
 }
 func (e *LdapUserGroupUpdateError) HttpStatusCode() int { return http.StatusForbidden }
 
+type ScimUserUpdateError struct{}
+
+func (e *ScimUserUpdateError) Error() string {
+	return "Users provisioned over SCIM can't be updated"
+}
+func (e *ScimUserUpdateError) HttpStatusCode() int { return http.StatusForbidden }
+
+type ScimUserGroupUpdateError struct{}
+
+func (e *ScimUserGroupUpdateError) Error() string {
+	return "User groups provisioned over SCIM can't be updated"
+}
+func (e *ScimUserGroupUpdateError) HttpStatusCode() int { return http.StatusForbidden }
+
 type OidcAccessDeniedError struct{}
 
 func (e *OidcAccessDeniedError) Error() string {
@@ -203,6 +223,14 @@ func (e *OidcAccessDeniedError) Error() string {
 }
 func (e *OidcAccessDeniedError) HttpStatusCode() int { return http.StatusForbidden }
 
+type OidcReauthenticationRequiredError struct{}
+
+func (e *OidcReauthenticationRequiredError) Error() string {
+	return "The client requires you to sign in again with a passkey"
+}
+func (e *OidcReauthenticationRequiredError) HttpStatusCode() int { return http.StatusUnauthorized }
+func (e *OidcReauthenticationRequiredError) ErrorCode() string   { return "reauthentication_required" }
+
 type OidcClientIdNotMatchingError struct{}
 
 func (e *OidcClientIdNotMatchingError) Error() string {
@@ -224,6 +252,13 @@ func (e *UiConfigDisabledError) Error() string {
 }
 func (e *UiConfigDisabledError) HttpStatusCode() int { return http.StatusForbidden }
 
+type ConfigManagedError struct{}
+
+func (e *ConfigManagedError) Error() string {
+	return "Resources managed by the declarative configuration file can't be updated"
+}
+func (e *ConfigManagedError) HttpStatusCode() int { return http.StatusForbidden }
+
 type InvalidUUIDError struct{}
 
 func (e *InvalidUUIDError) Error() string {
@@ -349,3 +384,174 @@ func (e *OidcAuthorizationPendingError) Error() string {
 func (e *OidcAuthorizationPendingError) HttpStatusCode() int {
 	return http.StatusBadRequest
 }
+
+type SessionNotFoundError struct{}
+
+func (e *SessionNotFoundError) Error() string {
+	return "Session not found"
+}
+func (e *SessionNotFoundError) HttpStatusCode() int { return http.StatusNotFound }
+
+type ReauthenticationRequiredError struct{}
+
+func (e *ReauthenticationRequiredError) Error() string {
+	return "You need to re-authenticate with a passkey to perform this action"
+}
+func (e *ReauthenticationRequiredError) HttpStatusCode() int { return http.StatusPreconditionRequired }
+
+type SudoModeApiKeyError struct{}
+
+func (e *SudoModeApiKeyError) Error() string {
+	return "This action requires a recent passkey authentication, so it can't be performed with an API key"
+}
+func (e *SudoModeApiKeyError) HttpStatusCode() int { return http.StatusForbidden }
+
+type SamlInvalidRequestError struct {
+	Message string
+}
+
+func (e *SamlInvalidRequestError) Error() string {
+	return "Invalid SAML request: " + e.Message
+}
+func (e *SamlInvalidRequestError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type SamlAccessDeniedError struct{}
+
+func (e *SamlAccessDeniedError) Error() string {
+	return "You're not allowed to access this service"
+}
+func (e *SamlAccessDeniedError) HttpStatusCode() int { return http.StatusForbidden }
+
+type ScimInvalidFilterError struct {
+	Message string
+}
+
+func (e *ScimInvalidFilterError) Error() string {
+	return "Invalid SCIM filter: " + e.Message
+}
+func (e *ScimInvalidFilterError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type ScimInvalidPatchError struct {
+	Message string
+}
+
+func (e *ScimInvalidPatchError) Error() string {
+	return "Invalid SCIM patch operation: " + e.Message
+}
+func (e *ScimInvalidPatchError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type ScimResourceNotProvisionedError struct{}
+
+func (e *ScimResourceNotProvisionedError) Error() string {
+	return "Only users and groups provisioned over SCIM can be changed over SCIM"
+}
+func (e *ScimResourceNotProvisionedError) HttpStatusCode() int { return http.StatusConflict }
+
+type AppPasswordNotFoundError struct{}
+
+func (e *AppPasswordNotFoundError) Error() string {
+	return "App password not found"
+}
+func (e *AppPasswordNotFoundError) HttpStatusCode() int { return http.StatusNotFound }
+
+type LdapServerInvalidCredentialsError struct{}
+
+func (e *LdapServerInvalidCredentialsError) Error() string {
+	return "Invalid credentials"
+}
+func (e *LdapServerInvalidCredentialsError) HttpStatusCode() int { return http.StatusUnauthorized }
+
+type LdapServerTooManyBindsError struct{}
+
+func (e *LdapServerTooManyBindsError) Error() string {
+	return "Too many failed bind attempts, please try again later"
+}
+func (e *LdapServerTooManyBindsError) HttpStatusCode() int { return http.StatusTooManyRequests }
+
+type LdapServerInsufficientAccessError struct{}
+
+func (e *LdapServerInsufficientAccessError) Error() string {
+	return "You have to bind to search the directory"
+}
+func (e *LdapServerInsufficientAccessError) HttpStatusCode() int { return http.StatusForbidden }
+
+type LdapServerNoSuchObjectError struct{}
+
+func (e *LdapServerNoSuchObjectError) Error() string {
+	return "The base DN doesn't exist"
+}
+func (e *LdapServerNoSuchObjectError) HttpStatusCode() int { return http.StatusNotFound }
+
+type LdapServerInvalidFilterError struct {
+	Message string
+}
+
+func (e *LdapServerInvalidFilterError) Error() string {
+	return "Invalid search filter: " + e.Message
+}
+func (e *LdapServerInvalidFilterError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type ForwardAuthHostNotConfiguredError struct{}
+
+func (e *ForwardAuthHostNotConfiguredError) Error() string {
+	return "The host isn't protected by any OIDC client"
+}
+func (e *ForwardAuthHostNotConfiguredError) HttpStatusCode() int { return http.StatusForbidden }
+
+type ForwardAuthInvalidRedirectError struct{}
+
+func (e *ForwardAuthInvalidRedirectError) Error() string {
+	return "Invalid redirect URL"
+}
+func (e *ForwardAuthInvalidRedirectError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type ForwardAuthInvalidCodeError struct{}
+
+func (e *ForwardAuthInvalidCodeError) Error() string {
+	return "Invalid or expired forward-auth code"
+}
+func (e *ForwardAuthInvalidCodeError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type IdentityProviderNotFoundError struct{}
+
+func (e *IdentityProviderNotFoundError) Error() string {
+	return "Identity provider not found"
+}
+func (e *IdentityProviderNotFoundError) HttpStatusCode() int { return http.StatusNotFound }
+
+type IdentityProviderLoginError struct {
+	Message string
+}
+
+func (e *IdentityProviderLoginError) Error() string {
+	return "Sign in with the identity provider failed: " + e.Message
+}
+func (e *IdentityProviderLoginError) HttpStatusCode() int { return http.StatusBadRequest }
+
+type IdentityProviderUserNotLinkedError struct{}
+
+func (e *IdentityProviderUserNotLinkedError) Error() string {
+	return "Your account at the identity provider isn't linked to a user"
+}
+func (e *IdentityProviderUserNotLinkedError) HttpStatusCode() int { return http.StatusForbidden }
+
+type IdentityProviderAdminNotLinkedError struct{}
+
+func (e *IdentityProviderAdminNotLinkedError) Error() string {
+	return "Admins can only sign in with the identity provider after linking it on their account page"
+}
+func (e *IdentityProviderAdminNotLinkedError) HttpStatusCode() int { return http.StatusForbidden }
+
+type UserIdentityAlreadyLinkedError struct{}
+
+func (e *UserIdentityAlreadyLinkedError) Error() string {
+	return "An account of this identity provider is already linked"
+}
+func (e *UserIdentityAlreadyLinkedError) HttpStatusCode() int { return http.StatusConflict }
+
+type UserIdentityNotFoundError struct{}
+
+func (e *UserIdentityNotFoundError) Error() string {
+	return "Linked account not found"
+}
+func (e *UserIdentityNotFoundError) HttpStatusCode() int { return http.StatusNotFound }
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+// AppPasswordController manages the app passwords of authenticated users
+type AppPasswordController struct {
+	appPasswordService *service.AppPasswordService
+}
+
+// NewAppPasswordController creates a new controller for app password management
+// @Summary App password management controller
+// @Description Initializes API endpoints for managing app passwords
+// @Tags App Passwords
+func NewAppPasswordController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, appPasswordService *service.AppPasswordService) {
+	apc := &AppPasswordController{appPasswordService: appPasswordService}
+
+	appPasswordGroup := group.Group("/app-passwords")
+	appPasswordGroup.Use(authMiddleware.WithAdminNotRequired().Add())
+	{
+		appPasswordGroup.GET("", apc.listAppPasswordsHandler)
+		appPasswordGroup.POST("", apc.createAppPasswordHandler)
+		appPasswordGroup.DELETE("/:id", apc.deleteAppPasswordHandler)
+	}
+}
+
+// listAppPasswordsHandler godoc
+// @Summary List app passwords
+// @Description Get the app passwords of the current user
+// @Tags App Passwords
+// @Success 200 {array} dto.AppPasswordDto
+// @Router /api/app-passwords [get]
+func (apc *AppPasswordController) listAppPasswordsHandler(c *gin.Context) {
+	appPasswords, err := apc.appPasswordService.ListAppPasswords(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var appPasswordsDto []dto.AppPasswordDto
+	if err := dto.MapStructList(appPasswords, &appPasswordsDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appPasswordsDto)
+}
+
+// createAppPasswordHandler godoc
+// @Summary Create app password
+// @Description Create a new app password for the current user
+// @Tags App Passwords
+// @Param app_password body dto.AppPasswordCreateDto true "App password information"
+// @Success 201 {object} dto.AppPasswordResponseDto "Created app password with the password"
+// @Router /api/app-passwords [post]
+func (apc *AppPasswordController) createAppPasswordHandler(c *gin.Context) {
+	var input dto.AppPasswordCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	appPassword, password, err := apc.appPasswordService.CreateAppPassword(c.Request.Context(), c.GetString("userID"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var appPasswordDto dto.AppPasswordDto
+	if err := dto.MapStruct(appPassword, &appPasswordDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.AppPasswordResponseDto{
+		AppPassword: appPasswordDto,
+		Password:    password,
+	})
+}
+
+// deleteAppPasswordHandler godoc
+// @Summary Delete app password
+// @Description Delete an app password of the current user
+// @Tags App Passwords
+// @Param id path string true "App password ID"
+// @Success 204 "No Content"
+// @Router /api/app-passwords/{id} [delete]
+func (apc *AppPasswordController) deleteAppPasswordHandler(c *gin.Context) {
+	err := apc.appPasswordService.DeleteAppPassword(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
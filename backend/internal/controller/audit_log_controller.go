@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/pocket-id/pocket-id/backend/internal/dto"
 	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
 	"github.com/pocket-id/pocket-id/backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,19 +21,23 @@ import (
 // @Summary Audit log controller
 // @Description Initializes API endpoints for accessing audit logs
 // @Tags Audit Logs
-func NewAuditLogController(group *gin.RouterGroup, auditLogService *service.AuditLogService, authMiddleware *middleware.AuthMiddleware) {
+func NewAuditLogController(group *gin.RouterGroup, auditLogService *service.AuditLogService, auditLogChainService *service.AuditLogChainService, authMiddleware *middleware.AuthMiddleware) {
 	alc := AuditLogController{
-		auditLogService: auditLogService,
+		auditLogService:      auditLogService,
+		auditLogChainService: auditLogChainService,
 	}
 
 	group.GET("/audit-logs/all", authMiddleware.Add(), alc.listAllAuditLogsHandler)
+	group.GET("/audit-logs/export", authMiddleware.Add(), alc.exportAuditLogsHandler)
+	group.GET("/audit-logs/verify", authMiddleware.Add(), alc.verifyAuditLogsHandler)
 	group.GET("/audit-logs", authMiddleware.WithAdminNotRequired().Add(), alc.listAuditLogsForUserHandler)
 	group.GET("/audit-logs/filters/client-names", authMiddleware.Add(), alc.listClientNamesHandler)
 	group.GET("/audit-logs/filters/users", authMiddleware.Add(), alc.listUserNamesWithIdsHandler)
 }
 
 type AuditLogController struct {
-	auditLogService *service.AuditLogService
+	auditLogService      *service.AuditLogService
+	auditLogChainService *service.AuditLogChainService
 }
 
 // listAuditLogsForUserHandler godoc
@@ -89,6 +99,11 @@ func (alc *AuditLogController) listAuditLogsForUserHandler(c *gin.Context) {
 // @Param filters[userId] query string false "Filter by user ID"
 // @Param filters[event] query string false "Filter by event type"
 // @Param filters[clientName] query string false "Filter by client name"
+// @Param filters[from] query string false "Filter by the earliest time (RFC 3339)"
+// @Param filters[to] query string false "Filter by the latest time (RFC 3339)"
+// @Param filters[ipAddress] query string false "Filter by IP address or CIDR range"
+// @Param filters[country] query string false "Filter by country"
+// @Param filters[search] query string false "Search the event, IP address, location, user agent, username and data"
 // @Success 200 {object} dto.Paginated[dto.AuditLogDto]
 // @Router /api/audit-logs/all [get]
 func (alc *AuditLogController) listAllAuditLogsHandler(c *gin.Context) {
@@ -129,6 +144,199 @@ func (alc *AuditLogController) listAllAuditLogsHandler(c *gin.Context) {
 	})
 }
 
+// exportAuditLogsHandler godoc
+// @Summary Export audit logs
+// @Description Stream all audit logs that match the filters as CSV or JSON Lines, the oldest first (admin only)
+// @Tags Audit Logs
+// @Produce text/csv,application/x-ndjson
+// @Param format query string false "Export format (csv or jsonl)" default("csv")
+// @Param filters[userId] query string false "Filter by user ID"
+// @Param filters[event] query string false "Filter by event type"
+// @Param filters[clientName] query string false "Filter by client name"
+// @Param filters[from] query string false "Filter by the earliest time (RFC 3339)"
+// @Param filters[to] query string false "Filter by the latest time (RFC 3339)"
+// @Param filters[ipAddress] query string false "Filter by IP address or CIDR range"
+// @Param filters[country] query string false "Filter by country"
+// @Param filters[search] query string false "Search the event, IP address, location, user agent, username and data"
+// @Success 200 {file} file "Audit logs"
+// @Router /api/audit-logs/export [get]
+func (alc *AuditLogController) exportAuditLogsHandler(c *gin.Context) {
+	var input dto.AuditLogExportDto
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var filters dto.AuditLogFilterDto
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	format := input.Format
+	if format == "" {
+		format = "csv"
+	}
+
+	var csvWriter *csv.Writer
+	jsonEncoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		csvWriter = csv.NewWriter(c.Writer)
+	}
+
+	// The response is only started with the first audit log, so that errors of the query can still be returned as JSON
+	started := false
+	startResponse := func() error {
+		started = true
+
+		contentType := "text/csv; charset=utf-8"
+		if format == "jsonl" {
+			contentType = "application/x-ndjson"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="audit-logs-`+time.Now().UTC().Format("2006-01-02")+"."+format+`"`)
+		c.Status(http.StatusOK)
+
+		if csvWriter != nil {
+			return csvWriter.Write(auditLogExportCsvHeader)
+		}
+		return nil
+	}
+
+	count := 0
+	err := alc.auditLogService.ExportAuditLogs(c.Request.Context(), filters, func(auditLog model.AuditLog) error {
+		if !started {
+			if err := startResponse(); err != nil {
+				return err
+			}
+		}
+
+		record := dto.AuditLogExportRecordDto{
+			ID:        auditLog.ID,
+			CreatedAt: auditLog.CreatedAt.UTC(),
+			Event:     auditLog.Event,
+			UserID:    auditLog.UserID,
+			Username:  auditLog.User.Username,
+			IpAddress: auditLog.IpAddress,
+			Country:   auditLog.Country,
+			City:      auditLog.City,
+			Device:    alc.auditLogService.DeviceStringFromUserAgent(auditLog.UserAgent),
+			UserAgent: auditLog.UserAgent,
+			Data:      auditLog.Data,
+		}
+
+		var err error
+		if csvWriter != nil {
+			err = csvWriter.Write(auditLogExportCsvRow(record))
+		} else {
+			err = jsonEncoder.Encode(record)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Flush regularly, so that large exports are streamed to the client instead of being buffered
+		count++
+		if count%1000 == 0 {
+			if csvWriter != nil {
+				csvWriter.Flush()
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		// There are no audit logs that match the filters
+		err = startResponse()
+	}
+	if err != nil && !started {
+		_ = c.Error(err)
+		return
+	}
+	if err != nil {
+		// The response has already been started, so the export can only be aborted
+		log.Printf("Failed to export audit logs: %v", err)
+		return
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			log.Printf("Failed to export audit logs: %v", err)
+		}
+	}
+}
+
+var auditLogExportCsvHeader = []string{"id", "createdAt", "event", "userId", "username", "ipAddress", "country", "city", "device", "userAgent", "data"}
+
+func auditLogExportCsvRow(record dto.AuditLogExportRecordDto) []string {
+	// The data is exported as JSON, as its fields depend on the event
+	data, _ := json.Marshal(record.Data)
+
+	row := []string{
+		record.ID,
+		record.CreatedAt.Format(time.RFC3339),
+		string(record.Event),
+		record.UserID,
+		record.Username,
+		record.IpAddress,
+		record.Country,
+		record.City,
+		record.Device,
+		record.UserAgent,
+		string(data),
+	}
+	for i, cell := range row {
+		row[i] = escapeCsvFormula(cell)
+	}
+	return row
+}
+
+// escapeCsvFormula prefixes cells that spreadsheet applications would interpret as a formula with a single quote.
+// Values such as the username and user agent are controlled by users, so they could otherwise inject formulas.
+func escapeCsvFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// verifyAuditLogsHandler godoc
+// @Summary Verify audit logs
+// @Description Walk the tamper-evident chain of the audit logs and report the first broken link
+// @Tags Audit Logs
+// @Success 200 {object} dto.AuditLogChainVerificationDto
+// @Router /api/audit-logs/verify [get]
+func (alc *AuditLogController) verifyAuditLogsHandler(c *gin.Context) {
+	verification, err := alc.auditLogChainService.VerifyChain(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	verificationDto := dto.AuditLogChainVerificationDto{
+		Valid:              verification.Valid,
+		Links:              verification.Links,
+		PurgedLinks:        verification.PurgedLinks,
+		Checkpoints:        verification.Checkpoints,
+		SkippedCheckpoints: verification.SkippedCheckpoints,
+		UnsignedLinks:      verification.UnsignedLinks,
+	}
+	if verification.LastCheckpoint != nil {
+		lastCheckpointAt := verification.LastCheckpoint.CreatedAt.UTC()
+		verificationDto.LastCheckpointAt = &lastCheckpointAt
+	}
+	if verification.BrokenLink != nil {
+		verificationDto.BrokenLink = &dto.AuditLogChainBrokenLinkDto{
+			Sequence:   verification.BrokenLink.Sequence,
+			AuditLogID: verification.BrokenLink.AuditLogID,
+			Reason:     verification.BrokenLink.Reason,
+		}
+	}
+
+	c.JSON(http.StatusOK, verificationDto)
+}
+
 // listClientNamesHandler godoc
 // @Summary List client names
 // @Description Get a list of all client names for audit log filtering
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+func TestAuditLogExportCsvRowEscapesFormulas(t *testing.T) {
+	row := auditLogExportCsvRow(dto.AuditLogExportRecordDto{
+		ID:        "id",
+		CreatedAt: time.Date(2025, 6, 15, 13, 0, 0, 0, time.UTC),
+		Event:     model.AuditLogEventSignIn,
+		Username:  "=HYPERLINK(\"https://example.com\")",
+		IpAddress: "::1",
+		Country:   "+1",
+		City:      "-1",
+		Device:    "@SUM(A1)",
+		UserAgent: "\tcmd",
+		Data:      model.AuditLogData{"clientName": "Wiki"},
+	})
+
+	assert.Equal(t, []string{
+		"id",
+		"2025-06-15T13:00:00Z",
+		"SIGN_IN",
+		"",
+		"'=HYPERLINK(\"https://example.com\")",
+		"::1",
+		"'+1",
+		"'-1",
+		"'@SUM(A1)",
+		"'\tcmd",
+		`{"clientName":"Wiki"}`,
+	}, row)
+	assert.Equal(t, "'\rcmd", escapeCsvFormula("\rcmd"))
+	assert.Equal(t, "Firefox", escapeCsvFormula("Firefox"))
+}
@@ -34,6 +34,12 @@ func (tc *TestController) resetAndSeedHandler(c *gin.Context) {
 
 	skipLdap := c.Query("skip-ldap") == "true"
 
+	sessions, err := tc.TestService.ListSessions()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	if err := tc.TestService.ResetDatabase(); err != nil {
 		_ = c.Error(err)
 		return
@@ -49,6 +55,11 @@ func (tc *TestController) resetAndSeedHandler(c *gin.Context) {
 		return
 	}
 
+	if err := tc.TestService.RestoreSessions(sessions); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	if err := tc.TestService.ResetAppConfig(c.Request.Context()); err != nil {
 		_ = c.Error(err)
 		return
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/cookie"
+)
+
+// NewForwardAuthController creates a new controller for the forward-auth endpoints
+// The verify and callback endpoints are called by the reverse proxy for every request to a protected host, so they aren't rate-limited
+// @Summary Forward-auth controller
+// @Description Initializes the endpoints that reverse proxies use to protect hosts with Pocket ID
+// @Tags Forward Auth
+func NewForwardAuthController(r *gin.Engine, group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, jwtAuthMiddleware *middleware.JwtAuthMiddleware, forwardAuthService *service.ForwardAuthService) {
+	fc := &ForwardAuthController{
+		jwtAuthMiddleware:  jwtAuthMiddleware,
+		forwardAuthService: forwardAuthService,
+	}
+
+	r.GET("/api/forward-auth", fc.verifyHandler)
+	r.GET("/api/forward-auth/callback", fc.callbackHandler)
+
+	group.POST("/forward-auth/authorize", authMiddleware.WithAdminNotRequired().Add(), fc.authorizeHandler)
+}
+
+type ForwardAuthController struct {
+	jwtAuthMiddleware  *middleware.JwtAuthMiddleware
+	forwardAuthService *service.ForwardAuthService
+}
+
+// verifyHandler godoc
+// @Summary Verify forward-auth request
+// @Description Called by the reverse proxy (e.g. Traefik's forwardAuth, Caddy's forward_auth or nginx's auth_request) for every request to a protected host.
+// @Description Responds with the identity of the user in the Remote-User, Remote-Email, Remote-Name and Remote-Groups headers if access is granted.
+// @Description Unauthenticated requests are redirected to the sign in page, or rejected with 401 if the request was made by nginx.
+// @Tags Forward Auth
+// @Param X-Forwarded-Proto header string false "Scheme of the original request"
+// @Param X-Forwarded-Host header string false "Host of the original request"
+// @Param X-Forwarded-Uri header string false "URI of the original request"
+// @Param X-Original-URL header string false "URL of the original request, as sent by nginx"
+// @Success 200 "Access granted"
+// @Failure 302 "Redirect to the sign in page"
+// @Failure 401 "Not signed in"
+// @Failure 403 "Access denied"
+// @Router /api/forward-auth [get]
+func (fc *ForwardAuthController) verifyHandler(c *gin.Context) {
+	originalURL, fromNginx, err := forwardedURL(c)
+	if err != nil {
+		_ = c.Error(&common.ForwardAuthInvalidRedirectError{})
+		return
+	}
+	host := strings.ToLower(originalURL.Host)
+
+	// Traefik and Caddy send the callback request to the forward-auth endpoint too
+	if originalURL.Path == service.ForwardAuthCallbackPath {
+		fc.exchangeCode(c, host, originalURL.Query())
+		return
+	}
+
+	user, err := fc.jwtAuthMiddleware.VerifyForwardAuth(c, host)
+	var notSignedInErr *common.NotSignedInError
+	if errors.As(err, &notSignedInErr) {
+		if fromNginx {
+			// nginx can't forward redirects of auth_request, so it has to redirect to the sign in page itself
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.Redirect(http.StatusFound, common.EnvConfig.AppURL+"/forward-auth?rd="+url.QueryEscape(originalURL.String()))
+		return
+	} else if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = fc.forwardAuthService.VerifyAccess(c.Request.Context(), user, host)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	groups := make([]string, len(user.UserGroups))
+	for i, group := range user.UserGroups {
+		groups[i] = group.Name
+	}
+
+	c.Header("Remote-User", user.Username)
+	c.Header("Remote-Email", user.Email)
+	c.Header("Remote-Name", strings.TrimSpace(user.FullName()))
+	c.Header("Remote-Groups", strings.Join(groups, ","))
+	c.Status(http.StatusOK)
+}
+
+// callbackHandler godoc
+// @Summary Forward-auth callback
+// @Description Sets the forward-auth cookie on the protected host and redirects back to the original URL.
+// @Description Reverse proxies that don't send the callback to the verify endpoint (like nginx) have to proxy the callback path of the protected host to this endpoint.
+// @Tags Forward Auth
+// @Param code query string true "One-time code"
+// @Param rd query string false "URL to redirect to"
+// @Success 302 "Redirect to the original URL"
+// @Router /api/forward-auth/callback [get]
+func (fc *ForwardAuthController) callbackHandler(c *gin.Context) {
+	originalURL, _, err := forwardedURL(c)
+	if err != nil {
+		_ = c.Error(&common.ForwardAuthInvalidRedirectError{})
+		return
+	}
+
+	fc.exchangeCode(c, strings.ToLower(originalURL.Host), c.Request.URL.Query())
+}
+
+// authorizeHandler godoc
+// @Summary Authorize forward-auth
+// @Description Create a one-time code that signs the current user in to a host protected by forward-auth
+// @Tags Forward Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForwardAuthAuthorizeDto true "URL of the protected host"
+// @Success 200 {object} dto.ForwardAuthAuthorizeResponseDto
+// @Router /api/forward-auth/authorize [post]
+func (fc *ForwardAuthController) authorizeHandler(c *gin.Context) {
+	var input dto.ForwardAuthAuthorizeDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	callbackURL, err := fc.forwardAuthService.Authorize(c.Request.Context(), c.GetString("userID"), c.GetString("sessionID"), input.RedirectURL)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ForwardAuthAuthorizeResponseDto{CallbackURL: callbackURL})
+}
+
+func (fc *ForwardAuthController) exchangeCode(c *gin.Context, host string, query url.Values) {
+	token, expiresAt, err := fc.forwardAuthService.ExchangeCode(c.Request.Context(), query.Get("code"), host)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	cookie.AddForwardAuthCookie(c, int(time.Until(expiresAt).Seconds()), token)
+
+	// Only redirect to the protected host to prevent open redirects
+	redirectURL := "/"
+	rd, err := url.Parse(query.Get("rd"))
+	if err == nil && strings.EqualFold(rd.Host, host) && (rd.Scheme == "http" || rd.Scheme == "https") {
+		redirectURL = rd.String()
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// forwardedURL reconstructs the URL of the request that the reverse proxy is asking about
+func forwardedURL(c *gin.Context) (u *url.URL, fromNginx bool, err error) {
+	if originalURL := c.GetHeader("X-Original-URL"); originalURL != "" {
+		u, err = url.Parse(originalURL)
+		if err != nil || u.Host == "" {
+			return nil, false, errors.New("invalid X-Original-URL header")
+		}
+		return u, true, nil
+	}
+
+	proto := c.GetHeader("X-Forwarded-Proto")
+	if proto == "" {
+		proto = "http"
+		if c.Request.TLS != nil {
+			proto = "https"
+		}
+	}
+
+	host := c.GetHeader("X-Forwarded-Host")
+	if host == "" {
+		host = c.Request.Host
+	}
+
+	uri := c.GetHeader("X-Forwarded-Uri")
+	if uri == "" {
+		uri = c.Request.URL.RequestURI()
+	}
+
+	u, err = url.Parse(proto + "://" + host + uri)
+	if err != nil || u.Host == "" {
+		return nil, false, errors.New("invalid forwarded headers")
+	}
+	return u, false, nil
+}
@@ -0,0 +1,331 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/cookie"
+)
+
+// NewIdentityProviderController creates a new controller for the upstream identity providers
+// @Summary Identity provider controller
+// @Description Initializes the endpoints to manage identity providers, sign in with them and link them to accounts
+// @Tags Identity Providers
+func NewIdentityProviderController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, identityProviderService *service.IdentityProviderService) {
+	ipc := &IdentityProviderController{identityProviderService: identityProviderService}
+
+	group.GET("/identity-providers/enabled", ipc.listEnabledProvidersHandler)
+	group.GET("/identity-providers/callback", ipc.callbackHandler)
+	group.GET("/identity-providers/:id/authorize", ipc.authorizeHandler)
+	group.GET("/identity-providers/:id/link", authMiddleware.WithAdminNotRequired().Add(), ipc.linkHandler)
+
+	group.GET("/identity-providers", authMiddleware.Add(), ipc.listProvidersHandler)
+	group.POST("/identity-providers", authMiddleware.Add(), ipc.createProviderHandler)
+	group.GET("/identity-providers/:id", authMiddleware.Add(), ipc.getProviderHandler)
+	group.PUT("/identity-providers/:id", authMiddleware.Add(), ipc.updateProviderHandler)
+	group.DELETE("/identity-providers/:id", authMiddleware.Add(), ipc.deleteProviderHandler)
+
+	group.GET("/user-identities", authMiddleware.WithAdminNotRequired().Add(), ipc.listUserIdentitiesHandler)
+	group.DELETE("/user-identities/:id", authMiddleware.WithAdminNotRequired().Add(), ipc.deleteUserIdentityHandler)
+}
+
+type IdentityProviderController struct {
+	identityProviderService *service.IdentityProviderService
+}
+
+// listEnabledProvidersHandler godoc
+// @Summary List enabled identity providers
+// @Description Get the identity providers that users can sign in with
+// @Tags Identity Providers
+// @Produce json
+// @Success 200 {array} dto.IdentityProviderMinimalDto
+// @Router /api/identity-providers/enabled [get]
+func (ipc *IdentityProviderController) listEnabledProvidersHandler(c *gin.Context) {
+	providers, err := ipc.identityProviderService.ListEnabledProviders(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var providersDto []dto.IdentityProviderMinimalDto
+	if err := dto.MapStructList(providers, &providersDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, providersDto)
+}
+
+// authorizeHandler godoc
+// @Summary Sign in with identity provider
+// @Description Redirect to the identity provider to sign in
+// @Tags Identity Providers
+// @Param id path string true "Identity provider ID"
+// @Param redirect query string false "Path to redirect to after signing in"
+// @Success 302 "Redirect to the identity provider"
+// @Router /api/identity-providers/{id}/authorize [get]
+func (ipc *IdentityProviderController) authorizeHandler(c *gin.Context) {
+	ipc.beginLogin(c, "", c.Query("redirect"), "/login")
+}
+
+// linkHandler godoc
+// @Summary Link identity provider
+// @Description Redirect to the identity provider to link the account there to the current user
+// @Tags Identity Providers
+// @Param id path string true "Identity provider ID"
+// @Success 302 "Redirect to the identity provider"
+// @Router /api/identity-providers/{id}/link [get]
+func (ipc *IdentityProviderController) linkHandler(c *gin.Context) {
+	ipc.beginLogin(c, c.GetString("userID"), "", "/settings/account")
+}
+
+func (ipc *IdentityProviderController) beginLogin(c *gin.Context, userID string, redirectPath string, errorPath string) {
+	authorizationURL, state, err := ipc.identityProviderService.BeginLogin(c.Request.Context(), c.Param("id"), userID, redirectPath)
+	if err != nil {
+		redirectWithIdentityProviderError(c, errorPath, err)
+		return
+	}
+
+	// The state is bound to the browser to prevent login CSRF
+	cookie.AddIdentityProviderStateCookie(c, int((10 * time.Minute).Seconds()), state)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// callbackHandler godoc
+// @Summary Identity provider callback
+// @Description Handles the response of the identity provider and signs in the user or links the account to the current user
+// @Tags Identity Providers
+// @Param state query string true "State"
+// @Param code query string false "Authorization code"
+// @Param error query string false "Error returned by the identity provider"
+// @Success 302 "Redirect to the application"
+// @Router /api/identity-providers/callback [get]
+func (ipc *IdentityProviderController) callbackHandler(c *gin.Context) {
+	state := c.Query("state")
+	stateCookie, _ := c.Cookie(cookie.IdentityProviderStateCookieName)
+	cookie.AddIdentityProviderStateCookie(c, -1, "")
+
+	if state == "" || stateCookie != state {
+		redirectWithIdentityProviderError(c, "/login", &common.IdentityProviderLoginError{Message: "invalid state"})
+		return
+	}
+
+	code := c.Query("code")
+	var upstreamErr error
+	if errorCode := c.Query("error"); errorCode != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			message = errorCode
+		}
+		upstreamErr = &common.IdentityProviderLoginError{Message: message}
+		// The state is still consumed, so that it can't be used again
+		code = ""
+	}
+
+	result, err := ipc.identityProviderService.FinishLogin(c.Request.Context(), state, code, c.ClientIP(), c.Request.UserAgent())
+	if upstreamErr != nil {
+		err = upstreamErr
+	}
+	if err != nil {
+		errorPath := "/login"
+		if result.Linking {
+			errorPath = "/settings/account"
+		}
+		redirectWithIdentityProviderError(c, errorPath, err)
+		return
+	}
+
+	if result.AccessToken != "" {
+		maxAge := int(time.Until(result.Session.ExpiresAt.ToTime()).Seconds())
+		cookie.AddAccessTokenCookie(c, maxAge, result.AccessToken)
+	}
+
+	c.Redirect(http.StatusFound, common.EnvConfig.AppURL+result.RedirectPath)
+}
+
+// listProvidersHandler godoc
+// @Summary List identity providers
+// @Description Get all identity providers
+// @Tags Identity Providers
+// @Produce json
+// @Success 200 {array} dto.IdentityProviderDto
+// @Router /api/identity-providers [get]
+func (ipc *IdentityProviderController) listProvidersHandler(c *gin.Context) {
+	providers, err := ipc.identityProviderService.ListProviders(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var providersDto []dto.IdentityProviderDto
+	if err := dto.MapStructList(providers, &providersDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, providersDto)
+}
+
+// getProviderHandler godoc
+// @Summary Get identity provider
+// @Description Get an identity provider by its ID
+// @Tags Identity Providers
+// @Produce json
+// @Param id path string true "Identity provider ID"
+// @Success 200 {object} dto.IdentityProviderDto
+// @Router /api/identity-providers/{id} [get]
+func (ipc *IdentityProviderController) getProviderHandler(c *gin.Context) {
+	provider, err := ipc.identityProviderService.GetProvider(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var providerDto dto.IdentityProviderDto
+	if err := dto.MapStruct(provider, &providerDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, providerDto)
+}
+
+// createProviderHandler godoc
+// @Summary Create identity provider
+// @Description Create a new upstream OIDC provider that users can sign in with
+// @Tags Identity Providers
+// @Accept json
+// @Produce json
+// @Param provider body dto.IdentityProviderCreateDto true "Identity provider"
+// @Success 201 {object} dto.IdentityProviderDto
+// @Router /api/identity-providers [post]
+func (ipc *IdentityProviderController) createProviderHandler(c *gin.Context) {
+	var input dto.IdentityProviderCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	provider, err := ipc.identityProviderService.CreateProvider(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var providerDto dto.IdentityProviderDto
+	if err := dto.MapStruct(provider, &providerDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, providerDto)
+}
+
+// updateProviderHandler godoc
+// @Summary Update identity provider
+// @Description Update an identity provider. The client secret is only changed if a new one is provided.
+// @Tags Identity Providers
+// @Accept json
+// @Produce json
+// @Param id path string true "Identity provider ID"
+// @Param provider body dto.IdentityProviderCreateDto true "Identity provider"
+// @Success 200 {object} dto.IdentityProviderDto
+// @Router /api/identity-providers/{id} [put]
+func (ipc *IdentityProviderController) updateProviderHandler(c *gin.Context) {
+	var input dto.IdentityProviderCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	provider, err := ipc.identityProviderService.UpdateProvider(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var providerDto dto.IdentityProviderDto
+	if err := dto.MapStruct(provider, &providerDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, providerDto)
+}
+
+// deleteProviderHandler godoc
+// @Summary Delete identity provider
+// @Description Delete an identity provider and unlink all accounts of it
+// @Tags Identity Providers
+// @Param id path string true "Identity provider ID"
+// @Success 204 "No Content"
+// @Router /api/identity-providers/{id} [delete]
+func (ipc *IdentityProviderController) deleteProviderHandler(c *gin.Context) {
+	if err := ipc.identityProviderService.DeleteProvider(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listUserIdentitiesHandler godoc
+// @Summary List linked accounts
+// @Description Get the accounts at identity providers that are linked to the current user
+// @Tags Identity Providers
+// @Produce json
+// @Success 200 {array} dto.UserIdentityDto
+// @Router /api/user-identities [get]
+func (ipc *IdentityProviderController) listUserIdentitiesHandler(c *gin.Context) {
+	identities, err := ipc.identityProviderService.ListUserIdentities(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var identitiesDto []dto.UserIdentityDto
+	if err := dto.MapStructList(identities, &identitiesDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, identitiesDto)
+}
+
+// deleteUserIdentityHandler godoc
+// @Summary Unlink account
+// @Description Unlink an account at an identity provider from the current user
+// @Tags Identity Providers
+// @Param id path string true "Linked account ID"
+// @Success 204 "No Content"
+// @Router /api/user-identities/{id} [delete]
+func (ipc *IdentityProviderController) deleteUserIdentityHandler(c *gin.Context) {
+	if err := ipc.identityProviderService.DeleteUserIdentity(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// redirectWithIdentityProviderError redirects to a page of the frontend that shows the error
+func redirectWithIdentityProviderError(c *gin.Context, path string, err error) {
+	message := "Something went wrong"
+	var appErr common.AppError
+	if errors.As(err, &appErr) {
+		message = appErr.Error()
+	} else {
+		log.Printf("Failed to sign in with identity provider: %v", err)
+	}
+
+	c.Redirect(http.StatusFound, common.EnvConfig.AppURL+path+"?error="+url.QueryEscape(message))
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/jimlambrt/gldap"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+// LdapServerController handles the requests of the read-only LDAP server
+type LdapServerController struct {
+	ldapServerService *service.LdapServerService
+	// startTLSConfig is used to upgrade the connections with StartTLS, binds are only allowed after the upgrade
+	startTLSConfig *tls.Config
+
+	// Maps the IDs of the connections to the IDs of the users that are bound to them
+	boundUsers map[int]string
+	// Maps the IDs of the connections that were upgraded with StartTLS to the IP addresses of their clients
+	tlsConnections map[int]string
+	connectionsMu  sync.RWMutex
+}
+
+// NewLdapServerController registers the handlers of the LDAP server on the mux.
+// StartTLS is required before binding, as gldap doesn't expose the address of a connection and the IP address of the
+// client is only known from the TLS handshake. Failed binds couldn't be throttled per IP address otherwise.
+func NewLdapServerController(mux *gldap.Mux, ldapServerService *service.LdapServerService, startTLSConfig *tls.Config) (*LdapServerController, error) {
+	if startTLSConfig == nil {
+		return nil, errors.New("the LDAP server requires a StartTLS configuration")
+	}
+
+	lc := &LdapServerController{
+		ldapServerService: ldapServerService,
+		startTLSConfig:    startTLSConfig,
+		boundUsers:        make(map[int]string),
+		tlsConnections:    make(map[int]string),
+	}
+
+	err := errors.Join(
+		mux.ExtendedOperation(lc.startTLSHandler, gldap.ExtendedOperationStartTLS),
+		mux.Bind(lc.bindHandler),
+		mux.Search(lc.searchHandler),
+		mux.Unbind(lc.unbindHandler),
+		mux.Add(lc.readOnlyHandler(gldap.ApplicationAddResponse)),
+		mux.Modify(lc.readOnlyHandler(gldap.ApplicationModifyResponse)),
+		mux.Delete(lc.readOnlyHandler(gldap.ApplicationDelResponse)),
+		mux.DefaultRoute(lc.readOnlyHandler(gldap.ApplicationExtendedResponse)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return lc, nil
+}
+
+// OnClose forgets the state of a closed connection
+func (lc *LdapServerController) OnClose(connectionID int) {
+	lc.connectionsMu.Lock()
+	defer lc.connectionsMu.Unlock()
+	delete(lc.boundUsers, connectionID)
+	delete(lc.tlsConnections, connectionID)
+}
+
+// unbind forgets the bound user of a connection
+func (lc *LdapServerController) unbind(connectionID int) {
+	lc.connectionsMu.Lock()
+	defer lc.connectionsMu.Unlock()
+	delete(lc.boundUsers, connectionID)
+}
+
+func (lc *LdapServerController) startTLSHandler(w *gldap.ResponseWriter, r *gldap.Request) {
+	lc.connectionsMu.RLock()
+	_, alreadyTLS := lc.tlsConnections[r.ConnectionID()]
+	lc.connectionsMu.RUnlock()
+	if alreadyTLS {
+		_ = w.Write(r.NewResponse(
+			gldap.WithApplicationCode(gldap.ApplicationExtendedResponse),
+			gldap.WithResponseCode(gldap.ResultOperationsError),
+			gldap.WithDiagnosticMessage("TLS is already established"),
+		))
+		return
+	}
+
+	// The response is sent in plain text, then the TLS handshake starts
+	res := r.NewExtendedResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+	res.SetResponseName(gldap.ExtendedOperationStartTLS)
+	err := w.Write(res)
+	if err != nil {
+		return
+	}
+
+	// The handshake is the only place where the address of the connection is exposed
+	var clientIP string
+	tlsConfig := lc.startTLSConfig.Clone()
+	tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		host, _, err := net.SplitHostPort(hello.Conn.RemoteAddr().String())
+		if err != nil {
+			return nil, err
+		}
+		clientIP = host
+		return nil, nil
+	}
+
+	err = r.StartTLS(tlsConfig)
+	if err != nil {
+		log.Printf("LDAP StartTLS handshake failed: %v", err)
+		return
+	}
+
+	lc.connectionsMu.Lock()
+	lc.tlsConnections[r.ConnectionID()] = clientIP
+	lc.connectionsMu.Unlock()
+}
+
+func (lc *LdapServerController) bindHandler(w *gldap.ResponseWriter, r *gldap.Request) {
+	res := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer func() {
+		_ = w.Write(res)
+	}()
+
+	// A new bind resets the authentication state of the connection, even if it fails
+	lc.unbind(r.ConnectionID())
+
+	// The password must not be sent in plain text
+	lc.connectionsMu.RLock()
+	clientIP, isTLS := lc.tlsConnections[r.ConnectionID()]
+	lc.connectionsMu.RUnlock()
+	if !isTLS {
+		res.SetResultCode(gldap.ResultConfidentialityRequired)
+		res.SetDiagnosticMessage("StartTLS is required before binding")
+		return
+	}
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil || m.AuthChoice != gldap.SimpleAuthChoice {
+		res.SetResultCode(gldap.ResultAuthMethodNotSupported)
+		return
+	}
+
+	userID, err := lc.ldapServerService.Bind(context.Background(), m.UserName, string(m.Password), clientIP)
+	if err != nil {
+		res.SetResultCode(ldapResultCode(err))
+		return
+	}
+
+	if userID != "" {
+		lc.connectionsMu.Lock()
+		lc.boundUsers[r.ConnectionID()] = userID
+		lc.connectionsMu.Unlock()
+	}
+	res.SetResultCode(gldap.ResultSuccess)
+}
+
+func (lc *LdapServerController) searchHandler(w *gldap.ResponseWriter, r *gldap.Request) {
+	res := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultOperationsError))
+	defer func() {
+		_ = w.Write(res)
+	}()
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		res.SetResultCode(gldap.ResultProtocolError)
+		return
+	}
+
+	lc.connectionsMu.RLock()
+	userID := lc.boundUsers[r.ConnectionID()]
+	lc.connectionsMu.RUnlock()
+
+	entries, truncated, err := lc.ldapServerService.Search(context.Background(), userID, service.LdapSearchRequest{
+		BaseDN:     m.BaseDN,
+		Scope:      int64(m.Scope),
+		Filter:     m.Filter,
+		Attributes: m.Attributes,
+		SizeLimit:  m.SizeLimit,
+		TypesOnly:  m.TypesOnly,
+	})
+	if err != nil {
+		code := ldapResultCode(err)
+		res.SetResultCode(code)
+		if code != gldap.ResultOperationsError {
+			res.SetDiagnosticMessage(err.Error())
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		e := r.NewSearchResponseEntry(entry.DN)
+		for _, attr := range entry.Attributes {
+			e.AddAttribute(attr.Name, attr.Values)
+		}
+		if err := w.Write(e); err != nil {
+			log.Printf("Failed to write LDAP search result: %v", err)
+			return
+		}
+	}
+
+	if truncated {
+		res.SetResultCode(gldap.ResultSizeLimitExceeded)
+		return
+	}
+	res.SetResultCode(gldap.ResultSuccess)
+}
+
+func (lc *LdapServerController) unbindHandler(_ *gldap.ResponseWriter, r *gldap.Request) {
+	lc.unbind(r.ConnectionID())
+}
+
+// readOnlyHandler rejects all operations that would change the directory
+func (lc *LdapServerController) readOnlyHandler(applicationCode int) gldap.HandlerFunc {
+	return func(w *gldap.ResponseWriter, r *gldap.Request) {
+		_ = w.Write(r.NewResponse(
+			gldap.WithApplicationCode(applicationCode),
+			gldap.WithResponseCode(gldap.ResultUnwillingToPerform),
+			gldap.WithDiagnosticMessage("The directory is read-only"),
+		))
+	}
+}
+
+func ldapResultCode(err error) int {
+	var invalidCredentialsErr *common.LdapServerInvalidCredentialsError
+	var tooManyBindsErr *common.LdapServerTooManyBindsError
+	var insufficientAccessErr *common.LdapServerInsufficientAccessError
+	var noSuchObjectErr *common.LdapServerNoSuchObjectError
+	var invalidFilterErr *common.LdapServerInvalidFilterError
+
+	switch {
+	case errors.As(err, &invalidCredentialsErr):
+		return gldap.ResultInvalidCredentials
+	case errors.As(err, &tooManyBindsErr):
+		return gldap.ResultUnwillingToPerform
+	case errors.As(err, &insufficientAccessErr):
+		return gldap.ResultInsufficientAccessRights
+	case errors.As(err, &noSuchObjectErr):
+		return gldap.ResultNoSuchObject
+	case errors.As(err, &invalidFilterErr):
+		return gldap.ResultProtocolError
+	default:
+		log.Printf("LDAP server error: %v", err)
+		return gldap.ResultOperationsError
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang-migrate/migrate/v4"
+	sqliteMigrate "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jimlambrt/gldap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+	"github.com/pocket-id/pocket-id/backend/resources"
+)
+
+func TestLdapServerControllerStartTLS(t *testing.T) {
+	mux, err := gldap.NewMux()
+	require.NoError(t, err)
+	db := newDatabaseForTest(t)
+	appConfigService := service.NewAppConfigService(t.Context(), db)
+	auditLogService := service.NewAuditLogService(db, appConfigService, nil, service.NewGeoLiteService(nil), service.NewWebhookService(db, nil), nil, nil)
+	ldapServerService := service.NewLdapServerService(db, nil, nil, auditLogService, "dc=example,dc=com")
+
+	// The server doesn't start without StartTLS, as the IP addresses of the clients are only known from the handshake
+	_, err = NewLdapServerController(mux, ldapServerService, nil)
+	require.Error(t, err)
+
+	lc, err := NewLdapServerController(mux, ldapServerService, &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t)}})
+	require.NoError(t, err)
+
+	server, err := gldap.NewServer(gldap.WithOnClose(lc.OnClose))
+	require.NoError(t, err)
+	require.NoError(t, server.Router(mux))
+
+	// Get a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	go func() {
+		_ = server.Run(addr)
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+	require.Eventually(t, server.Ready, 5*time.Second, 10*time.Millisecond)
+
+	conn, err := ldap.DialURL("ldap://" + addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The password must not be sent before the connection is upgraded
+	err = conn.Bind("uid=tim,ou=people,dc=other", "secret")
+	require.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultConfidentialityRequired), "unexpected error: %v", err)
+
+	require.NoError(t, conn.StartTLS(&tls.Config{InsecureSkipVerify: true})) //nolint:gosec
+
+	err = conn.Bind("uid=tim,ou=people,dc=other", "secret")
+	require.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials), "unexpected error: %v", err)
+
+	// The failed bind is audited with the IP address of the client
+	var auditLog model.AuditLog
+	require.NoError(t, db.Where("event = ?", model.AuditLogEventLdapBindFailed).First(&auditLog).Error)
+	assert.Equal(t, "127.0.0.1", auditLog.IpAddress)
+	assert.Equal(t, "uid=tim,ou=people,dc=other", auditLog.Data["bindDn"])
+}
+
+func newTestCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}
+}
+
+// newDatabaseForTest returns a new in-memory SQLite database with the embedded migrations applied
+func newDatabaseForTest(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+utils.CreateSha256Hash(t.Name())+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err, "Failed to connect to test database")
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err, "Failed to get sql.DB")
+	driver, err := sqliteMigrate.WithInstance(sqlDB, &sqliteMigrate.Config{})
+	require.NoError(t, err, "Failed to create migration driver")
+	source, err := iofs.New(resources.FS, "migrations/sqlite")
+	require.NoError(t, err, "Failed to create embedded migration source")
+	m, err := migrate.NewWithInstance("iofs", source, "pocket-id", driver)
+	require.NoError(t, err, "Failed to create migration instance")
+	require.NoError(t, m.Up(), "Failed to perform migrations")
+
+	return db
+}
@@ -0,0 +1,281 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// NewSamlController creates a new controller for the SAML identity provider
+// @Summary SAML controller
+// @Description Initializes the SAML identity provider endpoints and the management of service providers
+// @Tags SAML
+func NewSamlController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, samlService *service.SamlService) {
+	sc := &SamlController{samlService: samlService}
+
+	group.GET("/saml/metadata", sc.metadataHandler)
+	group.GET("/saml/sso", sc.ssoHandler)
+	group.POST("/saml/sso", sc.ssoHandler)
+	group.POST("/saml/sso/response", authMiddleware.WithAdminNotRequired().Add(), sc.createSsoResponseHandler)
+
+	group.GET("/saml/service-providers", authMiddleware.Add(), sc.listServiceProvidersHandler)
+	group.POST("/saml/service-providers", authMiddleware.Add(), sc.createServiceProviderHandler)
+	group.GET("/saml/service-providers/:id", authMiddleware.Add(), sc.getServiceProviderHandler)
+	group.PUT("/saml/service-providers/:id", authMiddleware.Add(), sc.updateServiceProviderHandler)
+	group.DELETE("/saml/service-providers/:id", authMiddleware.Add(), sc.deleteServiceProviderHandler)
+	group.PUT("/saml/service-providers/:id/allowed-user-groups", authMiddleware.Add(), sc.updateAllowedUserGroupsHandler)
+}
+
+type SamlController struct {
+	samlService *service.SamlService
+}
+
+// metadataHandler godoc
+// @Summary Get identity provider metadata
+// @Description Get the SAML metadata of the identity provider, including the signing certificate
+// @Tags SAML
+// @Produce xml
+// @Success 200 {string} string "SAML metadata"
+// @Router /api/saml/metadata [get]
+func (sc *SamlController) metadataHandler(c *gin.Context) {
+	metadata, err := sc.samlService.GetMetadata(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// ssoHandler godoc
+// @Summary Single sign-on endpoint
+// @Description Receive an AuthnRequest with the HTTP-Redirect or HTTP-POST binding and redirect to the sign in page
+// @Tags SAML
+// @Param SAMLRequest query string true "SAML authentication request"
+// @Param RelayState query string false "Relay state"
+// @Success 302
+// @Router /api/saml/sso [get]
+func (sc *SamlController) ssoHandler(c *gin.Context) {
+	samlRequest, relayState, err := sc.samlService.DecodeSsoRequest(c.Request)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("SAMLRequest", samlRequest)
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+
+	c.Redirect(http.StatusFound, common.EnvConfig.AppURL+"/saml/sso?"+query.Encode())
+}
+
+// createSsoResponseHandler godoc
+// @Summary Create SAML response
+// @Description Create the signed SAML response for a SP-initiated or IdP-initiated sign in of the current user
+// @Tags SAML
+// @Accept json
+// @Produce json
+// @Param request body dto.SamlSsoRequestDto true "SAML request"
+// @Success 200 {object} dto.SamlSsoResponseDto "SAML response to post to the service provider"
+// @Router /api/saml/sso/response [post]
+func (sc *SamlController) createSsoResponseHandler(c *gin.Context) {
+	var input dto.SamlSsoRequestDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if input.SamlRequest == "" && input.ServiceProviderID == "" {
+		_ = c.Error(&common.SamlInvalidRequestError{Message: "a SAML request or a service provider ID is required"})
+		return
+	}
+
+	response, err := sc.samlService.CreateSsoResponse(c.Request.Context(), input, c.GetString("userID"), c.GetString("sessionID"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// listServiceProvidersHandler godoc
+// @Summary List SAML service providers
+// @Description Get a paginated list of SAML service providers with optional search and sorting
+// @Tags SAML
+// @Param search query string false "Search term to filter service providers by name or entity ID"
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.SamlServiceProviderDto]
+// @Router /api/saml/service-providers [get]
+func (sc *SamlController) listServiceProvidersHandler(c *gin.Context) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serviceProviders, pagination, err := sc.samlService.ListServiceProviders(c.Request.Context(), c.Query("search"), sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var serviceProvidersDto []dto.SamlServiceProviderDto
+	if err := dto.MapStructList(serviceProviders, &serviceProvidersDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.SamlServiceProviderDto]{
+		Data:       serviceProvidersDto,
+		Pagination: pagination,
+	})
+}
+
+// getServiceProviderHandler godoc
+// @Summary Get SAML service provider
+// @Description Get detailed information about a SAML service provider
+// @Tags SAML
+// @Produce json
+// @Param id path string true "Service provider ID"
+// @Success 200 {object} dto.SamlServiceProviderWithAllowedUserGroupsDto "Service provider information"
+// @Router /api/saml/service-providers/{id} [get]
+func (sc *SamlController) getServiceProviderHandler(c *gin.Context) {
+	serviceProvider, err := sc.samlService.GetServiceProvider(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var serviceProviderDto dto.SamlServiceProviderWithAllowedUserGroupsDto
+	if err := dto.MapStruct(serviceProvider, &serviceProviderDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serviceProviderDto)
+}
+
+// createServiceProviderHandler godoc
+// @Summary Create SAML service provider
+// @Description Register a new SAML service provider
+// @Tags SAML
+// @Accept json
+// @Produce json
+// @Param serviceProvider body dto.SamlServiceProviderCreateDto true "Service provider information"
+// @Success 201 {object} dto.SamlServiceProviderWithAllowedUserGroupsDto "Created service provider"
+// @Router /api/saml/service-providers [post]
+func (sc *SamlController) createServiceProviderHandler(c *gin.Context) {
+	var input dto.SamlServiceProviderCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serviceProvider, err := sc.samlService.CreateServiceProvider(c.Request.Context(), input, c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var serviceProviderDto dto.SamlServiceProviderWithAllowedUserGroupsDto
+	if err := dto.MapStruct(serviceProvider, &serviceProviderDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serviceProviderDto)
+}
+
+// updateServiceProviderHandler godoc
+// @Summary Update SAML service provider
+// @Description Update an existing SAML service provider
+// @Tags SAML
+// @Accept json
+// @Produce json
+// @Param id path string true "Service provider ID"
+// @Param serviceProvider body dto.SamlServiceProviderCreateDto true "Service provider information"
+// @Success 200 {object} dto.SamlServiceProviderWithAllowedUserGroupsDto "Updated service provider"
+// @Router /api/saml/service-providers/{id} [put]
+func (sc *SamlController) updateServiceProviderHandler(c *gin.Context) {
+	var input dto.SamlServiceProviderCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serviceProvider, err := sc.samlService.UpdateServiceProvider(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var serviceProviderDto dto.SamlServiceProviderWithAllowedUserGroupsDto
+	if err := dto.MapStruct(serviceProvider, &serviceProviderDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serviceProviderDto)
+}
+
+// deleteServiceProviderHandler godoc
+// @Summary Delete SAML service provider
+// @Description Delete a SAML service provider by ID
+// @Tags SAML
+// @Param id path string true "Service provider ID"
+// @Success 204 "No Content"
+// @Router /api/saml/service-providers/{id} [delete]
+func (sc *SamlController) deleteServiceProviderHandler(c *gin.Context) {
+	err := sc.samlService.DeleteServiceProvider(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// updateAllowedUserGroupsHandler godoc
+// @Summary Update allowed user groups
+// @Description Update the user groups allowed to sign in to a SAML service provider
+// @Tags SAML
+// @Accept json
+// @Produce json
+// @Param id path string true "Service provider ID"
+// @Param groups body dto.SamlUpdateAllowedUserGroupsDto true "User group IDs"
+// @Success 200 {object} dto.SamlServiceProviderWithAllowedUserGroupsDto "Updated service provider"
+// @Router /api/saml/service-providers/{id}/allowed-user-groups [put]
+func (sc *SamlController) updateAllowedUserGroupsHandler(c *gin.Context) {
+	var input dto.SamlUpdateAllowedUserGroupsDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serviceProvider, err := sc.samlService.UpdateAllowedUserGroups(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var serviceProviderDto dto.SamlServiceProviderWithAllowedUserGroupsDto
+	if err := dto.MapStruct(serviceProvider, &serviceProviderDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serviceProviderDto)
+}
@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+// NewScimController creates a new controller for the SCIM 2.0 server
+// @Summary SCIM controller
+// @Description Initializes the SCIM 2.0 endpoints to provision users and groups
+// @Tags SCIM
+func NewScimController(group *gin.RouterGroup, scimService *service.ScimService) {
+	sc := &ScimController{scimService: scimService}
+
+	group.GET("/ServiceProviderConfig", sc.getServiceProviderConfigHandler)
+	group.GET("/ResourceTypes", sc.listResourceTypesHandler)
+	group.GET("/Schemas", sc.listSchemasHandler)
+
+	group.GET("/Users", sc.listUsersHandler)
+	group.POST("/Users", sc.createUserHandler)
+	group.GET("/Users/:id", sc.getUserHandler)
+	group.PUT("/Users/:id", sc.replaceUserHandler)
+	group.PATCH("/Users/:id", sc.patchUserHandler)
+	group.DELETE("/Users/:id", sc.deleteUserHandler)
+
+	group.GET("/Groups", sc.listGroupsHandler)
+	group.POST("/Groups", sc.createGroupHandler)
+	group.GET("/Groups/:id", sc.getGroupHandler)
+	group.PUT("/Groups/:id", sc.replaceGroupHandler)
+	group.PATCH("/Groups/:id", sc.patchGroupHandler)
+	group.DELETE("/Groups/:id", sc.deleteGroupHandler)
+}
+
+type ScimController struct {
+	scimService *service.ScimService
+}
+
+// getServiceProviderConfigHandler godoc
+// @Summary Get service provider configuration
+// @Description Get the SCIM features that are supported by Pocket ID
+// @Tags SCIM
+// @Produce json
+// @Success 200 {object} object "Service provider configuration"
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (sc *ScimController) getServiceProviderConfigHandler(c *gin.Context) {
+	scimJSON(c, http.StatusOK, sc.scimService.GetServiceProviderConfig())
+}
+
+// listResourceTypesHandler godoc
+// @Summary List resource types
+// @Description Get the SCIM resource types that are supported by Pocket ID
+// @Tags SCIM
+// @Produce json
+// @Success 200 {object} object "Resource types"
+// @Router /scim/v2/ResourceTypes [get]
+func (sc *ScimController) listResourceTypesHandler(c *gin.Context) {
+	resourceTypes := sc.scimService.GetResourceTypes()
+	scimJSON(c, http.StatusOK, dto.ScimListResponseDto[map[string]any]{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: int64(len(resourceTypes)),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+// listSchemasHandler godoc
+// @Summary List schemas
+// @Description Get the SCIM schemas of the resources that are supported by Pocket ID
+// @Tags SCIM
+// @Produce json
+// @Success 200 {object} object "Schemas"
+// @Router /scim/v2/Schemas [get]
+func (sc *ScimController) listSchemasHandler(c *gin.Context) {
+	schemas := sc.scimService.GetSchemas()
+	scimJSON(c, http.StatusOK, dto.ScimListResponseDto[map[string]any]{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: int64(len(schemas)),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+		Resources:    schemas,
+	})
+}
+
+// listUsersHandler godoc
+// @Summary List users
+// @Description Get a paginated list of users with an optional SCIM filter
+// @Tags SCIM
+// @Param filter query string false "SCIM filter, e.g. userName eq \"tim\""
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Number of results per page" default(100)
+// @Success 200 {object} dto.ScimListResponseDto[dto.ScimUserDto]
+// @Router /scim/v2/Users [get]
+func (sc *ScimController) listUsersHandler(c *gin.Context) {
+	var input dto.ScimListRequestDto
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	users, err := sc.scimService.ListUsers(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, users)
+}
+
+// getUserHandler godoc
+// @Summary Get user
+// @Description Get a user by ID
+// @Tags SCIM
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.ScimUserDto
+// @Router /scim/v2/Users/{id} [get]
+func (sc *ScimController) getUserHandler(c *gin.Context) {
+	user, err := sc.scimService.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+// createUserHandler godoc
+// @Summary Create user
+// @Description Provision a new user
+// @Tags SCIM
+// @Accept json
+// @Param user body dto.ScimUserCreateDto true "User"
+// @Success 201 {object} dto.ScimUserDto
+// @Router /scim/v2/Users [post]
+func (sc *ScimController) createUserHandler(c *gin.Context) {
+	var input dto.ScimUserCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	user, err := sc.scimService.CreateUser(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusCreated, user)
+}
+
+// replaceUserHandler godoc
+// @Summary Replace user
+// @Description Replace all attributes of a user
+// @Tags SCIM
+// @Accept json
+// @Param id path string true "User ID"
+// @Param user body dto.ScimUserCreateDto true "User"
+// @Success 200 {object} dto.ScimUserDto
+// @Router /scim/v2/Users/{id} [put]
+func (sc *ScimController) replaceUserHandler(c *gin.Context) {
+	var input dto.ScimUserCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	user, err := sc.scimService.ReplaceUser(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+// patchUserHandler godoc
+// @Summary Patch user
+// @Description Update attributes of a user with SCIM patch operations
+// @Tags SCIM
+// @Accept json
+// @Param id path string true "User ID"
+// @Param operations body dto.ScimPatchRequestDto true "Patch operations"
+// @Success 200 {object} dto.ScimUserDto
+// @Router /scim/v2/Users/{id} [patch]
+func (sc *ScimController) patchUserHandler(c *gin.Context) {
+	var input dto.ScimPatchRequestDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	user, err := sc.scimService.PatchUser(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+// deleteUserHandler godoc
+// @Summary Delete user
+// @Description Delete a user by ID
+// @Tags SCIM
+// @Param id path string true "User ID"
+// @Success 204 "No Content"
+// @Router /scim/v2/Users/{id} [delete]
+func (sc *ScimController) deleteUserHandler(c *gin.Context) {
+	if err := sc.scimService.DeleteUser(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listGroupsHandler godoc
+// @Summary List groups
+// @Description Get a paginated list of groups with an optional SCIM filter
+// @Tags SCIM
+// @Param filter query string false "SCIM filter, e.g. displayName eq \"Developers\""
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Number of results per page" default(100)
+// @Success 200 {object} dto.ScimListResponseDto[dto.ScimGroupDto]
+// @Router /scim/v2/Groups [get]
+func (sc *ScimController) listGroupsHandler(c *gin.Context) {
+	var input dto.ScimListRequestDto
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	groups, err := sc.scimService.ListGroups(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, groups)
+}
+
+// getGroupHandler godoc
+// @Summary Get group
+// @Description Get a group by ID
+// @Tags SCIM
+// @Param id path string true "Group ID"
+// @Success 200 {object} dto.ScimGroupDto
+// @Router /scim/v2/Groups/{id} [get]
+func (sc *ScimController) getGroupHandler(c *gin.Context) {
+	group, err := sc.scimService.GetGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// createGroupHandler godoc
+// @Summary Create group
+// @Description Provision a new group
+// @Tags SCIM
+// @Accept json
+// @Param group body dto.ScimGroupCreateDto true "Group"
+// @Success 201 {object} dto.ScimGroupDto
+// @Router /scim/v2/Groups [post]
+func (sc *ScimController) createGroupHandler(c *gin.Context) {
+	var input dto.ScimGroupCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	group, err := sc.scimService.CreateGroup(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusCreated, group)
+}
+
+// replaceGroupHandler godoc
+// @Summary Replace group
+// @Description Replace all attributes of a group, including its members
+// @Tags SCIM
+// @Accept json
+// @Param id path string true "Group ID"
+// @Param group body dto.ScimGroupCreateDto true "Group"
+// @Success 200 {object} dto.ScimGroupDto
+// @Router /scim/v2/Groups/{id} [put]
+func (sc *ScimController) replaceGroupHandler(c *gin.Context) {
+	var input dto.ScimGroupCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	group, err := sc.scimService.ReplaceGroup(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// patchGroupHandler godoc
+// @Summary Patch group
+// @Description Update attributes and members of a group with SCIM patch operations
+// @Tags SCIM
+// @Accept json
+// @Param id path string true "Group ID"
+// @Param operations body dto.ScimPatchRequestDto true "Patch operations"
+// @Success 200 {object} dto.ScimGroupDto
+// @Router /scim/v2/Groups/{id} [patch]
+func (sc *ScimController) patchGroupHandler(c *gin.Context) {
+	var input dto.ScimPatchRequestDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	group, err := sc.scimService.PatchGroup(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// deleteGroupHandler godoc
+// @Summary Delete group
+// @Description Delete a group by ID
+// @Tags SCIM
+// @Param id path string true "Group ID"
+// @Success 204 "No Content"
+// @Router /scim/v2/Groups/{id} [delete]
+func (sc *ScimController) deleteGroupHandler(c *gin.Context) {
+	if err := sc.scimService.DeleteGroup(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// scimJSON writes a JSON response with the SCIM media type
+func scimJSON(c *gin.Context, statusCode int, obj any) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(statusCode, obj)
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+// NewScimProvisioningController creates a new controller for the SCIM provisioning of OIDC clients
+// @Summary SCIM provisioning controller
+// @Description Initializes the endpoints to manage the SCIM servers that users are provisioned to
+// @Tags SCIM Provisioning
+func NewScimProvisioningController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, scimProvisioningService *service.ScimProvisioningService) {
+	spc := &ScimProvisioningController{scimProvisioningService: scimProvisioningService}
+
+	group.GET("/oidc/clients/:id/scim", authMiddleware.Add(), spc.getTargetHandler)
+	group.PUT("/oidc/clients/:id/scim", authMiddleware.Add(), spc.updateTargetHandler)
+	group.DELETE("/oidc/clients/:id/scim", authMiddleware.Add(), spc.deleteTargetHandler)
+	group.POST("/oidc/clients/:id/scim/sync", authMiddleware.Add(), spc.syncTargetHandler)
+}
+
+type ScimProvisioningController struct {
+	scimProvisioningService *service.ScimProvisioningService
+}
+
+// getTargetHandler godoc
+// @Summary Get SCIM provisioning target
+// @Description Get the SCIM server of an OIDC client and its sync status
+// @Tags SCIM Provisioning
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} dto.ScimProvisioningTargetDto
+// @Router /api/oidc/clients/{id}/scim [get]
+func (spc *ScimProvisioningController) getTargetHandler(c *gin.Context) {
+	target, err := spc.scimProvisioningService.GetTarget(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// updateTargetHandler godoc
+// @Summary Create or update SCIM provisioning target
+// @Description Configure the SCIM server of an OIDC client and queue all users to be synced
+// @Tags SCIM Provisioning
+// @Accept json
+// @Produce json
+// @Param id path string true "Client ID"
+// @Param target body dto.ScimProvisioningTargetUpdateDto true "SCIM server"
+// @Success 200 {object} dto.ScimProvisioningTargetDto
+// @Router /api/oidc/clients/{id}/scim [put]
+func (spc *ScimProvisioningController) updateTargetHandler(c *gin.Context) {
+	var input dto.ScimProvisioningTargetUpdateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	target, err := spc.scimProvisioningService.UpdateTarget(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// deleteTargetHandler godoc
+// @Summary Delete SCIM provisioning target
+// @Description Stop provisioning users to the SCIM server of an OIDC client
+// @Tags SCIM Provisioning
+// @Param id path string true "Client ID"
+// @Success 204 "No Content"
+// @Router /api/oidc/clients/{id}/scim [delete]
+func (spc *ScimProvisioningController) deleteTargetHandler(c *gin.Context) {
+	if err := spc.scimProvisioningService.DeleteTarget(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// syncTargetHandler godoc
+// @Summary Sync SCIM provisioning target
+// @Description Queue all users to be synced to the SCIM server of an OIDC client
+// @Tags SCIM Provisioning
+// @Param id path string true "Client ID"
+// @Success 204 "No Content"
+// @Router /api/oidc/clients/{id}/scim/sync [post]
+func (spc *ScimProvisioningController) syncTargetHandler(c *gin.Context) {
+	if err := spc.scimProvisioningService.SyncTarget(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
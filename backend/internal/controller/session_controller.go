@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+	"github.com/pocket-id/pocket-id/backend/internal/utils/cookie"
+)
+
+// NewSessionController creates a new controller for session management
+// @Summary Session management controller
+// @Description Initializes API endpoints for listing and revoking sessions
+// @Tags Sessions
+func NewSessionController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, sessionService *service.SessionService, auditLogService *service.AuditLogService) {
+	sc := &SessionController{sessionService: sessionService, auditLogService: auditLogService}
+
+	group.GET("/sessions", authMiddleware.WithAdminNotRequired().Add(), sc.listOwnSessionsHandler)
+	group.DELETE("/sessions", authMiddleware.WithAdminNotRequired().Add(), sc.revokeAllOwnSessionsHandler)
+	group.DELETE("/sessions/:id", authMiddleware.WithAdminNotRequired().Add(), sc.revokeOwnSessionHandler)
+
+	group.GET("/sessions/all", authMiddleware.Add(), sc.listAllSessionsHandler)
+	group.GET("/users/:id/sessions", authMiddleware.Add(), sc.listUserSessionsHandler)
+	group.DELETE("/users/:id/sessions", authMiddleware.Add(), sc.revokeAllUserSessionsHandler)
+	group.DELETE("/users/:id/sessions/:sessionId", authMiddleware.Add(), sc.revokeUserSessionHandler)
+}
+
+type SessionController struct {
+	sessionService  *service.SessionService
+	auditLogService *service.AuditLogService
+}
+
+// listOwnSessionsHandler godoc
+// @Summary List own sessions
+// @Description Get a paginated list of the active sessions of the current user
+// @Tags Sessions
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.SessionDto]
+// @Router /api/sessions [get]
+func (sc *SessionController) listOwnSessionsHandler(c *gin.Context) {
+	sc.listSessionsForUser(c, c.GetString("userID"))
+}
+
+// revokeAllOwnSessionsHandler godoc
+// @Summary Sign out everywhere
+// @Description Revoke all sessions of the current user except the current one
+// @Tags Sessions
+// @Success 204 "No Content"
+// @Router /api/sessions [delete]
+func (sc *SessionController) revokeAllOwnSessionsHandler(c *gin.Context) {
+	err := sc.sessionService.RevokeAllSessionsForUser(c.Request.Context(), c.GetString("userID"), c.GetString("sessionID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// revokeOwnSessionHandler godoc
+// @Summary Revoke own session
+// @Description Revoke a session of the current user
+// @Tags Sessions
+// @Param id path string true "Session ID"
+// @Success 204 "No Content"
+// @Router /api/sessions/{id} [delete]
+func (sc *SessionController) revokeOwnSessionHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	err := sc.sessionService.RevokeSession(c.Request.Context(), c.GetString("userID"), sessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// If the current session was revoked, also remove the cookie
+	if sessionID == c.GetString("sessionID") {
+		cookie.AddAccessTokenCookie(c, 0, "")
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listAllSessionsHandler godoc
+// @Summary List all sessions
+// @Description Get a paginated list of the active sessions of all users (admin only)
+// @Tags Sessions
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Param filters[userId] query string false "Filter by user ID"
+// @Success 200 {object} dto.Paginated[dto.SessionDto]
+// @Router /api/sessions/all [get]
+func (sc *SessionController) listAllSessionsHandler(c *gin.Context) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var filters dto.SessionFilterDto
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	sessions, pagination, err := sc.sessionService.ListAllSessions(c.Request.Context(), sortedPaginationRequest, filters)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	sessionDtos, err := sc.mapSessions(c, sessions)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.SessionDto]{
+		Data:       sessionDtos,
+		Pagination: pagination,
+	})
+}
+
+// listUserSessionsHandler godoc
+// @Summary List sessions of a user
+// @Description Get a paginated list of the active sessions of a specific user (admin only)
+// @Tags Sessions
+// @Param id path string true "User ID"
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.SessionDto]
+// @Router /api/users/{id}/sessions [get]
+func (sc *SessionController) listUserSessionsHandler(c *gin.Context) {
+	sc.listSessionsForUser(c, c.Param("id"))
+}
+
+// revokeAllUserSessionsHandler godoc
+// @Summary Revoke all sessions of a user
+// @Description Sign a specific user out everywhere (admin only)
+// @Tags Sessions
+// @Param id path string true "User ID"
+// @Success 204 "No Content"
+// @Router /api/users/{id}/sessions [delete]
+func (sc *SessionController) revokeAllUserSessionsHandler(c *gin.Context) {
+	userID := c.Param("id")
+
+	// Keep the admin's own current session if they are revoking their own sessions
+	exceptSessionID := ""
+	if userID == c.GetString("userID") {
+		exceptSessionID = c.GetString("sessionID")
+	}
+
+	err := sc.sessionService.RevokeAllSessionsForUser(c.Request.Context(), userID, exceptSessionID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// revokeUserSessionHandler godoc
+// @Summary Revoke session of a user
+// @Description Revoke a specific session of a user (admin only)
+// @Tags Sessions
+// @Param id path string true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Success 204 "No Content"
+// @Router /api/users/{id}/sessions/{sessionId} [delete]
+func (sc *SessionController) revokeUserSessionHandler(c *gin.Context) {
+	err := sc.sessionService.RevokeSession(c.Request.Context(), c.Param("id"), c.Param("sessionId"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (sc *SessionController) listSessionsForUser(c *gin.Context, userID string) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	sessions, pagination, err := sc.sessionService.ListSessionsForUser(c.Request.Context(), userID, sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	sessionDtos, err := sc.mapSessions(c, sessions)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.SessionDto]{
+		Data:       sessionDtos,
+		Pagination: pagination,
+	})
+}
+
+func (sc *SessionController) mapSessions(c *gin.Context, sessions []model.Session) ([]dto.SessionDto, error) {
+	var sessionDtos []dto.SessionDto
+	if err := dto.MapStructList(sessions, &sessionDtos); err != nil {
+		return nil, err
+	}
+
+	currentSessionID := c.GetString("sessionID")
+	for i, sessionDto := range sessionDtos {
+		sessionDto.Device = sc.auditLogService.DeviceStringFromUserAgent(sessions[i].UserAgent)
+		sessionDto.Username = sessions[i].User.Username
+		sessionDto.Current = sessionDto.ID == currentSessionID
+		sessionDtos[i] = sessionDto
+	}
+
+	return sessionDtos, nil
+}
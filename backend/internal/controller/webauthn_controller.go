@@ -15,14 +15,16 @@ import (
 	"golang.org/x/time/rate"
 )
 
-func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, rateLimitMiddleware *middleware.RateLimitMiddleware, webauthnService *service.WebAuthnService, appConfigService *service.AppConfigService) {
-	wc := &WebauthnController{webAuthnService: webauthnService, appConfigService: appConfigService}
+func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, rateLimitMiddleware *middleware.RateLimitMiddleware, webauthnService *service.WebAuthnService, appConfigService *service.AppConfigService, sessionService *service.SessionService) {
+	wc := &WebauthnController{webAuthnService: webauthnService, appConfigService: appConfigService, sessionService: sessionService}
 	group.GET("/webauthn/register/start", authMiddleware.WithAdminNotRequired().Add(), wc.beginRegistrationHandler)
 	group.POST("/webauthn/register/finish", authMiddleware.WithAdminNotRequired().Add(), wc.verifyRegistrationHandler)
 
 	group.GET("/webauthn/login/start", wc.beginLoginHandler)
 	group.POST("/webauthn/login/finish", rateLimitMiddleware.Add(rate.Every(10*time.Second), 5), wc.verifyLoginHandler)
 
+	group.POST("/webauthn/reauthenticate", rateLimitMiddleware.Add(rate.Every(10*time.Second), 5), authMiddleware.WithAdminNotRequired().Add(), wc.reauthenticateHandler)
+
 	group.POST("/webauthn/logout", authMiddleware.WithAdminNotRequired().Add(), wc.logoutHandler)
 
 	group.GET("/webauthn/credentials", authMiddleware.WithAdminNotRequired().Add(), wc.listCredentialsHandler)
@@ -33,6 +35,7 @@ func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.Au
 type WebauthnController struct {
 	webAuthnService  *service.WebAuthnService
 	appConfigService *service.AppConfigService
+	sessionService   *service.SessionService
 }
 
 func (wc *WebauthnController) beginRegistrationHandler(c *gin.Context) {
@@ -94,7 +97,10 @@ func (wc *WebauthnController) verifyLoginHandler(c *gin.Context) {
 		return
 	}
 
-	user, token, err := wc.webAuthnService.VerifyLogin(c.Request.Context(), sessionID, credentialAssertionData, c.ClientIP(), c.Request.UserAgent())
+	// The session of the user that is currently signed in, if any, is replaced by the new one
+	previousAccessToken, _ := c.Cookie(cookie.AccessTokenCookieName)
+
+	user, token, err := wc.webAuthnService.VerifyLogin(c.Request.Context(), sessionID, credentialAssertionData, previousAccessToken, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -106,12 +112,41 @@ func (wc *WebauthnController) verifyLoginHandler(c *gin.Context) {
 		return
 	}
 
-	maxAge := int(wc.appConfigService.GetDbConfig().SessionDuration.AsDurationMinutes().Seconds())
+	maxAge := int(wc.appConfigService.GetDbConfig().SessionDurationForUser(user.IsAdmin).Seconds())
 	cookie.AddAccessTokenCookie(c, maxAge, token)
 
 	c.JSON(http.StatusOK, userDto)
 }
 
+func (wc *WebauthnController) reauthenticateHandler(c *gin.Context) {
+	webauthnSessionID, err := c.Cookie(cookie.SessionIdCookieName)
+	if err != nil {
+		_ = c.Error(&common.MissingSessionIdError{})
+		return
+	}
+
+	// Sudo mode is bound to the server-side session, so API keys can't be elevated
+	userSessionID := c.GetString("sessionID")
+	if userSessionID == "" {
+		_ = c.Error(&common.NotSignedInError{})
+		return
+	}
+
+	credentialAssertionData, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = wc.webAuthnService.VerifyReauthentication(c.Request.Context(), webauthnSessionID, credentialAssertionData, c.GetString("userID"), userSessionID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func (wc *WebauthnController) listCredentialsHandler(c *gin.Context) {
 	userID := c.GetString("userID")
 	credentials, err := wc.webAuthnService.ListCredentials(c.Request.Context(), userID)
@@ -168,6 +203,15 @@ func (wc *WebauthnController) updateCredentialHandler(c *gin.Context) {
 }
 
 func (wc *WebauthnController) logoutHandler(c *gin.Context) {
+	// Requests authenticated with an API key don't have a session
+	if sessionID := c.GetString("sessionID"); sessionID != "" {
+		err := wc.sessionService.RevokeSession(c.Request.Context(), c.GetString("userID"), sessionID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
 	cookie.AddAccessTokenCookie(c, 0, "")
 	c.Status(http.StatusNoContent)
 }
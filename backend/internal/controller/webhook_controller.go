@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// NewWebhookController creates a new controller for webhooks
+// @Summary Webhook controller
+// @Description Initializes the endpoints to manage webhooks and their deliveries
+// @Tags Webhooks
+func NewWebhookController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, webhookService *service.WebhookService) {
+	wc := &WebhookController{webhookService: webhookService}
+
+	webhookGroup := group.Group("/webhooks")
+	webhookGroup.Use(authMiddleware.Add())
+	{
+		webhookGroup.GET("", wc.listWebhooksHandler)
+		webhookGroup.POST("", wc.createWebhookHandler)
+		webhookGroup.GET("/:id", wc.getWebhookHandler)
+		webhookGroup.PUT("/:id", wc.updateWebhookHandler)
+		webhookGroup.DELETE("/:id", wc.deleteWebhookHandler)
+		webhookGroup.POST("/:id/secret", wc.regenerateSecretHandler)
+		webhookGroup.GET("/:id/deliveries", wc.listDeliveriesHandler)
+		webhookGroup.POST("/:id/deliveries/:deliveryId/replay", wc.replayDeliveryHandler)
+	}
+}
+
+type WebhookController struct {
+	webhookService *service.WebhookService
+}
+
+// listWebhooksHandler godoc
+// @Summary List webhooks
+// @Description Get a paginated list of webhooks
+// @Tags Webhooks
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.WebhookDto]
+// @Router /api/webhooks [get]
+func (wc *WebhookController) listWebhooksHandler(c *gin.Context) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	webhooks, pagination, err := wc.webhookService.ListWebhooks(c.Request.Context(), sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var webhooksDto []dto.WebhookDto
+	if err := dto.MapStructList(webhooks, &webhooksDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.WebhookDto]{
+		Data:       webhooksDto,
+		Pagination: pagination,
+	})
+}
+
+// getWebhookHandler godoc
+// @Summary Get webhook
+// @Description Get a webhook by its ID
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.WebhookDto
+// @Router /api/webhooks/{id} [get]
+func (wc *WebhookController) getWebhookHandler(c *gin.Context) {
+	webhook, err := wc.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var webhookDto dto.WebhookDto
+	if err := dto.MapStruct(webhook, &webhookDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookDto)
+}
+
+// createWebhookHandler godoc
+// @Summary Create webhook
+// @Description Create a new webhook. The secret that the payloads are signed with is only returned once.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body dto.WebhookCreateDto true "Webhook"
+// @Success 201 {object} dto.WebhookResponseDto
+// @Router /api/webhooks [post]
+func (wc *WebhookController) createWebhookHandler(c *gin.Context) {
+	var input dto.WebhookCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	webhook, secret, err := wc.webhookService.CreateWebhook(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var webhookDto dto.WebhookDto
+	if err := dto.MapStruct(webhook, &webhookDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.WebhookResponseDto{
+		Webhook: webhookDto,
+		Secret:  secret,
+	})
+}
+
+// updateWebhookHandler godoc
+// @Summary Update webhook
+// @Description Update the URL and the subscribed events of a webhook
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body dto.WebhookCreateDto true "Webhook"
+// @Success 200 {object} dto.WebhookDto
+// @Router /api/webhooks/{id} [put]
+func (wc *WebhookController) updateWebhookHandler(c *gin.Context) {
+	var input dto.WebhookCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var webhookDto dto.WebhookDto
+	if err := dto.MapStruct(webhook, &webhookDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookDto)
+}
+
+// deleteWebhookHandler godoc
+// @Summary Delete webhook
+// @Description Delete a webhook and its deliveries
+// @Tags Webhooks
+// @Param id path string true "Webhook ID"
+// @Success 204 "No Content"
+// @Router /api/webhooks/{id} [delete]
+func (wc *WebhookController) deleteWebhookHandler(c *gin.Context) {
+	if err := wc.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// regenerateSecretHandler godoc
+// @Summary Regenerate webhook secret
+// @Description Replace the secret that the payloads of a webhook are signed with
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} object "{ \"secret\": \"string\" }"
+// @Router /api/webhooks/{id}/secret [post]
+func (wc *WebhookController) regenerateSecretHandler(c *gin.Context) {
+	secret, err := wc.webhookService.RegenerateSecret(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+// listDeliveriesHandler godoc
+// @Summary List webhook deliveries
+// @Description Get a paginated log of the deliveries of a webhook, the newest first
+// @Tags Webhooks
+// @Param id path string true "Webhook ID"
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.WebhookDeliveryDto]
+// @Router /api/webhooks/{id}/deliveries [get]
+func (wc *WebhookController) listDeliveriesHandler(c *gin.Context) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	deliveries, pagination, err := wc.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var deliveriesDto []dto.WebhookDeliveryDto
+	if err := dto.MapStructList(deliveries, &deliveriesDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.WebhookDeliveryDto]{
+		Data:       deliveriesDto,
+		Pagination: pagination,
+	})
+}
+
+// replayDeliveryHandler godoc
+// @Summary Replay webhook delivery
+// @Description Queue the payload of a delivery to be sent to the webhook again
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 201 {object} dto.WebhookDeliveryDto
+// @Router /api/webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (wc *WebhookController) replayDeliveryHandler(c *gin.Context) {
+	delivery, err := wc.webhookService.ReplayDelivery(c.Request.Context(), c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var deliveryDto dto.WebhookDeliveryDto
+	if err := dto.MapStruct(delivery, &deliveryDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, deliveryDto)
+}
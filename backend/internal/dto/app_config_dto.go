@@ -8,12 +8,18 @@ type PublicAppConfigVariableDto struct {
 
 type AppConfigVariableDto struct {
 	PublicAppConfigVariableDto
-	IsPublic bool `json:"isPublic"`
+	IsPublic      bool `json:"isPublic"`
+	ConfigManaged bool `json:"configManaged"`
 }
 
 type AppConfigUpdateDto struct {
 	AppName                                    string `json:"appName" binding:"required,min=1,max=30"`
 	SessionDuration                            string `json:"sessionDuration" binding:"required"`
+	SessionIdleTimeout                         string `json:"sessionIdleTimeout" binding:"omitempty,number"`
+	AdminSessionDuration                       string `json:"adminSessionDuration" binding:"omitempty,number"`
+	AdminSessionIdleTimeout                    string `json:"adminSessionIdleTimeout" binding:"omitempty,number"`
+	SudoModeDuration                           string `json:"sudoModeDuration" binding:"omitempty,number"`
+	SudoModeActions                            string `json:"sudoModeActions"`
 	EmailsVerified                             string `json:"emailsVerified" binding:"required"`
 	DisableAnimations                          string `json:"disableAnimations" binding:"required"`
 	AllowOwnAccountEdit                        string `json:"allowOwnAccountEdit" binding:"required"`
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type AppPasswordCreateDto struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+}
+
+type AppPasswordDto struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	LastUsedAt *datatype.DateTime `json:"lastUsedAt"`
+	CreatedAt  datatype.DateTime  `json:"createdAt"`
+}
+
+type AppPasswordResponseDto struct {
+	AppPassword AppPasswordDto `json:"appPassword"`
+	Password    string         `json:"password"`
+}
@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/pocket-id/pocket-id/backend/internal/model"
 	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
 )
@@ -20,7 +22,51 @@ type AuditLogDto struct {
 }
 
 type AuditLogFilterDto struct {
-	UserID     string `form:"filters[userId]"`
-	Event      string `form:"filters[event]"`
-	ClientName string `form:"filters[clientName]"`
+	UserID     string    `form:"filters[userId]"`
+	Event      string    `form:"filters[event]"`
+	ClientName string    `form:"filters[clientName]"`
+	From       time.Time `form:"filters[from]"`
+	To         time.Time `form:"filters[to]"`
+	// IpAddress is either a single IP address or a CIDR range
+	IpAddress string `form:"filters[ipAddress]"`
+	Country   string `form:"filters[country]"`
+	Search    string `form:"filters[search]"`
+}
+
+type AuditLogExportDto struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv jsonl"`
+}
+
+// AuditLogExportRecordDto is an audit log in an export
+type AuditLogExportRecordDto struct {
+	ID        string              `json:"id"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Event     model.AuditLogEvent `json:"event"`
+	UserID    string              `json:"userId"`
+	Username  string              `json:"username"`
+	IpAddress string              `json:"ipAddress"`
+	Country   string              `json:"country"`
+	City      string              `json:"city"`
+	Device    string              `json:"device"`
+	UserAgent string              `json:"userAgent"`
+	Data      model.AuditLogData  `json:"data"`
+}
+
+// AuditLogChainVerificationDto is the result of the verification of the audit log chain
+type AuditLogChainVerificationDto struct {
+	Valid              bool                        `json:"valid"`
+	Links              int64                       `json:"links"`
+	PurgedLinks        int64                       `json:"purgedLinks"`
+	Checkpoints        int64                       `json:"checkpoints"`
+	SkippedCheckpoints int64                       `json:"skippedCheckpoints"`
+	LastCheckpointAt   *time.Time                  `json:"lastCheckpointAt"`
+	UnsignedLinks      int64                       `json:"unsignedLinks"`
+	BrokenLink         *AuditLogChainBrokenLinkDto `json:"brokenLink"`
+}
+
+type AuditLogChainBrokenLinkDto struct {
+	// Sequence is 0 if the audit log isn't part of the chain at all
+	Sequence   int64  `json:"sequence"`
+	AuditLogID string `json:"auditLogId,omitempty"`
+	Reason     string `json:"reason"`
 }
@@ -0,0 +1,9 @@
+package dto
+
+type ForwardAuthAuthorizeDto struct {
+	RedirectURL string `json:"redirectUrl" binding:"required,url"`
+}
+
+type ForwardAuthAuthorizeResponseDto struct {
+	CallbackURL string `json:"callbackUrl"`
+}
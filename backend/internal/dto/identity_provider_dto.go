@@ -0,0 +1,49 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type IdentityProviderMinimalDto struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type IdentityProviderDto struct {
+	IdentityProviderMinimalDto
+	Issuer            string                            `json:"issuer"`
+	ClientID          string                            `json:"clientId"`
+	Scopes            string                            `json:"scopes"`
+	Enabled           bool                              `json:"enabled"`
+	LinkByEmail       bool                              `json:"linkByEmail"`
+	AllowUserCreation bool                              `json:"allowUserCreation"`
+	GroupsClaim       *string                           `json:"groupsClaim"`
+	GroupMappings     []IdentityProviderGroupMappingDto `json:"groupMappings"`
+	CreatedAt         datatype.DateTime                 `json:"createdAt"`
+}
+
+type IdentityProviderGroupMappingDto struct {
+	Value       string `json:"value" binding:"required"`
+	UserGroupID string `json:"userGroupId" binding:"required"`
+}
+
+type IdentityProviderCreateDto struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Issuer   string `json:"issuer" binding:"required,url"`
+	ClientID string `json:"clientId" binding:"required"`
+	// ClientSecret keeps the current secret if it's empty when a provider is updated
+	ClientSecret      string                            `json:"clientSecret"`
+	Scopes            string                            `json:"scopes"`
+	Enabled           bool                              `json:"enabled"`
+	LinkByEmail       bool                              `json:"linkByEmail"`
+	AllowUserCreation bool                              `json:"allowUserCreation"`
+	GroupsClaim       string                            `json:"groupsClaim"`
+	GroupMappings     []IdentityProviderGroupMappingDto `json:"groupMappings" binding:"dive"`
+}
+
+type UserIdentityDto struct {
+	ID               string                     `json:"id"`
+	Email            *string                    `json:"email"`
+	IdentityProvider IdentityProviderMinimalDto `json:"identityProvider"`
+	CreatedAt        datatype.DateTime          `json:"createdAt"`
+}
@@ -13,6 +13,8 @@ type OidcClientDto struct {
 	IsPublic           bool                     `json:"isPublic"`
 	PkceEnabled        bool                     `json:"pkceEnabled"`
 	Credentials        OidcClientCredentialsDto `json:"credentials"`
+	ForwardAuthHosts   []string                 `json:"forwardAuthHosts"`
+	ConfigManaged      bool                     `json:"configManaged"`
 }
 
 type OidcClientWithAllowedUserGroupsDto struct {
@@ -32,6 +34,7 @@ type OidcClientCreateDto struct {
 	IsPublic           bool                     `json:"isPublic"`
 	PkceEnabled        bool                     `json:"pkceEnabled"`
 	Credentials        OidcClientCredentialsDto `json:"credentials"`
+	ForwardAuthHosts   []string                 `json:"forwardAuthHosts" binding:"omitempty,dive,hostname_port|hostname_rfc1123"`
 }
 
 type OidcClientCredentialsDto struct {
@@ -52,6 +55,7 @@ type AuthorizeOidcClientRequestDto struct {
 	Nonce               string `json:"nonce"`
 	CodeChallenge       string `json:"codeChallenge"`
 	CodeChallengeMethod string `json:"codeChallengeMethod"`
+	AcrValues           string `json:"acrValues"`
 }
 
 type AuthorizeOidcClientResponseDto struct {
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type SamlServiceProviderDto struct {
+	ID               string             `json:"id"`
+	Name             string             `json:"name"`
+	EntityID         string             `json:"entityId"`
+	AcsURL           string             `json:"acsUrl"`
+	Metadata         string             `json:"metadata"`
+	NameIDFormat     string             `json:"nameIdFormat"`
+	AttributeMapping []SamlAttributeDto `json:"attributeMapping"`
+	CreatedAt        datatype.DateTime  `json:"createdAt"`
+}
+
+type SamlServiceProviderWithAllowedUserGroupsDto struct {
+	SamlServiceProviderDto
+	AllowedUserGroups []UserGroupDtoWithUserCount `json:"allowedUserGroups"`
+}
+
+type SamlServiceProviderCreateDto struct {
+	Name             string             `json:"name" binding:"required,max=50"`
+	EntityID         string             `json:"entityId" binding:"required"`
+	AcsURL           string             `json:"acsUrl" binding:"required_without=Metadata,omitempty,url"`
+	Metadata         string             `json:"metadata"`
+	NameIDFormat     string             `json:"nameIdFormat" binding:"omitempty,oneof=persistent email unspecified"`
+	AttributeMapping []SamlAttributeDto `json:"attributeMapping" binding:"dive"`
+}
+
+type SamlAttributeDto struct {
+	Name  string `json:"name" binding:"required"`
+	Value string `json:"value" binding:"required"`
+}
+
+type SamlUpdateAllowedUserGroupsDto struct {
+	UserGroupIDs []string `json:"userGroupIds" binding:"required"`
+}
+
+type SamlSsoRequestDto struct {
+	// SamlRequest is the AuthnRequest of a SP-initiated sign in, encoded for the HTTP-Redirect binding
+	SamlRequest string `json:"samlRequest"`
+	// ServiceProviderID is the ID of the service provider for IdP-initiated sign ins
+	ServiceProviderID string `json:"serviceProviderId"`
+	RelayState        string `json:"relayState"`
+}
+
+type SamlSsoResponseDto struct {
+	URL          string `json:"url"`
+	SamlResponse string `json:"samlResponse"`
+	RelayState   string `json:"relayState"`
+}
@@ -0,0 +1,112 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ScimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	ScimSchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	ScimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+type ScimMetaDto struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type ScimUserDto struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	UserName    string          `json:"userName"`
+	Name        ScimNameDto     `json:"name"`
+	DisplayName string          `json:"displayName"`
+	Emails      []ScimEmailDto  `json:"emails"`
+	Active      bool            `json:"active"`
+	Groups      []ScimMemberDto `json:"groups"`
+	Meta        ScimMetaDto     `json:"meta"`
+}
+
+type ScimUserCreateDto struct {
+	Schemas     []string       `json:"schemas"`
+	ExternalID  string         `json:"externalId"`
+	UserName    string         `json:"userName" binding:"required,username,min=2,max=50"`
+	Name        ScimNameDto    `json:"name"`
+	DisplayName string         `json:"displayName"`
+	Emails      []ScimEmailDto `json:"emails" binding:"required,min=1,dive"`
+	// Active defaults to true if it isn't set
+	Active *bool `json:"active"`
+}
+
+type ScimNameDto struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+}
+
+type ScimEmailDto struct {
+	Value   string `json:"value" binding:"required,email"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary"`
+}
+
+type ScimGroupDto struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []ScimMemberDto `json:"members"`
+	Meta        ScimMetaDto     `json:"meta"`
+}
+
+type ScimGroupCreateDto struct {
+	ExternalID  string          `json:"externalId"`
+	DisplayName string          `json:"displayName" binding:"required,min=2,max=50"`
+	Members     []ScimMemberDto `json:"members" binding:"dive"`
+}
+
+type ScimMemberDto struct {
+	Value   string `json:"value" binding:"required"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type ScimPatchRequestDto struct {
+	Schemas    []string                `json:"schemas"`
+	Operations []ScimPatchOperationDto `json:"Operations" binding:"required,dive"`
+}
+
+type ScimPatchOperationDto struct {
+	Op    string          `json:"op" binding:"required"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type ScimListRequestDto struct {
+	Filter     string `form:"filter"`
+	StartIndex int    `form:"startIndex"`
+	Count      *int   `form:"count"`
+}
+
+type ScimListResponseDto[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+type ScimErrorDto struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
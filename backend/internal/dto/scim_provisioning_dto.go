@@ -0,0 +1,25 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type ScimProvisioningTargetDto struct {
+	ID            string             `json:"id"`
+	Endpoint      string             `json:"endpoint"`
+	Enabled       bool               `json:"enabled"`
+	LastSyncedAt  *datatype.DateTime `json:"lastSyncedAt"`
+	LastSyncError *string            `json:"lastSyncError"`
+	// PendingUsers is the number of users that are waiting to be synced, including the ones that failed
+	PendingUsers int64 `json:"pendingUsers"`
+	// FailedUsers is the number of users whose last sync attempt failed and that will be retried
+	FailedUsers      int64 `json:"failedUsers"`
+	ProvisionedUsers int64 `json:"provisionedUsers"`
+}
+
+type ScimProvisioningTargetUpdateDto struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	// Token is only required when the target is created, if it's empty the existing token is kept
+	Token   string `json:"token"`
+	Enabled bool   `json:"enabled"`
+}
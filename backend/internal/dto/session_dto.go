@@ -0,0 +1,23 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type SessionDto struct {
+	ID         string            `json:"id"`
+	CreatedAt  datatype.DateTime `json:"createdAt"`
+	IpAddress  string            `json:"ipAddress"`
+	Country    string            `json:"country"`
+	City       string            `json:"city"`
+	Device     string            `json:"device"`
+	LastSeenAt datatype.DateTime `json:"lastSeenAt"`
+	ExpiresAt  datatype.DateTime `json:"expiresAt"`
+	Current    bool              `json:"current"`
+	UserID     string            `json:"userID"`
+	Username   string            `json:"username"`
+}
+
+type SessionFilterDto struct {
+	UserID string `form:"filters[userId]"`
+}
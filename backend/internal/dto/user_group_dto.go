@@ -5,38 +5,45 @@ import (
 )
 
 type UserGroupDto struct {
-	ID           string            `json:"id"`
-	FriendlyName string            `json:"friendlyName"`
-	Name         string            `json:"name"`
-	CustomClaims []CustomClaimDto  `json:"customClaims"`
-	LdapID       *string           `json:"ldapId"`
-	CreatedAt    datatype.DateTime `json:"createdAt"`
+	ID             string            `json:"id"`
+	FriendlyName   string            `json:"friendlyName"`
+	Name           string            `json:"name"`
+	CustomClaims   []CustomClaimDto  `json:"customClaims"`
+	LdapID         *string           `json:"ldapId"`
+	ScimExternalID *string           `json:"scimExternalId"`
+	ConfigManaged  bool              `json:"configManaged"`
+	CreatedAt      datatype.DateTime `json:"createdAt"`
 }
 
 type UserGroupDtoWithUsers struct {
-	ID           string            `json:"id"`
-	FriendlyName string            `json:"friendlyName"`
-	Name         string            `json:"name"`
-	CustomClaims []CustomClaimDto  `json:"customClaims"`
-	Users        []UserDto         `json:"users"`
-	LdapID       *string           `json:"ldapId"`
-	CreatedAt    datatype.DateTime `json:"createdAt"`
+	ID             string            `json:"id"`
+	FriendlyName   string            `json:"friendlyName"`
+	Name           string            `json:"name"`
+	CustomClaims   []CustomClaimDto  `json:"customClaims"`
+	Users          []UserDto         `json:"users"`
+	LdapID         *string           `json:"ldapId"`
+	ScimExternalID *string           `json:"scimExternalId"`
+	ConfigManaged  bool              `json:"configManaged"`
+	CreatedAt      datatype.DateTime `json:"createdAt"`
 }
 
 type UserGroupDtoWithUserCount struct {
-	ID           string            `json:"id"`
-	FriendlyName string            `json:"friendlyName"`
-	Name         string            `json:"name"`
-	CustomClaims []CustomClaimDto  `json:"customClaims"`
-	UserCount    int64             `json:"userCount"`
-	LdapID       *string           `json:"ldapId"`
-	CreatedAt    datatype.DateTime `json:"createdAt"`
+	ID             string            `json:"id"`
+	FriendlyName   string            `json:"friendlyName"`
+	Name           string            `json:"name"`
+	CustomClaims   []CustomClaimDto  `json:"customClaims"`
+	UserCount      int64             `json:"userCount"`
+	LdapID         *string           `json:"ldapId"`
+	ScimExternalID *string           `json:"scimExternalId"`
+	ConfigManaged  bool              `json:"configManaged"`
+	CreatedAt      datatype.DateTime `json:"createdAt"`
 }
 
 type UserGroupCreateDto struct {
-	FriendlyName string `json:"friendlyName" binding:"required,min=2,max=50"`
-	Name         string `json:"name" binding:"required,min=2,max=255"`
-	LdapID       string `json:"-"`
+	FriendlyName   string  `json:"friendlyName" binding:"required,min=2,max=50"`
+	Name           string  `json:"name" binding:"required,min=2,max=255"`
+	LdapID         string  `json:"-"`
+	ScimExternalID *string `json:"-"`
 }
 
 type UserGroupUpdateUsersDto struct {
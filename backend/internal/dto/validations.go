@@ -17,6 +17,11 @@ var validateUsername validator.Func = func(fl validator.FieldLevel) bool {
 	return matched
 }
 
+// Validate validates the struct with the same rules as the request bodies
+func Validate(v any) error {
+	return binding.Validator.ValidateStruct(v)
+}
+
 func init() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		if err := v.RegisterValidation("username", validateUsername); err != nil {
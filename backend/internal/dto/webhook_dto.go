@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type WebhookDto struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	URL       string              `json:"url"`
+	Events    model.WebhookEvents `json:"events"`
+	Enabled   bool                `json:"enabled"`
+	CreatedAt datatype.DateTime   `json:"createdAt"`
+}
+
+type WebhookCreateDto struct {
+	Name    string   `json:"name" binding:"required,max=50"`
+	URL     string   `json:"url" binding:"required,url"`
+	Events  []string `json:"events" binding:"required,min=1"`
+	Enabled bool     `json:"enabled"`
+}
+
+type WebhookResponseDto struct {
+	Webhook WebhookDto `json:"webhook"`
+	Secret  string     `json:"secret"`
+}
+
+type WebhookDeliveryDto struct {
+	ID                 string                      `json:"id"`
+	Event              model.WebhookEvent          `json:"event"`
+	Payload            string                      `json:"payload"`
+	Status             model.WebhookDeliveryStatus `json:"status"`
+	Attempts           int                         `json:"attempts"`
+	NextAttemptAt      *datatype.DateTime          `json:"nextAttemptAt"`
+	LastAttemptAt      *datatype.DateTime          `json:"lastAttemptAt"`
+	ResponseStatusCode *int                        `json:"responseStatusCode"`
+	LastError          *string                     `json:"lastError"`
+	CreatedAt          datatype.DateTime           `json:"createdAt"`
+}
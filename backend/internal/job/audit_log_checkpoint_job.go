@@ -0,0 +1,34 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+type AuditLogCheckpointJobs struct {
+	auditLogChainService *service.AuditLogChainService
+}
+
+func (s *Scheduler) RegisterAuditLogCheckpointJobs(ctx context.Context, auditLogChainService *service.AuditLogChainService) error {
+	jobs := &AuditLogCheckpointJobs{auditLogChainService: auditLogChainService}
+
+	// Sign the audit log chain every hour, so that at most the audit logs of the last hour aren't covered by a checkpoint
+	return s.registerJob(ctx, "CreateAuditLogCheckpoint", gocron.DurationJob(time.Hour), jobs.createCheckpoint, true)
+}
+
+func (j *AuditLogCheckpointJobs) createCheckpoint(ctx context.Context) error {
+	created, err := j.auditLogChainService.CreateCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		slog.InfoContext(ctx, "Created audit log checkpoint")
+	}
+	return nil
+}
@@ -10,26 +10,33 @@ import (
 	"github.com/go-co-op/gocron/v2"
 	"gorm.io/gorm"
 
+	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/model"
 	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
 )
 
-func (s *Scheduler) RegisterDbCleanupJobs(ctx context.Context, db *gorm.DB) error {
-	jobs := &DbCleanupJobs{db: db}
+func (s *Scheduler) RegisterDbCleanupJobs(ctx context.Context, db *gorm.DB, auditLogService *service.AuditLogService) error {
+	jobs := &DbCleanupJobs{db: db, auditLogService: auditLogService}
 
 	// Run every 24 hours (but with some jitter so they don't run at the exact same time), and now
 	def := gocron.DurationRandomJob(24*time.Hour-2*time.Minute, 24*time.Hour+2*time.Minute)
 	return errors.Join(
 		s.registerJob(ctx, "ClearWebauthnSessions", def, jobs.clearWebauthnSessions, true),
+		s.registerJob(ctx, "ClearSessions", def, jobs.clearSessions, true),
 		s.registerJob(ctx, "ClearOneTimeAccessTokens", def, jobs.clearOneTimeAccessTokens, true),
 		s.registerJob(ctx, "ClearOidcAuthorizationCodes", def, jobs.clearOidcAuthorizationCodes, true),
 		s.registerJob(ctx, "ClearOidcRefreshTokens", def, jobs.clearOidcRefreshTokens, true),
+		s.registerJob(ctx, "ClearForwardAuthCodes", def, jobs.clearForwardAuthCodes, true),
+		s.registerJob(ctx, "ClearIdentityProviderLoginStates", def, jobs.clearIdentityProviderLoginStates, true),
+		s.registerJob(ctx, "ClearWebhookDeliveries", def, jobs.clearWebhookDeliveries, true),
 		s.registerJob(ctx, "ClearAuditLogs", def, jobs.clearAuditLogs, true),
 	)
 }
 
 type DbCleanupJobs struct {
-	db *gorm.DB
+	db              *gorm.DB
+	auditLogService *service.AuditLogService
 }
 
 // ClearWebauthnSessions deletes WebAuthn sessions that have expired
@@ -46,6 +53,20 @@ func (j *DbCleanupJobs) clearWebauthnSessions(ctx context.Context) error {
 	return nil
 }
 
+// ClearSessions deletes user sessions that have expired
+func (j *DbCleanupJobs) clearSessions(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.Session{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired sessions: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired sessions", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
 // ClearOneTimeAccessTokens deletes one-time access tokens that have expired
 func (j *DbCleanupJobs) clearOneTimeAccessTokens(ctx context.Context) error {
 	st := j.db.
@@ -88,16 +109,65 @@ func (j *DbCleanupJobs) clearOidcRefreshTokens(ctx context.Context) error {
 	return nil
 }
 
-// ClearAuditLogs deletes audit logs older than 90 days
-func (j *DbCleanupJobs) clearAuditLogs(ctx context.Context) error {
+// ClearForwardAuthCodes deletes forward-auth codes that have expired
+func (j *DbCleanupJobs) clearForwardAuthCodes(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.ForwardAuthCode{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired forward-auth codes: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired forward-auth codes", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearIdentityProviderLoginStates deletes sign ins with identity providers that have timed out
+func (j *DbCleanupJobs) clearIdentityProviderLoginStates(ctx context.Context) error {
 	st := j.db.
 		WithContext(ctx).
-		Delete(&model.AuditLog{}, "created_at < ?", datatype.DateTime(time.Now().AddDate(0, 0, -90)))
+		Delete(&model.IdentityProviderLoginState{}, "expires_at < ?", datatype.DateTime(time.Now()))
 	if st.Error != nil {
-		return fmt.Errorf("failed to delete old audit logs: %w", st.Error)
+		return fmt.Errorf("failed to clean expired identity provider login states: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired identity provider login states", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearWebhookDeliveries deletes completed webhook deliveries older than 30 days
+func (j *DbCleanupJobs) clearWebhookDeliveries(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.WebhookDelivery{}, "status <> ? AND created_at < ?", model.WebhookDeliveryStatusPending, datatype.DateTime(time.Now().AddDate(0, 0, -30)))
+	if st.Error != nil {
+		return fmt.Errorf("failed to delete old webhook deliveries: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Deleted old webhook deliveries", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearAuditLogs deletes audit logs that are older than their configured retention, archiving them first if enabled
+func (j *DbCleanupJobs) clearAuditLogs(ctx context.Context) error {
+	retention := service.AuditLogRetention{
+		Days:        common.EnvConfig.AuditLogRetentionDays,
+		DaysByEvent: make(map[model.AuditLogEvent]int, len(common.EnvConfig.AuditLogRetentionDaysByEvent)),
+		ArchivePath: common.EnvConfig.AuditLogArchivePath,
+	}
+	for event, days := range common.EnvConfig.AuditLogRetentionDaysByEvent {
+		retention.DaysByEvent[model.AuditLogEvent(event)] = days
+	}
+
+	count, err := j.auditLogService.DeleteExpiredAuditLogs(ctx, retention)
+	if err != nil {
+		return fmt.Errorf("failed to delete old audit logs: %w", err)
 	}
 
-	slog.InfoContext(ctx, "Deleted old audit logs", slog.Int64("count", st.RowsAffected))
+	slog.InfoContext(ctx, "Deleted old audit logs", slog.Int64("count", count))
 
 	return nil
 }
@@ -0,0 +1,25 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+type ScimProvisioningJobs struct {
+	scimProvisioningService *service.ScimProvisioningService
+}
+
+func (s *Scheduler) RegisterScimProvisioningJobs(ctx context.Context, scimProvisioningService *service.ScimProvisioningService) error {
+	jobs := &ScimProvisioningJobs{scimProvisioningService: scimProvisioningService}
+
+	// Process the provisioning queue every minute, so that changes reach the applications quickly
+	return s.registerJob(ctx, "ProcessScimProvisioningQueue", gocron.DurationJob(time.Minute), jobs.processQueue, true)
+}
+
+func (j *ScimProvisioningJobs) processQueue(ctx context.Context) error {
+	return j.scimProvisioningService.ProcessQueue(ctx)
+}
@@ -0,0 +1,25 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+)
+
+type WebhookJobs struct {
+	webhookService *service.WebhookService
+}
+
+func (s *Scheduler) RegisterWebhookJobs(ctx context.Context, webhookService *service.WebhookService) error {
+	jobs := &WebhookJobs{webhookService: webhookService}
+
+	// Send the queued deliveries every minute, so that the webhooks are notified quickly
+	return s.registerJob(ctx, "ProcessWebhookQueue", gocron.DurationJob(time.Minute), jobs.processQueue, true)
+}
+
+func (j *WebhookJobs) processQueue(ctx context.Context) error {
+	return j.webhookService.ProcessQueue(ctx)
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/service"
@@ -9,6 +11,8 @@ import (
 type ApiKeyAuthMiddleware struct {
 	apiKeyService *service.ApiKeyService
 	jwtService    *service.JwtService
+	// allowBearerToken accepts the API key in the Authorization header as well
+	allowBearerToken bool
 }
 
 func NewApiKeyAuthMiddleware(apiKeyService *service.ApiKeyService, jwtService *service.JwtService) *ApiKeyAuthMiddleware {
@@ -18,6 +22,17 @@ func NewApiKeyAuthMiddleware(apiKeyService *service.ApiKeyService, jwtService *s
 	}
 }
 
+// WithBearerToken accepts the API key as a bearer token, for clients that only support those, like SCIM clients.
+// It must only be used for routes that don't accept access tokens, as both are sent in the Authorization header.
+func (m *ApiKeyAuthMiddleware) WithBearerToken() *ApiKeyAuthMiddleware {
+	// Create a new instance to avoid modifying the original
+	return &ApiKeyAuthMiddleware{
+		apiKeyService:    m.apiKeyService,
+		jwtService:       m.jwtService,
+		allowBearerToken: true,
+	}
+}
+
 func (m *ApiKeyAuthMiddleware) Add(adminRequired bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, isAdmin, err := m.Verify(c, adminRequired)
@@ -27,14 +42,16 @@ func (m *ApiKeyAuthMiddleware) Add(adminRequired bool) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("userID", userID)
-		c.Set("userIsAdmin", isAdmin)
+		setAuthenticatedUser(c, userID, isAdmin)
 		c.Next()
 	}
 }
 
 func (m *ApiKeyAuthMiddleware) Verify(c *gin.Context, adminRequired bool) (userID string, isAdmin bool, err error) {
 	apiKey := c.GetHeader("X-API-KEY")
+	if apiKey == "" && m.allowBearerToken {
+		apiKey, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
 
 	user, err := m.apiKeyService.ValidateApiKey(c.Request.Context(), apiKey)
 	if err != nil {
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+func TestApiKeyAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	admin := model.User{Username: "tim", Email: "tim@example.com", IsAdmin: true}
+	require.NoError(t, db.Create(&admin).Error)
+	const apiKey = "test-api-key"
+	require.NoError(t, db.Create(&model.ApiKey{
+		Name:      "SCIM",
+		Key:       utils.CreateSha256Hash(apiKey),
+		ExpiresAt: datatype.DateTime(time.Now().Add(time.Hour)),
+		UserID:    admin.ID,
+	}).Error)
+
+	appConfigService := service.NewAppConfigService(t.Context(), db)
+	geoliteService := service.NewGeoLiteService(nil)
+	sessionService := service.NewSessionService(db, appConfigService, geoliteService)
+	webhookService := service.NewWebhookService(db, nil)
+	auditLogService := service.NewAuditLogService(db, appConfigService, nil, geoliteService, webhookService, nil, nil)
+	scimProvisioningService := service.NewScimProvisioningService(db, nil)
+	userService := service.NewUserService(db, nil, auditLogService, nil, appConfigService, sessionService, scimProvisioningService, webhookService)
+	userGroupService := service.NewUserGroupService(db, appConfigService, scimProvisioningService, webhookService, auditLogService)
+	scimService := service.NewScimService(db, userService, userGroupService, auditLogService)
+	apiKeyMiddleware := NewApiKeyAuthMiddleware(service.NewApiKeyService(db, nil, nil), service.NewJwtService(appConfigService))
+
+	router := gin.New()
+	router.Use(NewErrorHandlerMiddleware().Add())
+	router.GET("/api", apiKeyMiddleware.Add(true), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.POST("/scim/v2/Users", apiKeyMiddleware.WithBearerToken().Add(true), func(c *gin.Context) {
+		_, err := scimService.CreateUser(c.Request.Context(), dto.ScimUserCreateDto{
+			UserName: "craig",
+			Emails:   []dto.ScimEmailDto{{Value: "craig@example.com", Primary: true}},
+		})
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	request := func(method, path, header, value string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("accepts bearer tokens only if enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, request(http.MethodGet, "/api", "X-API-KEY", apiKey))
+		assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api", "Authorization", "Bearer "+apiKey))
+	})
+
+	t.Run("attributes SCIM changes to the owner of the API key", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/scim/v2/Users", "Authorization", "Bearer "+apiKey))
+
+		var auditLog model.AuditLog
+		require.NoError(t, db.Where("event = ?", model.AuditLogEventUserCreated).First(&auditLog).Error)
+		assert.Equal(t, admin.ID, auditLog.UserID)
+		assert.Equal(t, "192.0.2.1", auditLog.IpAddress)
+		assert.Equal(t, "craig", auditLog.Data["targetName"])
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
 	"github.com/pocket-id/pocket-id/backend/internal/service"
 )
 
@@ -18,16 +19,20 @@ type AuthMiddleware struct {
 type AuthOptions struct {
 	AdminRequired   bool
 	SuccessOptional bool
+	// SudoModeAction is the sensitive action protected by the route. If sudo mode is enabled for it,
+	// users authenticated with a session must have re-authenticated with a passkey recently and API keys are rejected.
+	SudoModeAction string
 }
 
 func NewAuthMiddleware(
 	apiKeyService *service.ApiKeyService,
 	userService *service.UserService,
 	jwtService *service.JwtService,
+	sessionService *service.SessionService,
 ) *AuthMiddleware {
 	return &AuthMiddleware{
 		apiKeyMiddleware: NewApiKeyAuthMiddleware(apiKeyService, jwtService),
-		jwtMiddleware:    NewJwtAuthMiddleware(jwtService, userService),
+		jwtMiddleware:    NewJwtAuthMiddleware(jwtService, userService, sessionService),
 		options: AuthOptions{
 			AdminRequired:   true,
 			SuccessOptional: false,
@@ -59,12 +64,32 @@ func (m *AuthMiddleware) WithSuccessOptional() *AuthMiddleware {
 	return clone
 }
 
+// WithSudoMode requires a recent passkey authentication if sudo mode is enabled for the given action
+func (m *AuthMiddleware) WithSudoMode(action string) *AuthMiddleware {
+	// Create a new instance to avoid modifying the original
+	clone := &AuthMiddleware{
+		apiKeyMiddleware: m.apiKeyMiddleware,
+		jwtMiddleware:    m.jwtMiddleware,
+		options:          m.options,
+	}
+	clone.options.SudoModeAction = action
+	return clone
+}
+
 func (m *AuthMiddleware) Add() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, isAdmin, err := m.jwtMiddleware.Verify(c, m.options.AdminRequired)
 		if err == nil {
-			c.Set("userID", userID)
-			c.Set("userIsAdmin", isAdmin)
+			setAuthenticatedUser(c, userID, isAdmin)
+			if m.options.SudoModeAction != "" {
+				session := c.MustGet("session").(model.Session)
+				err = m.jwtMiddleware.sessionService.CheckSudoMode(session, m.options.SudoModeAction)
+				if err != nil {
+					c.Abort()
+					_ = c.Error(err)
+					return
+				}
+			}
 			if c.IsAborted() {
 				return
 			}
@@ -82,8 +107,16 @@ func (m *AuthMiddleware) Add() gin.HandlerFunc {
 		// JWT auth failed, try API key auth
 		userID, isAdmin, err = m.apiKeyMiddleware.Verify(c, m.options.AdminRequired)
 		if err == nil {
-			c.Set("userID", userID)
-			c.Set("userIsAdmin", isAdmin)
+			if m.options.SudoModeAction != "" {
+				// API keys can't re-authenticate with a passkey, otherwise they could be used to bypass sudo mode
+				err = m.jwtMiddleware.sessionService.CheckSudoModeForApiKey(m.options.SudoModeAction)
+				if err != nil {
+					c.Abort()
+					_ = c.Error(err)
+					return
+				}
+			}
+			setAuthenticatedUser(c, userID, isAdmin)
 			if c.IsAborted() {
 				return
 			}
@@ -101,3 +134,16 @@ func (m *AuthMiddleware) Add() gin.HandlerFunc {
 		_ = c.Error(err)
 	}
 }
+
+// setAuthenticatedUser stores the authenticated user in the context and attributes the admin actions of the request to them
+func setAuthenticatedUser(c *gin.Context, userID string, isAdmin bool) {
+	c.Set("userID", userID)
+	c.Set("userIsAdmin", isAdmin)
+
+	ctx := service.ContextWithAuditActor(c.Request.Context(), service.AuditActor{
+		UserID:    userID,
+		IpAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	c.Request = c.Request.WithContext(ctx)
+}
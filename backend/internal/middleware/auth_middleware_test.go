@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-migrate/migrate/v4"
+	sqliteMigrate "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+	"github.com/pocket-id/pocket-id/backend/resources"
+)
+
+func TestAuthMiddlewareSudoModeWithApiKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	admin := model.User{Username: "tim", Email: "tim@example.com", IsAdmin: true}
+	require.NoError(t, db.Create(&admin).Error)
+	const apiKey = "test-api-key"
+	require.NoError(t, db.Create(&model.ApiKey{
+		Name:      "Automation",
+		Key:       utils.CreateSha256Hash(apiKey),
+		ExpiresAt: datatype.DateTime(time.Now().Add(time.Hour)),
+		UserID:    admin.ID,
+	}).Error)
+
+	// Enable sudo mode, which is required for the users action by default
+	require.NoError(t, db.Create(&model.AppConfigVariable{Key: "sudoModeDuration", Value: "15"}).Error)
+	appConfigService := service.NewAppConfigService(t.Context(), db)
+	sessionService := service.NewSessionService(db, appConfigService, nil)
+	jwtService := service.NewJwtService(appConfigService)
+	authMiddleware := NewAuthMiddleware(service.NewApiKeyService(db, nil, nil), nil, jwtService, sessionService)
+
+	router := gin.New()
+	router.Use(NewErrorHandlerMiddleware().Add())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/regular", authMiddleware.Add(), ok)
+	router.GET("/sudo", authMiddleware.WithSudoMode(model.SudoModeActionUsers).Add(), ok)
+	router.GET("/sudo-disabled", authMiddleware.WithSudoMode("not-configured").Add(), ok)
+
+	request := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-KEY", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, request("/regular"))
+	assert.Equal(t, http.StatusForbidden, request("/sudo"), "API keys must not bypass sudo mode")
+	assert.Equal(t, http.StatusNoContent, request("/sudo-disabled"))
+}
+
+// newDatabaseForTest returns a new in-memory SQLite database with the embedded migrations applied
+func newDatabaseForTest(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+utils.CreateSha256Hash(t.Name())+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err, "Failed to connect to test database")
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err, "Failed to get sql.DB")
+	driver, err := sqliteMigrate.WithInstance(sqlDB, &sqliteMigrate.Config{})
+	require.NoError(t, err, "Failed to create migration driver")
+	source, err := iofs.New(resources.FS, "migrations/sqlite")
+	require.NoError(t, err, "Failed to create embedded migration source")
+	m, err := migrate.NewWithInstance("iofs", source, "pocket-id", driver)
+	require.NoError(t, err, "Failed to create migration instance")
+	require.NoError(t, m.Up(), "Failed to perform migrations")
+
+	return db
+}
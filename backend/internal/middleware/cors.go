@@ -2,14 +2,27 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
 
-type CorsMiddleware struct{}
+type CorsMiddleware struct {
+	// allowedOrigins can be changed at runtime when the configuration is reloaded
+	allowedOrigins atomic.Pointer[[]string]
+}
+
+// NewCorsMiddleware creates a middleware that allows browsers to call the OIDC endpoints from the allowed origins,
+// or from all origins if there are none
+func NewCorsMiddleware(allowedOrigins []string) *CorsMiddleware {
+	m := &CorsMiddleware{}
+	m.SetAllowedOrigins(allowedOrigins)
+	return m
+}
 
-func NewCorsMiddleware() *CorsMiddleware {
-	return &CorsMiddleware{}
+func (m *CorsMiddleware) SetAllowedOrigins(allowedOrigins []string) {
+	m.allowedOrigins.Store(&allowedOrigins)
 }
 
 func (m *CorsMiddleware) Add() gin.HandlerFunc {
@@ -25,7 +38,17 @@ func (m *CorsMiddleware) Add() gin.HandlerFunc {
 			return
 		}
 
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		allowedOrigins := *m.allowedOrigins.Load()
+		if len(allowedOrigins) == 0 {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			// The response depends on the origin, so caches must not share it between origins
+			c.Writer.Header().Add("Vary", "Origin")
+			origin := c.GetHeader("Origin")
+			if slices.Contains(allowedOrigins, origin) {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST")
 
@@ -23,46 +23,59 @@ func (m *ErrorHandlerMiddleware) Add() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 		for _, err := range c.Errors {
+			statusCode, message := resolveError(err)
 
-			// Check for record not found errors
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				errorResponse(c, http.StatusNotFound, "Record not found")
+			var codeErr common.AppErrorWithCode
+			if errors.As(err, &codeErr) {
+				c.JSON(statusCode, gin.H{"error": capitalize(message), "code": codeErr.ErrorCode()})
 				return
 			}
 
-			// Check for validation errors
-			var validationErrors validator.ValidationErrors
-			if errors.As(err, &validationErrors) {
-				message := handleValidationError(validationErrors)
-				errorResponse(c, http.StatusBadRequest, message)
-				return
-			}
+			errorResponse(c, statusCode, message)
+			return
+		}
+	}
+}
 
-			// Check for slice validation errors
-			var sliceValidationErrors binding.SliceValidationError
-			if errors.As(err, &sliceValidationErrors) {
-				if errors.As(sliceValidationErrors[0], &validationErrors) {
-					message := handleValidationError(validationErrors)
-					errorResponse(c, http.StatusBadRequest, message)
-					return
-				}
-			}
+// resolveError returns the HTTP status code and the message that should be sent to the client for an error
+func resolveError(err error) (statusCode int, message string) {
+	// Check for record not found errors
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound, "Record not found"
+	}
 
-			var appErr common.AppError
-			if errors.As(err, &appErr) {
-				errorResponse(c, appErr.HttpStatusCode(), appErr.Error())
-				return
-			}
+	// Check for validation errors
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return http.StatusBadRequest, handleValidationError(validationErrors)
+	}
 
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+	// Check for slice validation errors
+	var sliceValidationErrors binding.SliceValidationError
+	if errors.As(err, &sliceValidationErrors) {
+		if errors.As(sliceValidationErrors[0], &validationErrors) {
+			return http.StatusBadRequest, handleValidationError(validationErrors)
 		}
 	}
+
+	var appErr common.AppError
+	if errors.As(err, &appErr) {
+		return appErr.HttpStatusCode(), appErr.Error()
+	}
+
+	return http.StatusInternalServerError, "Something went wrong"
+}
+
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
 }
 
 func errorResponse(c *gin.Context, statusCode int, message string) {
 	// Capitalize the first letter of the message
-	message = strings.ToUpper(message[:1]) + message[1:]
-	c.JSON(statusCode, gin.H{"error": message})
+	c.JSON(statusCode, gin.H{"error": capitalize(message)})
 }
 
 func handleValidationError(validationErrors validator.ValidationErrors) string {
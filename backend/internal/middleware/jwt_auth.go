@@ -1,21 +1,26 @@
 package middleware
 
 import (
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v3/jwt"
 	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
 	"github.com/pocket-id/pocket-id/backend/internal/service"
 	"github.com/pocket-id/pocket-id/backend/internal/utils/cookie"
 )
 
 type JwtAuthMiddleware struct {
-	userService *service.UserService
-	jwtService  *service.JwtService
+	userService    *service.UserService
+	jwtService     *service.JwtService
+	sessionService *service.SessionService
 }
 
-func NewJwtAuthMiddleware(jwtService *service.JwtService, userService *service.UserService) *JwtAuthMiddleware {
-	return &JwtAuthMiddleware{jwtService: jwtService, userService: userService}
+func NewJwtAuthMiddleware(jwtService *service.JwtService, userService *service.UserService, sessionService *service.SessionService) *JwtAuthMiddleware {
+	return &JwtAuthMiddleware{jwtService: jwtService, userService: userService, sessionService: sessionService}
 }
 
 func (m *JwtAuthMiddleware) Add(adminRequired bool) gin.HandlerFunc {
@@ -36,6 +41,7 @@ func (m *JwtAuthMiddleware) Add(adminRequired bool) gin.HandlerFunc {
 func (m *JwtAuthMiddleware) Verify(c *gin.Context, adminRequired bool) (subject string, isAdmin bool, err error) {
 	// Extract the token from the cookie
 	accessToken, err := c.Cookie(cookie.AccessTokenCookieName)
+	fromCookie := err == nil
 	if err != nil {
 		// Try to extract the token from the Authorization header if it's not in the cookie
 		var ok bool
@@ -50,24 +56,82 @@ func (m *JwtAuthMiddleware) Verify(c *gin.Context, adminRequired bool) (subject
 		return "", false, &common.NotSignedInError{}
 	}
 
+	user, session, renewed, err := m.validateToken(c, token)
+	if err != nil {
+		return "", false, err
+	}
+
+	if adminRequired && !user.IsAdmin {
+		return "", false, &common.MissingPermissionError{}
+	}
+
+	// If the session was renewed, issue a new access token so the idle timeout slides forward
+	if renewed && fromCookie {
+		m.renewAccessTokenCookie(c, user, session)
+	}
+
+	c.Set("sessionID", session.ID)
+	c.Set("session", session)
+
+	return user.ID, user.IsAdmin, nil
+}
+
+// VerifyForwardAuth verifies the forward-auth cookie that was issued for the given host and returns the signed in user
+// Unlike the access token, the cookie isn't renewed as it's only sent to the protected host
+func (m *JwtAuthMiddleware) VerifyForwardAuth(c *gin.Context, host string) (model.User, error) {
+	forwardAuthToken, err := c.Cookie(cookie.ForwardAuthCookieName)
+	if err != nil || forwardAuthToken == "" {
+		return model.User{}, &common.NotSignedInError{}
+	}
+
+	token, err := m.jwtService.VerifyForwardAuthToken(forwardAuthToken, host)
+	if err != nil {
+		return model.User{}, &common.NotSignedInError{}
+	}
+
+	user, _, _, err := m.validateToken(c, token)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// validateToken makes sure that the user of the token exists and that its server-side session hasn't been revoked or expired
+func (m *JwtAuthMiddleware) validateToken(c *gin.Context, token jwt.Token) (user model.User, session model.Session, renewed bool, err error) {
 	subject, ok := token.Subject()
 	if !ok {
-		_ = c.Error(&common.TokenInvalidError{})
-		return
+		return model.User{}, model.Session{}, false, &common.TokenInvalidError{}
 	}
 
-	user, err := m.userService.GetUser(c, subject)
+	user, err = m.userService.GetUser(c, subject)
 	if err != nil {
-		return "", false, &common.NotSignedInError{}
+		return model.User{}, model.Session{}, false, &common.NotSignedInError{}
+	}
+
+	sessionID, err := service.GetSessionID(token)
+	if err != nil {
+		return model.User{}, model.Session{}, false, &common.NotSignedInError{}
+	}
+	session, renewed, err = m.sessionService.ValidateSession(c.Request.Context(), sessionID, user)
+	if err != nil {
+		return model.User{}, model.Session{}, false, &common.NotSignedInError{}
 	}
 
 	if user.Disabled {
-		return "", false, &common.UserDisabledError{}
+		return model.User{}, model.Session{}, false, &common.UserDisabledError{}
 	}
 
-	if adminRequired && !user.IsAdmin {
-		return "", false, &common.MissingPermissionError{}
+	return user, session, renewed, nil
+}
+
+func (m *JwtAuthMiddleware) renewAccessTokenCookie(c *gin.Context, user model.User, session model.Session) {
+	accessToken, err := m.jwtService.GenerateAccessToken(user, session.ID, m.sessionService.AccessTokenExpiration(session, user.IsAdmin))
+	if err != nil {
+		log.Printf("Failed to renew access token: %v", err)
+		return
 	}
 
-	return subject, isAdmin, nil
+	maxAge := int(time.Until(session.ExpiresAt.ToTime()).Seconds())
+	cookie.AddAccessTokenCookie(c, maxAge, accessToken)
 }
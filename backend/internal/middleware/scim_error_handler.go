@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+)
+
+// ScimErrorHandlerMiddleware writes errors in the format defined by RFC 7644 section 3.12.
+// It must be registered after the global ErrorHandlerMiddleware, so that it handles the errors first.
+type ScimErrorHandlerMiddleware struct{}
+
+func NewScimErrorHandlerMiddleware() *ScimErrorHandlerMiddleware {
+	return &ScimErrorHandlerMiddleware{}
+}
+
+func (m *ScimErrorHandlerMiddleware) Add() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 {
+			return
+		}
+
+		err := c.Errors[0].Err
+		statusCode, message := resolveError(err)
+
+		var scimType string
+		var filterErr *common.ScimInvalidFilterError
+		var patchErr *common.ScimInvalidPatchError
+		switch {
+		case errors.Is(err, &common.AlreadyInUseError{}):
+			statusCode = http.StatusConflict
+			scimType = "uniqueness"
+		case errors.As(err, &filterErr):
+			scimType = "invalidFilter"
+		case errors.As(err, &patchErr):
+			scimType = "invalidValue"
+		}
+
+		c.Header("Content-Type", "application/scim+json")
+		c.JSON(statusCode, dto.ScimErrorDto{
+			Schemas:  []string{dto.ScimSchemaError},
+			Status:   strconv.Itoa(statusCode),
+			ScimType: scimType,
+			Detail:   capitalize(message),
+		})
+
+		// The errors are handled, so the global error handler must not write another response
+		c.Errors = c.Errors[:0]
+	}
+}
@@ -13,6 +13,8 @@ import (
 type AppConfigVariable struct {
 	Key   string `gorm:"primaryKey;not null"`
 	Value string
+	// ConfigManaged is set for values that are managed by the declarative configuration file
+	ConfigManaged bool
 }
 
 // IsTrue returns true if the value is a truthy string, such as "true", "t", "yes", "1", etc.
@@ -32,11 +34,16 @@ func (a *AppConfigVariable) AsDurationMinutes() time.Duration {
 
 type AppConfig struct {
 	// General
-	AppName             AppConfigVariable `key:"appName,public"` // Public
-	SessionDuration     AppConfigVariable `key:"sessionDuration"`
-	EmailsVerified      AppConfigVariable `key:"emailsVerified"`
-	DisableAnimations   AppConfigVariable `key:"disableAnimations,public"`   // Public
-	AllowOwnAccountEdit AppConfigVariable `key:"allowOwnAccountEdit,public"` // Public
+	AppName                 AppConfigVariable `key:"appName,public"` // Public
+	SessionDuration         AppConfigVariable `key:"sessionDuration"`
+	SessionIdleTimeout      AppConfigVariable `key:"sessionIdleTimeout"`
+	AdminSessionDuration    AppConfigVariable `key:"adminSessionDuration"`    // If empty, SessionDuration is used
+	AdminSessionIdleTimeout AppConfigVariable `key:"adminSessionIdleTimeout"` // If empty, SessionIdleTimeout is used
+	SudoModeDuration        AppConfigVariable `key:"sudoModeDuration"`        // If 0, sudo mode is disabled
+	SudoModeActions         AppConfigVariable `key:"sudoModeActions"`
+	EmailsVerified          AppConfigVariable `key:"emailsVerified"`
+	DisableAnimations       AppConfigVariable `key:"disableAnimations,public"`   // Public
+	AllowOwnAccountEdit     AppConfigVariable `key:"allowOwnAccountEdit,public"` // Public
 	// Internal
 	BackgroundImageType AppConfigVariable `key:"backgroundImageType,internal"` // Internal
 	LogoLightImageType  AppConfigVariable `key:"logoLightImageType,internal"`  // Internal
@@ -47,7 +54,7 @@ type AppConfig struct {
 	SmtpPort                                   AppConfigVariable `key:"smtpPort"`
 	SmtpFrom                                   AppConfigVariable `key:"smtpFrom"`
 	SmtpUser                                   AppConfigVariable `key:"smtpUser"`
-	SmtpPassword                               AppConfigVariable `key:"smtpPassword"`
+	SmtpPassword                               AppConfigVariable `key:"smtpPassword,sensitive"`
 	SmtpTls                                    AppConfigVariable `key:"smtpTls"`
 	SmtpSkipCertVerify                         AppConfigVariable `key:"smtpSkipCertVerify"`
 	EmailLoginNotificationEnabled              AppConfigVariable `key:"emailLoginNotificationEnabled"`
@@ -58,7 +65,7 @@ type AppConfig struct {
 	LdapEnabled                        AppConfigVariable `key:"ldapEnabled,public"` // Public
 	LdapUrl                            AppConfigVariable `key:"ldapUrl"`
 	LdapBindDn                         AppConfigVariable `key:"ldapBindDn"`
-	LdapBindPassword                   AppConfigVariable `key:"ldapBindPassword"`
+	LdapBindPassword                   AppConfigVariable `key:"ldapBindPassword,sensitive"`
 	LdapBase                           AppConfigVariable `key:"ldapBase"`
 	LdapUserSearchFilter               AppConfigVariable `key:"ldapUserSearchFilter"`
 	LdapUserGroupSearchFilter          AppConfigVariable `key:"ldapUserGroupSearchFilter"`
@@ -76,6 +83,44 @@ type AppConfig struct {
 	LdapSoftDeleteUsers                AppConfigVariable `key:"ldapSoftDeleteUsers"`
 }
 
+// SessionDurationForUser returns the absolute lifetime of a session, which depends on whether the user is an admin
+func (c *AppConfig) SessionDurationForUser(isAdmin bool) time.Duration {
+	if isAdmin && c.AdminSessionDuration.Value != "" {
+		return c.AdminSessionDuration.AsDurationMinutes()
+	}
+	return c.SessionDuration.AsDurationMinutes()
+}
+
+// SessionIdleTimeoutForUser returns the maximum inactivity time of a session, which depends on whether the user is an admin
+// A value of 0 means that sessions don't expire because of inactivity
+func (c *AppConfig) SessionIdleTimeoutForUser(isAdmin bool) time.Duration {
+	if isAdmin && c.AdminSessionIdleTimeout.Value != "" {
+		return c.AdminSessionIdleTimeout.AsDurationMinutes()
+	}
+	return c.SessionIdleTimeout.AsDurationMinutes()
+}
+
+// Sensitive actions that can be protected by sudo mode, configured with SudoModeActions
+const (
+	SudoModeActionUsers         = "users"
+	SudoModeActionClientSecrets = "client-secrets"
+	SudoModeActionAppConfig     = "app-config"
+)
+
+// IsSudoModeRequired returns true if the given sensitive action requires a recent re-authentication
+func (c *AppConfig) IsSudoModeRequired(action string) bool {
+	if c.SudoModeDuration.AsDurationMinutes() <= 0 {
+		return false
+	}
+
+	for a := range strings.SplitSeq(c.SudoModeActions.Value, ",") {
+		if strings.TrimSpace(a) == action {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *AppConfig) ToAppConfigVariableSlice(showAll bool) []AppConfigVariable {
 	// Use reflection to iterate through all fields
 	cfgValue := reflect.ValueOf(c).Elem()
@@ -99,8 +144,9 @@ func (c *AppConfig) ToAppConfigVariableSlice(showAll bool) []AppConfigVariable {
 		fieldValue := cfgValue.Field(i)
 
 		appConfigVariable := AppConfigVariable{
-			Key:   key,
-			Value: fieldValue.FieldByName("Value").String(),
+			Key:           key,
+			Value:         fieldValue.FieldByName("Value").String(),
+			ConfigManaged: fieldValue.FieldByName("ConfigManaged").Bool(),
 		}
 
 		res = append(res, appConfigVariable)
@@ -109,6 +155,18 @@ func (c *AppConfig) ToAppConfigVariableSlice(showAll bool) []AppConfigVariable {
 	return res
 }
 
+// IsSensitiveAppConfigKey returns true if the value of the configuration variable is a secret
+func IsSensitiveAppConfigKey(key string) bool {
+	cfgType := reflect.TypeFor[AppConfig]()
+	for i := range cfgType.NumField() {
+		fieldKey, attrs, _ := strings.Cut(cfgType.Field(i).Tag.Get("key"), ",")
+		if fieldKey == key {
+			return attrs == "sensitive"
+		}
+	}
+	return false
+}
+
 func (c *AppConfig) FieldByKey(key string) (defaultValue string, isInternal bool, err error) {
 	rv := reflect.ValueOf(c).Elem()
 	rt := rv.Type()
@@ -164,6 +222,22 @@ func (c *AppConfig) UpdateField(key string, value string, noInternal bool) error
 	return AppConfigKeyNotFoundError{field: key}
 }
 
+// SetFieldConfigManaged marks the field as managed by the declarative configuration file
+func (c *AppConfig) SetFieldConfigManaged(key string, configManaged bool) error {
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		tagValue, _, _ := strings.Cut(rt.Field(i).Tag.Get("key"), ",")
+		if tagValue == key {
+			rv.Field(i).FieldByName("ConfigManaged").SetBool(configManaged)
+			return nil
+		}
+	}
+
+	return AppConfigKeyNotFoundError{field: key}
+}
+
 type AppConfigKeyNotFoundError struct {
 	field string
 }
@@ -127,3 +127,22 @@ func TestAppConfigStructMatchesUpdateDto(t *testing.T) {
 		assert.True(t, found, "Field %s with json tag '%s' in AppConfigUpdateDto has no matching field in AppConfig", fieldName, jsonName)
 	}
 }
+
+func TestAppConfig_SessionLimitsForUser(t *testing.T) {
+	config := model.AppConfig{
+		SessionDuration:    model.AppConfigVariable{Value: "720"},
+		SessionIdleTimeout: model.AppConfigVariable{Value: "30"},
+	}
+
+	// Without admin-specific limits, admins use the general ones
+	assert.Equal(t, 12*time.Hour, config.SessionDurationForUser(true))
+	assert.Equal(t, 30*time.Minute, config.SessionIdleTimeoutForUser(true))
+
+	config.AdminSessionDuration = model.AppConfigVariable{Value: "60"}
+	config.AdminSessionIdleTimeout = model.AppConfigVariable{Value: "10"}
+
+	assert.Equal(t, 12*time.Hour, config.SessionDurationForUser(false))
+	assert.Equal(t, 30*time.Minute, config.SessionIdleTimeoutForUser(false))
+	assert.Equal(t, time.Hour, config.SessionDurationForUser(true))
+	assert.Equal(t, 10*time.Minute, config.SessionIdleTimeoutForUser(true))
+}
@@ -0,0 +1,15 @@
+package model
+
+import datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+
+// AppPassword is a password a user can use to authenticate to applications that don't support passkeys, like the LDAP server
+type AppPassword struct {
+	Base
+
+	Name string
+	// Password is the SHA-256 hash of the generated password
+	Password   string
+	LastUsedAt *datatype.DateTime
+
+	UserID string
+}
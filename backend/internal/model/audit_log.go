@@ -4,6 +4,8 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
 )
 
 type AuditLog struct {
@@ -16,6 +18,8 @@ type AuditLog struct {
 	UserAgent string        `sortable:"true"`
 	Username  string        `gorm:"-"`
 	Data      AuditLogData
+	// ImportedAt is set for audit logs that were imported from an archive
+	ImportedAt *datatype.DateTime
 
 	UserID string
 	User   User
@@ -32,6 +36,40 @@ const (
 	AuditLogEventNewClientAuthorization     AuditLogEvent = "NEW_CLIENT_AUTHORIZATION"
 	AuditLogEventDeviceCodeAuthorization    AuditLogEvent = "DEVICE_CODE_AUTHORIZATION"
 	AuditLogEventNewDeviceCodeAuthorization AuditLogEvent = "NEW_DEVICE_CODE_AUTHORIZATION"
+	AuditLogEventUserDisabled               AuditLogEvent = "USER_DISABLED"
+	AuditLogEventSudoModeElevation          AuditLogEvent = "SUDO_MODE_ELEVATION"
+	AuditLogEventSamlSignIn                 AuditLogEvent = "SAML_SIGN_IN"
+	AuditLogEventIdentityProviderSignIn     AuditLogEvent = "IDENTITY_PROVIDER_SIGN_IN"
+	AuditLogEventLdapBindFailed             AuditLogEvent = "LDAP_BIND_FAILED"
+
+	// Admin and configuration actions, the user of these events is the user that performed the action
+	AuditLogEventUserCreated                    AuditLogEvent = "USER_CREATED"
+	AuditLogEventUserUpdated                    AuditLogEvent = "USER_UPDATED"
+	AuditLogEventUserDeleted                    AuditLogEvent = "USER_DELETED"
+	AuditLogEventUserGroupsUpdated              AuditLogEvent = "USER_GROUPS_UPDATED"
+	AuditLogEventOneTimeAccessTokenCreated      AuditLogEvent = "ONE_TIME_ACCESS_TOKEN_CREATED"
+	AuditLogEventUserGroupCreated               AuditLogEvent = "USER_GROUP_CREATED"
+	AuditLogEventUserGroupUpdated               AuditLogEvent = "USER_GROUP_UPDATED"
+	AuditLogEventUserGroupDeleted               AuditLogEvent = "USER_GROUP_DELETED"
+	AuditLogEventUserGroupMembersUpdated        AuditLogEvent = "USER_GROUP_MEMBERS_UPDATED"
+	AuditLogEventOidcClientCreated              AuditLogEvent = "OIDC_CLIENT_CREATED"
+	AuditLogEventOidcClientUpdated              AuditLogEvent = "OIDC_CLIENT_UPDATED"
+	AuditLogEventOidcClientDeleted              AuditLogEvent = "OIDC_CLIENT_DELETED"
+	AuditLogEventOidcClientSecretCreated        AuditLogEvent = "OIDC_CLIENT_SECRET_CREATED"
+	AuditLogEventOidcClientAllowedGroupsUpdated AuditLogEvent = "OIDC_CLIENT_ALLOWED_GROUPS_UPDATED"
+	AuditLogEventApiKeyCreated                  AuditLogEvent = "API_KEY_CREATED"
+	AuditLogEventApiKeyRevoked                  AuditLogEvent = "API_KEY_REVOKED"
+	AuditLogEventCustomClaimsUpdated            AuditLogEvent = "CUSTOM_CLAIMS_UPDATED"
+	AuditLogEventAppConfigUpdated               AuditLogEvent = "APP_CONFIG_UPDATED"
+)
+
+// Target types of the admin events, stored in the "targetType" field of the audit log data
+const (
+	AuditLogTargetUser       = "user"
+	AuditLogTargetUserGroup  = "userGroup"
+	AuditLogTargetOidcClient = "oidcClient"
+	AuditLogTargetApiKey     = "apiKey"
+	AuditLogTargetAppConfig  = "appConfig"
 )
 
 // Scan and Value methods for GORM to handle the custom type
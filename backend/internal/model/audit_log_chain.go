@@ -0,0 +1,43 @@
+package model
+
+// AuditLogChainLink chains an audit log to the one before it, so that modified and deleted audit logs can be detected.
+// The links are kept when the audit logs are purged, so the chain stays intact.
+type AuditLogChainLink struct {
+	Sequence int64 `gorm:"primaryKey"`
+	// AuditLogID is nil if the audit log was purged
+	AuditLogID *string
+	// UserID is the user of the audit log when it was created, as the user is removed from the audit log if it's deleted
+	UserID *string
+	// EntryHash is the SHA-256 hash of the audit log
+	EntryHash string
+	// Hash is the SHA-256 hash of the sequence, the hash of the previous link and the entry hash
+	Hash string
+	// PurgeCheckpointID is the checkpoint that was created when the audit log was purged
+	PurgeCheckpointID *string
+}
+
+// AuditLogChainHeadID is the ID of the only row of the audit_log_chain_heads table, which is created by the migrations
+const AuditLogChainHeadID = 1
+
+// AuditLogChainHead is the last link of the chain. Appending a link locks this row, so the chain can't fork.
+type AuditLogChainHead struct {
+	ID int64 `gorm:"primaryKey"`
+	// Sequence and Hash are those of the last link, or 0 and empty if the chain is empty
+	Sequence int64
+	Hash     string
+}
+
+// AuditLogCheckpoint is a snapshot of the audit log chain that is signed with the instance key
+type AuditLogCheckpoint struct {
+	Base
+
+	// Number is the position of the checkpoint, starting at 1
+	Number int64
+	// Sequence and Hash are those of the last link when the checkpoint was created
+	Sequence int64
+	Hash     string
+	// PurgeDigest covers the links that were purged up to and including this checkpoint
+	PurgeDigest string
+	// Signature is a JWT that contains the fields of the checkpoint
+	Signature string
+}
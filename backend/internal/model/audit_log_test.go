@@ -0,0 +1,42 @@
+package model
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+)
+
+func TestAuditLogEventsMatchEnvConfig(t *testing.T) {
+	// Collect the values of all AuditLogEvent constants, so that new events can't be forgotten in the env config
+	file, err := parser.ParseFile(token.NewFileSet(), "audit_log.go", nil, 0)
+	require.NoError(t, err)
+
+	var events []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		if ident, ok := spec.Type.(*ast.Ident); !ok || ident.Name != "AuditLogEvent" {
+			return true
+		}
+		for _, value := range spec.Values {
+			literal, ok := value.(*ast.BasicLit)
+			require.True(t, ok, "the value of an audit log event must be a string literal")
+			event, err := strconv.Unquote(literal.Value)
+			require.NoError(t, err)
+			events = append(events, event)
+		}
+		return true
+	})
+
+	require.NotEmpty(t, events)
+	assert.ElementsMatch(t, events, common.AuditLogEvents)
+}
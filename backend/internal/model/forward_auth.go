@@ -0,0 +1,15 @@
+package model
+
+import datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+
+// ForwardAuthCode is a short-lived code that hands over a session to a host protected by forward-auth
+type ForwardAuthCode struct {
+	Base
+
+	Code      string
+	Host      string
+	ExpiresAt datatype.DateTime
+
+	SessionID string
+	Session   Session
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+// IdentityProvider is an upstream OIDC provider that users can sign in with
+type IdentityProvider struct {
+	Base
+
+	Name         string `sortable:"true"`
+	Issuer       string `sortable:"true"`
+	ClientID     string
+	ClientSecret string
+	Scopes       string
+	Enabled      bool `sortable:"true"`
+	// LinkByEmail links the identity to the user with the same email address on the first sign in, if the provider verified the email address
+	LinkByEmail bool
+	// AllowUserCreation creates a user on the first sign in if no user could be linked
+	AllowUserCreation bool
+	// GroupsClaim is the claim of the ID token that contains the groups of the user at the provider
+	GroupsClaim   *string
+	GroupMappings IdentityProviderGroupMappings
+}
+
+// IdentityProviderGroupMapping adds users to a user group if the groups claim contains the value
+type IdentityProviderGroupMapping struct {
+	Value       string `json:"value"`
+	UserGroupID string `json:"userGroupId"`
+}
+
+type IdentityProviderGroupMappings []IdentityProviderGroupMapping //nolint:recvcheck
+
+func (m *IdentityProviderGroupMappings) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (m IdentityProviderGroupMappings) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// UserIdentity links a user to their account at an identity provider
+type UserIdentity struct {
+	Base
+
+	Subject string
+	Email   *string
+
+	IdentityProviderID string
+	IdentityProvider   IdentityProvider
+
+	UserID string
+	User   User
+}
+
+// IdentityProviderLoginState is created when a user is sent to an identity provider, and consumed when they return
+type IdentityProviderLoginState struct {
+	Base
+
+	State        string
+	Nonce        string
+	CodeVerifier string
+	RedirectPath *string
+	ExpiresAt    datatype.DateTime
+
+	IdentityProviderID string
+	// UserID is set if the identity is linked to a signed in user, instead of signing in
+	UserID *string
+}
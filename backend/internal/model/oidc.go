@@ -28,6 +28,8 @@ type OidcAuthorizationCode struct {
 	CodeChallenge             *string
 	CodeChallengeMethodSha256 *bool
 	ExpiresAt                 datatype.DateTime
+	Acr                       string
+	Amr                       string
 
 	UserID string
 	User   User
@@ -47,9 +49,13 @@ type OidcClient struct {
 	IsPublic           bool
 	PkceEnabled        bool
 	Credentials        OidcClientCredentials
+	// ForwardAuthHosts are the hosts that are protected by the forward-auth endpoint with the user group restrictions of this client
+	ForwardAuthHosts UrlList
+	// ConfigManaged is set for clients that are managed by the declarative configuration file
+	ConfigManaged bool
 
 	AllowedUserGroups []UserGroup `gorm:"many2many:oidc_clients_allowed_user_groups;"`
-	CreatedByID       string
+	CreatedByID       *string
 	CreatedBy         User
 }
 
@@ -59,6 +65,9 @@ type OidcRefreshToken struct {
 	Token     string
 	ExpiresAt datatype.DateTime
 	Scope     string
+	// The authentication context of the authorization, which is kept for the ID tokens issued on refresh
+	Acr string
+	Amr string
 
 	UserID string
 	User   User
@@ -137,6 +146,8 @@ type OidcDeviceCode struct {
 	Scope        string
 	ExpiresAt    datatype.DateTime
 	IsAuthorized bool
+	Acr          string
+	Amr          string
 
 	UserID   *string
 	User     User
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// SamlNameIDFormatPersistent uses the user's ID as the NameID
+	SamlNameIDFormatPersistent = "persistent"
+	// SamlNameIDFormatEmail uses the user's email address as the NameID
+	SamlNameIDFormatEmail = "email"
+	// SamlNameIDFormatUnspecified uses the user's username as the NameID
+	SamlNameIDFormatUnspecified = "unspecified"
+)
+
+type SamlServiceProvider struct {
+	Base
+
+	Name     string `sortable:"true"`
+	EntityID string `sortable:"true"`
+	// AcsURL is the assertion consumer service URL, used if no metadata is provided
+	AcsURL string
+	// Metadata is the optional metadata XML of the service provider
+	Metadata         string
+	NameIDFormat     string
+	AttributeMapping SamlAttributeMapping
+
+	AllowedUserGroups []UserGroup `gorm:"many2many:saml_service_providers_allowed_user_groups;"`
+	CreatedByID       string
+	CreatedBy         User
+}
+
+// SamlAttributeMapping maps the name of a SAML attribute to a user field, "groups", or the key of a custom claim
+type SamlAttributeMapping []SamlAttribute //nolint:recvcheck
+
+type SamlAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (m *SamlAttributeMapping) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (m SamlAttributeMapping) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
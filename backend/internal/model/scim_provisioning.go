@@ -0,0 +1,39 @@
+package model
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+// ScimProvisioningTarget is the SCIM server of an OIDC client, to which the users that are allowed to use the client are pushed
+type ScimProvisioningTarget struct {
+	Base
+
+	OidcClientID string
+	// Endpoint is the base URL of the SCIM server, e.g. https://app.example.com/scim/v2
+	Endpoint string
+	// Token is sent as bearer token to the SCIM server
+	Token   string
+	Enabled bool
+
+	LastSyncedAt  *datatype.DateTime
+	LastSyncError *string
+}
+
+// ScimProvisionedUser links a user to the ID the SCIM server of a provisioning target assigned to them
+type ScimProvisionedUser struct {
+	ScimProvisioningTargetID string `gorm:"primaryKey"`
+	UserID                   string `gorm:"primaryKey"`
+	RemoteID                 string
+	Active                   bool
+}
+
+// ScimProvisioningJob is an entry in the persistent queue of users that have to be synced to a provisioning target
+type ScimProvisioningJob struct {
+	Base
+
+	ScimProvisioningTargetID string
+	UserID                   string
+	Attempts                 int
+	NextAttemptAt            datatype.DateTime
+	LastError                *string
+}
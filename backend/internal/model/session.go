@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+// Methods used by users to authenticate when a session is created
+const (
+	SessionAuthMethodPasskey            = "passkey"
+	SessionAuthMethodOneTimeAccessToken = "one-time-access-token"
+	SessionAuthMethodSetup              = "setup"
+	SessionAuthMethodIdentityProvider   = "identity-provider"
+)
+
+// Authentication context class references (acr) that describe how strongly a user has been authenticated
+const (
+	// AcrBasic is used for sessions that were created with a one-time access token or during the initial setup
+	AcrBasic = "urn:pocket-id:acr:basic"
+	// AcrPasskey is used for sessions that were created by signing in with a passkey and user verification
+	AcrPasskey = "urn:pocket-id:acr:passkey"
+)
+
+type Session struct {
+	Base
+
+	IpAddress    string            `sortable:"true"`
+	Country      string            `sortable:"true"`
+	City         string            `sortable:"true"`
+	UserAgent    string            `sortable:"true"`
+	LastSeenAt   datatype.DateTime `sortable:"true"`
+	ExpiresAt    datatype.DateTime `sortable:"true"`
+	AuthMethod   string
+	UserVerified bool
+	// ElevatedAt is the time of the last passkey re-authentication, used for sudo mode
+	ElevatedAt *datatype.DateTime
+
+	UserID string
+	User   User
+}
+
+// LastAuthenticatedWithPasskeyAt returns the time at which the user last completed a passkey assertion in this session
+// It returns the zero time if the user never did
+func (s Session) LastAuthenticatedWithPasskeyAt() time.Time {
+	if s.ElevatedAt != nil {
+		return s.ElevatedAt.ToTime()
+	}
+	if s.AuthMethod == SessionAuthMethodPasskey {
+		return s.CreatedAt.ToTime()
+	}
+	return time.Time{}
+}
+
+// Acr returns the authentication context class reference of the session
+func (s Session) Acr() string {
+	if s.AuthMethod == SessionAuthMethodPasskey && s.UserVerified {
+		return AcrPasskey
+	}
+	return AcrBasic
+}
+
+// Amr returns the authentication method references of the session, as defined in RFC 8176
+func (s Session) Amr() []string {
+	switch s.AuthMethod {
+	case SessionAuthMethodPasskey:
+		// A passkey is a hardware-bound key; with user verification it also requires a PIN or biometric
+		if s.UserVerified {
+			return []string{"hwk", "user", "mfa"}
+		}
+		return []string{"hwk", "user"}
+	case SessionAuthMethodOneTimeAccessToken:
+		return []string{"otp"}
+	default:
+		return []string{}
+	}
+}
@@ -20,7 +20,9 @@ type User struct {
 	IsAdmin   bool   `sortable:"true"`
 	Locale    *string
 	LdapID    *string
-	Disabled  bool `sortable:"true"`
+	// ScimExternalID is set for users provisioned over SCIM and contains the ID of the user in the provisioning system
+	ScimExternalID *string
+	Disabled       bool `sortable:"true"`
 
 	CustomClaims []CustomClaim
 	UserGroups   []UserGroup `gorm:"many2many:user_groups_users;"`
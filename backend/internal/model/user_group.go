@@ -5,6 +5,10 @@ type UserGroup struct {
 	FriendlyName string `sortable:"true"`
 	Name         string `sortable:"true"`
 	LdapID       *string
-	Users        []User `gorm:"many2many:user_groups_users;"`
-	CustomClaims []CustomClaim
+	// ScimExternalID is set for groups provisioned over SCIM and contains the ID of the group in the provisioning system
+	ScimExternalID *string
+	// ConfigManaged is set for groups that are managed by the declarative configuration file
+	ConfigManaged bool
+	Users         []User `gorm:"many2many:user_groups_users;"`
+	CustomClaims  []CustomClaim
 }
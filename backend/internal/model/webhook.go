@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+// Webhook is an endpoint that is notified about the identity events it subscribed to
+type Webhook struct {
+	Base
+
+	Name string `sortable:"true"`
+	URL  string `sortable:"true"`
+	// Secret is used to sign the payloads with HMAC-SHA256
+	Secret  string
+	Events  WebhookEvents
+	Enabled bool `sortable:"true"`
+}
+
+type WebhookEvent string
+
+const (
+	WebhookEventSignIn                     WebhookEvent = "SIGN_IN"
+	WebhookEventNewClientAuthorization     WebhookEvent = "NEW_CLIENT_AUTHORIZATION"
+	WebhookEventUserCreated                WebhookEvent = "USER_CREATED"
+	WebhookEventUserDisabled               WebhookEvent = "USER_DISABLED"
+	WebhookEventUserGroupMembershipChanged WebhookEvent = "USER_GROUP_MEMBERSHIP_CHANGED"
+)
+
+// AllWebhookEvents are the events that webhooks can subscribe to
+var AllWebhookEvents = []WebhookEvent{
+	WebhookEventSignIn,
+	WebhookEventNewClientAuthorization,
+	WebhookEventUserCreated,
+	WebhookEventUserDisabled,
+	WebhookEventUserGroupMembershipChanged,
+}
+
+type WebhookEvents []WebhookEvent //nolint:recvcheck
+
+func (e *WebhookEvents) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (e WebhookEvents) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a payload that is sent to a webhook. Pending deliveries form the persistent queue of the webhooks.
+type WebhookDelivery struct {
+	Base
+
+	WebhookID string
+	Event     WebhookEvent `sortable:"true"`
+	// Payload is the JSON body that is sent to the webhook, it's stored as sent so that it can be replayed
+	Payload string
+	Status  WebhookDeliveryStatus `sortable:"true"`
+	// NextAttemptAt is nil once the delivery succeeded or all attempts failed
+	NextAttemptAt      *datatype.DateTime
+	LastAttemptAt      *datatype.DateTime `sortable:"true"`
+	Attempts           int
+	ResponseStatusCode *int
+	LastError          *string
+}
@@ -17,12 +17,13 @@ import (
 )
 
 type ApiKeyService struct {
-	db           *gorm.DB
-	emailService *EmailService
+	db              *gorm.DB
+	emailService    *EmailService
+	auditLogService *AuditLogService
 }
 
-func NewApiKeyService(db *gorm.DB, emailService *EmailService) *ApiKeyService {
-	return &ApiKeyService{db: db, emailService: emailService}
+func NewApiKeyService(db *gorm.DB, emailService *EmailService, auditLogService *AuditLogService) *ApiKeyService {
+	return &ApiKeyService{db: db, emailService: emailService, auditLogService: auditLogService}
 }
 
 func (s *ApiKeyService) ListApiKeys(ctx context.Context, userID string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.ApiKey, utils.PaginationResponse, error) {
@@ -60,7 +61,12 @@ func (s *ApiKeyService) CreateApiKey(ctx context.Context, userID string, input d
 		UserID:      userID,
 	}
 
-	err = s.db.
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	err = tx.
 		WithContext(ctx).
 		Create(&apiKey).
 		Error
@@ -68,25 +74,53 @@ func (s *ApiKeyService) CreateApiKey(ctx context.Context, userID string, input d
 		return model.ApiKey{}, "", err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventApiKeyCreated, apiKeyAuditTarget(apiKey), nil, apiKeyAuditState(apiKey), tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.ApiKey{}, "", err
+	}
+
 	// Return the raw token only once - it cannot be retrieved later
 	return apiKey, token, nil
 }
 
 func (s *ApiKeyService) RevokeApiKey(ctx context.Context, userID, apiKeyID string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// The API key is loaded before it's deleted, because not all databases can return deleted rows
 	var apiKey model.ApiKey
-	err := s.db.
+	err := tx.
 		WithContext(ctx).
 		Where("id = ? AND user_id = ?", apiKeyID, userID).
-		Delete(&apiKey).
+		Limit(1).
+		Find(&apiKey).
 		Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if apiKey.ID == "" {
+		return tx.Commit().Error
+	}
+
+	result := tx.
+		WithContext(ctx).
+		Delete(&apiKey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return &common.APIKeyNotFoundError{}
 		}
-		return err
+		return result.Error
 	}
 
-	return nil
+	if result.RowsAffected > 0 {
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventApiKeyRevoked, apiKeyAuditTarget(apiKey), apiKeyAuditState(apiKey), nil, tx)
+	}
+
+	return tx.Commit().Error
 }
 
 func (s *ApiKeyService) ValidateApiKey(ctx context.Context, apiKey string) (model.User, error) {
@@ -102,7 +136,7 @@ func (s *ApiKeyService) ValidateApiKey(ctx context.Context, apiKey string) (mode
 		WithContext(ctx).
 		Model(&model.ApiKey{}).
 		Clauses(clause.Returning{}).
-		Where("key = ? AND expires_at > ?", hashedKey, datatype.DateTime(now)).
+		Where(`"key" = ? AND expires_at > ?`, hashedKey, datatype.DateTime(now)).
 		Updates(&model.ApiKey{
 			LastUsedAt: utils.Ptr(datatype.DateTime(now)),
 		}).
@@ -163,3 +197,19 @@ func (s *ApiKeyService) SendApiKeyExpiringSoonEmail(ctx context.Context, apiKey
 		Update("expiration_email_sent", true).
 		Error
 }
+
+func apiKeyAuditTarget(apiKey model.ApiKey) AuditTarget {
+	return AuditTarget{Type: model.AuditLogTargetApiKey, ID: apiKey.ID, Name: apiKey.Name}
+}
+
+// apiKeyAuditState returns the attributes of the API key that are compared in the audit log
+func apiKeyAuditState(apiKey model.ApiKey) dto.ApiKeyCreateDto {
+	state := dto.ApiKeyCreateDto{
+		Name:      apiKey.Name,
+		ExpiresAt: apiKey.ExpiresAt,
+	}
+	if apiKey.Description != nil {
+		state.Description = *apiKey.Description
+	}
+	return state
+}
@@ -25,8 +25,9 @@ import (
 )
 
 type AppConfigService struct {
-	dbConfig atomic.Pointer[model.AppConfig]
-	db       *gorm.DB
+	dbConfig        atomic.Pointer[model.AppConfig]
+	db              *gorm.DB
+	auditLogService *AuditLogService
 }
 
 func NewAppConfigService(ctx context.Context, db *gorm.DB) *AppConfigService {
@@ -47,6 +48,12 @@ func NewAppConfigService(ctx context.Context, db *gorm.DB) *AppConfigService {
 	return service
 }
 
+// SetAuditLogService sets the service that records the configuration changes in the audit log.
+// It isn't passed to the constructor because the audit log service itself depends on the app config service.
+func (s *AppConfigService) SetAuditLogService(auditLogService *AuditLogService) {
+	s.auditLogService = auditLogService
+}
+
 // GetDbConfig returns the application configuration.
 // Important: Treat the object as read-only: do not modify its properties directly!
 func (s *AppConfigService) GetDbConfig() *model.AppConfig {
@@ -63,11 +70,16 @@ func (s *AppConfigService) getDefaultDbConfig() *model.AppConfig {
 	// Values are the default ones
 	return &model.AppConfig{
 		// General
-		AppName:             model.AppConfigVariable{Value: "Pocket ID"},
-		SessionDuration:     model.AppConfigVariable{Value: "60"},
-		EmailsVerified:      model.AppConfigVariable{Value: "false"},
-		DisableAnimations:   model.AppConfigVariable{Value: "false"},
-		AllowOwnAccountEdit: model.AppConfigVariable{Value: "true"},
+		AppName:                 model.AppConfigVariable{Value: "Pocket ID"},
+		SessionDuration:         model.AppConfigVariable{Value: "60"},
+		SessionIdleTimeout:      model.AppConfigVariable{Value: "0"},
+		AdminSessionDuration:    model.AppConfigVariable{},
+		AdminSessionIdleTimeout: model.AppConfigVariable{},
+		SudoModeDuration:        model.AppConfigVariable{Value: "0"},
+		SudoModeActions:         model.AppConfigVariable{Value: "users,client-secrets,app-config"},
+		EmailsVerified:          model.AppConfigVariable{Value: "false"},
+		DisableAnimations:       model.AppConfigVariable{Value: "false"},
+		AllowOwnAccountEdit:     model.AppConfigVariable{Value: "true"},
 		// Internal
 		BackgroundImageType: model.AppConfigVariable{Value: "jpg"},
 		LogoLightImageType:  model.AppConfigVariable{Value: "svg"},
@@ -118,7 +130,7 @@ func (s *AppConfigService) updateAppConfigStartTransaction(ctx context.Context)
 	}
 
 	// With SQLite there's nothing else we need to do, because a transaction blocks the entire database
-	// However, with Postgres we need to manually lock the table to prevent others from doing the same
+	// However, with Postgres and MySQL we need to manually lock the table to prevent others from doing the same
 	switch s.db.Name() {
 	case "postgres":
 		// We do not use "NOWAIT" so this blocks until the database is available, or the context is canceled
@@ -133,6 +145,20 @@ func (s *AppConfigService) updateAppConfigStartTransaction(ctx context.Context)
 			tx.Rollback()
 			return nil, fmt.Errorf("failed to acquire lock on app_config_variables table: %w", err)
 		}
+	case "mysql":
+		// MySQL can't lock a table within a transaction, so we lock all of its rows instead
+		lockCtx, lockCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer lockCancel()
+		var keys []string
+		err = tx.
+			WithContext(lockCtx).
+			Raw(`SELECT "key" FROM app_config_variables FOR UPDATE`).
+			Scan(&keys).
+			Error
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to acquire lock on app_config_variables table: %w", err)
+		}
 	default:
 		// Nothing to do here
 	}
@@ -179,6 +205,15 @@ func (s *AppConfigService) UpdateAppConfig(ctx context.Context, input dto.AppCon
 	}
 
 	defaultCfg := s.getDefaultDbConfig()
+	originalValues := cfg.ToAppConfigVariableSlice(true)
+
+	// Values that are managed by the declarative configuration file can't be changed
+	configManagedValues := make(map[string]string)
+	for _, variable := range originalValues {
+		if variable.ConfigManaged {
+			configManagedValues[variable.Key] = variable.Value
+		}
+	}
 
 	// Iterate through all the fields to update
 	// We update the in-memory data (in the cfg struct) and collect values to update in the database
@@ -192,6 +227,10 @@ func (s *AppConfigService) UpdateAppConfig(ctx context.Context, input dto.AppCon
 		// Get the value of the json tag, taking only what's before the comma
 		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
 
+		if configManagedValue, ok := configManagedValues[key]; ok && configManagedValue != value {
+			return nil, &common.ConfigManagedError{}
+		}
+
 		// Update the in-memory config value
 		// If the new value is an empty string, then we set the in-memory value to the default one
 		// Skip values that are internal only and can't be updated
@@ -223,6 +262,11 @@ func (s *AppConfigService) UpdateAppConfig(ctx context.Context, input dto.AppCon
 		return nil, err
 	}
 
+	if s.auditLogService != nil {
+		before, after := appConfigAuditStates(originalValues, cfg.ToAppConfigVariableSlice(true))
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventAppConfigUpdated, AuditTarget{Type: model.AuditLogTargetAppConfig}, before, after, tx)
+	}
+
 	// Commit the changes to the DB, then finally save the updated config in the object
 	err = tx.Commit().Error
 	if err != nil {
@@ -350,9 +394,47 @@ func (s *AppConfigService) UpdateImage(ctx context.Context, uploadedFile *multip
 
 	}
 
+	if s.auditLogService != nil {
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventAppConfigUpdated, AuditTarget{Type: model.AuditLogTargetAppConfig, Name: imageName + " image"}, nil, nil, s.db)
+	}
+
 	return nil
 }
 
+// appConfigAuditStates returns the configuration values by their keys to compare them in the audit log.
+// Sensitive values are redacted, but a changed sensitive value is still visible as a change.
+func appConfigAuditStates(before, after []model.AppConfigVariable) (map[string]string, map[string]string) {
+	const redacted = "********"
+
+	beforeState := make(map[string]string, len(before))
+	for _, variable := range before {
+		beforeState[variable.Key] = variable.Value
+	}
+	afterState := make(map[string]string, len(after))
+	for _, variable := range after {
+		afterState[variable.Key] = variable.Value
+	}
+
+	for key, value := range afterState {
+		if !model.IsSensitiveAppConfigKey(key) {
+			continue
+		}
+
+		changed := beforeState[key] != value
+		if beforeState[key] != "" {
+			beforeState[key] = redacted
+		}
+		if value != "" {
+			afterState[key] = redacted
+			if changed && beforeState[key] == redacted {
+				afterState[key] = redacted + " (changed)"
+			}
+		}
+	}
+
+	return beforeState, afterState
+}
+
 // LoadDbConfig loads the configuration values from the database into the DbConfig struct.
 func (s *AppConfigService) LoadDbConfig(ctx context.Context) (err error) {
 	dest, err := s.loadDbConfigInternal(ctx, s.db)
@@ -391,6 +473,9 @@ func (s *AppConfigService) loadDbConfigInternal(ctx context.Context, tx *gorm.DB
 	for _, v := range loaded {
 		// Find the field in the struct whose "key" tag matches, then update that
 		err = dest.UpdateField(v.Key, v.Value, false)
+		if err == nil {
+			err = dest.SetFieldConfigManaged(v.Key, v.ConfigManaged)
+		}
 
 		// We ignore the case of fields that don't exist, as there may be leftover data in the database
 		if err != nil && !errors.Is(err, model.AppConfigKeyNotFoundError{}) {
@@ -415,13 +500,14 @@ func (s *AppConfigService) loadDbConfigFromEnv(ctx context.Context, tx *gorm.DB)
 		tagValue := strings.Split(field.Tag.Get("key"), ",")
 		key := tagValue[0]
 		isInternal := slices.Contains(tagValue, "internal")
+		isSensitive := slices.Contains(tagValue, "sensitive")
 
 		// Internal fields are loaded from the database as they can't be set from the environment
 		if isInternal {
 			var value string
 			err := tx.WithContext(ctx).
 				Model(&model.AppConfigVariable{}).
-				Where("key = ?", key).
+				Where(`"key" = ?`, key).
 				Select("value").
 				First(&value).Error
 			if err == nil {
@@ -432,8 +518,16 @@ func (s *AppConfigService) loadDbConfigFromEnv(ctx context.Context, tx *gorm.DB)
 
 		envVarName := utils.CamelCaseToScreamingSnakeCase(key)
 
-		// Set the value if it's set
-		value, ok := os.LookupEnv(envVarName)
+		// Set the value if it's set in the environment or the config file
+		// Sensitive values can also be read from a file, e.g. SMTP_PASSWORD_FILE
+		value, ok := common.LookupEnv(envVarName)
+		if isSensitive {
+			var err error
+			value, ok, err = common.LookupEnvOrFile(envVarName)
+			if err != nil {
+				return nil, err
+			}
+		}
 		if ok {
 			rv.Field(i).FieldByName("Value").SetString(value)
 		}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 
@@ -185,6 +187,33 @@ func TestLoadDbConfig(t *testing.T) {
 		require.Equal(t, "DB App", config.AppName.Value, "Should load appName from DB, not env")
 		require.Equal(t, "120", config.SessionDuration.Value, "Should load sessionDuration from DB, not env")
 	})
+
+	t.Run("loads sensitive values from files when UiConfigDisabled is true", func(t *testing.T) {
+		// Save the original state and restore it after the test
+		originalUiConfigDisabled := common.EnvConfig.UiConfigDisabled
+		defer func() {
+			common.EnvConfig.UiConfigDisabled = originalUiConfigDisabled
+		}()
+		common.EnvConfig.UiConfigDisabled = true
+
+		// Secrets mounted by Docker or Kubernetes usually end with a newline
+		passwordFile := filepath.Join(t.TempDir(), "smtp-password")
+		err := os.WriteFile(passwordFile, []byte("file-secret\n"), 0600)
+		require.NoError(t, err)
+		t.Setenv("SMTP_PASSWORD_FILE", passwordFile)
+
+		service := &AppConfigService{
+			db: newDatabaseForTest(t),
+		}
+		err = service.LoadDbConfig(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, "file-secret", service.GetDbConfig().SmtpPassword.Value)
+
+		// Setting both variables is ambiguous
+		t.Setenv("SMTP_PASSWORD", "env-secret")
+		err = service.LoadDbConfig(t.Context())
+		require.ErrorContains(t, err, "only one of the environment variables SMTP_PASSWORD and SMTP_PASSWORD_FILE can be set")
+	})
 }
 
 func TestUpdateAppConfigValues(t *testing.T) {
@@ -468,4 +497,27 @@ func TestUpdateAppConfig(t *testing.T) {
 		var uiConfigDisabledErr *common.UiConfigDisabledError
 		require.ErrorAs(t, err, &uiConfigDisabledErr)
 	})
+
+	t.Run("audits changes without revealing secrets", func(t *testing.T) {
+		db := newDatabaseForTest(t)
+		service := &AppConfigService{
+			db: db,
+		}
+		err := service.LoadDbConfig(t.Context())
+		require.NoError(t, err)
+		service.SetAuditLogService(NewAuditLogService(db, service, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil))
+
+		_, err = service.UpdateAppConfig(t.Context(), dto.AppConfigUpdateDto{
+			AppName:      "Updated App Name",
+			SmtpPassword: "secret",
+		})
+		require.NoError(t, err)
+
+		var auditLog model.AuditLog
+		err = db.Where("event = ?", model.AuditLogEventAppConfigUpdated).First(&auditLog).Error
+		require.NoError(t, err)
+		require.NotContains(t, auditLog.Data["changes"], "secret")
+		require.Contains(t, auditLog.Data["changes"], `"smtpPassword":{"before":"","after":"********"}`)
+		require.Contains(t, auditLog.Data["changes"], `"appName":{"before":"Pocket ID","after":"Updated App Name"}`)
+	})
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+type AppPasswordService struct {
+	db *gorm.DB
+}
+
+func NewAppPasswordService(db *gorm.DB) *AppPasswordService {
+	return &AppPasswordService{db: db}
+}
+
+func (s *AppPasswordService) ListAppPasswords(ctx context.Context, userID string) ([]model.AppPassword, error) {
+	var appPasswords []model.AppPassword
+	err := s.db.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&appPasswords).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return appPasswords, nil
+}
+
+func (s *AppPasswordService) CreateAppPassword(ctx context.Context, userID string, input dto.AppPasswordCreateDto) (model.AppPassword, string, error) {
+	password, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return model.AppPassword{}, "", err
+	}
+
+	appPassword := model.AppPassword{
+		Name:     input.Name,
+		Password: utils.CreateSha256Hash(password),
+		UserID:   userID,
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Create(&appPassword).
+		Error
+	if err != nil {
+		return model.AppPassword{}, "", err
+	}
+
+	// Return the raw password only once - it cannot be retrieved later
+	return appPassword, password, nil
+}
+
+func (s *AppPasswordService) DeleteAppPassword(ctx context.Context, userID, appPasswordID string) error {
+	result := s.db.
+		WithContext(ctx).
+		Where("id = ? AND user_id = ?", appPasswordID, userID).
+		Delete(&model.AppPassword{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &common.AppPasswordNotFoundError{}
+	}
+
+	return nil
+}
+
+// ValidateAppPassword checks whether the password is one of the app passwords of the user
+func (s *AppPasswordService) ValidateAppPassword(ctx context.Context, userID, password string) (bool, error) {
+	if password == "" {
+		return false, nil
+	}
+
+	var appPassword model.AppPassword
+	err := s.db.
+		WithContext(ctx).
+		Where("user_id = ? AND password = ?", userID, utils.CreateSha256Hash(password)).
+		First(&appPassword).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Model(&appPassword).
+		Update("last_used_at", datatype.DateTime(time.Now())).
+		Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
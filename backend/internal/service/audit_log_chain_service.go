@@ -0,0 +1,613 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// auditLogChainBatchSize is the number of links and audit logs that are loaded at once
+const auditLogChainBatchSize = 1000
+
+// AuditLogChainService makes the audit logs tamper-evident. Every audit log is chained to the previous one by a hash
+// and the chain is periodically signed in checkpoints with the instance key.
+type AuditLogChainService struct {
+	db         *gorm.DB
+	jwtService *JwtService
+}
+
+// NewAuditLogChainService creates the service and chains the audit logs that were created before the chain existed
+func NewAuditLogChainService(ctx context.Context, db *gorm.DB, jwtService *JwtService) (*AuditLogChainService, error) {
+	s := &AuditLogChainService{db: db, jwtService: jwtService}
+
+	err := s.chainLegacyAuditLogs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chain existing audit logs: %w", err)
+	}
+
+	return s, nil
+}
+
+// AuditLogChainVerification is the result of the verification of the audit log chain
+type AuditLogChainVerification struct {
+	Valid bool
+	// Links is the number of verified links, including those of purged audit logs
+	Links       int64
+	PurgedLinks int64
+	// Checkpoints is the number of checkpoints with a valid signature
+	Checkpoints int64
+	// SkippedCheckpoints is the number of checkpoints that were signed with a previous key and can't be verified
+	SkippedCheckpoints int64
+	// LastCheckpoint is the last checkpoint with a valid signature
+	LastCheckpoint *model.AuditLogCheckpoint
+	// UnsignedLinks is the number of links that were appended after the last checkpoint
+	UnsignedLinks int64
+	// BrokenLink is the first broken link, if the chain isn't valid
+	BrokenLink *AuditLogChainBrokenLink
+}
+
+type AuditLogChainBrokenLink struct {
+	// Sequence is 0 if the audit log isn't part of the chain at all
+	Sequence   int64
+	AuditLogID string
+	Reason     string
+}
+
+// VerifyChain walks the audit log chain and reports the first broken link. The chain is broken if an audit log was
+// modified, deleted without being purged or inserted directly into the database, or if a checkpoint doesn't match
+// the chain.
+func (s *AuditLogChainService) VerifyChain(ctx context.Context) (AuditLogChainVerification, error) {
+	var result AuditLogChainVerification
+	broken := func(sequence int64, auditLogID string, reason string) (AuditLogChainVerification, error) {
+		result.BrokenLink = &AuditLogChainBrokenLink{Sequence: sequence, AuditLogID: auditLogID, Reason: reason}
+		return result, nil
+	}
+
+	var checkpoints []model.AuditLogCheckpoint
+	err := s.db.
+		WithContext(ctx).
+		Order("number ASC").
+		Find(&checkpoints).
+		Error
+	if err != nil {
+		return result, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+
+	// Verify the signatures of the checkpoints first, as only signed checkpoints can be trusted
+	verified := make([]bool, len(checkpoints))
+	checkpointsBySequence := make(map[int64][]model.AuditLogCheckpoint)
+	for i, checkpoint := range checkpoints {
+		if checkpoint.Number != int64(i+1) {
+			return broken(checkpoint.Sequence, "", fmt.Sprintf("checkpoint %d is missing", i+1))
+		}
+
+		err = s.jwtService.VerifyAuditLogCheckpointToken(checkpoint)
+		if err != nil {
+			// Checkpoints signed with a previous key are skipped, as long as a later checkpoint covers them
+			if auditLogCheckpointKeyID(checkpoint) != s.jwtService.keyId && i < len(checkpoints)-1 {
+				result.SkippedCheckpoints++
+				continue
+			}
+			return broken(checkpoint.Sequence, "", fmt.Sprintf("the signature of checkpoint %d is invalid", checkpoint.Number))
+		}
+
+		verified[i] = true
+		checkpointsBySequence[checkpoint.Sequence] = append(checkpointsBySequence[checkpoint.Sequence], checkpoint)
+		result.Checkpoints++
+		result.LastCheckpoint = &checkpoints[i]
+	}
+
+	// The sequences of the purged links are hashed per checkpoint, to compare them with the signed purge digests
+	type purgedLinks struct {
+		hash          hash.Hash
+		firstSequence int64
+	}
+	purgedByCheckpoint := make(map[string]*purgedLinks)
+
+	previousHash := ""
+	var lastSequence int64
+	for {
+		var links []model.AuditLogChainLink
+		err = s.db.
+			WithContext(ctx).
+			Where("sequence > ?", lastSequence).
+			Order("sequence ASC").
+			Limit(auditLogChainBatchSize).
+			Find(&links).
+			Error
+		if err != nil {
+			return result, fmt.Errorf("failed to query links: %w", err)
+		}
+		if len(links) == 0 {
+			break
+		}
+
+		auditLogs, existingUsers, err := s.loadLinkedAuditLogs(ctx, links)
+		if err != nil {
+			return result, err
+		}
+
+		for _, link := range links {
+			auditLogID := ""
+			if link.AuditLogID != nil {
+				auditLogID = *link.AuditLogID
+			}
+
+			if link.Sequence != lastSequence+1 {
+				return broken(lastSequence+1, "", "the link is missing")
+			}
+			if link.Hash != auditLogChainHash(link.Sequence, previousHash, link.EntryHash) {
+				return broken(link.Sequence, auditLogID, "the hash doesn't match the previous link")
+			}
+
+			switch {
+			case link.AuditLogID == nil && link.PurgeCheckpointID == nil:
+				return broken(link.Sequence, "", "the audit log was removed from the link")
+			case link.AuditLogID == nil:
+				purged, ok := purgedByCheckpoint[*link.PurgeCheckpointID]
+				if !ok {
+					purged = &purgedLinks{hash: sha256.New(), firstSequence: link.Sequence}
+					purgedByCheckpoint[*link.PurgeCheckpointID] = purged
+				}
+				_, _ = purged.hash.Write([]byte(strconv.FormatInt(link.Sequence, 10) + "\n"))
+				result.PurgedLinks++
+			default:
+				auditLog, ok := auditLogs[auditLogID]
+				if !ok {
+					return broken(link.Sequence, auditLogID, "the audit log was deleted")
+				}
+				// The user is removed from the audit logs when it's deleted
+				if auditLog.UserID == "" && link.UserID != nil && !existingUsers[*link.UserID] {
+					auditLog.UserID = *link.UserID
+				}
+				entryHash, err := auditLogEntryHash(auditLog)
+				if err != nil {
+					return result, err
+				}
+				if entryHash != link.EntryHash {
+					return broken(link.Sequence, auditLogID, "the audit log was modified")
+				}
+			}
+
+			for _, checkpoint := range checkpointsBySequence[link.Sequence] {
+				if checkpoint.Hash != link.Hash {
+					return broken(link.Sequence, auditLogID, fmt.Sprintf("the link doesn't match checkpoint %d", checkpoint.Number))
+				}
+			}
+
+			previousHash = link.Hash
+			lastSequence = link.Sequence
+			result.Links++
+		}
+	}
+
+	purgeDigest := ""
+	for i, checkpoint := range checkpoints {
+		if !verified[i] {
+			continue
+		}
+		if checkpoint.Sequence > lastSequence || (checkpoint.Sequence == 0 && checkpoint.Hash != "") {
+			return broken(lastSequence+1, "", fmt.Sprintf("the links up to checkpoint %d are missing", checkpoint.Number))
+		}
+	}
+	for i, checkpoint := range checkpoints {
+		purgedHash := sha256.New()
+		if purged, ok := purgedByCheckpoint[checkpoint.ID]; ok {
+			purgedHash = purged.hash
+			delete(purgedByCheckpoint, checkpoint.ID)
+		}
+		purgeDigest = auditLogPurgeDigest(purgeDigest, hex.EncodeToString(purgedHash.Sum(nil)))
+
+		if verified[i] && purgeDigest != checkpoint.PurgeDigest {
+			return broken(checkpoint.Sequence, "", fmt.Sprintf("the purged audit logs don't match checkpoint %d", checkpoint.Number))
+		}
+	}
+	// The remaining links were purged by checkpoints that don't exist
+	var firstUncovered int64
+	for _, purged := range purgedByCheckpoint {
+		if firstUncovered == 0 || purged.firstSequence < firstUncovered {
+			firstUncovered = purged.firstSequence
+		}
+	}
+	if firstUncovered > 0 {
+		return broken(firstUncovered, "", "the audit log was purged without a checkpoint")
+	}
+
+	// Audit logs that were inserted directly into the database aren't part of the chain
+	var unchained []model.AuditLog
+	err = s.db.
+		WithContext(ctx).
+		Where("id NOT IN (?)", s.db.Model(&model.AuditLogChainLink{}).Select("audit_log_id").Where("audit_log_id IS NOT NULL")).
+		Order("created_at ASC").
+		Limit(1).
+		Find(&unchained).
+		Error
+	if err != nil {
+		return result, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	if len(unchained) > 0 {
+		return broken(0, unchained[0].ID, "the audit log isn't part of the chain")
+	}
+
+	result.Valid = true
+	result.UnsignedLinks = lastSequence
+	if result.LastCheckpoint != nil {
+		result.UnsignedLinks -= result.LastCheckpoint.Sequence
+	}
+	return result, nil
+}
+
+// loadLinkedAuditLogs returns the audit logs of the links by their ID and which of the users of the links still exist
+func (s *AuditLogChainService) loadLinkedAuditLogs(ctx context.Context, links []model.AuditLogChainLink) (map[string]model.AuditLog, map[string]bool, error) {
+	auditLogIDs := make([]string, 0, len(links))
+	userIDs := make([]string, 0)
+	for _, link := range links {
+		if link.AuditLogID != nil {
+			auditLogIDs = append(auditLogIDs, *link.AuditLogID)
+		}
+		if link.UserID != nil {
+			userIDs = append(userIDs, *link.UserID)
+		}
+	}
+
+	var auditLogs []model.AuditLog
+	if len(auditLogIDs) > 0 {
+		err := s.db.
+			WithContext(ctx).
+			Where("id IN ?", auditLogIDs).
+			Find(&auditLogs).
+			Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query audit logs: %w", err)
+		}
+	}
+
+	var existingUserIDs []string
+	if len(userIDs) > 0 {
+		err := s.db.
+			WithContext(ctx).
+			Model(&model.User{}).
+			Where("id IN ?", userIDs).
+			Pluck("id", &existingUserIDs).
+			Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query users: %w", err)
+		}
+	}
+
+	auditLogsByID := make(map[string]model.AuditLog, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		auditLogsByID[auditLog.ID] = auditLog
+	}
+	existingUsers := make(map[string]bool, len(existingUserIDs))
+	for _, userID := range existingUserIDs {
+		existingUsers[userID] = true
+	}
+
+	return auditLogsByID, existingUsers, nil
+}
+
+// CreateCheckpoint signs the current state of the chain and returns false if there's nothing new to sign
+func (s *AuditLogChainService) CreateCheckpoint(ctx context.Context) (bool, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	head, err := lockAuditLogChainHead(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	var latest model.AuditLogCheckpoint
+	err = tx.
+		WithContext(ctx).
+		Order("number DESC").
+		Limit(1).
+		Find(&latest).
+		Error
+	if err != nil {
+		return false, fmt.Errorf("failed to query the latest checkpoint: %w", err)
+	}
+
+	// A new checkpoint is also created if the key has changed, so that the latest checkpoint can always be verified
+	if latest.Sequence == head.Sequence && (latest.Number == 0 || auditLogCheckpointKeyID(latest) == s.jwtService.keyId) {
+		return false, nil
+	}
+
+	_, err = s.createCheckpointInternal(ctx, tx, head, uuid.NewString())
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// createCheckpointInternal signs the given head of the chain, which must be locked by the transaction
+func (s *AuditLogChainService) createCheckpointInternal(ctx context.Context, tx *gorm.DB, head model.AuditLogChainHead, checkpointID string) (model.AuditLogCheckpoint, error) {
+	var previous model.AuditLogCheckpoint
+	err := tx.
+		WithContext(ctx).
+		Order("number DESC").
+		Limit(1).
+		Find(&previous).
+		Error
+	if err != nil {
+		return model.AuditLogCheckpoint{}, fmt.Errorf("failed to query the previous checkpoint: %w", err)
+	}
+
+	var purgedSequences []int64
+	err = tx.
+		WithContext(ctx).
+		Model(&model.AuditLogChainLink{}).
+		Where("purge_checkpoint_id = ?", checkpointID).
+		Order("sequence ASC").
+		Pluck("sequence", &purgedSequences).
+		Error
+	if err != nil {
+		return model.AuditLogCheckpoint{}, fmt.Errorf("failed to query purged links: %w", err)
+	}
+	purgedHash := sha256.New()
+	for _, sequence := range purgedSequences {
+		_, _ = purgedHash.Write([]byte(strconv.FormatInt(sequence, 10) + "\n"))
+	}
+
+	checkpoint := model.AuditLogCheckpoint{
+		Base:        model.Base{ID: checkpointID},
+		Number:      previous.Number + 1,
+		Sequence:    head.Sequence,
+		Hash:        head.Hash,
+		PurgeDigest: auditLogPurgeDigest(previous.PurgeDigest, hex.EncodeToString(purgedHash.Sum(nil))),
+	}
+	checkpoint.Signature, err = s.jwtService.GenerateAuditLogCheckpointToken(checkpoint)
+	if err != nil {
+		return model.AuditLogCheckpoint{}, fmt.Errorf("failed to sign checkpoint: %w", err)
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Create(&checkpoint).
+		Error
+	if err != nil {
+		return model.AuditLogCheckpoint{}, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// purgeAuditLogsInternal deletes the audit logs that match the condition but keeps their links, so the chain stays
+// intact. The purged links are covered by a new checkpoint, which distinguishes them from deleted audit logs.
+func (s *AuditLogChainService) purgeAuditLogsInternal(ctx context.Context, tx *gorm.DB, condition string, args []any) (int64, error) {
+	head, err := lockAuditLogChainHead(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	checkpointID := uuid.NewString()
+	err = tx.
+		WithContext(ctx).
+		Model(&model.AuditLogChainLink{}).
+		Where("audit_log_id IN (?)", tx.Model(&model.AuditLog{}).Select("id").Where(condition, args...)).
+		Updates(map[string]any{"audit_log_id": nil, "purge_checkpoint_id": checkpointID}).
+		Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to update links: %w", err)
+	}
+
+	st := tx.
+		WithContext(ctx).
+		Where(condition, args...).
+		Delete(&model.AuditLog{})
+	if st.Error != nil {
+		return 0, fmt.Errorf("failed to delete audit logs: %w", st.Error)
+	}
+	if st.RowsAffected == 0 {
+		return 0, nil
+	}
+
+	_, err = s.createCheckpointInternal(ctx, tx, head, checkpointID)
+	if err != nil {
+		return 0, err
+	}
+
+	return st.RowsAffected, nil
+}
+
+// chainLegacyAuditLogs chains the audit logs that were created before the chain existed, the oldest first.
+// This only happens once, so that audit logs inserted into the database later on aren't chained.
+func (s *AuditLogChainService) chainLegacyAuditLogs(ctx context.Context) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	writer, err := newAuditLogChainWriter(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if writer.head.Sequence > 0 {
+		return nil
+	}
+
+	var checkpointCount int64
+	err = tx.
+		WithContext(ctx).
+		Model(&model.AuditLogCheckpoint{}).
+		Count(&checkpointCount).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to count checkpoints: %w", err)
+	}
+	if checkpointCount > 0 {
+		return nil
+	}
+
+	var chained int
+	var last *model.AuditLog
+	for {
+		query := tx.
+			WithContext(ctx).
+			Order("created_at ASC, id ASC").
+			Limit(auditLogChainBatchSize)
+		if last != nil {
+			query = query.Where("created_at > ? OR (created_at = ? AND id > ?)", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+
+		var auditLogs []model.AuditLog
+		err = query.Find(&auditLogs).Error
+		if err != nil {
+			return fmt.Errorf("failed to query audit logs: %w", err)
+		}
+		if len(auditLogs) == 0 {
+			break
+		}
+
+		for _, auditLog := range auditLogs {
+			err = writer.append(ctx, auditLog)
+			if err != nil {
+				return err
+			}
+		}
+		chained += len(auditLogs)
+		last = &auditLogs[len(auditLogs)-1]
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	if chained > 0 {
+		log.Printf("Chained %d existing audit logs", chained)
+	}
+	return nil
+}
+
+// auditLogChainWriter appends audit logs to the chain within a transaction
+type auditLogChainWriter struct {
+	tx   *gorm.DB
+	head model.AuditLogChainHead
+}
+
+// newAuditLogChainWriter locks the head of the chain until the end of the transaction
+func newAuditLogChainWriter(ctx context.Context, tx *gorm.DB) (*auditLogChainWriter, error) {
+	head, err := lockAuditLogChainHead(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auditLogChainWriter{tx: tx, head: head}, nil
+}
+
+func (w *auditLogChainWriter) append(ctx context.Context, auditLog model.AuditLog) error {
+	entryHash, err := auditLogEntryHash(auditLog)
+	if err != nil {
+		return err
+	}
+
+	sequence := w.head.Sequence + 1
+	link := model.AuditLogChainLink{
+		Sequence:   sequence,
+		AuditLogID: &auditLog.ID,
+		EntryHash:  entryHash,
+		Hash:       auditLogChainHash(sequence, w.head.Hash, entryHash),
+	}
+	if auditLog.UserID != "" {
+		link.UserID = &auditLog.UserID
+	}
+
+	err = w.tx.
+		WithContext(ctx).
+		Create(&link).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to save link: %w", err)
+	}
+
+	err = w.tx.
+		WithContext(ctx).
+		Model(&model.AuditLogChainHead{}).
+		Where("id = ?", w.head.ID).
+		Updates(map[string]any{"sequence": link.Sequence, "hash": link.Hash}).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to update the head of the chain: %w", err)
+	}
+
+	w.head.Sequence = link.Sequence
+	w.head.Hash = link.Hash
+	return nil
+}
+
+// lockAuditLogChainHead locks the head of the chain until the end of the transaction and returns it.
+// Only the single head row is locked, so that links can't be appended concurrently, which would fork the chain,
+// without blocking the other tables.
+func lockAuditLogChainHead(ctx context.Context, tx *gorm.DB) (model.AuditLogChainHead, error) {
+	query := tx.WithContext(ctx)
+	// With SQLite there's no row locking, but a transaction blocks the entire database anyway
+	if tx.Name() != "sqlite" {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var head model.AuditLogChainHead
+	err := query.
+		First(&head, "id = ?", model.AuditLogChainHeadID).
+		Error
+	if err != nil {
+		return model.AuditLogChainHead{}, fmt.Errorf("failed to lock the head of the chain: %w", err)
+	}
+	return head, nil
+}
+
+// auditLogEntryHash returns the hash of the content of an audit log.
+// The creation time is truncated to seconds, as SQLite doesn't store fractional seconds.
+func auditLogEntryHash(auditLog model.AuditLog) (string, error) {
+	record := newAuditLogRecord(auditLog)
+	record.CreatedAt = record.CreatedAt.Truncate(time.Second)
+	if record.Data == nil {
+		record.Data = model.AuditLogData{}
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+	return utils.CreateSha256Hash(string(recordJSON)), nil
+}
+
+func auditLogChainHash(sequence int64, previousHash string, entryHash string) string {
+	return utils.CreateSha256Hash(strconv.FormatInt(sequence, 10) + ":" + previousHash + ":" + entryHash)
+}
+
+func auditLogPurgeDigest(previousDigest string, purgedHash string) string {
+	return utils.CreateSha256Hash(previousDigest + ":" + purgedHash)
+}
+
+// auditLogCheckpointKeyID returns the ID of the key the checkpoint claims to be signed with, without verifying it
+func auditLogCheckpointKeyID(checkpoint model.AuditLogCheckpoint) string {
+	message, err := jws.ParseString(checkpoint.Signature)
+	if err != nil || len(message.Signatures()) == 0 {
+		return ""
+	}
+	keyID, _ := message.Signatures()[0].ProtectedHeaders().KeyID()
+	return keyID
+}
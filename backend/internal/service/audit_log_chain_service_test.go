@@ -0,0 +1,211 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+func newAuditLogChainServiceForTest(t *testing.T, db *gorm.DB) *AuditLogChainService {
+	t.Helper()
+
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(nil, t.TempDir()))
+	chainService, err := NewAuditLogChainService(t.Context(), db, jwtService)
+	require.NoError(t, err)
+	return chainService
+}
+
+func TestAuditLogChainService(t *testing.T) {
+	// setup creates three chained audit logs, of which the first two are covered by a checkpoint
+	setup := func(t *testing.T) (*gorm.DB, *AuditLogService, *AuditLogChainService, []model.AuditLog) {
+		t.Helper()
+		db := newDatabaseForTest(t)
+		chainService := newAuditLogChainServiceForTest(t, db)
+		auditLogService := NewAuditLogService(db, NewTestAppConfigService(&model.AppConfig{}), nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, chainService)
+
+		tim := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+		require.NoError(t, db.Create(&tim).Error)
+
+		auditLogs := []model.AuditLog{
+			auditLogService.Create(t.Context(), model.AuditLogEventSignIn, "10.0.0.1", "test-agent", tim.ID, model.AuditLogData{}, db),
+			auditLogService.Create(t.Context(), model.AuditLogEventUserCreated, "", "", "", model.AuditLogData{"targetName": "craig"}, db),
+		}
+		created, err := chainService.CreateCheckpoint(t.Context())
+		require.NoError(t, err)
+		require.True(t, created)
+		auditLogs = append(auditLogs, auditLogService.Create(t.Context(), model.AuditLogEventNewClientAuthorization, "10.0.0.1", "test-agent", tim.ID, model.AuditLogData{"clientName": "Wiki"}, db))
+		for _, auditLog := range auditLogs {
+			require.NotEmpty(t, auditLog.ID)
+		}
+
+		return db, auditLogService, chainService, auditLogs
+	}
+
+	t.Run("verifies an intact chain", func(t *testing.T) {
+		db, _, chainService, _ := setup(t)
+
+		verification, err := chainService.VerifyChain(t.Context())
+		require.NoError(t, err)
+		assert.True(t, verification.Valid)
+		assert.EqualValues(t, 3, verification.Links)
+		assert.EqualValues(t, 1, verification.Checkpoints)
+		assert.EqualValues(t, 1, verification.UnsignedLinks)
+		assert.Nil(t, verification.BrokenLink)
+
+		created, err := chainService.CreateCheckpoint(t.Context())
+		require.NoError(t, err)
+		assert.True(t, created)
+		created, err = chainService.CreateCheckpoint(t.Context())
+		require.NoError(t, err)
+		assert.False(t, created, "nothing was appended since the last checkpoint")
+
+		var count int64
+		require.NoError(t, db.Model(&model.AuditLogCheckpoint{}).Count(&count).Error)
+		assert.EqualValues(t, 2, count)
+	})
+
+	t.Run("keeps the head at the last link", func(t *testing.T) {
+		db, _, _, auditLogs := setup(t)
+
+		var head model.AuditLogChainHead
+		require.NoError(t, db.First(&head, "id = ?", model.AuditLogChainHeadID).Error)
+		var last model.AuditLogChainLink
+		require.NoError(t, db.Order("sequence DESC").First(&last).Error)
+		assert.EqualValues(t, 3, head.Sequence)
+		assert.Equal(t, last.Hash, head.Hash)
+		assert.Equal(t, auditLogs[2].ID, *last.AuditLogID)
+	})
+
+	t.Run("survives retention purges and deleted users", func(t *testing.T) {
+		db, auditLogService, chainService, auditLogs := setup(t)
+
+		require.NoError(t, db.Model(&auditLogs[0]).Update("created_at", datatype.DateTime(time.Now().AddDate(0, 0, -100))).Error)
+		deleted, err := auditLogService.DeleteExpiredAuditLogs(t.Context(), AuditLogRetention{Days: 90})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+
+		// Postgres removes the user from the audit logs when the user is deleted
+		require.NoError(t, db.Exec("DELETE FROM users").Error)
+		require.NoError(t, db.Model(&model.AuditLog{}).Where("user_id IS NOT NULL").Update("user_id", nil).Error)
+
+		verification, err := chainService.VerifyChain(t.Context())
+		require.NoError(t, err)
+		assert.True(t, verification.Valid, "%+v", verification.BrokenLink)
+		assert.EqualValues(t, 3, verification.Links)
+		assert.EqualValues(t, 1, verification.PurgedLinks)
+		assert.EqualValues(t, 2, verification.Checkpoints)
+		assert.EqualValues(t, 0, verification.UnsignedLinks)
+	})
+
+	tests := []struct {
+		name           string
+		tamper         func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog)
+		brokenSequence int64
+		reason         string
+	}{
+		{
+			name: "detects modified audit logs",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Model(&auditLogs[1]).Update("ip_address", "192.168.0.1").Error)
+			},
+			brokenSequence: 2,
+			reason:         "the audit log was modified",
+		},
+		{
+			name: "detects deleted audit logs",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Delete(&auditLogs[1]).Error)
+			},
+			brokenSequence: 2,
+			reason:         "the audit log was deleted",
+		},
+		{
+			name: "detects deleted links",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Delete(&model.AuditLogChainLink{}, "sequence = ?", 2).Error)
+				require.NoError(t, db.Delete(&auditLogs[1]).Error)
+			},
+			brokenSequence: 2,
+			reason:         "the link is missing",
+		},
+		{
+			name: "detects audit logs purged without a checkpoint",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				var checkpoint model.AuditLogCheckpoint
+				require.NoError(t, db.First(&checkpoint).Error)
+				require.NoError(t, db.Model(&model.AuditLogChainLink{}).Where("sequence = ?", 1).Updates(map[string]any{"audit_log_id": nil, "purge_checkpoint_id": checkpoint.ID}).Error)
+				require.NoError(t, db.Delete(&auditLogs[0]).Error)
+			},
+			brokenSequence: 2,
+			reason:         "the purged audit logs don't match checkpoint 1",
+		},
+		{
+			name: "detects forged checkpoints",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Model(&model.AuditLogCheckpoint{}).Where("number = ?", 1).Update("sequence", 3).Error)
+			},
+			brokenSequence: 3,
+			reason:         "the signature of checkpoint 1 is invalid",
+		},
+		{
+			name: "detects truncated chains",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Delete(&model.AuditLogChainLink{}, "sequence >= ?", 2).Error)
+				require.NoError(t, db.Delete(&model.AuditLog{}, "id IN ?", []string{auditLogs[1].ID, auditLogs[2].ID}).Error)
+			},
+			brokenSequence: 2,
+			reason:         "the links up to checkpoint 1 are missing",
+		},
+		{
+			name: "detects audit logs that were inserted directly",
+			tamper: func(t *testing.T, db *gorm.DB, auditLogs []model.AuditLog) {
+				require.NoError(t, db.Omit("UserID").Create(&model.AuditLog{Event: model.AuditLogEventSignIn, Data: model.AuditLogData{}}).Error)
+			},
+			brokenSequence: 0,
+			reason:         "the audit log isn't part of the chain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _, chainService, auditLogs := setup(t)
+			tt.tamper(t, db, auditLogs)
+
+			verification, err := chainService.VerifyChain(t.Context())
+			require.NoError(t, err)
+			assert.False(t, verification.Valid)
+			require.NotNil(t, verification.BrokenLink)
+			assert.Equal(t, tt.brokenSequence, verification.BrokenLink.Sequence)
+			assert.Equal(t, tt.reason, verification.BrokenLink.Reason)
+		})
+	}
+}
+
+func TestNewAuditLogChainService_ChainsExistingAuditLogs(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	// Audit logs created before the chain existed
+	for _, ipAddress := range []string{"10.0.0.1", "10.0.0.2"} {
+		require.NoError(t, db.Omit("UserID").Create(&model.AuditLog{Event: model.AuditLogEventSignIn, IpAddress: ipAddress, Data: model.AuditLogData{}}).Error)
+	}
+
+	chainService := newAuditLogChainServiceForTest(t, db)
+	verification, err := chainService.VerifyChain(t.Context())
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.EqualValues(t, 2, verification.Links)
+
+	// The existing audit logs are only chained once
+	require.NoError(t, db.Omit("UserID").Create(&model.AuditLog{Event: model.AuditLogEventSignIn, Data: model.AuditLogData{}}).Error)
+	chainService = newAuditLogChainServiceForTest(t, db)
+	verification, err = chainService.VerifyChain(t.Context())
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+}
@@ -1,34 +1,87 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	sqliteDriver "github.com/glebarez/go-sqlite"
 	userAgentParser "github.com/mileusna/useragent"
+	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/dto"
 	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
 	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	"github.com/pocket-id/pocket-id/backend/internal/utils/email"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+func init() {
+	// SQLite has no IP address type, so the audit logs are filtered by CIDR ranges with a custom function
+	sqliteDriver.MustRegisterDeterministicScalarFunction("ip_in_cidr", 2, func(_ *sqliteDriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		ipAddress, _ := args[0].(string)
+		cidr, _ := args[1].(string)
+
+		ip, err := netip.ParseAddr(ipAddress)
+		if err != nil {
+			return false, nil
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return false, nil
+		}
+		return prefix.Contains(ip.Unmap()), nil
+	})
+}
+
+// ipPrefixRange returns the first and the last address of the range in their binary form
+func ipPrefixRange(prefix netip.Prefix) (first []byte, last []byte) {
+	first = prefix.Addr().AsSlice()
+	last = bytes.Clone(first)
+	for i := prefix.Bits(); i < len(last)*8; i++ {
+		last[i/8] |= 1 << (7 - i%8)
+	}
+	return first, last
+}
+
 type AuditLogService struct {
 	db               *gorm.DB
 	appConfigService *AppConfigService
 	emailService     *EmailService
 	geoliteService   *GeoLiteService
+	webhookService   *WebhookService
+	sinkService      *AuditLogSinkService
+	chainService     *AuditLogChainService
 }
 
-func NewAuditLogService(db *gorm.DB, appConfigService *AppConfigService, emailService *EmailService, geoliteService *GeoLiteService) *AuditLogService {
-	return &AuditLogService{db: db, appConfigService: appConfigService, emailService: emailService, geoliteService: geoliteService}
+func NewAuditLogService(db *gorm.DB, appConfigService *AppConfigService, emailService *EmailService, geoliteService *GeoLiteService, webhookService *WebhookService, sinkService *AuditLogSinkService, chainService *AuditLogChainService) *AuditLogService {
+	return &AuditLogService{db: db, appConfigService: appConfigService, emailService: emailService, geoliteService: geoliteService, webhookService: webhookService, sinkService: sinkService, chainService: chainService}
 }
 
 // Create creates a new audit log entry in the database
 func (s *AuditLogService) Create(ctx context.Context, event model.AuditLogEvent, ipAddress, userAgent, userID string, data model.AuditLogData, tx *gorm.DB) model.AuditLog {
-	country, city, err := s.geoliteService.GetLocationByIP(ipAddress)
-	if err != nil {
-		log.Printf("Failed to get IP location: %v", err)
+	// Events that are not triggered by a request, such as those from the LDAP sync, don't have an IP address
+	var country, city string
+	if ipAddress != "" {
+		var err error
+		country, city, err = s.geoliteService.GetLocationByIP(ipAddress)
+		if err != nil {
+			log.Printf("Failed to get IP location: %v", err)
+		}
 	}
 
 	auditLog := model.AuditLog{
@@ -41,19 +94,187 @@ func (s *AuditLogService) Create(ctx context.Context, event model.AuditLogEvent,
 		Data:      data,
 	}
 
-	// Save the audit log in the database
-	err = tx.
-		WithContext(ctx).
-		Create(&auditLog).
-		Error
+	// Save the audit log in the database and chain it to the previous one
+	err := tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx
+		if userID == "" {
+			// Actions that aren't performed by a user, such as those of the CLI, aren't associated with one
+			query = query.Omit("UserID")
+		}
+		if ipAddress == "" {
+			query = query.Omit("IpAddress")
+		}
+		err := query.
+			Create(&auditLog).
+			Error
+		if err != nil {
+			return err
+		}
+
+		writer, err := newAuditLogChainWriter(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return writer.append(ctx, auditLog)
+	})
 	if err != nil {
 		log.Printf("Failed to create audit log: %v", err)
 		return model.AuditLog{}
 	}
 
+	err = s.webhookService.enqueueAuditLogInternal(ctx, auditLog, tx)
+	if err != nil {
+		log.Printf("Failed to queue webhook deliveries: %v", err)
+	}
+
+	// Only stream the audit log once it's persisted, so rolled back actions are never reported
+	utils.AfterCommit(tx, func() {
+		s.sinkService.enqueue(auditLog)
+	})
+
 	return auditLog
 }
 
+type auditActorContextKey struct{}
+
+// AuditActor is the user that performs the actions of a request and the client they use
+type AuditActor struct {
+	UserID    string
+	IpAddress string
+	UserAgent string
+	// The identity provider is set instead of the user if it performs the actions, e.g. when it creates a user during a sign in
+	IdentityProviderID   string
+	IdentityProviderName string
+}
+
+// ContextWithAuditActor returns a copy of the context that attributes the admin events created with it to the actor
+func ContextWithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorContextKey{}, actor)
+}
+
+func auditActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorContextKey{}).(AuditActor)
+	return actor
+}
+
+// AuditTarget is the entity that an admin action was performed on
+type AuditTarget struct {
+	Type string
+	ID   string
+	Name string
+}
+
+// CreateAdminEvent records an action that the actor of the context performed on the target.
+// The before and after states are compared by their JSON representation and the changed fields are stored in the
+// "changes" field of the data, so the states must not contain secrets. The before state is nil if the target was
+// created and the after state is nil if it was deleted.
+func (s *AuditLogService) CreateAdminEvent(ctx context.Context, event model.AuditLogEvent, target AuditTarget, before, after any, tx *gorm.DB) model.AuditLog {
+	data := model.AuditLogData{"targetType": target.Type}
+	if target.ID != "" {
+		data["targetId"] = target.ID
+	}
+	if target.Name != "" {
+		data["targetName"] = target.Name
+	}
+
+	changes, err := auditLogChanges(before, after)
+	if err != nil {
+		log.Printf("Failed to compute audit log changes: %v", err)
+	} else if changes != "" {
+		data["changes"] = changes
+	}
+
+	actor := auditActorFromContext(ctx)
+	if actor.IdentityProviderID != "" {
+		data["actorIdentityProviderId"] = actor.IdentityProviderID
+		data["actorIdentityProvider"] = actor.IdentityProviderName
+	}
+	return s.Create(ctx, event, actor.IpAddress, actor.UserAgent, actor.UserID, data, tx)
+}
+
+// auditLogChanges returns the JSON encoded fields that differ between the two states, in the format
+// {"field": {"before": ..., "after": ...}}. It returns an empty string if nothing has changed.
+func auditLogChanges(before, after any) (string, error) {
+	beforeFields, err := auditLogFields(before)
+	if err != nil {
+		return "", err
+	}
+	afterFields, err := auditLogFields(after)
+	if err != nil {
+		return "", err
+	}
+
+	type change struct {
+		Before any `json:"before"`
+		After  any `json:"after"`
+	}
+	changes := make(map[string]change)
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = change{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok && value != nil {
+			changes[field] = change{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal changes: %w", err)
+	}
+	return string(changesJSON), nil
+}
+
+func auditLogFields(state any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if state == nil {
+		return fields, nil
+	}
+
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	err = json.Unmarshal(stateJSON, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	return fields, nil
+}
+
+// auditLogRecord is the JSON representation of an audit log that is sent to the sinks and stored in the archives
+type auditLogRecord struct {
+	ID        string             `json:"id"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Event     string             `json:"event"`
+	UserID    string             `json:"userId,omitempty"`
+	IpAddress string             `json:"ipAddress,omitempty"`
+	Country   string             `json:"country,omitempty"`
+	City      string             `json:"city,omitempty"`
+	UserAgent string             `json:"userAgent,omitempty"`
+	Data      model.AuditLogData `json:"data"`
+}
+
+func newAuditLogRecord(auditLog model.AuditLog) auditLogRecord {
+	return auditLogRecord{
+		ID:        auditLog.ID,
+		CreatedAt: auditLog.CreatedAt.UTC(),
+		Event:     string(auditLog.Event),
+		UserID:    auditLog.UserID,
+		IpAddress: auditLog.IpAddress,
+		Country:   auditLog.Country,
+		City:      auditLog.City,
+		UserAgent: auditLog.UserAgent,
+		Data:      auditLog.Data,
+	}
+}
+
 // CreateNewSignInWithEmail creates a new audit log entry in the database and sends an email if the device hasn't been used before
 func (s *AuditLogService) CreateNewSignInWithEmail(ctx context.Context, ipAddress, userAgent, userID string, tx *gorm.DB) model.AuditLog {
 	createdAuditLog := s.Create(ctx, model.AuditLogEventSignIn, ipAddress, userAgent, userID, model.AuditLogData{}, tx)
@@ -133,30 +354,134 @@ func (s *AuditLogService) ListAllAuditLogs(ctx context.Context, sortedPagination
 		Preload("User").
 		Model(&model.AuditLog{})
 
+	query, err := s.applyAuditLogFilters(query, filters)
+	if err != nil {
+		return nil, utils.PaginationResponse{}, err
+	}
+
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &logs)
+	if err != nil {
+		return nil, pagination, err
+	}
+
+	return logs, pagination, nil
+}
+
+// ExportAuditLogs calls fn for every audit log that matches the filters, the oldest first.
+// The audit logs are read with a cursor, so there is no limit to the number of exported audit logs.
+func (s *AuditLogService) ExportAuditLogs(ctx context.Context, filters dto.AuditLogFilterDto, fn func(auditLog model.AuditLog) error) error {
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Select("audit_logs.*, users.username AS username").
+		Joins("LEFT JOIN users ON users.id = audit_logs.user_id")
+
+	query, err := s.applyAuditLogFilters(query, filters)
+	if err != nil {
+		return err
+	}
+
+	rows, err := query.
+		Order("audit_logs.created_at ASC, audit_logs.id ASC").
+		Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row struct {
+			model.AuditLog
+			Username *string
+		}
+		err = s.db.ScanRows(rows, &row)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		if row.Username != nil {
+			row.AuditLog.User.Username = *row.Username
+		}
+
+		err = fn(row.AuditLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *AuditLogService) applyAuditLogFilters(query *gorm.DB, filters dto.AuditLogFilterDto) (*gorm.DB, error) {
+	dialect := s.db.Name()
+	if dialect != "sqlite" && dialect != "postgres" && dialect != "mysql" {
+		return nil, fmt.Errorf("unsupported database dialect: %s", dialect)
+	}
+
 	if filters.UserID != "" {
-		query = query.Where("user_id = ?", filters.UserID)
+		query = query.Where("audit_logs.user_id = ?", filters.UserID)
 	}
 	if filters.Event != "" {
-		query = query.Where("event = ?", filters.Event)
+		query = query.Where("audit_logs.event = ?", filters.Event)
 	}
 	if filters.ClientName != "" {
-		dialect := s.db.Name()
 		switch dialect {
 		case "sqlite":
-			query = query.Where("json_extract(data, '$.clientName') = ?", filters.ClientName)
+			query = query.Where("json_extract(audit_logs.data, '$.clientName') = ?", filters.ClientName)
 		case "postgres":
-			query = query.Where("data->>'clientName' = ?", filters.ClientName)
-		default:
-			return nil, utils.PaginationResponse{}, fmt.Errorf("unsupported database dialect: %s", dialect)
+			query = query.Where("audit_logs.data->>'clientName' = ?", filters.ClientName)
+		case "mysql":
+			query = query.Where("JSON_UNQUOTE(JSON_EXTRACT(audit_logs.data, '$.clientName')) = ?", filters.ClientName)
 		}
 	}
+	if !filters.From.IsZero() {
+		query = query.Where("audit_logs.created_at >= ?", datatype.DateTime(filters.From))
+	}
+	if !filters.To.IsZero() {
+		query = query.Where("audit_logs.created_at <= ?", datatype.DateTime(filters.To))
+	}
+	if filters.Country != "" {
+		query = query.Where("audit_logs.country = ?", filters.Country)
+	}
 
-	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &logs)
-	if err != nil {
-		return nil, pagination, err
+	if filters.IpAddress != "" {
+		if ip, err := netip.ParseAddr(filters.IpAddress); err == nil {
+			query = query.Where("audit_logs.ip_address = ?", ip.String())
+		} else if prefix, err := netip.ParsePrefix(filters.IpAddress); err == nil {
+			switch dialect {
+			case "sqlite":
+				query = query.Where("ip_in_cidr(audit_logs.ip_address, ?)", prefix.Masked().String())
+			case "postgres":
+				query = query.Where("audit_logs.ip_address <<= ?::cidr", prefix.Masked().String())
+			case "mysql":
+				// MySQL has no type for IP ranges, so the binary addresses are compared with the first and last address of the range
+				first, last := ipPrefixRange(prefix.Masked())
+				query = query.Where("LENGTH(INET6_ATON(audit_logs.ip_address)) = ? AND INET6_ATON(audit_logs.ip_address) BETWEEN ? AND ?", len(first), first, last)
+			}
+		} else {
+			return nil, &common.ValidationError{Message: "The IP address filter must be an IP address or a CIDR range"}
+		}
 	}
 
-	return logs, pagination, nil
+	if filters.Search != "" {
+		search := "%" + filters.Search + "%"
+		dataColumn, ipAddressColumn := "", "audit_logs.ip_address"
+		switch dialect {
+		case "sqlite":
+			dataColumn = "CAST(audit_logs.data AS TEXT)"
+		case "postgres":
+			dataColumn = "audit_logs.data::text"
+			ipAddressColumn = "host(audit_logs.ip_address)"
+		case "mysql":
+			dataColumn = "CAST(audit_logs.data AS CHAR)"
+		}
+		query = query.Where(
+			"(audit_logs.event LIKE ? OR "+ipAddressColumn+" LIKE ? OR audit_logs.country LIKE ? OR audit_logs.city LIKE ? OR audit_logs.user_agent LIKE ? OR "+dataColumn+" LIKE ? OR "+
+				"audit_logs.user_id IN (SELECT id FROM users WHERE username LIKE ?))",
+			search, search, search, search, search, search, search,
+		)
+	}
+
+	return query, nil
 }
 
 func (s *AuditLogService) ListUsernamesWithIds(ctx context.Context) (users map[string]string, err error) {
@@ -200,6 +525,10 @@ func (s *AuditLogService) ListClientNames(ctx context.Context) (clientNames []st
 		query = query.
 			Select("DISTINCT data->>'clientName' AS client_name").
 			Where("data->>'clientName' IS NOT NULL")
+	case "mysql":
+		query = query.
+			Select("DISTINCT JSON_UNQUOTE(JSON_EXTRACT(data, '$.clientName')) AS client_name").
+			Where("JSON_EXTRACT(data, '$.clientName') IS NOT NULL")
 	default:
 		return nil, fmt.Errorf("unsupported database dialect: %s", dialect)
 	}
@@ -220,3 +549,287 @@ func (s *AuditLogService) ListClientNames(ctx context.Context) (clientNames []st
 
 	return clientNames, nil
 }
+
+// AuditLogRetention defines how long the audit logs are kept
+type AuditLogRetention struct {
+	// Days is the number of days the audit logs are kept, 0 keeps them forever
+	Days int
+	// DaysByEvent overrides the number of days for single events
+	DaysByEvent map[model.AuditLogEvent]int
+	// ArchivePath is the directory the expired audit logs are archived to before they're deleted.
+	// If it's empty, the expired audit logs are deleted without archiving them.
+	ArchivePath string
+}
+
+// expiredCondition returns the SQL condition that matches the audit logs whose time in the column is older than
+// their retention, or an empty string if all audit logs are kept forever
+func (r AuditLogRetention) expiredCondition(now time.Time, column string) (string, []any) {
+	var conditions []string
+	var args []any
+	overriddenEvents := make([]model.AuditLogEvent, 0, len(r.DaysByEvent))
+	for event, days := range r.DaysByEvent {
+		overriddenEvents = append(overriddenEvents, event)
+		if days > 0 {
+			conditions = append(conditions, "(event = ? AND "+column+" < ?)")
+			args = append(args, event, datatype.DateTime(now.AddDate(0, 0, -days)))
+		}
+	}
+	if r.Days > 0 {
+		if len(overriddenEvents) > 0 {
+			conditions = append(conditions, "(event NOT IN ? AND "+column+" < ?)")
+			args = append(args, overriddenEvents, datatype.DateTime(now.AddDate(0, 0, -r.Days)))
+		} else {
+			conditions = append(conditions, column+" < ?")
+			args = append(args, datatype.DateTime(now.AddDate(0, 0, -r.Days)))
+		}
+	}
+
+	return strings.Join(conditions, " OR "), args
+}
+
+// DeleteExpiredAuditLogs deletes the audit logs that are older than their retention and returns how many were deleted.
+// If an archive path is set, the expired audit logs are first written to a gzip-compressed JSON Lines file in it,
+// which can be imported again with ImportAuditLogArchive.
+// The retention of imported audit logs starts when they were imported, and they aren't archived again when they expire.
+func (s *AuditLogService) DeleteExpiredAuditLogs(ctx context.Context, retention AuditLogRetention) (int64, error) {
+	now := time.Now()
+
+	expiredOriginals, originalArgs := retention.expiredCondition(now, "created_at")
+	if expiredOriginals == "" {
+		// All audit logs are kept forever
+		return 0, nil
+	}
+	expiredOriginals = "imported_at IS NULL AND (" + expiredOriginals + ")"
+	expiredImports, importArgs := retention.expiredCondition(now, "imported_at")
+	expired := "(" + expiredOriginals + ") OR (imported_at IS NOT NULL AND (" + expiredImports + "))"
+	args := slices.Concat(originalArgs, importArgs)
+
+	if retention.ArchivePath != "" {
+		err := s.archiveAuditLogs(ctx, retention.ArchivePath, now, expiredOriginals, originalArgs)
+		if err != nil {
+			return 0, fmt.Errorf("failed to archive expired audit logs: %w", err)
+		}
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// The cutoffs were calculated once, so exactly the archived audit logs and the expired imports are deleted
+	deleted, err := s.chainService.purgeAuditLogsInternal(ctx, tx, expired, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired audit logs: %w", err)
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// archiveAuditLogs writes the audit logs that match the condition to a new archive in the directory
+func (s *AuditLogService) archiveAuditLogs(ctx context.Context, dir string, now time.Time, condition string, args []any) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	// The archive is written to a temporary file first, so that there are never incomplete archives
+	tmpFile, err := os.CreateTemp(dir, ".audit-logs-*.jsonl.gz.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	gzipWriter := gzip.NewWriter(tmpFile)
+	encoder := json.NewEncoder(gzipWriter)
+
+	rows, err := s.db.
+		WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Where(condition, args...).
+		Order("created_at ASC, id ASC").
+		Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var auditLog model.AuditLog
+		err = s.db.ScanRows(rows, &auditLog)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit log: %w", err)
+		}
+
+		err = encoder.Encode(newAuditLogRecord(auditLog))
+		if err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to query audit logs: %w", err)
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	err = gzipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	err = tmpFile.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	archivePath := filepath.Join(dir, "audit-logs-"+now.UTC().Format("20060102T150405Z")+".jsonl.gz")
+	err = os.Rename(tmpFile.Name(), archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+
+	log.Printf("Archived %d expired audit logs to %s", count, archivePath)
+	return nil
+}
+
+// ImportAuditLogArchive imports the audit logs of an archive and returns how many were imported.
+// Both gzip-compressed and uncompressed JSON Lines are accepted. Audit logs that already exist are skipped and
+// the audit logs of users that don't exist anymore aren't associated with a user. The imported audit logs are
+// appended to the chain, while the links of the purged originals stay as they are.
+// The imported audit logs are kept for the retention period counted from the time of the import.
+func (s *AuditLogService) ImportAuditLogArchive(ctx context.Context, r io.Reader) (int64, error) {
+	bufReader := bufio.NewReader(r)
+	var reader io.Reader = bufReader
+	if magic, _ := bufReader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress archive: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	writer, err := newAuditLogChainWriter(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	existingUsers := make(map[string]bool)
+	userExists := func(userID string) (bool, error) {
+		if exists, ok := existingUsers[userID]; ok {
+			return exists, nil
+		}
+		var count int64
+		err := tx.
+			WithContext(ctx).
+			Model(&model.User{}).
+			Where("id = ?", userID).
+			Count(&count).
+			Error
+		if err != nil {
+			return false, err
+		}
+		existingUsers[userID] = count > 0
+		return count > 0, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	importedAt := datatype.DateTime(time.Now())
+	var imported int64
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record auditLogRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return 0, fmt.Errorf("invalid audit log on line %d: %w", line, err)
+		}
+		if record.ID == "" || record.Event == "" || record.CreatedAt.IsZero() {
+			return 0, fmt.Errorf("invalid audit log on line %d: missing ID, event or creation time", line)
+		}
+		if _, err := netip.ParseAddr(record.IpAddress); record.IpAddress != "" && err != nil {
+			return 0, fmt.Errorf("invalid audit log on line %d: invalid IP address", line)
+		}
+
+		auditLog := model.AuditLog{
+			Base:       model.Base{ID: record.ID, CreatedAt: datatype.DateTime(record.CreatedAt)},
+			Event:      model.AuditLogEvent(record.Event),
+			IpAddress:  record.IpAddress,
+			Country:    record.Country,
+			City:       record.City,
+			UserAgent:  record.UserAgent,
+			Data:       record.Data,
+			ImportedAt: &importedAt,
+		}
+		if auditLog.Data == nil {
+			auditLog.Data = model.AuditLogData{}
+		}
+
+		// The hooks are skipped, as they would overwrite the creation time
+		query := tx.
+			WithContext(ctx).
+			Session(&gorm.Session{SkipHooks: true}).
+			Clauses(clause.OnConflict{DoNothing: true})
+
+		exists := false
+		if record.UserID != "" {
+			exists, err = userExists(record.UserID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to query user: %w", err)
+			}
+		}
+		if exists {
+			auditLog.UserID = record.UserID
+		} else {
+			query = query.Omit("UserID")
+		}
+		if auditLog.IpAddress == "" {
+			query = query.Omit("IpAddress")
+		}
+
+		st := query.Create(&auditLog)
+		if st.Error != nil {
+			return 0, fmt.Errorf("failed to import audit log on line %d: %w", line, st.Error)
+		}
+		if st.RowsAffected == 0 {
+			continue
+		}
+
+		err = writer.append(ctx, auditLog)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import audit log on line %d: %w", line, err)
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return 0, err
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+func TestAuditLogService_Filters(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
+	service := NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil)
+
+	tim := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+	require.NoError(t, db.Create(&tim).Error)
+
+	now := time.Now()
+	auditLogs := []model.AuditLog{
+		{Event: model.AuditLogEventSignIn, IpAddress: "10.0.0.1", Country: "Switzerland", City: "Bern", UserID: tim.ID, Data: model.AuditLogData{}},
+		{Event: model.AuditLogEventNewClientAuthorization, IpAddress: "192.168.1.20", Country: "Germany", UserAgent: "curl/8.0", UserID: tim.ID, Data: model.AuditLogData{"clientName": "Wiki"}},
+		{Event: model.AuditLogEventUserCreated, IpAddress: "2001:db8::1", Data: model.AuditLogData{"targetName": "craig"}},
+	}
+	require.NoError(t, db.Create(auditLogs[:2]).Error)
+	require.NoError(t, db.Omit("UserID").Create(&auditLogs[2]).Error)
+	// The creation time is always set to the current time when an audit log is created
+	for i, createdAt := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+		require.NoError(t, db.Model(&auditLogs[i]).Update("created_at", datatype.DateTime(createdAt)).Error)
+	}
+
+	list := func(t *testing.T, filters dto.AuditLogFilterDto) []model.AuditLogEvent {
+		t.Helper()
+		var request utils.SortedPaginationRequest
+		request.Sort.Column = "createdAt"
+		request.Sort.Direction = "asc"
+		logs, _, err := service.ListAllAuditLogs(t.Context(), request, filters)
+		require.NoError(t, err)
+		events := make([]model.AuditLogEvent, len(logs))
+		for i, log := range logs {
+			events[i] = log.Event
+		}
+		return events
+	}
+
+	t.Run("filters by date range", func(t *testing.T) {
+		events := list(t, dto.AuditLogFilterDto{From: now.Add(-2 * time.Hour), To: now.Add(-time.Minute)})
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventNewClientAuthorization}, events)
+	})
+
+	t.Run("filters by IP address and CIDR range", func(t *testing.T) {
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventSignIn}, list(t, dto.AuditLogFilterDto{IpAddress: "10.0.0.1"}))
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventNewClientAuthorization}, list(t, dto.AuditLogFilterDto{IpAddress: "192.168.0.0/16"}))
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventUserCreated}, list(t, dto.AuditLogFilterDto{IpAddress: "2001:db8::/32"}))
+
+		_, _, err := service.ListAllAuditLogs(t.Context(), utils.SortedPaginationRequest{}, dto.AuditLogFilterDto{IpAddress: "10.0.0"})
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("filters by country", func(t *testing.T) {
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventNewClientAuthorization}, list(t, dto.AuditLogFilterDto{Country: "Germany"}))
+	})
+
+	t.Run("searches the text fields, the data and the username", func(t *testing.T) {
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventNewClientAuthorization}, list(t, dto.AuditLogFilterDto{Search: "curl"}))
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventUserCreated}, list(t, dto.AuditLogFilterDto{Search: "craig"}))
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventSignIn, model.AuditLogEventNewClientAuthorization}, list(t, dto.AuditLogFilterDto{Search: "tim"}))
+		assert.Equal(t, []model.AuditLogEvent{model.AuditLogEventSignIn}, list(t, dto.AuditLogFilterDto{Search: "Bern", UserID: tim.ID}))
+	})
+
+	t.Run("exports all matching audit logs the oldest first", func(t *testing.T) {
+		var exported []model.AuditLog
+		err := service.ExportAuditLogs(t.Context(), dto.AuditLogFilterDto{UserID: tim.ID}, func(auditLog model.AuditLog) error {
+			exported = append(exported, auditLog)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Len(t, exported, 2)
+		assert.Equal(t, model.AuditLogEventSignIn, exported[0].Event)
+		assert.Equal(t, model.AuditLogEventNewClientAuthorization, exported[1].Event)
+		assert.Equal(t, "tim", exported[1].User.Username)
+		assert.Equal(t, "Wiki", exported[1].Data["clientName"])
+	})
+}
+
+func TestAuditLogService_DeleteExpiredAuditLogs(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
+	chainService := newAuditLogChainServiceForTest(t, db)
+	service := NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, chainService)
+
+	tim := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+	require.NoError(t, db.Create(&tim).Error)
+
+	now := time.Now()
+	createAuditLog := func(t *testing.T, event model.AuditLogEvent, age time.Duration) model.AuditLog {
+		t.Helper()
+		auditLog := model.AuditLog{Event: event, IpAddress: "127.0.0.1", UserID: tim.ID, Data: model.AuditLogData{"clientName": "Wiki"}}
+		require.NoError(t, db.Create(&auditLog).Error)
+		// The creation time is always set to the current time when an audit log is created
+		auditLog.CreatedAt = datatype.DateTime(now.Add(-age).Truncate(time.Second))
+		require.NoError(t, db.Model(&auditLog).Update("created_at", auditLog.CreatedAt).Error)
+		return auditLog
+	}
+
+	const day = 24 * time.Hour
+	oldSignIn := createAuditLog(t, model.AuditLogEventSignIn, 200*day)
+	recentSignIn := createAuditLog(t, model.AuditLogEventSignIn, 20*day)
+	oldAuthorization := createAuditLog(t, model.AuditLogEventNewClientAuthorization, 20*day)
+	recentAuthorization := createAuditLog(t, model.AuditLogEventNewClientAuthorization, 5*day)
+	oldTokenSignIn := createAuditLog(t, model.AuditLogEventOneTimeAccessTokenSignIn, 1000*day)
+
+	remainingIDs := func(t *testing.T) []string {
+		t.Helper()
+		var ids []string
+		require.NoError(t, db.Model(&model.AuditLog{}).Order("id").Pluck("id", &ids).Error)
+		return ids
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "archive")
+	count, err := service.DeleteExpiredAuditLogs(t.Context(), AuditLogRetention{
+		Days: 90,
+		DaysByEvent: map[model.AuditLogEvent]int{
+			model.AuditLogEventNewClientAuthorization:   14,
+			model.AuditLogEventOneTimeAccessTokenSignIn: 0,
+		},
+		ArchivePath: archivePath,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.ElementsMatch(t, []string{recentSignIn.ID, recentAuthorization.ID, oldTokenSignIn.ID}, remainingIDs(t))
+
+	archives, err := filepath.Glob(filepath.Join(archivePath, "audit-logs-*.jsonl.gz"))
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+
+	t.Run("imports archives", func(t *testing.T) {
+		importArchive := func(t *testing.T) int64 {
+			t.Helper()
+			file, err := os.Open(archives[0])
+			require.NoError(t, err)
+			defer file.Close()
+			imported, err := service.ImportAuditLogArchive(t.Context(), file)
+			require.NoError(t, err)
+			return imported
+		}
+
+		assert.EqualValues(t, 2, importArchive(t))
+		assert.ElementsMatch(t, []string{oldSignIn.ID, recentSignIn.ID, oldAuthorization.ID, recentAuthorization.ID, oldTokenSignIn.ID}, remainingIDs(t))
+
+		var imported model.AuditLog
+		require.NoError(t, db.Where("id = ?", oldSignIn.ID).First(&imported).Error)
+		assert.Equal(t, oldSignIn.CreatedAt.ToTime().Unix(), imported.CreatedAt.ToTime().Unix())
+		assert.Equal(t, tim.ID, imported.UserID)
+		assert.Equal(t, "Wiki", imported.Data["clientName"])
+
+		// Audit logs that already exist are skipped
+		assert.EqualValues(t, 0, importArchive(t))
+
+		// The imported audit logs are appended to the chain
+		var links int64
+		require.NoError(t, db.Model(&model.AuditLogChainLink{}).Where("audit_log_id IN ?", []string{oldSignIn.ID, oldAuthorization.ID}).Count(&links).Error)
+		assert.EqualValues(t, 2, links)
+	})
+
+	t.Run("keeps imported audit logs for the retention from their import", func(t *testing.T) {
+		deleteExpired := func(t *testing.T) int64 {
+			t.Helper()
+			count, err := service.DeleteExpiredAuditLogs(t.Context(), AuditLogRetention{
+				Days: 90,
+				DaysByEvent: map[model.AuditLogEvent]int{
+					model.AuditLogEventNewClientAuthorization:   14,
+					model.AuditLogEventOneTimeAccessTokenSignIn: 0,
+				},
+				ArchivePath: archivePath,
+			})
+			require.NoError(t, err)
+			return count
+		}
+
+		// The imported audit logs are older than the retention, but were just imported
+		assert.EqualValues(t, 0, deleteExpired(t))
+		assert.ElementsMatch(t, []string{oldSignIn.ID, recentSignIn.ID, oldAuthorization.ID, recentAuthorization.ID, oldTokenSignIn.ID}, remainingIDs(t))
+
+		require.NoError(t, db.Model(&model.AuditLog{}).
+			Where("imported_at IS NOT NULL").
+			Update("imported_at", datatype.DateTime(now.Add(-30*day))).
+			Error)
+		assert.EqualValues(t, 1, deleteExpired(t))
+		assert.ElementsMatch(t, []string{oldSignIn.ID, recentSignIn.ID, recentAuthorization.ID, oldTokenSignIn.ID}, remainingIDs(t))
+
+		// Imported audit logs aren't archived again
+		archives, err := filepath.Glob(filepath.Join(archivePath, "audit-logs-*.jsonl.gz"))
+		require.NoError(t, err)
+		assert.Len(t, archives, 1)
+	})
+}
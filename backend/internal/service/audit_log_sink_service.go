@@ -0,0 +1,436 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+const (
+	// auditLogSinkAttempts is the number of times an audit log is sent to a sink before it's dropped
+	auditLogSinkAttempts = 3
+	// auditLogSinkTimeout is the time a sink has to accept an audit log
+	auditLogSinkTimeout = 10 * time.Second
+	// auditLogSinkShutdownTimeout is the time the sinks have to accept the buffered audit logs when the application stops
+	auditLogSinkShutdownTimeout = 5 * time.Second
+)
+
+// AuditLogSinkService streams the audit logs to external systems, such as syslog servers or SIEMs.
+// Every sink has its own buffer that is processed in the background, so a slow or unavailable sink never blocks
+// the request that created the audit log. If the buffer of a sink is full, new audit logs are dropped for that sink.
+type AuditLogSinkService struct {
+	workers []*auditLogSinkWorker
+}
+
+// NewAuditLogSinkService creates the sinks from their URLs. The supported URLs are:
+//   - syslog+udp://host:514, syslog+tcp://host:514 and syslog+tls://host:6514 for RFC 5424 syslog.
+//     The facility can be set with the "facility" query parameter and defaults to "authpriv".
+//   - file:///path/to/audit.log for newline-delimited JSON that is appended to a file
+//   - http(s)://host/path for JSON that is posted to an endpoint. The user info of the URL is sent as basic auth.
+func NewAuditLogSinkService(httpClient *http.Client, sinkURLs []string, bufferSize int) (*AuditLogSinkService, error) {
+	if bufferSize <= 0 {
+		return nil, errors.New("the buffer size of the audit log sinks must be positive")
+	}
+
+	s := &AuditLogSinkService{}
+	for _, rawURL := range sinkURLs {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" {
+			continue
+		}
+
+		sinkURL, err := url.Parse(rawURL)
+		if err != nil {
+			// The error isn't wrapped because it contains the URL, which may contain credentials
+			return nil, errors.New("invalid audit log sink URL")
+		}
+
+		sink, err := newAuditLogSink(sinkURL, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit log sink '%s': %w", sinkURL.Redacted(), err)
+		}
+
+		s.workers = append(s.workers, &auditLogSinkWorker{
+			name:  sinkURL.Redacted(),
+			sink:  sink,
+			queue: make(chan auditLogRecord, bufferSize),
+		})
+	}
+
+	return s, nil
+}
+
+// Run sends the buffered audit logs to the sinks until the context is canceled
+func (s *AuditLogSinkService) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, worker := range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// enqueue buffers the audit log for all sinks without blocking
+func (s *AuditLogSinkService) enqueue(auditLog model.AuditLog) {
+	if s == nil || len(s.workers) == 0 {
+		return
+	}
+
+	record := newAuditLogRecord(auditLog)
+	for _, worker := range s.workers {
+		select {
+		case worker.queue <- record:
+		default:
+			worker.dropped.Add(1)
+		}
+	}
+}
+
+type auditLogSink interface {
+	// Write sends an audit log to the sink
+	Write(ctx context.Context, record auditLogRecord) error
+	// Close releases the resources of the sink
+	Close() error
+}
+
+func newAuditLogSink(sinkURL *url.URL, httpClient *http.Client) (auditLogSink, error) {
+	switch sinkURL.Scheme {
+	case "syslog+udp", "syslog+tcp", "syslog+tls":
+		return newSyslogAuditLogSink(sinkURL)
+	case "file":
+		return newFileAuditLogSink(sinkURL)
+	case "http", "https":
+		return newHttpAuditLogSink(sinkURL, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme '%s'", sinkURL.Scheme)
+	}
+}
+
+type auditLogSinkWorker struct {
+	// name is the URL of the sink without credentials
+	name  string
+	sink  auditLogSink
+	queue chan auditLogRecord
+	// dropped is the number of audit logs that were dropped since the last warning because the queue was full
+	dropped atomic.Int64
+}
+
+func (w *auditLogSinkWorker) run(ctx context.Context) {
+	defer func() {
+		if err := w.sink.Close(); err != nil {
+			log.Printf("Failed to close audit log sink %s: %v", w.name, err)
+		}
+	}()
+
+	for {
+		select {
+		case record := <-w.queue:
+			w.warnDropped()
+			w.write(ctx, record)
+		case <-ctx.Done():
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain sends the audit logs that are still buffered when the application stops
+func (w *auditLogSinkWorker) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), auditLogSinkShutdownTimeout)
+	defer cancel()
+
+	for ctx.Err() == nil {
+		select {
+		case record := <-w.queue:
+			w.write(ctx, record)
+		default:
+			w.warnDropped()
+			return
+		}
+	}
+}
+
+// write sends the audit log to the sink and retries if that fails
+func (w *auditLogSinkWorker) write(ctx context.Context, record auditLogRecord) {
+	var err error
+	for attempt := range auditLogSinkAttempts {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				log.Printf("Failed to send audit log %s to sink %s: %v", record.ID, w.name, err)
+				return
+			}
+		}
+
+		writeCtx, cancel := context.WithTimeout(ctx, auditLogSinkTimeout)
+		err = w.sink.Write(writeCtx, record)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+
+	log.Printf("Failed to send audit log %s to sink %s: %v", record.ID, w.name, err)
+}
+
+func (w *auditLogSinkWorker) warnDropped() {
+	if dropped := w.dropped.Swap(0); dropped > 0 {
+		log.Printf("[WARN] Dropped %d audit logs for sink %s because it couldn't keep up", dropped, w.name)
+	}
+}
+
+// syslogFacilities are the syslog facilities that can be used for the audit logs
+var syslogFacilities = map[string]int{
+	"user":     1,
+	"daemon":   3,
+	"auth":     4,
+	"authpriv": 10,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+const (
+	// syslogSeverityInfo is the severity of all audit logs
+	syslogSeverityInfo = 6
+	// syslogMaxMsgIDLength is the maximum length of the MSGID field
+	syslogMaxMsgIDLength = 32
+)
+
+// syslogAuditLogSink sends the audit logs as RFC 5424 messages with a JSON body.
+// Messages sent over TCP and TLS are framed with octet counting as described in RFC 6587.
+type syslogAuditLogSink struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	priority  int
+	hostname  string
+	conn      net.Conn
+}
+
+func newSyslogAuditLogSink(sinkURL *url.URL) (*syslogAuditLogSink, error) {
+	if sinkURL.Host == "" {
+		return nil, errors.New("missing host")
+	}
+
+	facilityName := sinkURL.Query().Get("facility")
+	if facilityName == "" {
+		facilityName = "authpriv"
+	}
+	facility, ok := syslogFacilities[facilityName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported facility '%s'", facilityName)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &syslogAuditLogSink{
+		network:  strings.TrimPrefix(sinkURL.Scheme, "syslog+"),
+		address:  sinkURL.Host,
+		priority: facility*8 + syslogSeverityInfo,
+		hostname: hostname,
+	}
+	if s.network == "tls" {
+		s.network = "tcp"
+		s.tlsConfig = &tls.Config{
+			ServerName: sinkURL.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return s, nil
+}
+
+func (s *syslogAuditLogSink) Write(ctx context.Context, record auditLogRecord) error {
+	message, err := s.format(record)
+	if err != nil {
+		return err
+	}
+
+	if s.conn == nil {
+		s.conn, err = s.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+
+	if s.network != "udp" {
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	_, err = s.conn.Write(message)
+	if err != nil {
+		// Reconnect on the next attempt
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *syslogAuditLogSink) dial(ctx context.Context) (net.Conn, error) {
+	if s.tlsConfig != nil {
+		dialer := &tls.Dialer{Config: s.tlsConfig}
+		return dialer.DialContext(ctx, s.network, s.address)
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, s.network, s.address)
+}
+
+// format returns the RFC 5424 message of the audit log
+func (s *syslogAuditLogSink) format(record auditLogRecord) ([]byte, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+
+	msgID := record.Event
+	if len(msgID) > syslogMaxMsgIDLength {
+		msgID = msgID[:syslogMaxMsgIDLength]
+	}
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	header := fmt.Sprintf("<%d>1 %s %s pocket-id %d %s - ",
+		s.priority,
+		record.CreatedAt.Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		os.Getpid(),
+		msgID,
+	)
+
+	return append([]byte(header), body...), nil
+}
+
+func (s *syslogAuditLogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// fileAuditLogSink appends the audit logs as newline-delimited JSON to a file
+type fileAuditLogSink struct {
+	file *os.File
+}
+
+func newFileAuditLogSink(sinkURL *url.URL) (*fileAuditLogSink, error) {
+	path := sinkURL.Path
+	if path == "" {
+		// Relative paths, e.g. file:data/audit.log
+		path = sinkURL.Opaque
+	}
+	if path == "" {
+		return nil, errors.New("missing path")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return &fileAuditLogSink{file: file}, nil
+}
+
+func (s *fileAuditLogSink) Write(_ context.Context, record auditLogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+
+	_, err = s.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
+
+func (s *fileAuditLogSink) Close() error {
+	return s.file.Close()
+}
+
+// httpAuditLogSink posts every audit log as JSON to an endpoint
+type httpAuditLogSink struct {
+	httpClient *http.Client
+	url        string
+	username   string
+	password   string
+}
+
+func newHttpAuditLogSink(sinkURL *url.URL, httpClient *http.Client) *httpAuditLogSink {
+	s := &httpAuditLogSink{httpClient: httpClient}
+	if sinkURL.User != nil {
+		s.username = sinkURL.User.Username()
+		s.password, _ = sinkURL.User.Password()
+	}
+
+	endpoint := *sinkURL
+	endpoint.User = nil
+	s.url = endpoint.String()
+
+	return s
+}
+
+func (s *httpAuditLogSink) Write(ctx context.Context, record auditLogRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.username != "" || s.password != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpAuditLogSink) Close() error {
+	return nil
+}
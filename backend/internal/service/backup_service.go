@@ -0,0 +1,678 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+const (
+	backupManifestName = "manifest.json"
+	backupDatabaseDir  = "database"
+	backupKeysDir      = "keys"
+	backupUploadsDir   = "uploads"
+)
+
+// BackupManifest describes the contents of a backup
+type BackupManifest struct {
+	// Version is the version of Pocket ID that created the backup
+	Version    string    `json:"version"`
+	CreatedAt  time.Time `json:"createdAt"`
+	DbProvider string    `json:"dbProvider"`
+	// SchemaVersion is the version of the last migration that was applied to the database
+	SchemaVersion uint `json:"schemaVersion"`
+	// Tables are ordered so that the tables referenced by foreign keys come first
+	Tables []string `json:"tables"`
+}
+
+// BackupService creates and restores backups of the whole instance, which consist of the contents of the database,
+// including the app config, the signing keys and the uploaded files
+type BackupService struct {
+	db *gorm.DB
+}
+
+func NewBackupService(db *gorm.DB) *BackupService {
+	return &BackupService{db: db}
+}
+
+// CreateBackup writes a backup as a gzip-compressed tar archive to w. If a passphrase is set, the archive is encrypted.
+// The database is read in a single transaction, so that the backup is consistent even if the instance is running.
+func (s *BackupService) CreateBackup(ctx context.Context, w io.Writer, schemaVersion uint, passphrase string) error {
+	out := w
+	var encryptingWriter io.WriteCloser
+	if passphrase != "" {
+		var err error
+		encryptingWriter, err = utils.NewEncryptingWriter(w, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		out = encryptingWriter
+	}
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	// The tables are dumped to temporary files first, because the size of a file in a tar archive must be known upfront
+	tmpDir, err := os.MkdirTemp("", "pocket-id-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var tx *gorm.DB
+	switch common.EnvConfig.DbProvider {
+	case common.DbProviderPostgres, common.DbProviderMysql:
+		tx = s.db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	default:
+		// With SQLite, a transaction blocks the entire database
+		tx = s.db.WithContext(ctx).Begin()
+	}
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	tables, err := listDatabaseTables(ctx, tx, common.EnvConfig.DbProvider)
+	if err != nil {
+		return err
+	}
+
+	manifest := BackupManifest{
+		Version:       common.Version,
+		CreatedAt:     time.Now().UTC(),
+		DbProvider:    string(common.EnvConfig.DbProvider),
+		SchemaVersion: schemaVersion,
+		Tables:        tables,
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	err = writeBackupFile(tarWriter, backupManifestName, 0600, int64(len(manifestJSON)), strings.NewReader(string(manifestJSON)))
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		err = s.backupTable(ctx, tx, tarWriter, tmpDir, table)
+		if err != nil {
+			return fmt.Errorf("failed to back up table '%s': %w", table, err)
+		}
+	}
+
+	// The files are copied while the transaction is still open, so that they match the database as closely as possible
+	err = backupDirectory(tarWriter, backupKeysDir, common.EnvConfig.KeysPath)
+	if err != nil {
+		return fmt.Errorf("failed to back up keys: %w", err)
+	}
+	err = backupDirectory(tarWriter, backupUploadsDir, common.EnvConfig.UploadPath)
+	if err != nil {
+		return fmt.Errorf("failed to back up uploads: %w", err)
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if encryptingWriter != nil {
+		err = encryptingWriter.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// backupTable writes the rows of the table as JSON Lines to the archive
+func (s *BackupService) backupTable(ctx context.Context, tx *gorm.DB, tarWriter *tar.Writer, tmpDir string, table string) error {
+	tmpFile, err := os.Create(filepath.Join(tmpDir, table+".jsonl"))
+	if err != nil {
+		return err
+	}
+	defer tmpFile.Close()
+
+	rows, err := tx.
+		WithContext(ctx).
+		Raw("SELECT * FROM " + quoteIdentifier(table)).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	bufWriter := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(bufWriter)
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		err = rows.Scan(valuePtrs...)
+		if err != nil {
+			return err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column], err = encodeBackupValue(values[i])
+			if err != nil {
+				return fmt.Errorf("column '%s': %w", column, err)
+			}
+		}
+		err = encoder.Encode(row)
+		if err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	err = bufWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	info, err := tmpFile.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = tmpFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return writeBackupFile(tarWriter, path.Join(backupDatabaseDir, table+".jsonl"), 0600, info.Size(), tmpFile)
+}
+
+// backupDirectory adds the regular files of the directory to the archive, it does nothing if the directory doesn't exist
+func backupDirectory(tarWriter *tar.Writer, archiveDir string, dir string) error {
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && filePath == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return writeBackupFile(tarWriter, path.Join(archiveDir, filepath.ToSlash(relPath)), int64(info.Mode().Perm()), info.Size(), file)
+	})
+	if errors.Is(err, filepath.SkipDir) {
+		return nil
+	}
+	return err
+}
+
+func writeBackupFile(tarWriter *tar.Writer, name string, mode int64, size int64, r io.Reader) error {
+	err := tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write '%s' to backup: %w", name, err)
+	}
+
+	_, err = io.CopyN(tarWriter, r, size)
+	if err != nil {
+		return fmt.Errorf("failed to write '%s' to backup: %w", name, err)
+	}
+	return nil
+}
+
+// BackupArchive is an opened backup, whose contents are read sequentially
+type BackupArchive struct {
+	Manifest  BackupManifest
+	tarReader *tar.Reader
+}
+
+// OpenBackup opens a backup and reads its manifest. The passphrase is required if the backup is encrypted.
+func (s *BackupService) OpenBackup(r io.Reader, passphrase string) (*BackupArchive, error) {
+	bufReader := bufio.NewReader(r)
+	var reader io.Reader = bufReader
+	if header, _ := bufReader.Peek(utils.EncryptionHeaderSize); utils.IsEncrypted(header) {
+		if passphrase == "" {
+			return nil, errors.New("the backup is encrypted, but no passphrase was provided")
+		}
+		var err error
+		reader, err = utils.NewDecryptingReader(bufReader, passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		if errors.Is(err, utils.ErrWrongPassphrase) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("the file isn't a Pocket ID backup: %w", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	header, err := tarReader.Next()
+	if err != nil || header.Name != backupManifestName {
+		return nil, errors.New("the file isn't a Pocket ID backup: the manifest is missing")
+	}
+	var manifest BackupManifest
+	err = json.NewDecoder(tarReader).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest of the backup: %w", err)
+	}
+
+	if manifest.DbProvider != string(common.EnvConfig.DbProvider) {
+		return nil, fmt.Errorf("the backup was created with the '%s' database provider, but '%s' is configured", manifest.DbProvider, common.EnvConfig.DbProvider)
+	}
+
+	return &BackupArchive{Manifest: manifest, tarReader: tarReader}, nil
+}
+
+// IsDatabaseEmpty checks whether the database contains no rows, as backups are only restored into empty instances
+func (s *BackupService) IsDatabaseEmpty(ctx context.Context) (bool, error) {
+	return isDatabaseEmpty(ctx, s.db, common.EnvConfig.DbProvider)
+}
+
+// seededDatabaseTables contain rows that are created by the migrations. They are ignored when checking whether the
+// database is empty and their rows are replaced when a backup is restored or a database is transferred.
+var seededDatabaseTables = []string{"audit_log_chain_heads"}
+
+func isDatabaseEmpty(ctx context.Context, db *gorm.DB, provider common.DbProvider) (bool, error) {
+	tables, err := listDatabaseTables(ctx, db, provider)
+	if err != nil {
+		return false, err
+	}
+
+	for _, table := range tables {
+		if slices.Contains(seededDatabaseTables, table) {
+			continue
+		}
+
+		var count int64
+		err = db.
+			WithContext(ctx).
+			Table(table).
+			Count(&count).
+			Error
+		if err != nil {
+			return false, fmt.Errorf("failed to count rows of table '%s': %w", table, err)
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// RestoreBackup restores the contents of a backup. The database must be empty and migrated to the schema version of
+// the backup. The rows are inserted in a single transaction and the files are written once it's committed.
+func (s *BackupService) RestoreBackup(ctx context.Context, archive *BackupArchive) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// Rows of a table may reference rows of the same table that come later in the backup
+	if common.EnvConfig.DbProvider == common.DbProviderSqlite {
+		err := tx.WithContext(ctx).Exec("PRAGMA defer_foreign_keys = ON").Error
+		if err != nil {
+			return fmt.Errorf("failed to defer foreign keys: %w", err)
+		}
+	}
+
+	committed := false
+	for {
+		header, err := archive.tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		dir, name, _ := strings.Cut(header.Name, "/")
+		switch dir {
+		case backupDatabaseDir:
+			table := strings.TrimSuffix(name, ".jsonl")
+			if committed || !slices.Contains(archive.Manifest.Tables, table) {
+				return fmt.Errorf("unexpected file '%s' in backup", header.Name)
+			}
+			err = restoreTable(ctx, tx, table, archive.tarReader)
+			if err != nil {
+				return fmt.Errorf("failed to restore table '%s': %w", table, err)
+			}
+		case backupKeysDir, backupUploadsDir:
+			// The files come after the database, so the database is restored completely at this point
+			if !committed {
+				err = tx.Commit().Error
+				if err != nil {
+					return fmt.Errorf("failed to restore database: %w", err)
+				}
+				committed = true
+			}
+
+			targetDir := common.EnvConfig.KeysPath
+			if dir == backupUploadsDir {
+				targetDir = common.EnvConfig.UploadPath
+			}
+			err = restoreFile(targetDir, name, header, archive.tarReader)
+			if err != nil {
+				return fmt.Errorf("failed to restore '%s': %w", header.Name, err)
+			}
+		default:
+			return fmt.Errorf("unexpected file '%s' in backup", header.Name)
+		}
+	}
+
+	if !committed {
+		err := tx.Commit().Error
+		if err != nil {
+			return fmt.Errorf("failed to restore database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func restoreTable(ctx context.Context, tx *gorm.DB, table string, r io.Reader) error {
+	err := clearSeededDatabaseTable(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.UseNumber()
+		var row map[string]any
+		err := decoder.Decode(&row)
+		if err != nil {
+			return fmt.Errorf("invalid row on line %d: %w", line, err)
+		}
+
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		quotedColumns := make([]string, len(columns))
+		placeholders := make([]string, len(columns))
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			quotedColumns[i] = quoteIdentifier(column)
+			placeholders[i] = "?"
+			values[i], err = decodeBackupValue(row[column])
+			if err != nil {
+				return fmt.Errorf("invalid value of column '%s' on line %d: %w", column, line, err)
+			}
+		}
+
+		err = tx.
+			WithContext(ctx).
+			Exec("INSERT INTO "+quoteIdentifier(table)+" ("+strings.Join(quotedColumns, ", ")+") VALUES ("+strings.Join(placeholders, ", ")+")", values...).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to insert row on line %d: %w", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// clearSeededDatabaseTable deletes the rows that the migrations created in the table, before the rows of the backup or
+// the source database are inserted
+func clearSeededDatabaseTable(ctx context.Context, tx *gorm.DB, table string) error {
+	if !slices.Contains(seededDatabaseTables, table) {
+		return nil
+	}
+
+	err := tx.
+		WithContext(ctx).
+		Exec("DELETE FROM " + quoteIdentifier(table)).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to clear table: %w", err)
+	}
+	return nil
+}
+
+func restoreFile(targetDir string, name string, header *tar.Header, r io.Reader) error {
+	// Prevent the archive from writing files outside of the target directory
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return errors.New("invalid path")
+	}
+	targetPath := filepath.Join(targetDir, name)
+
+	err := os.MkdirAll(filepath.Dir(targetPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.FileMode(header.Mode).Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// listDatabaseTables returns the tables of the database, ordered so that the tables referenced by foreign keys come
+// before the tables that reference them
+func listDatabaseTables(ctx context.Context, tx *gorm.DB, provider common.DbProvider) ([]string, error) {
+	var tables []string
+	type foreignKey struct {
+		TableName       string
+		ReferencedTable string
+	}
+	var foreignKeys []foreignKey
+
+	switch provider {
+	case common.DbProviderSqlite:
+		err := tx.
+			WithContext(ctx).
+			Raw("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' ORDER BY name").
+			Scan(&tables).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		err = tx.
+			WithContext(ctx).
+			Raw(`SELECT DISTINCT m.name AS table_name, f."table" AS referenced_table FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f WHERE m.type = 'table'`).
+			Scan(&foreignKeys).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+		}
+	case common.DbProviderPostgres:
+		err := tx.
+			WithContext(ctx).
+			Raw("SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename != 'schema_migrations' ORDER BY tablename").
+			Scan(&tables).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		err = tx.
+			WithContext(ctx).
+			Raw(`
+				SELECT DISTINCT tc.table_name AS table_name, ccu.table_name AS referenced_table
+				FROM information_schema.table_constraints tc
+				JOIN information_schema.constraint_column_usage ccu
+					ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+				WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = 'public'
+			`).
+			Scan(&foreignKeys).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+		}
+	case common.DbProviderMysql:
+		err := tx.
+			WithContext(ctx).
+			Raw("SELECT table_name AS table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' AND table_name != 'schema_migrations' ORDER BY table_name").
+			Scan(&tables).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		err = tx.
+			WithContext(ctx).
+			Raw("SELECT DISTINCT table_name AS table_name, referenced_table_name AS referenced_table FROM information_schema.key_column_usage WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL").
+			Scan(&foreignKeys).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database provider: %s", provider)
+	}
+
+	// Sort the tables topologically, tables without dependencies between them stay in alphabetical order
+	dependencies := make(map[string]map[string]bool, len(tables))
+	for _, fk := range foreignKeys {
+		if fk.TableName == fk.ReferencedTable || !slices.Contains(tables, fk.ReferencedTable) {
+			continue
+		}
+		if dependencies[fk.TableName] == nil {
+			dependencies[fk.TableName] = make(map[string]bool)
+		}
+		dependencies[fk.TableName][fk.ReferencedTable] = true
+	}
+
+	ordered := make([]string, 0, len(tables))
+	added := make(map[string]bool, len(tables))
+	for len(ordered) < len(tables) {
+		progress := false
+		for _, table := range tables {
+			if added[table] {
+				continue
+			}
+			ready := true
+			for dependency := range dependencies[table] {
+				if !added[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, table)
+				added[table] = true
+				progress = true
+			}
+		}
+
+		// Cyclic foreign keys can't be ordered, so the remaining tables are added as they are
+		if !progress {
+			for _, table := range tables {
+				if !added[table] {
+					ordered = append(ordered, table)
+					added[table] = true
+				}
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+// encodeBackupValue converts a value of the database driver, so that its type survives the JSON encoding
+func encodeBackupValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, int64, float64, string:
+		return v, nil
+	case []byte:
+		return map[string]string{"bytes": base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return map[string]string{"time": v.Format(time.RFC3339Nano)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func decodeBackupValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]any:
+		if encoded, ok := v["bytes"].(string); ok {
+			return base64.StdEncoding.DecodeString(encoded)
+		}
+		if encoded, ok := v["time"].(string); ok {
+			return time.Parse(time.RFC3339Nano, encoded)
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v", value)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+func TestBackupService(t *testing.T) {
+	originalKeysPath := common.EnvConfig.KeysPath
+	originalUploadPath := common.EnvConfig.UploadPath
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+		common.EnvConfig.UploadPath = originalUploadPath
+	})
+
+	sourceKeysPath := t.TempDir()
+	sourceUploadPath := t.TempDir()
+	common.EnvConfig.KeysPath = sourceKeysPath
+	common.EnvConfig.UploadPath = sourceUploadPath
+	require.NoError(t, os.WriteFile(filepath.Join(sourceKeysPath, "jwt_private_key.json"), []byte(`{"kid":"test"}`), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceUploadPath, "profile-pictures"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceUploadPath, "profile-pictures", "tim.png"), []byte("png"), 0600))
+
+	sourceDB := newDatabaseForTest(t)
+	user := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+	require.NoError(t, sourceDB.Create(&user).Error)
+	group := model.UserGroup{Name: "developers", FriendlyName: "Developers", Users: []model.User{user}}
+	require.NoError(t, sourceDB.Create(&group).Error)
+	auditLog := model.AuditLog{Event: model.AuditLogEventSignIn, UserID: user.ID, Data: model.AuditLogData{"device": "laptop"}}
+	require.NoError(t, sourceDB.Create(&auditLog).Error)
+	expiresAt := datatype.DateTime(time.Now().Add(time.Hour).Truncate(time.Second))
+	require.NoError(t, sourceDB.Create(&model.OneTimeAccessToken{Token: "token", ExpiresAt: expiresAt, UserID: user.ID}).Error)
+
+	backupService := NewBackupService(sourceDB)
+
+	for _, passphrase := range []string{"", "correct horse battery staple"} {
+		name := "unencrypted"
+		if passphrase != "" {
+			name = "encrypted"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			common.EnvConfig.KeysPath = sourceKeysPath
+			common.EnvConfig.UploadPath = sourceUploadPath
+
+			var buf bytes.Buffer
+			require.NoError(t, backupService.CreateBackup(t.Context(), &buf, 42, passphrase))
+			assert.Equal(t, passphrase != "", utils.IsEncrypted(buf.Bytes()))
+
+			if passphrase != "" {
+				_, err := backupService.OpenBackup(bytes.NewReader(buf.Bytes()), "")
+				require.Error(t, err)
+				_, err = backupService.OpenBackup(bytes.NewReader(buf.Bytes()), "wrong")
+				require.ErrorIs(t, err, utils.ErrWrongPassphrase)
+			}
+
+			// Restore into a new, empty instance
+			targetDB := newDatabaseForTest(t)
+			common.EnvConfig.KeysPath = t.TempDir()
+			common.EnvConfig.UploadPath = t.TempDir()
+			targetBackupService := NewBackupService(targetDB)
+
+			empty, err := targetBackupService.IsDatabaseEmpty(t.Context())
+			require.NoError(t, err)
+			require.True(t, empty)
+
+			archive, err := targetBackupService.OpenBackup(bytes.NewReader(buf.Bytes()), passphrase)
+			require.NoError(t, err)
+			assert.EqualValues(t, 42, archive.Manifest.SchemaVersion)
+			assert.Less(t, slices.Index(archive.Manifest.Tables, "users"), slices.Index(archive.Manifest.Tables, "user_groups_users"), "referenced tables come first")
+			require.NoError(t, targetBackupService.RestoreBackup(t.Context(), archive))
+
+			var restoredUser model.User
+			require.NoError(t, targetDB.Preload("UserGroups").First(&restoredUser, "id = ?", user.ID).Error)
+			assert.Equal(t, user.Username, restoredUser.Username)
+			assert.Equal(t, user.CreatedAt.ToTime().Unix(), restoredUser.CreatedAt.ToTime().Unix())
+			require.Len(t, restoredUser.UserGroups, 1)
+			assert.Equal(t, group.ID, restoredUser.UserGroups[0].ID)
+
+			var restoredAuditLog model.AuditLog
+			require.NoError(t, targetDB.First(&restoredAuditLog, "id = ?", auditLog.ID).Error)
+			assert.Equal(t, auditLog.Data, restoredAuditLog.Data)
+
+			var restoredToken model.OneTimeAccessToken
+			require.NoError(t, targetDB.First(&restoredToken, "token = ?", "token").Error)
+			assert.Equal(t, expiresAt.ToTime().Unix(), restoredToken.ExpiresAt.ToTime().Unix())
+
+			key, err := os.ReadFile(filepath.Join(common.EnvConfig.KeysPath, "jwt_private_key.json"))
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"kid":"test"}`, string(key))
+			picture, err := os.ReadFile(filepath.Join(common.EnvConfig.UploadPath, "profile-pictures", "tim.png"))
+			require.NoError(t, err)
+			assert.Equal(t, "png", string(picture))
+
+			empty, err = targetBackupService.IsDatabaseEmpty(t.Context())
+			require.NoError(t, err)
+			assert.False(t, empty)
+		})
+	}
+}
@@ -10,11 +10,12 @@ import (
 )
 
 type CustomClaimService struct {
-	db *gorm.DB
+	db              *gorm.DB
+	auditLogService *AuditLogService
 }
 
-func NewCustomClaimService(db *gorm.DB) *CustomClaimService {
-	return &CustomClaimService{db: db}
+func NewCustomClaimService(db *gorm.DB, auditLogService *AuditLogService) *CustomClaimService {
+	return &CustomClaimService{db: db, auditLogService: auditLogService}
 }
 
 // isReservedClaim checks if a claim key is reserved e.g. email, preferred_username
@@ -79,6 +80,24 @@ func (s *CustomClaimService) updateCustomClaims(ctx context.Context, idType idTy
 		tx.Rollback()
 	}()
 
+	// The claims of groups that are managed by the declarative configuration file are part of the configuration
+	if idType == UserGroupID {
+		var configManaged bool
+		err := tx.
+			WithContext(ctx).
+			Model(&model.UserGroup{}).
+			Select("config_managed").
+			Where("id = ?", value).
+			Scan(&configManaged).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		if configManaged {
+			return nil, &common.ConfigManagedError{}
+		}
+	}
+
 	var existingClaims []model.CustomClaim
 	err := tx.
 		WithContext(ctx).
@@ -130,7 +149,7 @@ func (s *CustomClaimService) updateCustomClaims(ctx context.Context, idType idTy
 		// Update the claim if it already exists or create a new one
 		err = tx.
 			WithContext(ctx).
-			Where(string(idType)+" = ? AND key = ?", value, claim.Key).
+			Where(string(idType)+` = ? AND "key" = ?`, value, claim.Key).
 			Assign(&customClaim).
 			FirstOrCreate(&model.CustomClaim{}).
 			Error
@@ -150,6 +169,12 @@ func (s *CustomClaimService) updateCustomClaims(ctx context.Context, idType idTy
 		return nil, err
 	}
 
+	target, err := s.auditTargetInternal(ctx, idType, value, tx)
+	if err != nil {
+		return nil, err
+	}
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventCustomClaimsUpdated, target, customClaimsAuditState(existingClaims), customClaimsAuditState(updatedClaims), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return nil, err
@@ -158,6 +183,37 @@ func (s *CustomClaimService) updateCustomClaims(ctx context.Context, idType idTy
 	return updatedClaims, nil
 }
 
+// auditTargetInternal returns the user or user group that the custom claims belong to
+func (s *CustomClaimService) auditTargetInternal(ctx context.Context, idType idType, value string, tx *gorm.DB) (AuditTarget, error) {
+	target := AuditTarget{ID: value}
+
+	var query *gorm.DB
+	switch idType {
+	case UserID:
+		target.Type = model.AuditLogTargetUser
+		query = tx.Model(&model.User{}).Select("username")
+	case UserGroupID:
+		target.Type = model.AuditLogTargetUserGroup
+		query = tx.Model(&model.UserGroup{}).Select("name")
+	}
+
+	err := query.
+		WithContext(ctx).
+		Where("id = ?", value).
+		First(&target.Name).
+		Error
+	return target, err
+}
+
+// customClaimsAuditState returns the values of the claims by their keys to compare them in the audit log
+func customClaimsAuditState(claims []model.CustomClaim) map[string]string {
+	state := make(map[string]string, len(claims))
+	for _, claim := range claims {
+		state[claim.Key] = claim.Value
+	}
+	return state
+}
+
 func (s *CustomClaimService) GetCustomClaimsForUser(ctx context.Context, userID string, tx *gorm.DB) ([]model.CustomClaim, error) {
 	var customClaims []model.CustomClaim
 	err := tx.
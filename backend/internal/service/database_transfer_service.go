@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+)
+
+const databaseTransferBatchSize = 100
+
+// DatabaseTransferService copies all data from one database into another one, which may use a different database
+// provider. Both databases must be migrated to the same schema version.
+type DatabaseTransferService struct {
+	source         *gorm.DB
+	sourceProvider common.DbProvider
+	target         *gorm.DB
+	targetProvider common.DbProvider
+}
+
+func NewDatabaseTransferService(source *gorm.DB, sourceProvider common.DbProvider, target *gorm.DB, targetProvider common.DbProvider) *DatabaseTransferService {
+	return &DatabaseTransferService{
+		source:         source,
+		sourceProvider: sourceProvider,
+		target:         target,
+		targetProvider: targetProvider,
+	}
+}
+
+// DatabaseTransferTable is the result of the transfer of a table
+type DatabaseTransferTable struct {
+	Name string
+	Rows int64
+}
+
+// databaseColumnKind is the kind of value a column stores, independently of the database provider
+type databaseColumnKind int
+
+const (
+	databaseColumnKindOther databaseColumnKind = iota
+	databaseColumnKindText
+	databaseColumnKindInteger
+	databaseColumnKindBool
+	databaseColumnKindTime
+	// databaseColumnKindUnixTime is a time that is stored as unix timestamp, like datatype.DateTime does with SQLite
+	databaseColumnKindUnixTime
+	databaseColumnKindJSON
+	databaseColumnKindBytes
+)
+
+// Transfer copies the rows of all tables into the target database, which must be empty.
+// The source database is read in a single transaction and the rows are written in a single transaction, so that
+// the target either contains a consistent copy of the source or nothing. The row counts are verified afterwards.
+func (s *DatabaseTransferService) Transfer(ctx context.Context) ([]DatabaseTransferTable, error) {
+	empty, err := isDatabaseEmpty(ctx, s.target, s.targetProvider)
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, errors.New("the target database isn't empty")
+	}
+
+	var sourceTx *gorm.DB
+	switch s.sourceProvider {
+	case common.DbProviderPostgres, common.DbProviderMysql:
+		sourceTx = s.source.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	default:
+		sourceTx = s.source.WithContext(ctx).Begin()
+	}
+	if sourceTx.Error != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", sourceTx.Error)
+	}
+	defer func() {
+		sourceTx.Rollback()
+	}()
+
+	tables, err := listDatabaseTables(ctx, sourceTx, s.sourceProvider)
+	if err != nil {
+		return nil, err
+	}
+	targetColumns, err := listDatabaseColumns(ctx, s.target, s.targetProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	targetTx := s.target.WithContext(ctx).Begin()
+	defer func() {
+		targetTx.Rollback()
+	}()
+
+	// Rows of a table may reference rows of the same table that are copied later
+	if s.targetProvider == common.DbProviderSqlite {
+		err = targetTx.Exec("PRAGMA defer_foreign_keys = ON").Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to defer foreign keys: %w", err)
+		}
+	}
+
+	result := make([]DatabaseTransferTable, len(tables))
+	for i, table := range tables {
+		columns, ok := targetColumns[table]
+		if !ok {
+			return nil, fmt.Errorf("the table '%s' doesn't exist in the target database", table)
+		}
+
+		rows, err := transferTable(ctx, sourceTx, targetTx, table, columns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer table '%s': %w", table, err)
+		}
+		result[i] = DatabaseTransferTable{Name: table, Rows: rows}
+	}
+
+	err = targetTx.Commit().Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Compare the row counts of the committed target with the snapshot of the source
+	for _, table := range result {
+		var sourceCount, targetCount int64
+		err = sourceTx.WithContext(ctx).Table(table.Name).Count(&sourceCount).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows of table '%s' in the source database: %w", table.Name, err)
+		}
+		err = s.target.WithContext(ctx).Table(table.Name).Count(&targetCount).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows of table '%s' in the target database: %w", table.Name, err)
+		}
+		if sourceCount != targetCount || sourceCount != table.Rows {
+			return nil, fmt.Errorf("the row counts of table '%s' don't match: %d in the source database, %d in the target database", table.Name, sourceCount, targetCount)
+		}
+	}
+
+	return result, nil
+}
+
+func transferTable(ctx context.Context, sourceTx *gorm.DB, targetTx *gorm.DB, table string, targetColumns map[string]databaseColumnKind) (int64, error) {
+	err := clearSeededDatabaseTable(ctx, targetTx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := sourceTx.
+		WithContext(ctx).
+		Raw("SELECT * FROM " + quoteIdentifier(table)).
+		Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	kinds := make([]databaseColumnKind, len(columns))
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		kind, ok := targetColumns[column]
+		if !ok {
+			return 0, fmt.Errorf("the column '%s' doesn't exist in the target database", column)
+		}
+		kinds[i] = kind
+		quotedColumns[i] = quoteIdentifier(column)
+	}
+	rowPlaceholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	var transferred int64
+	batch := make([]any, 0, databaseTransferBatchSize*len(columns))
+	batchRows := 0
+	insertBatch := func() error {
+		if batchRows == 0 {
+			return nil
+		}
+		err := targetTx.
+			WithContext(ctx).
+			Exec("INSERT INTO "+quoteIdentifier(table)+" ("+strings.Join(quotedColumns, ", ")+") VALUES "+strings.TrimSuffix(strings.Repeat(rowPlaceholders+", ", batchRows), ", "), batch...).
+			Error
+		if err != nil {
+			return err
+		}
+		transferred += int64(batchRows)
+		batch = batch[:0]
+		batchRows = 0
+		return nil
+	}
+
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		err = rows.Scan(valuePtrs...)
+		if err != nil {
+			return 0, err
+		}
+
+		for i, value := range values {
+			converted, err := convertDatabaseValue(value, kinds[i])
+			if err != nil {
+				return 0, fmt.Errorf("column '%s': %w", columns[i], err)
+			}
+			batch = append(batch, converted)
+		}
+		batchRows++
+
+		if batchRows == databaseTransferBatchSize {
+			err = insertBatch()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	err = insertBatch()
+	if err != nil {
+		return 0, err
+	}
+	return transferred, nil
+}
+
+// listDatabaseColumns returns the kinds of the columns of all tables, keyed by table and column name
+func listDatabaseColumns(ctx context.Context, db *gorm.DB, provider common.DbProvider) (map[string]map[string]databaseColumnKind, error) {
+	type column struct {
+		TableName  string
+		ColumnName string
+		DataType   string
+	}
+	var columns []column
+
+	switch provider {
+	case common.DbProviderSqlite:
+		err := db.
+			WithContext(ctx).
+			Raw(`SELECT m.name AS table_name, p.name AS column_name, p.type AS data_type FROM sqlite_master m JOIN pragma_table_info(m.name) p WHERE m.type = 'table'`).
+			Scan(&columns).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns: %w", err)
+		}
+	case common.DbProviderPostgres:
+		err := db.
+			WithContext(ctx).
+			Raw("SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = 'public'").
+			Scan(&columns).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns: %w", err)
+		}
+	case common.DbProviderMysql:
+		err := db.
+			WithContext(ctx).
+			Raw("SELECT table_name AS table_name, column_name AS column_name, data_type AS data_type FROM information_schema.columns WHERE table_schema = DATABASE()").
+			Scan(&columns).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database provider: %s", provider)
+	}
+
+	result := make(map[string]map[string]databaseColumnKind)
+	for _, c := range columns {
+		if result[c.TableName] == nil {
+			result[c.TableName] = make(map[string]databaseColumnKind)
+		}
+		result[c.TableName][c.ColumnName] = databaseColumnKindFromType(provider, c.DataType)
+	}
+	return result, nil
+}
+
+func databaseColumnKindFromType(provider common.DbProvider, dataType string) databaseColumnKind {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case dataType == "boolean", dataType == "numeric", dataType == "tinyint":
+		// SQLite and MySQL have no boolean type, all NUMERIC and TINYINT columns of the schema are booleans
+		return databaseColumnKindBool
+	case dataType == "datetime" && provider == common.DbProviderSqlite:
+		return databaseColumnKindUnixTime
+	case dataType == "datetime", strings.HasPrefix(dataType, "timestamp"):
+		return databaseColumnKindTime
+	case dataType == "integer", dataType == "int", dataType == "bigint":
+		return databaseColumnKindInteger
+	case dataType == "jsonb", dataType == "json":
+		return databaseColumnKindJSON
+	case dataType == "blob", dataType == "bytea", dataType == "varbinary":
+		return databaseColumnKindBytes
+	case strings.HasSuffix(dataType, "text"), dataType == "uuid", dataType == "inet", strings.HasPrefix(dataType, "varchar"), strings.HasPrefix(dataType, "char"):
+		return databaseColumnKindText
+	default:
+		return databaseColumnKindOther
+	}
+}
+
+// convertDatabaseValue converts a value read from the source database to the representation of the target database,
+// as SQLite stores times as unix timestamps and booleans as integers, Postgres returns JSON as bytes and MySQL returns
+// most values as bytes
+func convertDatabaseValue(value any, kind databaseColumnKind) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case databaseColumnKindText, databaseColumnKindJSON:
+		if b, ok := value.([]byte); ok {
+			return string(b), nil
+		}
+	case databaseColumnKindBytes:
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+	case databaseColumnKindInteger:
+		switch v := value.(type) {
+		case []byte:
+			return strconv.ParseInt(string(v), 10, 64)
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case databaseColumnKindBool:
+		switch v := value.(type) {
+		case int64:
+			return v != 0, nil
+		case []byte:
+			return strconv.ParseBool(string(v))
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case databaseColumnKindTime, databaseColumnKindUnixTime:
+		var t time.Time
+		switch v := value.(type) {
+		case time.Time:
+			t = v
+		case int64:
+			t = time.Unix(v, 0)
+		case string:
+			var err error
+			t, err = parseDatabaseTime(v)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected type for a time: %T", value)
+		}
+
+		if kind == databaseColumnKindUnixTime {
+			return t.Unix(), nil
+		}
+		return t.UTC(), nil
+	}
+
+	return value, nil
+}
+
+// parseDatabaseTime parses times that SQLite stores as text, e.g. the ones set by CURRENT_TIMESTAMP
+func parseDatabaseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
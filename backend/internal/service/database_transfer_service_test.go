@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+func TestDatabaseTransferService_Transfer(t *testing.T) {
+	source := newDatabaseForTest(t)
+	user := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim", IsAdmin: true}
+	require.NoError(t, source.Create(&user).Error)
+	group := model.UserGroup{Name: "developers", FriendlyName: "Developers", Users: []model.User{user}}
+	require.NoError(t, source.Create(&group).Error)
+	client := model.OidcClient{
+		Name:              "Wiki",
+		CallbackURLs:      model.UrlList{"https://wiki.example.com/callback"},
+		IsPublic:          true,
+		AllowedUserGroups: []model.UserGroup{group},
+		CreatedByID:       &user.ID,
+	}
+	require.NoError(t, source.Create(&client).Error)
+	auditLog := model.AuditLog{Event: model.AuditLogEventSignIn, UserID: user.ID, Data: model.AuditLogData{"device": "laptop"}}
+	require.NoError(t, source.Create(&auditLog).Error)
+
+	t.Run("copies all rows", func(t *testing.T) {
+		target := newDatabaseForTest(t)
+
+		transferService := NewDatabaseTransferService(source, common.EnvConfig.DbProvider, target, common.EnvConfig.DbProvider)
+		tables, err := transferService.Transfer(t.Context())
+		require.NoError(t, err)
+
+		rows := make(map[string]int64, len(tables))
+		for _, table := range tables {
+			rows[table.Name] = table.Rows
+		}
+		assert.EqualValues(t, 1, rows["users"])
+		assert.EqualValues(t, 1, rows["user_groups_users"])
+		assert.EqualValues(t, 1, rows["oidc_clients_allowed_user_groups"])
+
+		var transferredUser model.User
+		require.NoError(t, target.Preload("UserGroups").First(&transferredUser, "id = ?", user.ID).Error)
+		assert.True(t, transferredUser.IsAdmin)
+		assert.Equal(t, user.CreatedAt.ToTime().Unix(), transferredUser.CreatedAt.ToTime().Unix())
+		require.Len(t, transferredUser.UserGroups, 1)
+
+		var transferredClient model.OidcClient
+		require.NoError(t, target.First(&transferredClient, "id = ?", client.ID).Error)
+		assert.Equal(t, client.CallbackURLs, transferredClient.CallbackURLs)
+		assert.True(t, transferredClient.IsPublic)
+
+		var transferredAuditLog model.AuditLog
+		require.NoError(t, target.First(&transferredAuditLog, "id = ?", auditLog.ID).Error)
+		assert.Equal(t, auditLog.Data, transferredAuditLog.Data)
+
+		// The target must be empty
+		_, err = transferService.Transfer(t.Context())
+		require.EqualError(t, err, "the target database isn't empty")
+	})
+}
+
+func TestConvertDatabaseValue(t *testing.T) {
+	createdAt := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    any
+		kind     databaseColumnKind
+		expected any
+	}{
+		{name: "SQLite time to Postgres", value: createdAt.Unix(), kind: databaseColumnKindTime, expected: createdAt},
+		{name: "Postgres time to SQLite", value: createdAt.In(time.FixedZone("CEST", 2*60*60)), kind: databaseColumnKindUnixTime, expected: createdAt.Unix()},
+		{name: "SQLite text time to Postgres", value: "2025-06-01 12:30:00", kind: databaseColumnKindTime, expected: createdAt},
+		{name: "SQLite boolean to Postgres", value: int64(1), kind: databaseColumnKindBool, expected: true},
+		{name: "Postgres boolean to SQLite", value: false, kind: databaseColumnKindBool, expected: false},
+		{name: "SQLite JSON to Postgres", value: []byte(`["https://example.com"]`), kind: databaseColumnKindJSON, expected: `["https://example.com"]`},
+		{name: "Postgres JSON to SQLite", value: []byte(`{"a":1}`), kind: databaseColumnKindBytes, expected: []byte(`{"a":1}`)},
+		{name: "MySQL boolean to Postgres", value: []byte("1"), kind: databaseColumnKindBool, expected: true},
+		{name: "MySQL integer to Postgres", value: []byte("42"), kind: databaseColumnKindInteger, expected: int64(42)},
+		{name: "Postgres UUID to SQLite", value: "0b1a5e0c-7c5e-4b8a-8d1a-2f4c2e7f1c9d", kind: databaseColumnKindText, expected: "0b1a5e0c-7c5e-4b8a-8d1a-2f4c2e7f1c9d"},
+		{name: "null", value: nil, kind: databaseColumnKindTime, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := convertDatabaseValue(tt.value, tt.kind)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, converted)
+		})
+	}
+}
@@ -0,0 +1,943 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+// DeclarativeConfig is the content of the declarative configuration file, which is YAML or JSON.
+// The resources it describes are created and updated when it's applied, and they can't be changed in the UI.
+type DeclarativeConfig struct {
+	// AppConfig contains the values of the application configuration by their keys
+	AppConfig   map[string]string       `yaml:"appConfig"`
+	UserGroups  []DeclarativeUserGroup  `yaml:"userGroups"`
+	OidcClients []DeclarativeOidcClient `yaml:"oidcClients"`
+}
+
+// DeclarativeUserGroup is a user group that is identified by its name. Its members aren't part of the configuration.
+type DeclarativeUserGroup struct {
+	Name         string            `yaml:"name"`
+	FriendlyName string            `yaml:"friendlyName"`
+	CustomClaims map[string]string `yaml:"customClaims"`
+}
+
+// DeclarativeOidcClient is an OIDC client that is identified by its client ID
+type DeclarativeOidcClient struct {
+	ID                 string   `yaml:"id"`
+	Name               string   `yaml:"name"`
+	CallbackURLs       []string `yaml:"callbackURLs"`
+	LogoutCallbackURLs []string `yaml:"logoutCallbackURLs"`
+	IsPublic           bool     `yaml:"isPublic"`
+	PkceEnabled        bool     `yaml:"pkceEnabled"`
+	// SecretFile is the path of a file that contains the client secret, it's required for confidential clients
+	SecretFile       string   `yaml:"secretFile"`
+	ForwardAuthHosts []string `yaml:"forwardAuthHosts"`
+	// AllowedUserGroups are the names of the groups whose members can use the client, all users can if it's empty
+	AllowedUserGroups []string `yaml:"allowedUserGroups"`
+}
+
+// LoadDeclarativeConfigFile reads and parses the declarative configuration file
+func LoadDeclarativeConfigFile(path string) (DeclarativeConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return DeclarativeConfig{}, fmt.Errorf("failed to open declarative configuration file: %w", err)
+	}
+	defer file.Close()
+
+	return ParseDeclarativeConfig(file)
+}
+
+// ParseDeclarativeConfig parses a declarative configuration, unknown fields are rejected to catch typos
+func ParseDeclarativeConfig(r io.Reader) (DeclarativeConfig, error) {
+	var config DeclarativeConfig
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	err := decoder.Decode(&config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return DeclarativeConfig{}, fmt.Errorf("failed to parse declarative configuration: %w", err)
+	}
+	return config, nil
+}
+
+type DeclarativeConfigAction string
+
+const (
+	DeclarativeConfigActionCreate DeclarativeConfigAction = "create"
+	DeclarativeConfigActionUpdate DeclarativeConfigAction = "update"
+	// DeclarativeConfigActionRelease means that a resource was removed from the configuration. It isn't deleted, but
+	// it can be changed in the UI again.
+	DeclarativeConfigActionRelease DeclarativeConfigAction = "release"
+)
+
+// DeclarativeConfigChange is a change that applying the declarative configuration makes to a resource
+type DeclarativeConfigChange struct {
+	Action DeclarativeConfigAction
+	Target AuditTarget
+	Fields []DeclarativeConfigFieldChange
+}
+
+func (c DeclarativeConfigChange) String() string {
+	if c.Target.ID != "" {
+		return fmt.Sprintf("%s %s '%s' (%s)", c.Action, c.Target.Type, c.Target.Name, c.Target.ID)
+	}
+	return fmt.Sprintf("%s %s '%s'", c.Action, c.Target.Type, c.Target.Name)
+}
+
+// DeclarativeConfigFieldChange is a changed field of a resource. Before is nil if the resource is created.
+type DeclarativeConfigFieldChange struct {
+	Field  string
+	Before any
+	After  any
+}
+
+type DeclarativeConfigService struct {
+	db               *gorm.DB
+	appConfigService *AppConfigService
+	oidcService      *OidcService
+	auditLogService  *AuditLogService
+}
+
+func NewDeclarativeConfigService(db *gorm.DB, appConfigService *AppConfigService, oidcService *OidcService, auditLogService *AuditLogService) *DeclarativeConfigService {
+	return &DeclarativeConfigService{db: db, appConfigService: appConfigService, oidcService: oidcService, auditLogService: auditLogService}
+}
+
+// declarativeConfigPlan contains the changes and the resources that have to be written to apply the configuration
+type declarativeConfigPlan struct {
+	changes []DeclarativeConfigChange
+
+	appConfig             []declarativeAppConfigPlan
+	releasedAppConfigKeys []string
+	userGroups            []declarativeUserGroupPlan
+	releasedUserGroupIDs  []string
+	oidcClients           []declarativeOidcClientPlan
+	releasedOidcClientIDs []string
+}
+
+type declarativeAppConfigPlan struct {
+	key      string
+	before   string
+	after    string
+	existing bool
+}
+
+type declarativeUserGroupPlan struct {
+	// existing is nil if the group is created
+	existing *model.UserGroup
+	desired  DeclarativeUserGroup
+}
+
+type declarativeOidcClientPlan struct {
+	// existing is nil if the client is created
+	existing *model.OidcClient
+	desired  DeclarativeOidcClient
+	// hashedSecret is empty if the secret doesn't change
+	hashedSecret string
+}
+
+// declarativeUserGroupState contains the attributes of a user group that the declarative configuration describes
+type declarativeUserGroupState struct {
+	Name          string            `json:"name"`
+	FriendlyName  string            `json:"friendlyName"`
+	CustomClaims  map[string]string `json:"customClaims"`
+	ConfigManaged bool              `json:"configManaged"`
+}
+
+// declarativeOidcClientState contains the attributes of an OIDC client that the declarative configuration describes
+type declarativeOidcClientState struct {
+	Name               string   `json:"name"`
+	CallbackURLs       []string `json:"callbackURLs"`
+	LogoutCallbackURLs []string `json:"logoutCallbackURLs"`
+	IsPublic           bool     `json:"isPublic"`
+	PkceEnabled        bool     `json:"pkceEnabled"`
+	Secret             string   `json:"secret"`
+	ForwardAuthHosts   []string `json:"forwardAuthHosts"`
+	AllowedUserGroups  []string `json:"allowedUserGroups"`
+	ConfigManaged      bool     `json:"configManaged"`
+}
+
+type declarativeAppConfigState struct {
+	Value         string `json:"value"`
+	ConfigManaged bool   `json:"configManaged"`
+}
+
+// Plan returns the changes that applying the configuration would make, without making them
+func (s *DeclarativeConfigService) Plan(ctx context.Context, config DeclarativeConfig) ([]DeclarativeConfigChange, error) {
+	// We only perform select queries here, so we can rollback in all cases
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	plan, err := s.planInternal(ctx, config, tx)
+	if err != nil {
+		return nil, err
+	}
+	return plan.changes, nil
+}
+
+// Apply creates and updates the resources of the configuration and marks them as managed by it.
+// Resources that were removed from the configuration are released, so that they can be changed in the UI again.
+func (s *DeclarativeConfigService) Apply(ctx context.Context, config DeclarativeConfig) ([]DeclarativeConfigChange, error) {
+	// Lock the configuration, so that multiple instances that start at the same time don't apply it concurrently
+	tx, err := s.appConfigService.updateAppConfigStartTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	plan, err := s.planInternal(ctx, config, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.changes) == 0 {
+		return nil, nil
+	}
+
+	err = s.applyAppConfigInternal(ctx, plan, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply application configuration: %w", err)
+	}
+	for _, groupPlan := range plan.userGroups {
+		err = s.applyUserGroupInternal(ctx, groupPlan, tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply user group '%s': %w", groupPlan.desired.Name, err)
+		}
+	}
+	for _, clientPlan := range plan.oidcClients {
+		err = s.applyOidcClientInternal(ctx, clientPlan, tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply OIDC client '%s': %w", clientPlan.desired.Name, err)
+		}
+	}
+
+	if len(plan.releasedUserGroupIDs) > 0 {
+		err = tx.
+			WithContext(ctx).
+			Model(&model.UserGroup{}).
+			Where("id IN ?", plan.releasedUserGroupIDs).
+			Update("config_managed", false).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to release user groups: %w", err)
+		}
+	}
+	if len(plan.releasedOidcClientIDs) > 0 {
+		err = tx.
+			WithContext(ctx).
+			Model(&model.OidcClient{}).
+			Where("id IN ?", plan.releasedOidcClientIDs).
+			Update("config_managed", false).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to release OIDC clients: %w", err)
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(plan.appConfig) > 0 || len(plan.releasedAppConfigKeys) > 0 {
+		err = s.appConfigService.LoadDbConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan.changes, nil
+}
+
+func (s *DeclarativeConfigService) planInternal(ctx context.Context, config DeclarativeConfig, tx *gorm.DB) (plan declarativeConfigPlan, err error) {
+	err = s.planAppConfigInternal(ctx, config.AppConfig, &plan, tx)
+	if err != nil {
+		return declarativeConfigPlan{}, err
+	}
+
+	declaredGroupNames, err := s.planUserGroupsInternal(ctx, config.UserGroups, &plan, tx)
+	if err != nil {
+		return declarativeConfigPlan{}, err
+	}
+
+	err = s.planOidcClientsInternal(ctx, config.OidcClients, declaredGroupNames, &plan, tx)
+	if err != nil {
+		return declarativeConfigPlan{}, err
+	}
+
+	return plan, nil
+}
+
+func (s *DeclarativeConfigService) planAppConfigInternal(ctx context.Context, desired map[string]string, plan *declarativeConfigPlan, tx *gorm.DB) error {
+	if len(desired) > 0 && common.EnvConfig.UiConfigDisabled {
+		return errors.New("the application configuration can't be declared while UI_CONFIG_DISABLED is set")
+	}
+
+	defaultCfg := s.appConfigService.getDefaultDbConfig()
+	for key := range desired {
+		_, isInternal, err := defaultCfg.FieldByKey(key)
+		if err != nil {
+			return fmt.Errorf("invalid application configuration key '%s'", key)
+		}
+		if isInternal {
+			return fmt.Errorf("the application configuration key '%s' is internal and can't be declared", key)
+		}
+	}
+
+	var variables []model.AppConfigVariable
+	err := tx.
+		WithContext(ctx).
+		Find(&variables).
+		Error
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]model.AppConfigVariable, len(variables))
+	for _, variable := range variables {
+		existing[variable.Key] = variable
+	}
+
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		current, ok := existing[key]
+		if current.Value == desired[key] && current.ConfigManaged {
+			continue
+		}
+
+		before := declarativeAppConfigState{Value: current.Value, ConfigManaged: current.ConfigManaged}
+		after := declarativeAppConfigState{Value: desired[key], ConfigManaged: true}
+		if model.IsSensitiveAppConfigKey(key) {
+			before.Value, after.Value = redactedSecretStates(before.Value, after.Value, before.Value != after.Value)
+		}
+		err = plan.addChange(DeclarativeConfigActionUpdate, AuditTarget{Type: model.AuditLogTargetAppConfig, Name: key}, before, after)
+		if err != nil {
+			return err
+		}
+
+		plan.appConfig = append(plan.appConfig, declarativeAppConfigPlan{key: key, before: current.Value, after: desired[key], existing: ok})
+	}
+
+	for _, variable := range variables {
+		if _, ok := desired[variable.Key]; ok || !variable.ConfigManaged {
+			continue
+		}
+
+		err = plan.addChange(DeclarativeConfigActionRelease, AuditTarget{Type: model.AuditLogTargetAppConfig, Name: variable.Key},
+			declarativeAppConfigState{Value: variable.Value, ConfigManaged: true},
+			declarativeAppConfigState{Value: variable.Value, ConfigManaged: false})
+		if err != nil {
+			return err
+		}
+		plan.releasedAppConfigKeys = append(plan.releasedAppConfigKeys, variable.Key)
+	}
+
+	return nil
+}
+
+// planUserGroupsInternal adds the changes of the user groups to the plan and returns the names of the declared groups
+func (s *DeclarativeConfigService) planUserGroupsInternal(ctx context.Context, desired []DeclarativeUserGroup, plan *declarativeConfigPlan, tx *gorm.DB) (map[string]struct{}, error) {
+	declaredNames := make(map[string]struct{}, len(desired))
+	for _, group := range desired {
+		err := dto.Validate(dto.UserGroupCreateDto{Name: group.Name, FriendlyName: group.FriendlyName})
+		if err != nil {
+			return nil, fmt.Errorf("invalid user group '%s': %w", group.Name, err)
+		}
+		if _, ok := declaredNames[group.Name]; ok {
+			return nil, fmt.Errorf("the user group '%s' is declared more than once", group.Name)
+		}
+		declaredNames[group.Name] = struct{}{}
+
+		for key, value := range group.CustomClaims {
+			if key == "" || value == "" {
+				return nil, fmt.Errorf("the custom claims of the user group '%s' must have a key and a value", group.Name)
+			}
+			if isReservedClaim(key) {
+				return nil, fmt.Errorf("invalid custom claim of the user group '%s': %w", group.Name, &common.ReservedClaimError{Key: key})
+			}
+		}
+	}
+
+	var groups []model.UserGroup
+	err := tx.
+		WithContext(ctx).
+		Preload("CustomClaims").
+		Find(&groups).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*model.UserGroup, len(groups))
+	for i := range groups {
+		existing[groups[i].Name] = &groups[i]
+	}
+
+	for _, desiredGroup := range desired {
+		group := existing[desiredGroup.Name]
+		if group != nil && group.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
+			return nil, fmt.Errorf("the user group '%s' is synced from LDAP and can't be declared", group.Name)
+		}
+		if group != nil && group.ScimExternalID != nil {
+			return nil, fmt.Errorf("the user group '%s' is provisioned over SCIM and can't be declared", group.Name)
+		}
+
+		after := declarativeUserGroupState{
+			Name:          desiredGroup.Name,
+			FriendlyName:  desiredGroup.FriendlyName,
+			CustomClaims:  nonNilMap(desiredGroup.CustomClaims),
+			ConfigManaged: true,
+		}
+
+		action := DeclarativeConfigActionCreate
+		var before any
+		if group != nil {
+			beforeState := newDeclarativeUserGroupState(*group)
+			if reflect.DeepEqual(beforeState, after) {
+				continue
+			}
+			action = DeclarativeConfigActionUpdate
+			before = beforeState
+		}
+
+		err = plan.addChange(action, AuditTarget{Type: model.AuditLogTargetUserGroup, Name: desiredGroup.Name}, before, after)
+		if err != nil {
+			return nil, err
+		}
+		plan.userGroups = append(plan.userGroups, declarativeUserGroupPlan{existing: group, desired: desiredGroup})
+	}
+
+	for _, group := range groups {
+		if _, ok := declaredNames[group.Name]; ok || !group.ConfigManaged {
+			continue
+		}
+
+		before := newDeclarativeUserGroupState(group)
+		after := before
+		after.ConfigManaged = false
+		err = plan.addChange(DeclarativeConfigActionRelease, AuditTarget{Type: model.AuditLogTargetUserGroup, ID: group.ID, Name: group.Name}, before, after)
+		if err != nil {
+			return nil, err
+		}
+		plan.releasedUserGroupIDs = append(plan.releasedUserGroupIDs, group.ID)
+	}
+
+	return declaredNames, nil
+}
+
+func (s *DeclarativeConfigService) planOidcClientsInternal(ctx context.Context, desired []DeclarativeOidcClient, declaredGroupNames map[string]struct{}, plan *declarativeConfigPlan, tx *gorm.DB) error {
+	var clients []model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		Find(&clients).
+		Error
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*model.OidcClient, len(clients))
+	for i := range clients {
+		existing[clients[i].ID] = &clients[i]
+	}
+
+	var existingGroupNames []string
+	err = tx.
+		WithContext(ctx).
+		Model(&model.UserGroup{}).
+		Pluck("name", &existingGroupNames).
+		Error
+	if err != nil {
+		return err
+	}
+
+	declaredIDs := make(map[string]struct{}, len(desired))
+	forwardAuthHosts := make(map[string]string)
+	for _, desiredClient := range desired {
+		err = validateDeclarativeOidcClient(desiredClient)
+		if err != nil {
+			return fmt.Errorf("invalid OIDC client '%s': %w", desiredClient.Name, err)
+		}
+		if _, ok := declaredIDs[desiredClient.ID]; ok {
+			return fmt.Errorf("the OIDC client '%s' is declared more than once", desiredClient.ID)
+		}
+		declaredIDs[desiredClient.ID] = struct{}{}
+
+		for _, groupName := range desiredClient.AllowedUserGroups {
+			_, declared := declaredGroupNames[groupName]
+			if !declared && !slices.Contains(existingGroupNames, groupName) {
+				return fmt.Errorf("the allowed user group '%s' of the OIDC client '%s' doesn't exist", groupName, desiredClient.Name)
+			}
+		}
+
+		for _, host := range desiredClient.ForwardAuthHosts {
+			host = strings.ToLower(host)
+			if other, ok := forwardAuthHosts[host]; ok {
+				return fmt.Errorf("the forward auth host '%s' is declared for the OIDC clients '%s' and '%s'", host, other, desiredClient.Name)
+			}
+			forwardAuthHosts[host] = desiredClient.Name
+		}
+	}
+
+	// The forward auth hosts of the declared clients must not be protected by other clients
+	for _, client := range clients {
+		if _, ok := declaredIDs[client.ID]; ok {
+			continue
+		}
+		for _, host := range client.ForwardAuthHosts {
+			if declaredClient, ok := forwardAuthHosts[host]; ok {
+				return fmt.Errorf("the forward auth host '%s' of the OIDC client '%s' is already protected by the client '%s'", host, declaredClient, client.Name)
+			}
+		}
+	}
+
+	for _, desiredClient := range desired {
+		clientPlan := declarativeOidcClientPlan{existing: existing[desiredClient.ID], desired: desiredClient}
+
+		var before *declarativeOidcClientState
+		if clientPlan.existing != nil {
+			beforeState := newDeclarativeOidcClientState(*clientPlan.existing)
+			before = &beforeState
+		}
+
+		// The secret is only hashed again if it changed, as the hash is different every time
+		var secretChanged bool
+		if !desiredClient.IsPublic {
+			secret, err := readDeclarativeSecretFile(desiredClient.SecretFile)
+			if err != nil {
+				return fmt.Errorf("failed to read the secret of the OIDC client '%s': %w", desiredClient.Name, err)
+			}
+			if clientPlan.existing == nil || bcrypt.CompareHashAndPassword([]byte(clientPlan.existing.Secret), []byte(secret)) != nil {
+				hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				clientPlan.hashedSecret = string(hashedSecret)
+				secretChanged = true
+			}
+		}
+
+		desiredModel := clientPlan.desiredModel()
+		after := newDeclarativeOidcClientState(desiredModel)
+		after.AllowedUserGroups = sortedStrings(desiredClient.AllowedUserGroups)
+		var beforeSecret string
+		if before != nil {
+			beforeSecret = before.Secret
+		}
+		if !desiredClient.IsPublic {
+			_, after.Secret = redactedSecretStates(beforeSecret, "********", secretChanged)
+		}
+
+		action := DeclarativeConfigActionCreate
+		var beforeState any
+		if before != nil {
+			if reflect.DeepEqual(*before, after) {
+				continue
+			}
+			action = DeclarativeConfigActionUpdate
+			beforeState = *before
+		}
+
+		err = plan.addChange(action, AuditTarget{Type: model.AuditLogTargetOidcClient, ID: desiredClient.ID, Name: desiredClient.Name}, beforeState, after)
+		if err != nil {
+			return err
+		}
+		plan.oidcClients = append(plan.oidcClients, clientPlan)
+	}
+
+	for _, client := range clients {
+		if _, ok := declaredIDs[client.ID]; ok || !client.ConfigManaged {
+			continue
+		}
+
+		before := newDeclarativeOidcClientState(client)
+		after := before
+		after.ConfigManaged = false
+		err = plan.addChange(DeclarativeConfigActionRelease, oidcClientAuditTarget(client), before, after)
+		if err != nil {
+			return err
+		}
+		plan.releasedOidcClientIDs = append(plan.releasedOidcClientIDs, client.ID)
+	}
+
+	return nil
+}
+
+func validateDeclarativeOidcClient(client DeclarativeOidcClient) error {
+	if _, err := uuid.Parse(client.ID); err != nil {
+		return fmt.Errorf("the ID '%s' isn't a UUID", client.ID)
+	}
+
+	err := dto.Validate(dto.OidcClientCreateDto{
+		Name:               client.Name,
+		CallbackURLs:       client.CallbackURLs,
+		LogoutCallbackURLs: client.LogoutCallbackURLs,
+		IsPublic:           client.IsPublic,
+		PkceEnabled:        client.PkceEnabled,
+		ForwardAuthHosts:   client.ForwardAuthHosts,
+	})
+	if err != nil {
+		return err
+	}
+
+	if client.IsPublic && client.SecretFile != "" {
+		return errors.New("public clients don't have a secret")
+	}
+	if !client.IsPublic && client.SecretFile == "" {
+		return errors.New("the secret file of confidential clients is required")
+	}
+
+	return nil
+}
+
+// readDeclarativeSecretFile reads a secret from a file, ignoring surrounding whitespace like a trailing newline
+func readDeclarativeSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return "", fmt.Errorf("the file '%s' is empty", path)
+	}
+	return secret, nil
+}
+
+func (s *DeclarativeConfigService) applyAppConfigInternal(ctx context.Context, plan declarativeConfigPlan, tx *gorm.DB) error {
+	if len(plan.appConfig) > 0 {
+		variables := make([]model.AppConfigVariable, len(plan.appConfig))
+		before := make([]model.AppConfigVariable, 0, len(plan.appConfig))
+		for i, variablePlan := range plan.appConfig {
+			variables[i] = model.AppConfigVariable{Key: variablePlan.key, Value: variablePlan.after, ConfigManaged: true}
+			if variablePlan.existing {
+				before = append(before, model.AppConfigVariable{Key: variablePlan.key, Value: variablePlan.before})
+			}
+		}
+
+		err := tx.
+			WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "config_managed"}),
+			}).
+			Create(&variables).
+			Error
+		if err != nil {
+			return err
+		}
+
+		beforeState, afterState := appConfigAuditStates(before, variables)
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventAppConfigUpdated, AuditTarget{Type: model.AuditLogTargetAppConfig}, beforeState, afterState, tx)
+	}
+
+	if len(plan.releasedAppConfigKeys) > 0 {
+		err := tx.
+			WithContext(ctx).
+			Model(&model.AppConfigVariable{}).
+			Where(`"key" IN ?`, plan.releasedAppConfigKeys).
+			Update("config_managed", false).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *DeclarativeConfigService) applyUserGroupInternal(ctx context.Context, groupPlan declarativeUserGroupPlan, tx *gorm.DB) error {
+	group := model.UserGroup{
+		Name:          groupPlan.desired.Name,
+		FriendlyName:  groupPlan.desired.FriendlyName,
+		ConfigManaged: true,
+	}
+
+	var existingClaims []model.CustomClaim
+	if groupPlan.existing == nil {
+		err := tx.
+			WithContext(ctx).
+			Create(&group).
+			Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return &common.AlreadyInUseError{Property: "name"}
+		} else if err != nil {
+			return err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupCreated, userGroupAuditTarget(group), nil, userGroupAuditState(group), tx)
+	} else {
+		group.ID = groupPlan.existing.ID
+		existingClaims = groupPlan.existing.CustomClaims
+
+		err := tx.
+			WithContext(ctx).
+			Model(&model.UserGroup{}).
+			Where("id = ?", group.ID).
+			Updates(map[string]any{"friendly_name": group.FriendlyName, "config_managed": true}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if groupPlan.existing.FriendlyName != group.FriendlyName {
+			s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupUpdated, userGroupAuditTarget(group), userGroupAuditState(*groupPlan.existing), userGroupAuditState(group), tx)
+		}
+	}
+
+	// Replace the custom claims if they changed
+	desiredClaims := nonNilMap(groupPlan.desired.CustomClaims)
+	if reflect.DeepEqual(customClaimsAuditState(existingClaims), desiredClaims) {
+		return nil
+	}
+
+	err := tx.
+		WithContext(ctx).
+		Where("user_group_id = ?", group.ID).
+		Delete(&model.CustomClaim{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	claims := make([]model.CustomClaim, 0, len(desiredClaims))
+	for key, value := range desiredClaims {
+		claims = append(claims, model.CustomClaim{Key: key, Value: value, UserGroupID: &group.ID})
+	}
+	if len(claims) > 0 {
+		err = tx.
+			WithContext(ctx).
+			Create(&claims).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventCustomClaimsUpdated, userGroupAuditTarget(group), customClaimsAuditState(existingClaims), desiredClaims, tx)
+
+	return nil
+}
+
+func (s *DeclarativeConfigService) applyOidcClientInternal(ctx context.Context, clientPlan declarativeOidcClientPlan, tx *gorm.DB) error {
+	client := clientPlan.desiredModel()
+
+	if clientPlan.existing == nil {
+		err := tx.
+			WithContext(ctx).
+			Omit(clause.Associations).
+			Create(&client).
+			Error
+		if err != nil {
+			return err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientCreated, oidcClientAuditTarget(client), nil, newOidcClientAuditState(client), tx)
+	} else {
+		err := tx.
+			WithContext(ctx).
+			Omit(clause.Associations).
+			Save(&client).
+			Error
+		if err != nil {
+			return err
+		}
+
+		before, after := newOidcClientAuditState(*clientPlan.existing), newOidcClientAuditState(client)
+		if !reflect.DeepEqual(before, after) {
+			s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientUpdated, oidcClientAuditTarget(client), before, after, tx)
+		}
+	}
+
+	if clientPlan.hashedSecret != "" && clientPlan.existing != nil {
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientSecretCreated, oidcClientAuditTarget(client), nil, nil, tx)
+	}
+
+	// Replace the allowed user groups if they changed
+	var originalGroups []model.UserGroup
+	if clientPlan.existing != nil {
+		originalGroups = clientPlan.existing.AllowedUserGroups
+	}
+	var groups []model.UserGroup
+	if len(clientPlan.desired.AllowedUserGroups) > 0 {
+		err := tx.
+			WithContext(ctx).
+			Where("name IN ?", clientPlan.desired.AllowedUserGroups).
+			Find(&groups).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+	if reflect.DeepEqual(userGroupNamesAuditState(originalGroups), userGroupNamesAuditState(groups)) {
+		return nil
+	}
+
+	err := tx.
+		WithContext(ctx).
+		Model(&client).
+		Association("AllowedUserGroups").
+		Replace(groups)
+	if err != nil {
+		return err
+	}
+
+	// The users who gained or lost access have to be synced to the provisioning target of the client
+	err = s.oidcService.scimProvisioningService.enqueueClientInternal(ctx, client.ID, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientAllowedGroupsUpdated, oidcClientAuditTarget(client),
+		userGroupNamesAuditState(originalGroups), userGroupNamesAuditState(groups), tx)
+
+	return nil
+}
+
+// desiredModel returns the client with the declared attributes, based on the existing client if there is one
+func (p declarativeOidcClientPlan) desiredModel() model.OidcClient {
+	client := model.OidcClient{Base: model.Base{ID: p.desired.ID}}
+	if p.existing != nil {
+		client = *p.existing
+		client.AllowedUserGroups = nil
+	}
+
+	updateOIDCClientModelFromDto(&client, &dto.OidcClientCreateDto{
+		Name:               p.desired.Name,
+		CallbackURLs:       p.desired.CallbackURLs,
+		LogoutCallbackURLs: p.desired.LogoutCallbackURLs,
+		IsPublic:           p.desired.IsPublic,
+		PkceEnabled:        p.desired.PkceEnabled,
+		ForwardAuthHosts:   p.desired.ForwardAuthHosts,
+	})
+	if p.hashedSecret != "" {
+		client.Secret = p.hashedSecret
+	}
+	client.ConfigManaged = true
+
+	return client
+}
+
+func newDeclarativeUserGroupState(group model.UserGroup) declarativeUserGroupState {
+	return declarativeUserGroupState{
+		Name:          group.Name,
+		FriendlyName:  group.FriendlyName,
+		CustomClaims:  customClaimsAuditState(group.CustomClaims),
+		ConfigManaged: group.ConfigManaged,
+	}
+}
+
+func newDeclarativeOidcClientState(client model.OidcClient) declarativeOidcClientState {
+	state := declarativeOidcClientState{
+		Name:               client.Name,
+		CallbackURLs:       nonNilStrings(client.CallbackURLs),
+		LogoutCallbackURLs: nonNilStrings(client.LogoutCallbackURLs),
+		IsPublic:           client.IsPublic,
+		PkceEnabled:        client.PkceEnabled,
+		ForwardAuthHosts:   nonNilStrings(client.ForwardAuthHosts),
+		AllowedUserGroups:  make([]string, len(client.AllowedUserGroups)),
+		ConfigManaged:      client.ConfigManaged,
+	}
+	if client.Secret != "" {
+		state.Secret = "********"
+	}
+	for i, group := range client.AllowedUserGroups {
+		state.AllowedUserGroups[i] = group.Name
+	}
+	slices.Sort(state.AllowedUserGroups)
+	return state
+}
+
+// redactedSecretStates redacts the values of a secret, but still shows whether it changed
+func redactedSecretStates(before, after string, changed bool) (string, string) {
+	if before != "" {
+		before = "********"
+	}
+	if after != "" {
+		after = "********"
+		if changed && before != "" {
+			after = "******** (changed)"
+		}
+	}
+	return before, after
+}
+
+// addChange adds a change to the plan with the fields that differ between the states.
+// The before state is nil if the resource is created.
+func (p *declarativeConfigPlan) addChange(action DeclarativeConfigAction, target AuditTarget, before, after any) error {
+	beforeFields, err := auditLogFields(before)
+	if err != nil {
+		return err
+	}
+	afterFields, err := auditLogFields(after)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(afterFields))
+	for field := range afterFields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	change := DeclarativeConfigChange{Action: action, Target: target}
+	for _, field := range fields {
+		beforeValue, ok := beforeFields[field]
+		if ok && reflect.DeepEqual(beforeValue, afterFields[field]) {
+			continue
+		}
+		change.Fields = append(change.Fields, DeclarativeConfigFieldChange{Field: field, Before: beforeValue, After: afterFields[field]})
+	}
+
+	p.changes = append(p.changes, change)
+	return nil
+}
+
+// FormatDeclarativeConfigValue returns the JSON representation of the value of a field change
+func FormatDeclarativeConfigValue(value any) string {
+	formatted, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(formatted)
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilMap(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
+
+func sortedStrings(values []string) []string {
+	sorted := slices.Clone(nonNilStrings(values))
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+func TestParseDeclarativeConfig(t *testing.T) {
+	t.Run("parses YAML", func(t *testing.T) {
+		config, err := ParseDeclarativeConfig(strings.NewReader(`
+appConfig:
+  appName: Example
+  sessionDuration: 120
+userGroups:
+  - name: developers
+    friendlyName: Developers
+    customClaims:
+      team: platform
+oidcClients:
+  - id: 6b7e3f3e-6c2a-4b7e-9d43-4f2a1c9b1e10
+    name: Wiki
+    callbackURLs: [https://wiki.example.com/callback]
+    isPublic: true
+    allowedUserGroups: [developers]
+`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"appName": "Example", "sessionDuration": "120"}, config.AppConfig)
+		require.Len(t, config.UserGroups, 1)
+		assert.Equal(t, map[string]string{"team": "platform"}, config.UserGroups[0].CustomClaims)
+		require.Len(t, config.OidcClients, 1)
+		assert.True(t, config.OidcClients[0].IsPublic)
+		assert.Equal(t, []string{"developers"}, config.OidcClients[0].AllowedUserGroups)
+	})
+
+	t.Run("parses JSON", func(t *testing.T) {
+		config, err := ParseDeclarativeConfig(strings.NewReader(`{"userGroups": [{"name": "admins", "friendlyName": "Admins"}]}`))
+		require.NoError(t, err)
+		require.Len(t, config.UserGroups, 1)
+		assert.Equal(t, "admins", config.UserGroups[0].Name)
+	})
+
+	t.Run("accepts an empty file", func(t *testing.T) {
+		config, err := ParseDeclarativeConfig(strings.NewReader(""))
+		require.NoError(t, err)
+		assert.Empty(t, config.UserGroups)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := ParseDeclarativeConfig(strings.NewReader("userGroups:\n  - name: admins\n    friendlyNam: Admins\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestDeclarativeConfigService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfigService := &AppConfigService{db: db}
+	require.NoError(t, appConfigService.LoadDbConfig(t.Context()))
+	auditLogService := NewAuditLogService(db, appConfigService, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil)
+	appConfigService.SetAuditLogService(auditLogService)
+	scimProvisioningService := NewScimProvisioningService(db, nil)
+	oidcService := &OidcService{db: db, appConfigService: appConfigService, auditLogService: auditLogService, scimProvisioningService: scimProvisioningService}
+	userGroupService := NewUserGroupService(db, appConfigService, scimProvisioningService, NewWebhookService(db, nil), auditLogService)
+	service := NewDeclarativeConfigService(db, appConfigService, oidcService, auditLogService)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("client-secret\n"), 0600))
+
+	const clientID = "6b7e3f3e-6c2a-4b7e-9d43-4f2a1c9b1e10"
+	config := DeclarativeConfig{
+		AppConfig: map[string]string{"appName": "Managed", "smtpPassword": "smtp-secret"},
+		UserGroups: []DeclarativeUserGroup{
+			{Name: "developers", FriendlyName: "Developers", CustomClaims: map[string]string{"team": "platform"}},
+		},
+		OidcClients: []DeclarativeOidcClient{
+			{
+				ID:                clientID,
+				Name:              "Wiki",
+				CallbackURLs:      []string{"https://wiki.example.com/callback"},
+				SecretFile:        secretFile,
+				AllowedUserGroups: []string{"developers"},
+			},
+		},
+	}
+
+	t.Run("dry run doesn't change anything", func(t *testing.T) {
+		changes, err := service.Plan(t.Context(), config)
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+
+		var count int64
+		require.NoError(t, db.Model(&model.UserGroup{}).Count(&count).Error)
+		assert.Zero(t, count)
+		require.NoError(t, db.Model(&model.AuditLog{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("creates the resources", func(t *testing.T) {
+		changes, err := service.Apply(t.Context(), config)
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+		for _, change := range changes {
+			for _, field := range change.Fields {
+				assert.NotContains(t, FormatDeclarativeConfigValue(field.After), "secret", "secrets must be redacted")
+			}
+		}
+
+		assert.Equal(t, "Managed", appConfigService.GetDbConfig().AppName.Value)
+		assert.True(t, appConfigService.GetDbConfig().AppName.ConfigManaged)
+
+		var group model.UserGroup
+		require.NoError(t, db.Preload("CustomClaims").Where("name = ?", "developers").First(&group).Error)
+		assert.True(t, group.ConfigManaged)
+		require.Len(t, group.CustomClaims, 1)
+		assert.Equal(t, "platform", group.CustomClaims[0].Value)
+
+		var client model.OidcClient
+		require.NoError(t, db.Preload("AllowedUserGroups").First(&client, "id = ?", clientID).Error)
+		assert.True(t, client.ConfigManaged)
+		assert.Nil(t, client.CreatedByID)
+		require.NoError(t, bcrypt.CompareHashAndPassword([]byte(client.Secret), []byte("client-secret")))
+		require.Len(t, client.AllowedUserGroups, 1)
+		assert.Equal(t, group.ID, client.AllowedUserGroups[0].ID)
+	})
+
+	t.Run("applying again doesn't change anything", func(t *testing.T) {
+		changes, err := service.Apply(t.Context(), config)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("managed resources are read-only", func(t *testing.T) {
+		var group model.UserGroup
+		require.NoError(t, db.Where("name = ?", "developers").First(&group).Error)
+		var configManagedErr *common.ConfigManagedError
+
+		_, err := userGroupService.Update(t.Context(), group.ID, dto.UserGroupCreateDto{Name: "developers", FriendlyName: "Renamed"})
+		require.ErrorAs(t, err, &configManagedErr)
+		err = userGroupService.Delete(t.Context(), group.ID)
+		require.ErrorAs(t, err, &configManagedErr)
+
+		_, err = oidcService.UpdateClient(t.Context(), clientID, dto.OidcClientCreateDto{Name: "Renamed", CallbackURLs: []string{"https://wiki.example.com/callback"}})
+		require.ErrorAs(t, err, &configManagedErr)
+		err = oidcService.DeleteClient(t.Context(), clientID)
+		require.ErrorAs(t, err, &configManagedErr)
+
+		_, err = appConfigService.UpdateAppConfig(t.Context(), dto.AppConfigUpdateDto{AppName: "Other", SessionDuration: "60", SmtpPassword: "smtp-secret"})
+		require.ErrorAs(t, err, &configManagedErr)
+	})
+
+	t.Run("updates changed resources", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(secretFile, []byte("new-secret"), 0600))
+		updated := config
+		updated.UserGroups = []DeclarativeUserGroup{{Name: "developers", FriendlyName: "Engineers"}}
+
+		changes, err := service.Apply(t.Context(), updated)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, DeclarativeConfigActionUpdate, changes[0].Action)
+
+		var group model.UserGroup
+		require.NoError(t, db.Preload("CustomClaims").Where("name = ?", "developers").First(&group).Error)
+		assert.Equal(t, "Engineers", group.FriendlyName)
+		assert.Empty(t, group.CustomClaims)
+
+		var client model.OidcClient
+		require.NoError(t, db.First(&client, "id = ?", clientID).Error)
+		require.NoError(t, bcrypt.CompareHashAndPassword([]byte(client.Secret), []byte("new-secret")))
+	})
+
+	t.Run("releases resources that were removed", func(t *testing.T) {
+		changes, err := service.Apply(t.Context(), DeclarativeConfig{})
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+		for _, change := range changes {
+			assert.Equal(t, DeclarativeConfigActionRelease, change.Action)
+		}
+
+		var client model.OidcClient
+		require.NoError(t, db.First(&client, "id = ?", clientID).Error)
+		assert.False(t, client.ConfigManaged)
+		assert.False(t, appConfigService.GetDbConfig().AppName.ConfigManaged)
+		assert.Equal(t, "Managed", appConfigService.GetDbConfig().AppName.Value)
+
+		_, err = oidcService.UpdateClient(t.Context(), clientID, dto.OidcClientCreateDto{Name: "Renamed", CallbackURLs: []string{"https://wiki.example.com/callback"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects invalid configurations", func(t *testing.T) {
+		invalid := []DeclarativeConfig{
+			{AppConfig: map[string]string{"unknownKey": "value"}},
+			{UserGroups: []DeclarativeUserGroup{{Name: "admins", FriendlyName: "Admins"}, {Name: "admins", FriendlyName: "Admins"}}},
+			{UserGroups: []DeclarativeUserGroup{{Name: "admins", FriendlyName: "Admins", CustomClaims: map[string]string{"email": "x"}}}},
+			{OidcClients: []DeclarativeOidcClient{{ID: "not-a-uuid", Name: "Wiki", IsPublic: true}}},
+			{OidcClients: []DeclarativeOidcClient{{ID: clientID, Name: "Wiki"}}},
+			{OidcClients: []DeclarativeOidcClient{{ID: clientID, Name: "Wiki", IsPublic: true, AllowedUserGroups: []string{"unknown"}}}},
+		}
+		for _, config := range invalid {
+			_, err := service.Plan(t.Context(), config)
+			require.Error(t, err)
+		}
+	})
+}
@@ -156,7 +156,7 @@ func (s *TestService) SeedDatabase(baseURL string) error {
 				CallbackURLs:       model.UrlList{"http://nextcloud/auth/callback"},
 				LogoutCallbackURLs: model.UrlList{"http://nextcloud/auth/logout/callback"},
 				ImageType:          utils.StringPointer("png"),
-				CreatedByID:        users[0].ID,
+				CreatedByID:        &users[0].ID,
 			},
 			{
 				Base: model.Base{
@@ -165,7 +165,7 @@ func (s *TestService) SeedDatabase(baseURL string) error {
 				Name:         "Immich",
 				Secret:       "$2a$10$Ak.FP8riD1ssy2AGGbG.gOpnp/rBpymd74j0nxNMtW0GG1Lb4gzxe", // PYjrE9u4v9GVqXKi52eur0eb2Ci4kc0x
 				CallbackURLs: model.UrlList{"http://immich/auth/callback"},
-				CreatedByID:  users[1].ID,
+				CreatedByID:  &users[1].ID,
 				AllowedUserGroups: []model.UserGroup{
 					userGroups[1],
 				},
@@ -177,7 +177,7 @@ func (s *TestService) SeedDatabase(baseURL string) error {
 				Name:              "Federated",
 				Secret:            "$2a$10$Ak.FP8riD1ssy2AGGbG.gOpnp/rBpymd74j0nxNMtW0GG1Lb4gzxe", // PYjrE9u4v9GVqXKi52eur0eb2Ci4kc0x
 				CallbackURLs:      model.UrlList{"http://federated/auth/callback"},
-				CreatedByID:       users[1].ID,
+				CreatedByID:       &users[1].ID,
 				AllowedUserGroups: []model.UserGroup{},
 				Credentials: model.OidcClientCredentials{
 					FederatedIdentities: []model.OidcClientFederatedIdentity{
@@ -339,6 +339,21 @@ func (s *TestService) ResetDatabase() error {
             `).Scan(&tables).Error; err != nil {
 				return err
 			}
+		case common.DbProviderMysql:
+			// Query to get all tables for MySQL
+			if err := tx.Raw(`
+                SELECT table_name AS table_name
+                FROM information_schema.tables
+                WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' AND table_name != 'schema_migrations';
+            `).Scan(&tables).Error; err != nil {
+				return err
+			}
+
+			// MySQL checks the foreign keys of every statement, so the tables can't be emptied one after another otherwise
+			if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0;").Error; err != nil {
+				return err
+			}
+			defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1;")
 		default:
 			return fmt.Errorf("unsupported database provider: %s", common.EnvConfig.DbProvider)
 		}
@@ -356,6 +371,35 @@ func (s *TestService) ResetDatabase() error {
 	return err
 }
 
+// ListSessions returns all the sessions, so they can be restored after the database has been reset
+func (s *TestService) ListSessions() ([]model.Session, error) {
+	var sessions []model.Session
+	err := s.db.Find(&sessions).Error
+	return sessions, err
+}
+
+// RestoreSessions re-creates the given sessions for the users that still exist after seeding the database
+// This allows the E2E tests to keep the access token that was obtained during the setup
+func (s *TestService) RestoreSessions(sessions []model.Session) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, session := range sessions {
+			var count int64
+			if err := tx.Model(&model.User{}).Where("id = ?", session.UserID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				continue
+			}
+
+			if err := tx.Create(&session).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 func (s *TestService) ResetApplicationImages() error {
 	if err := os.RemoveAll(common.EnvConfig.UploadPath); err != nil {
 		log.Printf("Error removing directory: %v", err)
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// ForwardAuthCallbackPath is the path on the protected host that sets the forward-auth cookie after signing in
+// The reverse proxy forwards it to the forward-auth endpoint like any other request
+const ForwardAuthCallbackPath = "/.pocket-id/callback"
+
+type ForwardAuthService struct {
+	db          *gorm.DB
+	jwtService  *JwtService
+	oidcService *OidcService
+}
+
+func NewForwardAuthService(db *gorm.DB, jwtService *JwtService, oidcService *OidcService) *ForwardAuthService {
+	return &ForwardAuthService{
+		db:          db,
+		jwtService:  jwtService,
+		oidcService: oidcService,
+	}
+}
+
+// VerifyAccess makes sure that the user is allowed to access the host
+// The user's groups must be loaded
+func (s *ForwardAuthService) VerifyAccess(ctx context.Context, user model.User, host string) error {
+	return s.verifyAccessInternal(ctx, user, host, s.db)
+}
+
+// Authorize creates a one-time code for the session of the user and returns the URL of the protected host that exchanges it for the forward-auth cookie
+func (s *ForwardAuthService) Authorize(ctx context.Context, userID string, sessionID string, redirectURL string) (string, error) {
+	if sessionID == "" {
+		// Requests authenticated with an API key don't have a session that could be handed over
+		return "", &common.NotSignedInError{}
+	}
+
+	redirect, err := url.Parse(redirectURL)
+	if err != nil || (redirect.Scheme != "http" && redirect.Scheme != "https") || redirect.Host == "" {
+		return "", &common.ForwardAuthInvalidRedirectError{}
+	}
+	host := strings.ToLower(redirect.Host)
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var user model.User
+	err = tx.
+		WithContext(ctx).
+		Preload("UserGroups").
+		First(&user, "id = ?", userID).
+		Error
+	if err != nil {
+		return "", err
+	}
+
+	err = s.verifyAccessInternal(ctx, user, host, tx)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Create(&model.ForwardAuthCode{
+			Code:      code,
+			Host:      host,
+			ExpiresAt: datatype.DateTime(time.Now().Add(time.Minute)),
+			SessionID: sessionID,
+		}).
+		Error
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return "", err
+	}
+
+	callbackURL := url.URL{
+		Scheme: redirect.Scheme,
+		Host:   redirect.Host,
+		Path:   ForwardAuthCallbackPath,
+		RawQuery: url.Values{
+			"code": {code},
+			"rd":   {redirect.String()},
+		}.Encode(),
+	}
+
+	return callbackURL.String(), nil
+}
+
+// ExchangeCode redeems a one-time code that was created for the host and returns a forward-auth token for the session
+func (s *ForwardAuthService) ExchangeCode(ctx context.Context, code string, host string) (token string, expiresAt time.Time, err error) {
+	host = strings.ToLower(host)
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var forwardAuthCode model.ForwardAuthCode
+	err = tx.
+		WithContext(ctx).
+		Preload("Session").
+		Preload("Session.User").
+		Preload("Session.User.UserGroups").
+		First(&forwardAuthCode, "code = ?", code).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", time.Time{}, &common.ForwardAuthInvalidCodeError{}
+	} else if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// The code can only be used once, even if it's redeemed concurrently
+	result := tx.
+		WithContext(ctx).
+		Delete(&forwardAuthCode)
+	if result.Error != nil {
+		return "", time.Time{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", time.Time{}, &common.ForwardAuthInvalidCodeError{}
+	}
+
+	now := time.Now()
+	session := forwardAuthCode.Session
+	if forwardAuthCode.Host != host || forwardAuthCode.ExpiresAt.ToTime().Before(now) || session.ExpiresAt.ToTime().Before(now) {
+		return "", time.Time{}, &common.ForwardAuthInvalidCodeError{}
+	}
+
+	if session.User.Disabled {
+		return "", time.Time{}, &common.UserDisabledError{}
+	}
+
+	err = s.verifyAccessInternal(ctx, session.User, host, tx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt = session.ExpiresAt.ToTime()
+	token, err = s.jwtService.GenerateForwardAuthToken(session.User, session.ID, host, expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (s *ForwardAuthService) verifyAccessInternal(ctx context.Context, user model.User, host string, tx *gorm.DB) error {
+	client, err := s.getClientForHostInternal(ctx, host, tx)
+	if err != nil {
+		return err
+	}
+
+	if !s.oidcService.IsUserGroupAllowedToAuthorize(user, client) {
+		return &common.OidcAccessDeniedError{}
+	}
+
+	return nil
+}
+
+// getClientForHostInternal returns the OIDC client whose user group restrictions apply to the host
+func (s *ForwardAuthService) getClientForHostInternal(ctx context.Context, host string, tx *gorm.DB) (model.OidcClient, error) {
+	host = strings.ToLower(host)
+
+	var clients []model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		Where("forward_auth_hosts IS NOT NULL").
+		Find(&clients).
+		Error
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	for _, client := range clients {
+		if slices.Contains(client.ForwardAuthHosts, host) {
+			return client, nil
+		}
+	}
+
+	return model.OidcClient{}, &common.ForwardAuthHostNotConfiguredError{}
+}
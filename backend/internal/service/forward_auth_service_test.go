@@ -0,0 +1,115 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+func TestForwardAuthService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"},
+	})
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(appConfig, common.EnvConfig.KeysPath))
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	oidcService := &OidcService{db: db, auditLogService: NewAuditLogService(db, appConfig, nil, geoliteService, NewWebhookService(db, nil), nil, nil)}
+	service := NewForwardAuthService(db, jwtService, oidcService)
+
+	tim := model.User{Username: "tim", Email: "tim@example.com"}
+	require.NoError(t, db.Create(&tim).Error)
+	craig := model.User{Username: "craig", Email: "craig@example.com"}
+	require.NoError(t, db.Create(&craig).Error)
+	group := model.UserGroup{Name: "developers", FriendlyName: "Developers", Users: []model.User{tim}}
+	require.NoError(t, db.Create(&group).Error)
+
+	client, err := oidcService.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		Name:             "Wiki",
+		CallbackURLs:     []string{"https://wiki.example.com/callback"},
+		ForwardAuthHosts: []string{"Wiki.example.com"},
+	}, tim.ID)
+	require.NoError(t, err)
+	_, err = oidcService.UpdateAllowedUserGroups(t.Context(), client.ID, dto.OidcUpdateAllowedUserGroupsDto{UserGroupIDs: []string{group.ID}})
+	require.NoError(t, err)
+
+	timSession, err := sessionService.Create(t.Context(), tim, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+	require.NoError(t, err)
+	craigSession, err := sessionService.Create(t.Context(), craig, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+	require.NoError(t, err)
+
+	t.Run("hosts can only be protected by one client", func(t *testing.T) {
+		_, err := oidcService.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			Name:             "Other",
+			CallbackURLs:     []string{"https://other.example.com/callback"},
+			ForwardAuthHosts: []string{"wiki.example.com"},
+		}, tim.ID)
+		var alreadyInUseErr *common.AlreadyInUseError
+		require.ErrorAs(t, err, &alreadyInUseErr)
+	})
+
+	t.Run("exchanges a code for a token that is only valid for the host", func(t *testing.T) {
+		callbackURL, err := service.Authorize(t.Context(), tim.ID, timSession.ID, "https://wiki.example.com/pages/1?edit=true")
+		require.NoError(t, err)
+
+		callback, err := url.Parse(callbackURL)
+		require.NoError(t, err)
+		assert.Equal(t, "wiki.example.com", callback.Host)
+		assert.Equal(t, ForwardAuthCallbackPath, callback.Path)
+		assert.Equal(t, "https://wiki.example.com/pages/1?edit=true", callback.Query().Get("rd"))
+
+		token, expiresAt, err := service.ExchangeCode(t.Context(), callback.Query().Get("code"), "WIKI.example.com")
+		require.NoError(t, err)
+		assert.WithinDuration(t, timSession.ExpiresAt.ToTime(), expiresAt, time.Second)
+
+		_, err = jwtService.VerifyForwardAuthToken(token, "wiki.example.com")
+		require.NoError(t, err)
+		_, err = jwtService.VerifyForwardAuthToken(token, "other.example.com")
+		require.Error(t, err)
+		_, err = jwtService.VerifyAccessToken(token)
+		require.Error(t, err)
+
+		// The code can only be used once
+		_, _, err = service.ExchangeCode(t.Context(), callback.Query().Get("code"), "wiki.example.com")
+		var invalidCodeErr *common.ForwardAuthInvalidCodeError
+		require.ErrorAs(t, err, &invalidCodeErr)
+	})
+
+	t.Run("rejects codes for another host", func(t *testing.T) {
+		callbackURL, err := service.Authorize(t.Context(), tim.ID, timSession.ID, "https://wiki.example.com/")
+		require.NoError(t, err)
+		callback, err := url.Parse(callbackURL)
+		require.NoError(t, err)
+
+		_, _, err = service.ExchangeCode(t.Context(), callback.Query().Get("code"), "evil.example.com")
+		var invalidCodeErr *common.ForwardAuthInvalidCodeError
+		require.ErrorAs(t, err, &invalidCodeErr)
+	})
+
+	t.Run("denies users that aren't in an allowed group", func(t *testing.T) {
+		_, err := service.Authorize(t.Context(), craig.ID, craigSession.ID, "https://wiki.example.com/")
+		var accessDeniedErr *common.OidcAccessDeniedError
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("denies hosts that aren't configured", func(t *testing.T) {
+		_, err := service.Authorize(t.Context(), tim.ID, timSession.ID, "https://grafana.example.com/")
+		var notConfiguredErr *common.ForwardAuthHostNotConfiguredError
+		require.ErrorAs(t, err, &notConfiguredErr)
+	})
+}
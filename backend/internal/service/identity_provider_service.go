@@ -0,0 +1,905 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+const (
+	// identityProviderLoginTimeout is the time users have to sign in at the identity provider
+	identityProviderLoginTimeout = 10 * time.Minute
+
+	defaultIdentityProviderScopes = "openid email profile"
+)
+
+var invalidUsernameCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_.@-]`)
+
+type IdentityProviderService struct {
+	db                      *gorm.DB
+	httpClient              *http.Client
+	jwtService              *JwtService
+	oidcService             *OidcService
+	userService             *UserService
+	sessionService          *SessionService
+	auditLogService         *AuditLogService
+	scimProvisioningService *ScimProvisioningService
+	webhookService          *WebhookService
+}
+
+func NewIdentityProviderService(db *gorm.DB, httpClient *http.Client, jwtService *JwtService, oidcService *OidcService, userService *UserService, sessionService *SessionService, auditLogService *AuditLogService, scimProvisioningService *ScimProvisioningService, webhookService *WebhookService) *IdentityProviderService {
+	return &IdentityProviderService{
+		db:                      db,
+		httpClient:              httpClient,
+		jwtService:              jwtService,
+		oidcService:             oidcService,
+		userService:             userService,
+		sessionService:          sessionService,
+		auditLogService:         auditLogService,
+		scimProvisioningService: scimProvisioningService,
+		webhookService:          webhookService,
+	}
+}
+
+// IdentityProviderCallbackURL returns the redirect URI that has to be registered at the identity providers
+func IdentityProviderCallbackURL() string {
+	return common.EnvConfig.AppURL + "/api/identity-providers/callback"
+}
+
+func (s *IdentityProviderService) ListProviders(ctx context.Context) ([]model.IdentityProvider, error) {
+	var providers []model.IdentityProvider
+	err := s.db.
+		WithContext(ctx).
+		Order("name").
+		Find(&providers).
+		Error
+	return providers, err
+}
+
+// ListEnabledProviders returns the identity providers that are shown on the sign in page
+func (s *IdentityProviderService) ListEnabledProviders(ctx context.Context) ([]model.IdentityProvider, error) {
+	var providers []model.IdentityProvider
+	err := s.db.
+		WithContext(ctx).
+		Where("enabled = ?", true).
+		Order("name").
+		Find(&providers).
+		Error
+	return providers, err
+}
+
+func (s *IdentityProviderService) GetProvider(ctx context.Context, id string) (model.IdentityProvider, error) {
+	return s.getProviderInternal(ctx, id, s.db)
+}
+
+func (s *IdentityProviderService) getProviderInternal(ctx context.Context, id string, tx *gorm.DB) (model.IdentityProvider, error) {
+	var provider model.IdentityProvider
+	err := tx.
+		WithContext(ctx).
+		First(&provider, "id = ?", id).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.IdentityProvider{}, &common.IdentityProviderNotFoundError{}
+	} else if err != nil {
+		return model.IdentityProvider{}, err
+	}
+	return provider, nil
+}
+
+func (s *IdentityProviderService) CreateProvider(ctx context.Context, input dto.IdentityProviderCreateDto) (model.IdentityProvider, error) {
+	if input.ClientSecret == "" {
+		return model.IdentityProvider{}, &common.ValidationError{Message: "Client secret is required"}
+	}
+
+	var provider model.IdentityProvider
+	updateIdentityProviderModelFromDto(&provider, input)
+
+	err := s.db.
+		WithContext(ctx).
+		Create(&provider).
+		Error
+	if err != nil {
+		return model.IdentityProvider{}, err
+	}
+
+	return provider, nil
+}
+
+func (s *IdentityProviderService) UpdateProvider(ctx context.Context, id string, input dto.IdentityProviderCreateDto) (model.IdentityProvider, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	provider, err := s.getProviderInternal(ctx, id, tx)
+	if err != nil {
+		return model.IdentityProvider{}, err
+	}
+
+	updateIdentityProviderModelFromDto(&provider, input)
+
+	err = tx.
+		WithContext(ctx).
+		Save(&provider).
+		Error
+	if err != nil {
+		return model.IdentityProvider{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.IdentityProvider{}, err
+	}
+
+	return provider, nil
+}
+
+func (s *IdentityProviderService) DeleteProvider(ctx context.Context, id string) error {
+	result := s.db.
+		WithContext(ctx).
+		Delete(&model.IdentityProvider{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &common.IdentityProviderNotFoundError{}
+	}
+	return nil
+}
+
+func updateIdentityProviderModelFromDto(provider *model.IdentityProvider, input dto.IdentityProviderCreateDto) {
+	provider.Name = input.Name
+	provider.Issuer = input.Issuer
+	provider.ClientID = input.ClientID
+	provider.Enabled = input.Enabled
+	provider.LinkByEmail = input.LinkByEmail
+	provider.AllowUserCreation = input.AllowUserCreation
+
+	// Keep the current secret if no new one was provided
+	if input.ClientSecret != "" {
+		provider.ClientSecret = input.ClientSecret
+	}
+
+	// The openid scope is required to receive an ID token
+	scopes := strings.Fields(input.Scopes)
+	if len(scopes) == 0 {
+		scopes = strings.Fields(defaultIdentityProviderScopes)
+	} else if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	provider.Scopes = strings.Join(scopes, " ")
+
+	provider.GroupsClaim = nil
+	if groupsClaim := strings.TrimSpace(input.GroupsClaim); groupsClaim != "" {
+		provider.GroupsClaim = &groupsClaim
+	}
+
+	provider.GroupMappings = make(model.IdentityProviderGroupMappings, len(input.GroupMappings))
+	for i, mapping := range input.GroupMappings {
+		provider.GroupMappings[i] = model.IdentityProviderGroupMapping{
+			Value:       mapping.Value,
+			UserGroupID: mapping.UserGroupID,
+		}
+	}
+}
+
+// BeginLogin prepares the authorization request to the identity provider and returns its URL and the state that has to be bound to the browser
+// If userID is set, the identity is linked to the user instead of signing in
+func (s *IdentityProviderService) BeginLogin(ctx context.Context, providerID string, userID string, redirectPath string) (authorizationURL string, state string, err error) {
+	provider, err := s.GetProvider(ctx, providerID)
+	if err != nil {
+		return "", "", err
+	}
+	if !provider.Enabled {
+		return "", "", &common.IdentityProviderNotFoundError{}
+	}
+
+	if userID != "" {
+		var count int64
+		err = s.db.
+			WithContext(ctx).
+			Model(&model.UserIdentity{}).
+			Where("identity_provider_id = ? AND user_id = ?", provider.ID, userID).
+			Count(&count).
+			Error
+		if err != nil {
+			return "", "", err
+		}
+		if count > 0 {
+			return "", "", &common.UserIdentityAlreadyLinkedError{}
+		}
+	}
+
+	metadata, err := s.discover(ctx, provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.GenerateRandomAlphanumericString(64)
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState := model.IdentityProviderLoginState{
+		State:              state,
+		Nonce:              nonce,
+		CodeVerifier:       codeVerifier,
+		ExpiresAt:          datatype.DateTime(time.Now().Add(identityProviderLoginTimeout)),
+		IdentityProviderID: provider.ID,
+	}
+	if userID != "" {
+		loginState.UserID = &userID
+	}
+	// Only allow relative paths to prevent open redirects
+	if strings.HasPrefix(redirectPath, "/") && !strings.HasPrefix(redirectPath, "//") && !strings.HasPrefix(redirectPath, "/\\") {
+		loginState.RedirectPath = &redirectPath
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Create(&loginState).
+		Error
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", &common.IdentityProviderLoginError{Message: "invalid authorization endpoint"}
+	}
+
+	codeChallenge := sha256.Sum256([]byte(codeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", IdentityProviderCallbackURL())
+	query.Set("scope", provider.Scopes)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(codeChallenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), state, nil
+}
+
+// IdentityProviderLoginResult is the outcome of a sign in with an identity provider
+type IdentityProviderLoginResult struct {
+	// Linking is true if the identity was linked to a signed in user, instead of signing in
+	// It's also set if the sign in failed, as long as the state could be loaded
+	Linking bool
+
+	User         model.User
+	Session      model.Session
+	AccessToken  string
+	RedirectPath string
+}
+
+// FinishLogin handles the response of the identity provider, and either signs in the user or links the identity to the signed in user
+func (s *IdentityProviderService) FinishLogin(ctx context.Context, state string, code string, ipAddress string, userAgent string) (result IdentityProviderLoginResult, err error) {
+	// Consume the state first, so that it can only be used once
+	// It's loaded before it's deleted, because not all databases can return deleted rows
+	var loginState model.IdentityProviderLoginState
+	err = s.db.
+		WithContext(ctx).
+		First(&loginState, "state = ?", state).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, &common.IdentityProviderLoginError{Message: "invalid state"}
+	} else if err != nil {
+		return result, err
+	}
+	deleted := s.db.
+		WithContext(ctx).
+		Delete(&loginState)
+	if deleted.Error != nil {
+		return result, deleted.Error
+	}
+	if deleted.RowsAffected == 0 {
+		// The state was consumed by another request in the meantime
+		return result, &common.IdentityProviderLoginError{Message: "invalid state"}
+	}
+
+	result.Linking = loginState.UserID != nil
+	if loginState.ExpiresAt.ToTime().Before(time.Now()) {
+		return result, &common.IdentityProviderLoginError{Message: "the sign in has timed out"}
+	}
+	if code == "" {
+		return result, &common.IdentityProviderLoginError{Message: "missing authorization code"}
+	}
+
+	provider, err := s.GetProvider(ctx, loginState.IdentityProviderID)
+	if err != nil {
+		return result, err
+	}
+	if !provider.Enabled {
+		return result, &common.IdentityProviderNotFoundError{}
+	}
+
+	metadata, err := s.discover(ctx, provider)
+	if err != nil {
+		return result, err
+	}
+
+	rawIDToken, err := s.exchangeCode(ctx, provider, metadata, code, loginState.CodeVerifier)
+	if err != nil {
+		return result, err
+	}
+
+	claims, err := s.verifyIDToken(ctx, provider, metadata, rawIDToken, loginState.Nonce)
+	if err != nil {
+		return result, err
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if result.Linking {
+		err = s.linkIdentityInternal(ctx, provider, *loginState.UserID, claims, tx)
+		if err != nil {
+			return result, err
+		}
+
+		result.RedirectPath = "/settings/account"
+		return result, tx.Commit().Error
+	}
+
+	// The users and group memberships that the identity provider manages are audited with it as the actor
+	auditCtx := ContextWithAuditActor(ctx, AuditActor{
+		IpAddress:            ipAddress,
+		UserAgent:            userAgent,
+		IdentityProviderID:   provider.ID,
+		IdentityProviderName: provider.Name,
+	})
+
+	user, err := s.resolveUserInternal(auditCtx, provider, claims, tx)
+	if err != nil {
+		return result, err
+	}
+	if user.Disabled {
+		return result, &common.UserDisabledError{}
+	}
+
+	err = s.syncGroupsInternal(auditCtx, provider, user, claims, tx)
+	if err != nil {
+		return result, err
+	}
+
+	session, err := s.sessionService.Create(ctx, user, model.SessionAuthMethodIdentityProvider, false, ipAddress, userAgent, tx)
+	if err != nil {
+		return result, err
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(user, session.ID, s.sessionService.AccessTokenExpiration(session, user.IsAdmin))
+	if err != nil {
+		return result, err
+	}
+
+	s.auditLogService.Create(ctx, model.AuditLogEventIdentityProviderSignIn, ipAddress, userAgent, user.ID, model.AuditLogData{"identityProvider": provider.Name}, tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return result, err
+	}
+
+	result.User = user
+	result.Session = session
+	result.AccessToken = accessToken
+	result.RedirectPath = "/settings"
+	if loginState.RedirectPath != nil {
+		result.RedirectPath = *loginState.RedirectPath
+	}
+
+	return result, nil
+}
+
+func (s *IdentityProviderService) ListUserIdentities(ctx context.Context, userID string) ([]model.UserIdentity, error) {
+	var identities []model.UserIdentity
+	err := s.db.
+		WithContext(ctx).
+		Preload("IdentityProvider").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&identities).
+		Error
+	return identities, err
+}
+
+func (s *IdentityProviderService) DeleteUserIdentity(ctx context.Context, userID string, id string) error {
+	result := s.db.
+		WithContext(ctx).
+		Delete(&model.UserIdentity{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &common.UserIdentityNotFoundError{}
+	}
+	return nil
+}
+
+// linkIdentityInternal links the identity to a signed in user
+func (s *IdentityProviderService) linkIdentityInternal(ctx context.Context, provider model.IdentityProvider, userID string, claims identityProviderClaims, tx *gorm.DB) error {
+	var existing []model.UserIdentity
+	err := tx.
+		WithContext(ctx).
+		Where("identity_provider_id = ? AND (subject = ? OR user_id = ?)", provider.ID, claims.Subject, userID).
+		Find(&existing).
+		Error
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return &common.UserIdentityAlreadyLinkedError{}
+	}
+
+	return s.createIdentityInternal(ctx, provider, userID, claims, tx)
+}
+
+// resolveUserInternal returns the user that the identity is linked to
+// If the identity isn't linked yet, it's linked by the email address or a new user is created, if the identity provider allows it.
+// Admins are never linked by the email address.
+func (s *IdentityProviderService) resolveUserInternal(ctx context.Context, provider model.IdentityProvider, claims identityProviderClaims, tx *gorm.DB) (model.User, error) {
+	var identity model.UserIdentity
+	err := tx.
+		WithContext(ctx).
+		Preload("User").
+		Preload("User.UserGroups").
+		First(&identity, "identity_provider_id = ? AND subject = ?", provider.ID, claims.Subject).
+		Error
+	if err == nil {
+		return identity.User, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, err
+	}
+
+	var user model.User
+	if provider.LinkByEmail && claims.EmailVerified && claims.Email != "" {
+		err = tx.
+			WithContext(ctx).
+			Preload("UserGroups").
+			First(&user, "LOWER(email) = ?", strings.ToLower(claims.Email)).
+			Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, err
+		}
+
+		// A compromised identity provider would otherwise gain access to the admin accounts,
+		// so admins must link their identities from the account page
+		if user.IsAdmin {
+			return model.User{}, &common.IdentityProviderAdminNotLinkedError{}
+		}
+	}
+
+	if user.ID == "" {
+		if !provider.AllowUserCreation {
+			return model.User{}, &common.IdentityProviderUserNotLinkedError{}
+		}
+
+		user, err = s.createUserInternal(ctx, claims, tx)
+		if err != nil {
+			return model.User{}, err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserCreated, userAuditTarget(user), nil, userAuditState(user), tx)
+	}
+
+	err = s.createIdentityInternal(ctx, provider, user.ID, claims, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *IdentityProviderService) createIdentityInternal(ctx context.Context, provider model.IdentityProvider, userID string, claims identityProviderClaims, tx *gorm.DB) error {
+	identity := model.UserIdentity{
+		Subject:            claims.Subject,
+		IdentityProviderID: provider.ID,
+		UserID:             userID,
+	}
+	if claims.Email != "" {
+		identity.Email = &claims.Email
+	}
+
+	return tx.
+		WithContext(ctx).
+		Create(&identity).
+		Error
+}
+
+// createUserInternal creates a user from the claims of the identity provider
+func (s *IdentityProviderService) createUserInternal(ctx context.Context, claims identityProviderClaims, tx *gorm.DB) (model.User, error) {
+	// A user with an unverified email address could take over the accounts of other users at the clients
+	if claims.Email == "" || !claims.EmailVerified {
+		return model.User{}, &common.IdentityProviderLoginError{Message: "the identity provider didn't provide a verified email address"}
+	}
+
+	var count int64
+	err := tx.
+		WithContext(ctx).
+		Model(&model.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(claims.Email)).
+		Count(&count).
+		Error
+	if err != nil {
+		return model.User{}, err
+	}
+	if count > 0 {
+		return model.User{}, &common.AlreadyInUseError{Property: "email"}
+	}
+
+	username, err := s.uniqueUsernameInternal(ctx, claims, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	firstName := claims.GivenName
+	if firstName == "" {
+		firstName = claims.Name
+	}
+	if firstName == "" {
+		firstName = username
+	}
+
+	return s.userService.createUserInternal(ctx, dto.UserCreateDto{
+		Username:  username,
+		Email:     claims.Email,
+		FirstName: truncate(firstName, 50),
+		LastName:  truncate(claims.FamilyName, 50),
+	}, false, tx)
+}
+
+// uniqueUsernameInternal derives a username from the claims that isn't taken yet
+func (s *IdentityProviderService) uniqueUsernameInternal(ctx context.Context, claims identityProviderClaims, tx *gorm.DB) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = invalidUsernameCharsRegex.ReplaceAllString(base, "")
+	base = strings.TrimFunc(base, func(r rune) bool { return r == '_' || r == '.' || r == '@' || r == '-' })
+	base = truncate(base, 40)
+	if len(base) < 2 {
+		base = "user"
+	}
+
+	username := base
+	for range 5 {
+		var count int64
+		err := tx.
+			WithContext(ctx).
+			Model(&model.User{}).
+			Where("username = ?", username).
+			Count(&count).
+			Error
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+
+		suffix, err := utils.GenerateRandomAlphanumericString(4)
+		if err != nil {
+			return "", err
+		}
+		username = base + "-" + strings.ToLower(suffix)
+	}
+
+	return "", &common.AlreadyInUseError{Property: "username"}
+}
+
+// syncGroupsInternal updates the membership of the user in the mapped user groups according to the groups claim
+// User groups that aren't mapped aren't changed
+func (s *IdentityProviderService) syncGroupsInternal(ctx context.Context, provider model.IdentityProvider, user model.User, claims identityProviderClaims, tx *gorm.DB) error {
+	if provider.GroupsClaim == nil || len(provider.GroupMappings) == 0 {
+		return nil
+	}
+
+	upstreamGroups := claims.stringValues(*provider.GroupsClaim)
+
+	desired := make(map[string]bool, len(provider.GroupMappings))
+	for _, mapping := range provider.GroupMappings {
+		desired[mapping.UserGroupID] = desired[mapping.UserGroupID] || slices.Contains(upstreamGroups, mapping.Value)
+	}
+
+	current := make(map[string]bool, len(user.UserGroups))
+	for _, group := range user.UserGroups {
+		current[group.ID] = true
+	}
+
+	var toAdd, toRemove []string
+	for groupID, isMember := range desired {
+		switch {
+		case isMember && !current[groupID]:
+			toAdd = append(toAdd, groupID)
+		case !isMember && current[groupID]:
+			toRemove = append(toRemove, groupID)
+		}
+	}
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		return nil
+	}
+
+	// The association updates the groups of the user in place
+	originalGroups := slices.Clone(user.UserGroups)
+	association := tx.WithContext(ctx).Model(&user).Association("UserGroups")
+
+	var joinedGroups []model.UserGroup
+	if len(toAdd) > 0 {
+		// Mappings of deleted groups are ignored
+		err := tx.
+			WithContext(ctx).
+			Where("id IN ?", toAdd).
+			Find(&joinedGroups).
+			Error
+		if err != nil {
+			return err
+		}
+		if len(joinedGroups) > 0 {
+			err = association.Append(joinedGroups)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var leftGroups []model.UserGroup
+	for _, group := range originalGroups {
+		if slices.Contains(toRemove, group.ID) {
+			leftGroups = append(leftGroups, group)
+		}
+	}
+	if len(leftGroups) > 0 {
+		err := association.Delete(leftGroups)
+		if err != nil {
+			return err
+		}
+	}
+
+	groups := slices.DeleteFunc(slices.Clone(originalGroups), func(group model.UserGroup) bool {
+		return slices.Contains(toRemove, group.ID)
+	})
+	groups = append(groups, joinedGroups...)
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupsUpdated, userAuditTarget(user),
+		userGroupNamesAuditState(originalGroups), userGroupNamesAuditState(groups), tx)
+
+	err := s.scimProvisioningService.enqueueUsersInternal(ctx, []string{user.ID}, tx)
+	if err != nil {
+		return err
+	}
+
+	return s.webhookService.enqueueUserGroupsChangedInternal(ctx, user.ID, joinedGroups, leftGroups, tx)
+}
+
+// identityProviderMetadata contains the parts of the OpenID Provider Metadata that are used
+type identityProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// discover loads the metadata of the identity provider from its discovery endpoint
+func (s *IdentityProviderService) discover(ctx context.Context, provider model.IdentityProvider) (identityProviderMetadata, error) {
+	discoveryURL := strings.TrimSuffix(provider.Issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return identityProviderMetadata{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to load the configuration of identity provider '%s': %v", provider.Name, err)
+		return identityProviderMetadata{}, &common.IdentityProviderLoginError{Message: "failed to load the configuration of the identity provider"}
+	}
+	defer res.Body.Close()
+
+	var metadata identityProviderMetadata
+	if res.StatusCode != http.StatusOK || json.NewDecoder(res.Body).Decode(&metadata) != nil {
+		log.Printf("Failed to load the configuration of identity provider '%s': unexpected response with status %d", provider.Name, res.StatusCode)
+		return identityProviderMetadata{}, &common.IdentityProviderLoginError{Message: "failed to load the configuration of the identity provider"}
+	}
+
+	if metadata.Issuer != provider.Issuer {
+		return identityProviderMetadata{}, &common.IdentityProviderLoginError{Message: "the issuer of the identity provider doesn't match its configuration"}
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksURI == "" {
+		return identityProviderMetadata{}, &common.IdentityProviderLoginError{Message: "the configuration of the identity provider is incomplete"}
+	}
+
+	return metadata, nil
+}
+
+// exchangeCode redeems the authorization code at the token endpoint of the identity provider and returns the ID token
+func (s *IdentityProviderService) exchangeCode(ctx context.Context, provider model.IdentityProvider, metadata identityProviderMetadata, code string, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {IdentityProviderCallbackURL()},
+		"code_verifier": {codeVerifier},
+	}
+
+	// client_secret_basic is the default authentication method, unless the provider only supports client_secret_post
+	useBasicAuth := slices.Contains(metadata.TokenEndpointAuthMethodsSupported, "client_secret_basic") ||
+		!slices.Contains(metadata.TokenEndpointAuthMethodsSupported, "client_secret_post")
+	if !useBasicAuth {
+		form.Set("client_id", provider.ClientID)
+		form.Set("client_secret", provider.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasicAuth {
+		req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to redeem the authorization code at identity provider '%s': %v", provider.Name, err)
+		return "", &common.IdentityProviderLoginError{Message: "failed to redeem the authorization code"}
+	}
+	defer res.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	_ = json.Unmarshal(body, &tokenResponse)
+
+	if res.StatusCode != http.StatusOK {
+		message := tokenResponse.ErrorDescription
+		if message == "" {
+			message = tokenResponse.Error
+		}
+		if message == "" {
+			message = http.StatusText(res.StatusCode)
+		}
+		return "", &common.IdentityProviderLoginError{Message: "failed to redeem the authorization code: " + message}
+	}
+	if tokenResponse.IDToken == "" {
+		return "", &common.IdentityProviderLoginError{Message: "the identity provider didn't return an ID token"}
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// identityProviderClaims are the claims of an ID token issued by an identity provider
+type identityProviderClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+
+	all map[string]any
+}
+
+// stringValues returns the values of a claim that is either a list of strings or a single string
+func (c identityProviderClaims) stringValues(name string) []string {
+	switch v := c.all[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func (c identityProviderClaims) string(name string) string {
+	s, _ := c.all[name].(string)
+	return s
+}
+
+// verifyIDToken verifies the signature and the claims of the ID token with the JWKS of the identity provider
+func (s *IdentityProviderService) verifyIDToken(ctx context.Context, provider model.IdentityProvider, metadata identityProviderMetadata, rawIDToken string, nonce string) (identityProviderClaims, error) {
+	jwks, err := s.oidcService.jwkSetForURL(ctx, metadata.JwksURI)
+	if err != nil {
+		log.Printf("Failed to load the JWKS of identity provider '%s': %v", provider.Name, err)
+		return identityProviderClaims{}, &common.IdentityProviderLoginError{Message: "failed to load the keys of the identity provider"}
+	}
+
+	token, err := jwt.ParseString(rawIDToken,
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(clockSkew),
+		jwt.WithKeySet(jwks, jws.WithInferAlgorithmFromKey(true), jws.WithUseDefault(true)),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(provider.ClientID),
+	)
+	if err != nil {
+		log.Printf("Invalid ID token from identity provider '%s': %v", provider.Name, err)
+		return identityProviderClaims{}, &common.IdentityProviderLoginError{Message: "invalid ID token"}
+	}
+
+	encoded, err := json.Marshal(token)
+	if err != nil {
+		return identityProviderClaims{}, fmt.Errorf("failed to encode ID token claims: %w", err)
+	}
+	claims := identityProviderClaims{}
+	err = json.Unmarshal(encoded, &claims.all)
+	if err != nil {
+		return identityProviderClaims{}, fmt.Errorf("failed to decode ID token claims: %w", err)
+	}
+
+	if claims.string("nonce") != nonce {
+		return identityProviderClaims{}, &common.IdentityProviderLoginError{Message: "invalid nonce"}
+	}
+
+	claims.Subject = claims.string("sub")
+	if claims.Subject == "" {
+		return identityProviderClaims{}, &common.IdentityProviderLoginError{Message: "the ID token doesn't contain a subject"}
+	}
+	claims.Email = claims.string("email")
+	claims.Name = claims.string("name")
+	claims.GivenName = claims.string("given_name")
+	claims.FamilyName = claims.string("family_name")
+	claims.PreferredUsername = claims.string("preferred_username")
+
+	// Some providers return the email_verified claim as string
+	switch v := claims.all["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string:
+		claims.EmailVerified = v == "true"
+	}
+
+	return claims, nil
+}
+
+// truncate shortens the string to the given number of characters
+func truncate(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	return string(runes[:maxLength])
+}
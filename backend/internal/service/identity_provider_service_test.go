@@ -0,0 +1,309 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+// fakeIdentityProvider is a minimal OIDC provider that issues ID tokens with the configured claims
+type fakeIdentityProvider struct {
+	t      *testing.T
+	issuer string
+	key    jwk.Key
+	claims map[string]any
+	// nonces maps the issued authorization codes to the nonces of the authorization requests
+	nonces map[string]string
+}
+
+func (f *fakeIdentityProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 f.issuer,
+			"authorization_endpoint": f.issuer + "/authorize",
+			"token_endpoint":         f.issuer + "/token",
+			"jwks_uri":               f.issuer + "/jwks",
+		})
+	case "/jwks":
+		publicKey, err := f.key.PublicKey()
+		require.NoError(f.t, err)
+		set := jwk.NewSet()
+		require.NoError(f.t, set.AddKey(publicKey))
+		_ = json.NewEncoder(w).Encode(set)
+	case "/token":
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "pocket-id" || clientSecret != "secret" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+
+		builder := jwt.NewBuilder().
+			Issuer(f.issuer).
+			Audience([]string{clientID}).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(time.Minute)).
+			Claim("nonce", f.nonces[r.FormValue("code")])
+		for name, value := range f.claims {
+			builder = builder.Claim(name, value)
+		}
+		token, err := builder.Build()
+		require.NoError(f.t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256(), f.key))
+		require.NoError(f.t, err)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"id_token": string(signed)})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// authorize simulates the user signing in at the identity provider and returns the code and state of the callback
+func (f *fakeIdentityProvider) authorize(t *testing.T, authorizationURL string) (code string, state string) {
+	t.Helper()
+	u, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	require.Equal(t, f.issuer+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	code = "code-" + u.Query().Get("state")
+	f.nonces[code] = u.Query().Get("nonce")
+	return code, u.Query().Get("state")
+}
+
+func TestIdentityProviderService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	rawKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := jwk.Import(rawKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, "test"))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RS256()))
+
+	idp := &fakeIdentityProvider{t: t, key: key, nonces: map[string]string{}}
+	server := httptest.NewServer(idp)
+	t.Cleanup(server.Close)
+	idp.issuer = server.URL
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"},
+	})
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(appConfig, common.EnvConfig.KeysPath))
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	scimProvisioningService := NewScimProvisioningService(db, nil)
+	userService := NewUserService(db, jwtService, auditLogService, nil, appConfig, sessionService, scimProvisioningService, webhookService)
+	oidcService := &OidcService{db: db, httpClient: server.Client()}
+	oidcService.jwkCache, err = oidcService.getJWKCache(t.Context())
+	require.NoError(t, err)
+	service := NewIdentityProviderService(db, server.Client(), jwtService, oidcService, userService, sessionService, auditLogService, scimProvisioningService, webhookService)
+
+	tim := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+	require.NoError(t, db.Create(&tim).Error)
+	developers := model.UserGroup{Name: "developers", FriendlyName: "Developers"}
+	require.NoError(t, db.Create(&developers).Error)
+	admins := model.UserGroup{Name: "admins", FriendlyName: "Admins"}
+	require.NoError(t, db.Create(&admins).Error)
+
+	provider, err := service.CreateProvider(t.Context(), dto.IdentityProviderCreateDto{
+		Name:         "Corporate",
+		Issuer:       server.URL,
+		ClientID:     "pocket-id",
+		ClientSecret: "secret",
+		Scopes:       "email profile",
+		Enabled:      true,
+		LinkByEmail:  true,
+		GroupsClaim:  "groups",
+		GroupMappings: []dto.IdentityProviderGroupMappingDto{
+			{Value: "engineering", UserGroupID: developers.ID},
+			{Value: "it-admins", UserGroupID: admins.ID},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "openid email profile", provider.Scopes)
+
+	signIn := func(t *testing.T, userID string, claims map[string]any) (IdentityProviderLoginResult, error) {
+		t.Helper()
+		idp.claims = claims
+		authorizationURL, state, err := service.BeginLogin(t.Context(), provider.ID, userID, "/authorize?client_id=abc")
+		require.NoError(t, err)
+		code, returnedState := idp.authorize(t, authorizationURL)
+		require.Equal(t, state, returnedState)
+		return service.FinishLogin(t.Context(), state, code, "127.0.0.1", "test-agent")
+	}
+
+	// adminEvent returns the last admin event of the target
+	adminEvent := func(t *testing.T, event model.AuditLogEvent, targetID string) model.AuditLog {
+		t.Helper()
+		var auditLogs []model.AuditLog
+		require.NoError(t, db.Where("event = ?", event).Order("created_at DESC").Find(&auditLogs).Error)
+		for _, auditLog := range auditLogs {
+			if auditLog.Data["targetId"] == targetID {
+				return auditLog
+			}
+		}
+		require.FailNow(t, "admin event not found", "%s of %s", event, targetID)
+		return model.AuditLog{}
+	}
+
+	t.Run("links an existing user by verified email and maps groups", func(t *testing.T) {
+		result, err := signIn(t, "", map[string]any{
+			"sub":            "upstream-tim",
+			"email":          "TIM@example.com",
+			"email_verified": true,
+			"groups":         []string{"engineering"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tim.ID, result.User.ID)
+		assert.NotEmpty(t, result.AccessToken)
+		assert.Equal(t, "/authorize?client_id=abc", result.RedirectPath)
+
+		groups, err := userService.GetUserGroups(t.Context(), tim.ID)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, developers.ID, groups[0].ID)
+
+		// The identity provider is the actor of the group sync
+		auditLog := adminEvent(t, model.AuditLogEventUserGroupsUpdated, tim.ID)
+		assert.Empty(t, auditLog.UserID)
+		assert.Equal(t, provider.ID, auditLog.Data["actorIdentityProviderId"])
+		assert.Equal(t, "Corporate", auditLog.Data["actorIdentityProvider"])
+		assert.JSONEq(t, `{"userGroups": {"before": [], "after": ["developers"]}}`, auditLog.Data["changes"])
+	})
+
+	t.Run("removes mapped groups that aren't in the claim anymore", func(t *testing.T) {
+		result, err := signIn(t, "", map[string]any{
+			"sub":    "upstream-tim",
+			"groups": []string{"it-admins"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tim.ID, result.User.ID)
+
+		groups, err := userService.GetUserGroups(t.Context(), tim.ID)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, admins.ID, groups[0].ID)
+	})
+
+	t.Run("doesn't link users by unverified email", func(t *testing.T) {
+		_, err := signIn(t, "", map[string]any{
+			"sub":            "upstream-attacker",
+			"email":          "tim@example.com",
+			"email_verified": false,
+		})
+		var notLinkedErr *common.IdentityProviderUserNotLinkedError
+		require.ErrorAs(t, err, &notLinkedErr)
+	})
+
+	t.Run("doesn't link admins by email", func(t *testing.T) {
+		alice := model.User{Username: "alice", Email: "alice@example.com", FirstName: "Alice", IsAdmin: true}
+		require.NoError(t, db.Create(&alice).Error)
+
+		_, err := signIn(t, "", map[string]any{
+			"sub":            "upstream-alice",
+			"email":          "alice@example.com",
+			"email_verified": true,
+		})
+		var adminNotLinkedErr *common.IdentityProviderAdminNotLinkedError
+		require.ErrorAs(t, err, &adminNotLinkedErr)
+
+		// Admins can link their identity explicitly
+		result, err := signIn(t, alice.ID, map[string]any{"sub": "upstream-alice", "email": "alice@example.com"})
+		require.NoError(t, err)
+		assert.True(t, result.Linking)
+
+		result, err = signIn(t, "", map[string]any{"sub": "upstream-alice"})
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, result.User.ID)
+	})
+
+	t.Run("creates users just in time if allowed", func(t *testing.T) {
+		provider.AllowUserCreation = true
+		require.NoError(t, db.Save(&provider).Error)
+
+		result, err := signIn(t, "", map[string]any{
+			"sub":                "upstream-craig",
+			"email":              "craig@example.com",
+			"email_verified":     "true",
+			"preferred_username": "tim",
+			"given_name":         "Craig",
+			"family_name":        "Federighi",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "craig@example.com", result.User.Email)
+		assert.Equal(t, "Craig", result.User.FirstName)
+		// The preferred username is already taken
+		assert.Regexp(t, `^tim-[a-z0-9]{4}$`, result.User.Username)
+
+		auditLog := adminEvent(t, model.AuditLogEventUserCreated, result.User.ID)
+		assert.Empty(t, auditLog.UserID)
+		assert.Equal(t, provider.ID, auditLog.Data["actorIdentityProviderId"])
+		assert.Equal(t, "Corporate", auditLog.Data["actorIdentityProvider"])
+	})
+
+	t.Run("links the identity to a signed in user", func(t *testing.T) {
+		lisa := model.User{Username: "lisa", Email: "lisa@example.com", FirstName: "Lisa"}
+		require.NoError(t, db.Create(&lisa).Error)
+
+		result, err := signIn(t, lisa.ID, map[string]any{"sub": "upstream-lisa", "email": "lisa@corp.example.com"})
+		require.NoError(t, err)
+		assert.True(t, result.Linking)
+		assert.Empty(t, result.AccessToken)
+
+		identities, err := service.ListUserIdentities(t.Context(), lisa.ID)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		assert.Equal(t, "Corporate", identities[0].IdentityProvider.Name)
+
+		_, _, err = service.BeginLogin(t.Context(), provider.ID, lisa.ID, "")
+		var alreadyLinkedErr *common.UserIdentityAlreadyLinkedError
+		require.ErrorAs(t, err, &alreadyLinkedErr)
+
+		require.NoError(t, service.DeleteUserIdentity(t.Context(), lisa.ID, identities[0].ID))
+	})
+
+	t.Run("rejects reused states and wrong nonces", func(t *testing.T) {
+		idp.claims = map[string]any{"sub": "upstream-tim"}
+		authorizationURL, state, err := service.BeginLogin(t.Context(), provider.ID, "", "")
+		require.NoError(t, err)
+		code, _ := idp.authorize(t, authorizationURL)
+		idp.nonces[code] = "wrong"
+
+		_, err = service.FinishLogin(t.Context(), state, code, "127.0.0.1", "test-agent")
+		var loginErr *common.IdentityProviderLoginError
+		require.ErrorAs(t, err, &loginErr)
+		assert.Contains(t, loginErr.Message, "nonce")
+
+		_, err = service.FinishLogin(t.Context(), state, code, "127.0.0.1", "test-agent")
+		require.ErrorAs(t, err, &loginErr)
+		assert.Contains(t, loginErr.Message, "state")
+	})
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/json"
@@ -42,6 +43,9 @@ const (
 	// RefreshTokenClaim is the claim used for the refresh token's value
 	RefreshTokenClaim = "rt"
 
+	// SessionIDClaim is the claim used in access tokens for the ID of the server-side session
+	SessionIDClaim = "sid"
+
 	// OAuthAccessTokenJWTType identifies a JWT as an OAuth access token
 	OAuthAccessTokenJWTType = "oauth-access-token" //nolint:gosec
 
@@ -54,6 +58,12 @@ const (
 	// IDTokenJWTType identifies a JWT as an ID token used by Pocket ID
 	IDTokenJWTType = "id-token"
 
+	// ForwardAuthTokenJWTType identifies a JWT as a token that grants access to a host protected by forward-auth
+	ForwardAuthTokenJWTType = "forward-auth-token" //nolint:gosec
+
+	// AuditLogCheckpointJWTType identifies a JWT as the signature of an audit log checkpoint
+	AuditLogCheckpointJWTType = "audit-log-checkpoint"
+
 	// Acceptable clock skew for verifying tokens
 	clockSkew = time.Minute
 )
@@ -186,11 +196,12 @@ func (s *JwtService) SetKey(privateKey jwk.Key) error {
 	return nil
 }
 
-func (s *JwtService) GenerateAccessToken(user model.User) (string, error) {
+// GenerateAccessToken creates an access token for the user's session, which expires at the given time
+func (s *JwtService) GenerateAccessToken(user model.User, sessionID string, expiration time.Time) (string, error) {
 	now := time.Now()
 	token, err := jwt.NewBuilder().
 		Subject(user.ID).
-		Expiration(now.Add(s.appConfigService.GetDbConfig().SessionDuration.AsDurationMinutes())).
+		Expiration(expiration).
 		IssuedAt(now).
 		Issuer(common.EnvConfig.AppURL).
 		Build()
@@ -213,6 +224,11 @@ func (s *JwtService) GenerateAccessToken(user model.User) (string, error) {
 		return "", fmt.Errorf("failed to set 'isAdmin' claim in token: %w", err)
 	}
 
+	err = token.Set(SessionIDClaim, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'sid' claim in token: %w", err)
+	}
+
 	alg, _ := s.privateKey.Algorithm()
 	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
 	if err != nil {
@@ -240,6 +256,127 @@ func (s *JwtService) VerifyAccessToken(tokenString string) (jwt.Token, error) {
 	return token, nil
 }
 
+// GenerateForwardAuthToken creates a token for the user's session that is only valid for the given host protected by forward-auth
+func (s *JwtService) GenerateForwardAuthToken(user model.User, sessionID string, host string, expiration time.Time) (string, error) {
+	now := time.Now()
+	token, err := jwt.NewBuilder().
+		Subject(user.ID).
+		Expiration(expiration).
+		IssuedAt(now).
+		Issuer(common.EnvConfig.AppURL).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build token: %w", err)
+	}
+
+	err = SetAudienceString(token, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'aud' claim in token: %w", err)
+	}
+
+	err = SetTokenType(token, ForwardAuthTokenJWTType)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'type' claim in token: %w", err)
+	}
+
+	err = token.Set(SessionIDClaim, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'sid' claim in token: %w", err)
+	}
+
+	alg, _ := s.privateKey.Algorithm()
+	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+// VerifyForwardAuthToken verifies a forward-auth token that was issued for the given host
+func (s *JwtService) VerifyForwardAuthToken(tokenString string, host string) (jwt.Token, error) {
+	alg, _ := s.privateKey.Algorithm()
+	token, err := jwt.ParseString(
+		tokenString,
+		jwt.WithValidate(true),
+		jwt.WithKey(alg, s.privateKey),
+		jwt.WithAcceptableSkew(clockSkew),
+		jwt.WithAudience(host),
+		jwt.WithIssuer(common.EnvConfig.AppURL),
+		jwt.WithValidator(TokenTypeValidator(ForwardAuthTokenJWTType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GenerateAuditLogCheckpointToken signs the fields of an audit log checkpoint, so that it can't be forged without the key.
+// The token doesn't expire, as the checkpoints must be verifiable for as long as the audit logs are kept.
+func (s *JwtService) GenerateAuditLogCheckpointToken(checkpoint model.AuditLogCheckpoint) (string, error) {
+	token, err := jwt.NewBuilder().
+		JwtID(checkpoint.ID).
+		IssuedAt(time.Now()).
+		Claim("n", checkpoint.Number).
+		Claim("seq", checkpoint.Sequence).
+		Claim("hash", checkpoint.Hash).
+		Claim("purgeDigest", checkpoint.PurgeDigest).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build token: %w", err)
+	}
+
+	err = SetTokenType(token, AuditLogCheckpointJWTType)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'type' claim in token: %w", err)
+	}
+
+	alg, _ := s.privateKey.Algorithm()
+	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+// VerifyAuditLogCheckpointToken verifies the signature of an audit log checkpoint and checks that it contains the
+// fields of the checkpoint
+func (s *JwtService) VerifyAuditLogCheckpointToken(checkpoint model.AuditLogCheckpoint) error {
+	alg, _ := s.privateKey.Algorithm()
+	token, err := jwt.ParseString(
+		checkpoint.Signature,
+		jwt.WithValidate(true),
+		jwt.WithKey(alg, s.privateKey),
+		jwt.WithAcceptableSkew(clockSkew),
+		jwt.WithValidator(TokenTypeValidator(AuditLogCheckpointJWTType)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	// Numeric claims are always decoded as float64
+	var number, sequence float64
+	var hash, purgeDigest string
+	jti, _ := token.JwtID()
+	err = errors.Join(
+		token.Get("n", &number),
+		token.Get("seq", &sequence),
+		token.Get("hash", &hash),
+		token.Get("purgeDigest", &purgeDigest),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get checkpoint claims: %w", err)
+	}
+
+	if jti != checkpoint.ID || int64(number) != checkpoint.Number || int64(sequence) != checkpoint.Sequence || hash != checkpoint.Hash || purgeDigest != checkpoint.PurgeDigest {
+		return errors.New("the claims of the token don't match the checkpoint")
+	}
+
+	return nil
+}
+
 // BuildIDToken creates an ID token with all claims
 func (s *JwtService) BuildIDToken(userClaims map[string]any, clientID string, nonce string) (jwt.Token, error) {
 	now := time.Now()
@@ -517,6 +654,26 @@ func (s *JwtService) GetKeyAlg() (jwa.KeyAlgorithm, error) {
 	return alg, nil
 }
 
+// GetSigner returns the private key as a crypto.Signer, so it can be used outside of JWTs, e.g. to sign SAML assertions
+func (s *JwtService) GetSigner() (crypto.Signer, error) {
+	if s.privateKey == nil {
+		return nil, errors.New("key is not initialized")
+	}
+
+	var rawKey any
+	err := jwk.Export(s.privateKey, &rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export private key: %w", err)
+	}
+
+	signer, ok := rawKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key of type %T can't be used as a signer", rawKey)
+	}
+
+	return signer, nil
+}
+
 func (s *JwtService) loadKeyJWK(path string) (jwk.Key, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -580,6 +737,16 @@ func GetIsAdmin(token jwt.Token) (bool, error) {
 	return isAdmin, nil
 }
 
+// GetSessionID returns the value of the "sid" claim in the token
+func GetSessionID(token jwt.Token) (string, error) {
+	var sessionID string
+	err := token.Get(SessionIDClaim, &sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get 'sid' claim from token: %w", err)
+	}
+	return sessionID, nil
+}
+
 // SetTokenType sets the "type" claim in the token
 func SetTokenType(token jwt.Token, tokenType string) error {
 	if tokenType == "" {
@@ -298,7 +298,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, "session123", time.Now().Add(time.Hour))
 		require.NoError(t, err, "Failed to generate access token")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -341,7 +341,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(adminUser)
+		tokenString, err := service.GenerateAccessToken(adminUser, "session123", time.Now().Add(time.Hour))
 		require.NoError(t, err, "Failed to generate access token")
 
 		// Verify the token
@@ -357,14 +357,9 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 			assert.Equal(t, adminUser.ID, subject, "Token subject should match user ID")
 	})
 
-	t.Run("uses session duration from config", func(t *testing.T) {
-		// Create a JWT service with a different session duration
-		customMockConfig := NewTestAppConfigService(&model.AppConfig{
-			SessionDuration: model.AppConfigVariable{Value: "30"}, // 30 minutes
-		})
-
+	t.Run("uses the given expiration", func(t *testing.T) {
 		service := &JwtService{}
-		err := service.init(customMockConfig, tempDir)
+		err := service.init(mockConfig, tempDir)
 		require.NoError(t, err, "Failed to initialize JWT service")
 
 		// Create a test user
@@ -375,7 +370,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, "session123", time.Now().Add(30*time.Minute))
 		require.NoError(t, err, "Failed to generate access token")
 
 		// Verify the token
@@ -417,7 +412,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, "session123", time.Now().Add(time.Hour))
 		require.NoError(t, err, "Failed to generate access token with Ed25519 key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -471,7 +466,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, "session123", time.Now().Add(time.Hour))
 		require.NoError(t, err, "Failed to generate access token with ECDSA key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -525,7 +520,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, "session123", time.Now().Add(time.Hour))
 		require.NoError(t, err, "Failed to generate access token with RSA key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -0,0 +1,625 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+const (
+	LdapScopeBaseObject   = 0
+	LdapScopeSingleLevel  = 1
+	LdapScopeWholeSubtree = 2
+)
+
+// Attributes whose values are DNs and have to be compared as such
+var ldapDNAttributes = map[string]bool{
+	"member":       true,
+	"uniquemember": true,
+	"memberof":     true,
+}
+
+// LdapServerService exposes the users and groups as a read-only LDAP directory:
+//
+//	<base DN>
+//	├── ou=users: uid=<username>,ou=users,<base DN>
+//	└── ou=groups: cn=<name>,ou=groups,<base DN>
+type LdapServerService struct {
+	db                 *gorm.DB
+	apiKeyService      *ApiKeyService
+	appPasswordService *AppPasswordService
+	auditLogService    *AuditLogService
+	baseDN             string
+
+	// The failed binds are throttled per client IP address and per user, to prevent brute-force attacks
+	ipAddressBindThrottle *ldapBindThrottle
+	userBindThrottle      *ldapBindThrottle
+}
+
+func NewLdapServerService(db *gorm.DB, apiKeyService *ApiKeyService, appPasswordService *AppPasswordService, auditLogService *AuditLogService, baseDN string) *LdapServerService {
+	return &LdapServerService{
+		db:                    db,
+		apiKeyService:         apiKeyService,
+		appPasswordService:    appPasswordService,
+		auditLogService:       auditLogService,
+		baseDN:                baseDN,
+		ipAddressBindThrottle: newLdapBindThrottle(rate.Every(6*time.Second), 10),
+		userBindThrottle:      newLdapBindThrottle(rate.Every(30*time.Second), 10),
+	}
+}
+
+type LdapSearchRequest struct {
+	BaseDN     string
+	Scope      int64
+	Filter     string
+	Attributes []string
+	SizeLimit  int64
+	TypesOnly  bool
+}
+
+type LdapEntry struct {
+	DN         string
+	Attributes []LdapAttribute
+}
+
+type LdapAttribute struct {
+	Name   string
+	Values []string
+}
+
+// Get returns the values of the attribute with the given name, which is case-insensitive
+func (e LdapEntry) Get(name string) []string {
+	for _, attr := range e.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Values
+		}
+	}
+	return nil
+}
+
+func (s *LdapServerService) UsersDN() string {
+	return "ou=users," + s.baseDN
+}
+
+func (s *LdapServerService) GroupsDN() string {
+	return "ou=groups," + s.baseDN
+}
+
+func (s *LdapServerService) userDN(username string) string {
+	return "uid=" + ldap.EscapeDN(username) + "," + s.UsersDN()
+}
+
+func (s *LdapServerService) groupDN(name string) string {
+	return "cn=" + ldap.EscapeDN(name) + "," + s.GroupsDN()
+}
+
+// Bind authenticates a user with the DN of the user and one of their app passwords or API keys.
+// It returns an empty user ID for anonymous binds.
+// Failed binds are recorded in the audit log and throttled per client IP address and per user.
+func (s *LdapServerService) Bind(ctx context.Context, bindDN string, password string, ipAddress string) (userID string, err error) {
+	userKey := strings.ToLower(bindDN)
+	if username, ok := s.usernameFromDN(bindDN); ok {
+		userKey = strings.ToLower(username)
+	}
+
+	if !s.ipAddressBindThrottle.allowed(ipAddress) || !s.userBindThrottle.allowed(userKey) {
+		return "", &common.LdapServerTooManyBindsError{}
+	}
+
+	user, err := s.verifyBindCredentials(ctx, bindDN, password)
+	var invalidCredentialsErr *common.LdapServerInvalidCredentialsError
+	if errors.As(err, &invalidCredentialsErr) {
+		s.ipAddressBindThrottle.recordFailure(ipAddress)
+		s.userBindThrottle.recordFailure(userKey)
+		s.auditLogService.Create(ctx, model.AuditLogEventLdapBindFailed, ipAddress, "", user.ID, model.AuditLogData{"bindDn": bindDN}, s.db)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
+// verifyBindCredentials returns the user of the bind DN if the password is valid.
+// If the credentials are invalid, the user is still returned if it exists, so the failed bind can be attributed to it.
+func (s *LdapServerService) verifyBindCredentials(ctx context.Context, bindDN string, password string) (model.User, error) {
+	if bindDN == "" {
+		// Unauthenticated binds with a password but without a DN aren't allowed (RFC 4513 section 5.1.2)
+		if password != "" {
+			return model.User{}, &common.LdapServerInvalidCredentialsError{}
+		}
+		return model.User{}, nil
+	}
+
+	username, ok := s.usernameFromDN(bindDN)
+	if !ok {
+		return model.User{}, &common.LdapServerInvalidCredentialsError{}
+	}
+
+	var user model.User
+	err := s.db.
+		WithContext(ctx).
+		Where("username = ?", username).
+		First(&user).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, &common.LdapServerInvalidCredentialsError{}
+	} else if err != nil {
+		return model.User{}, err
+	}
+	if user.Disabled || password == "" {
+		return user, &common.LdapServerInvalidCredentialsError{}
+	}
+
+	valid, err := s.appPasswordService.ValidateAppPassword(ctx, user.ID, password)
+	if err != nil {
+		return user, err
+	}
+	if valid {
+		return user, nil
+	}
+
+	apiKeyUser, err := s.apiKeyService.ValidateApiKey(ctx, password)
+	var invalidAPIKeyErr *common.InvalidAPIKeyError
+	if errors.As(err, &invalidAPIKeyErr) {
+		return user, &common.LdapServerInvalidCredentialsError{}
+	} else if err != nil {
+		return user, err
+	}
+	if apiKeyUser.ID != user.ID {
+		return user, &common.LdapServerInvalidCredentialsError{}
+	}
+
+	return user, nil
+}
+
+// ldapBindThrottle limits the failed binds per key. Every key can fail burst times in a row, after that it has to
+// wait until the limit allows another attempt.
+type ldapBindThrottle struct {
+	limit     rate.Limit
+	burst     int
+	limiters  map[string]*rate.Limiter
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func newLdapBindThrottle(limit rate.Limit, burst int) *ldapBindThrottle {
+	return &ldapBindThrottle{
+		limit:     limit,
+		burst:     burst,
+		limiters:  make(map[string]*rate.Limiter),
+		lastPrune: time.Now(),
+	}
+}
+
+// allowed returns whether another bind may be attempted for the key, without counting it
+func (t *ldapBindThrottle) allowed(key string) bool {
+	if key == "" {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limiter, ok := t.limiters[key]
+	return !ok || limiter.Tokens() >= 1
+}
+
+// recordFailure counts a failed bind for the key
+func (t *ldapBindThrottle) recordFailure(key string) {
+	if key == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Forget the keys that are allowed to fail burst times again, so the map doesn't grow indefinitely
+	if time.Since(t.lastPrune) > time.Minute {
+		for k, limiter := range t.limiters {
+			if limiter.Tokens() >= float64(t.burst) {
+				delete(t.limiters, k)
+			}
+		}
+		t.lastPrune = time.Now()
+	}
+
+	limiter, ok := t.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(t.limit, t.burst)
+		t.limiters[key] = limiter
+	}
+	limiter.Allow()
+}
+
+// usernameFromDN extracts the username from a DN like uid=<username>,ou=users,<base DN>
+func (s *LdapServerService) usernameFromDN(dn string) (string, bool) {
+	parsedDN, err := ldap.ParseDN(dn)
+	if err != nil || len(parsedDN.RDNs) < 2 {
+		return "", false
+	}
+
+	usersDN, err := ldap.ParseDN(s.UsersDN())
+	if err != nil {
+		return "", false
+	}
+
+	parentDN := &ldap.DN{RDNs: parsedDN.RDNs[1:]}
+	rdn := parsedDN.RDNs[0]
+	if !parentDN.EqualFold(usersDN) || len(rdn.Attributes) != 1 || !strings.EqualFold(rdn.Attributes[0].Type, "uid") {
+		return "", false
+	}
+
+	return rdn.Attributes[0].Value, true
+}
+
+// Search returns the entries matching the search request that are visible to the bound user.
+// Admins can see the whole directory, other users only see themselves and their groups.
+// The second return value is true if the result has been truncated because of the size limit.
+func (s *LdapServerService) Search(ctx context.Context, userID string, req LdapSearchRequest) ([]LdapEntry, bool, error) {
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, false, &common.LdapServerInvalidFilterError{Message: err.Error()}
+	}
+
+	// The root DSE can be read without binding, as clients use it to discover the naming context
+	if req.BaseDN == "" && req.Scope == LdapScopeBaseObject {
+		entries := []LdapEntry{s.rootDSE()}
+		return s.selectEntries(entries, nil, filter, req), false, nil
+	}
+
+	if userID == "" {
+		return nil, false, &common.LdapServerInsufficientAccessError{}
+	}
+
+	baseDN, err := ldap.ParseDN(req.BaseDN)
+	if err != nil {
+		return nil, false, &common.LdapServerNoSuchObjectError{}
+	}
+
+	entries, err := s.getVisibleEntries(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var baseExists bool
+	for _, entry := range entries {
+		entryDN, err := ldap.ParseDN(entry.DN)
+		if err == nil && entryDN.EqualFold(baseDN) {
+			baseExists = true
+			break
+		}
+	}
+	if !baseExists {
+		return nil, false, &common.LdapServerNoSuchObjectError{}
+	}
+
+	result := s.selectEntries(entries, baseDN, filter, req)
+	if req.SizeLimit > 0 && int64(len(result)) > req.SizeLimit {
+		return result[:req.SizeLimit], true, nil
+	}
+
+	return result, false, nil
+}
+
+// selectEntries returns the entries that are in the scope of the search and match the filter,
+// reduced to the requested attributes
+func (s *LdapServerService) selectEntries(entries []LdapEntry, baseDN *ldap.DN, filter *ber.Packet, req LdapSearchRequest) []LdapEntry {
+	result := make([]LdapEntry, 0, len(entries))
+	for _, entry := range entries {
+		if baseDN != nil {
+			entryDN, err := ldap.ParseDN(entry.DN)
+			if err != nil || !ldapDNInScope(entryDN, baseDN, req.Scope) {
+				continue
+			}
+		}
+
+		if !ldapFilterMatches(filter, entry) {
+			continue
+		}
+
+		result = append(result, selectLdapAttributes(entry, req.Attributes, req.TypesOnly))
+	}
+
+	return result
+}
+
+func (s *LdapServerService) getVisibleEntries(ctx context.Context, userID string) ([]LdapEntry, error) {
+	var boundUser model.User
+	err := s.db.
+		WithContext(ctx).
+		Where("id = ?", userID).
+		First(&boundUser).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &common.LdapServerInsufficientAccessError{}
+	} else if err != nil {
+		return nil, err
+	}
+	if boundUser.Disabled {
+		return nil, &common.LdapServerInsufficientAccessError{}
+	}
+
+	// Disabled users are hidden from the directory, so that applications don't treat them as valid accounts
+	usersQuery := s.db.
+		WithContext(ctx).
+		Preload("UserGroups").
+		Where("disabled = ?", false).
+		Order("username")
+	groupsQuery := s.db.
+		WithContext(ctx).
+		Preload("Users", "disabled = ?", false).
+		Order("name")
+	if !boundUser.IsAdmin {
+		usersQuery = usersQuery.Where("id = ?", boundUser.ID)
+		groupsQuery = groupsQuery.Where("id IN (SELECT user_group_id FROM user_groups_users WHERE user_id = ?)", boundUser.ID)
+	}
+
+	var users []model.User
+	err = usersQuery.Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []model.UserGroup
+	err = groupsQuery.Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LdapEntry, 0, len(users)+len(groups)+3)
+	entries = append(entries,
+		s.baseEntry(),
+		LdapEntry{
+			DN: s.UsersDN(),
+			Attributes: []LdapAttribute{
+				{Name: "objectClass", Values: []string{"top", "organizationalUnit"}},
+				{Name: "ou", Values: []string{"users"}},
+			},
+		},
+		LdapEntry{
+			DN: s.GroupsDN(),
+			Attributes: []LdapAttribute{
+				{Name: "objectClass", Values: []string{"top", "organizationalUnit"}},
+				{Name: "ou", Values: []string{"groups"}},
+			},
+		},
+	)
+
+	for _, user := range users {
+		entries = append(entries, s.userEntry(user))
+	}
+	for _, group := range groups {
+		entries = append(entries, s.groupEntry(group))
+	}
+
+	return entries, nil
+}
+
+func (s *LdapServerService) rootDSE() LdapEntry {
+	return LdapEntry{
+		DN: "",
+		Attributes: []LdapAttribute{
+			{Name: "objectClass", Values: []string{"top"}},
+			{Name: "namingContexts", Values: []string{s.baseDN}},
+			{Name: "supportedLDAPVersion", Values: []string{"3"}},
+			{Name: "vendorName", Values: []string{"Pocket ID"}},
+		},
+	}
+}
+
+func (s *LdapServerService) baseEntry() LdapEntry {
+	entry := LdapEntry{
+		DN: s.baseDN,
+		Attributes: []LdapAttribute{
+			{Name: "objectClass", Values: []string{"top"}},
+		},
+	}
+
+	baseDN, err := ldap.ParseDN(s.baseDN)
+	if err == nil && len(baseDN.RDNs) > 0 && len(baseDN.RDNs[0].Attributes) == 1 {
+		attr := baseDN.RDNs[0].Attributes[0]
+		if strings.EqualFold(attr.Type, "dc") {
+			entry.Attributes[0].Values = append(entry.Attributes[0].Values, "domain")
+		}
+		entry.Attributes = append(entry.Attributes, LdapAttribute{Name: attr.Type, Values: []string{attr.Value}})
+	}
+
+	return entry
+}
+
+func (s *LdapServerService) userEntry(user model.User) LdapEntry {
+	memberOf := make([]string, len(user.UserGroups))
+	for i, group := range user.UserGroups {
+		memberOf[i] = s.groupDN(group.Name)
+	}
+
+	displayName := strings.TrimSpace(user.FullName())
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	// sn is required by the person object class
+	lastName := user.LastName
+	if lastName == "" {
+		lastName = user.Username
+	}
+
+	attributes := []LdapAttribute{
+		{Name: "objectClass", Values: []string{"top", "person", "organizationalPerson", "inetOrgPerson"}},
+		{Name: "uid", Values: []string{user.Username}},
+		{Name: "cn", Values: []string{displayName}},
+		{Name: "displayName", Values: []string{displayName}},
+		{Name: "sn", Values: []string{lastName}},
+		{Name: "entryUUID", Values: []string{user.ID}},
+		{Name: "memberOf", Values: memberOf},
+	}
+	if user.FirstName != "" {
+		attributes = append(attributes, LdapAttribute{Name: "givenName", Values: []string{user.FirstName}})
+	}
+	if user.Email != "" {
+		attributes = append(attributes, LdapAttribute{Name: "mail", Values: []string{user.Email}})
+	}
+
+	return LdapEntry{
+		DN:         s.userDN(user.Username),
+		Attributes: attributes,
+	}
+}
+
+func (s *LdapServerService) groupEntry(group model.UserGroup) LdapEntry {
+	members := make([]string, len(group.Users))
+	for i, user := range group.Users {
+		members[i] = s.userDN(user.Username)
+	}
+
+	return LdapEntry{
+		DN: s.groupDN(group.Name),
+		Attributes: []LdapAttribute{
+			{Name: "objectClass", Values: []string{"top", "groupOfNames", "groupOfUniqueNames"}},
+			{Name: "cn", Values: []string{group.Name}},
+			{Name: "displayName", Values: []string{group.FriendlyName}},
+			{Name: "entryUUID", Values: []string{group.ID}},
+			{Name: "member", Values: members},
+			{Name: "uniqueMember", Values: members},
+		},
+	}
+}
+
+func ldapDNInScope(entryDN *ldap.DN, baseDN *ldap.DN, scope int64) bool {
+	switch scope {
+	case LdapScopeBaseObject:
+		return entryDN.EqualFold(baseDN)
+	case LdapScopeSingleLevel:
+		return len(entryDN.RDNs) == len(baseDN.RDNs)+1 && baseDN.AncestorOfFold(entryDN)
+	default:
+		return entryDN.EqualFold(baseDN) || baseDN.AncestorOfFold(entryDN)
+	}
+}
+
+// selectLdapAttributes reduces the entry to the requested attributes, see RFC 4511 section 4.5.1.8
+func selectLdapAttributes(entry LdapEntry, requested []string, typesOnly bool) LdapEntry {
+	all := len(requested) == 0 || slices.Contains(requested, "*")
+
+	result := LdapEntry{DN: entry.DN}
+	for _, attr := range entry.Attributes {
+		if !all && !slices.ContainsFunc(requested, func(name string) bool { return strings.EqualFold(name, attr.Name) }) {
+			continue
+		}
+		if typesOnly {
+			attr = LdapAttribute{Name: attr.Name, Values: []string{}}
+		}
+		result.Attributes = append(result.Attributes, attr)
+	}
+
+	return result
+}
+
+// ldapFilterMatches evaluates a compiled search filter against an entry
+func ldapFilterMatches(filter *ber.Packet, entry LdapEntry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !ldapFilterMatches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if ldapFilterMatches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !ldapFilterMatches(filter.Children[0], entry)
+	case ldap.FilterPresent:
+		return len(entry.Get(ber.DecodeString(filter.Data.Bytes()))) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := ber.DecodeString(filter.Children[0].Data.Bytes())
+		assertion := ber.DecodeString(filter.Children[1].Data.Bytes())
+		for _, value := range entry.Get(name) {
+			if ldapValueMatches(filter.Tag, name, value, assertion) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := ber.DecodeString(filter.Children[0].Data.Bytes())
+		for _, value := range entry.Get(name) {
+			if ldapSubstringsMatch(filter.Children[1].Children, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		// Extensible matches aren't supported
+		return false
+	}
+}
+
+func ldapValueMatches(tag ber.Tag, attribute string, value string, assertion string) bool {
+	if ldapDNAttributes[strings.ToLower(attribute)] {
+		valueDN, err1 := ldap.ParseDN(value)
+		assertionDN, err2 := ldap.ParseDN(assertion)
+		if err1 == nil && err2 == nil {
+			return valueDN.EqualFold(assertionDN)
+		}
+	}
+
+	value = strings.ToLower(value)
+	assertion = strings.ToLower(assertion)
+	switch tag {
+	case ldap.FilterGreaterOrEqual:
+		return value >= assertion
+	case ldap.FilterLessOrEqual:
+		return value <= assertion
+	default:
+		return value == assertion
+	}
+}
+
+func ldapSubstringsMatch(substrings []*ber.Packet, value string) bool {
+	value = strings.ToLower(value)
+	for _, substring := range substrings {
+		part := strings.ToLower(ber.DecodeString(substring.Data.Bytes()))
+		switch substring.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, part) {
+				return false
+			}
+			value = value[len(part):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, part)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(part):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, part) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
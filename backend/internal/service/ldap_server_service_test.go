@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+func TestLdapServerService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
+	auditLogService := NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil)
+	apiKeyService := NewApiKeyService(db, nil, auditLogService)
+	appPasswordService := NewAppPasswordService(db)
+	service := NewLdapServerService(db, apiKeyService, appPasswordService, auditLogService, "dc=example,dc=com")
+
+	admin := model.User{Username: "admin", Email: "admin@example.com", FirstName: "Ada", LastName: "Admin", IsAdmin: true}
+	require.NoError(t, db.Create(&admin).Error)
+	tim := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim", LastName: "Cook"}
+	require.NoError(t, db.Create(&tim).Error)
+	craig := model.User{Username: "craig", Email: "craig@example.com", FirstName: "Craig", LastName: "Federighi"}
+	require.NoError(t, db.Create(&craig).Error)
+	disabled := model.User{Username: "disabled", Email: "disabled@example.com", Disabled: true}
+	require.NoError(t, db.Create(&disabled).Error)
+
+	developers := model.UserGroup{Name: "developers", FriendlyName: "Developers", Users: []model.User{tim, disabled}}
+	require.NoError(t, db.Create(&developers).Error)
+	designers := model.UserGroup{Name: "designers", FriendlyName: "Designers", Users: []model.User{craig}}
+	require.NoError(t, db.Create(&designers).Error)
+
+	_, timPassword, err := appPasswordService.CreateAppPassword(t.Context(), tim.ID, dto.AppPasswordCreateDto{Name: "NAS"})
+	require.NoError(t, err)
+	_, disabledPassword, err := appPasswordService.CreateAppPassword(t.Context(), disabled.ID, dto.AppPasswordCreateDto{Name: "NAS"})
+	require.NoError(t, err)
+	_, adminApiKey, err := apiKeyService.CreateApiKey(t.Context(), admin.ID, dto.ApiKeyCreateDto{
+		Name:      "LDAP",
+		ExpiresAt: datatype.DateTime(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	search := func(t *testing.T, userID string, baseDN string, filter string, attributes ...string) []LdapEntry {
+		t.Helper()
+		entries, _, err := service.Search(t.Context(), userID, LdapSearchRequest{
+			BaseDN:     baseDN,
+			Scope:      LdapScopeWholeSubtree,
+			Filter:     filter,
+			Attributes: attributes,
+		})
+		require.NoError(t, err)
+		return entries
+	}
+
+	dns := func(entries []LdapEntry) []string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = entry.DN
+		}
+		return result
+	}
+
+	t.Run("binds with an app password", func(t *testing.T) {
+		userID, err := service.Bind(t.Context(), "uid=tim,ou=users,dc=example,dc=com", timPassword, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, tim.ID, userID)
+	})
+
+	t.Run("binds with an API key", func(t *testing.T) {
+		userID, err := service.Bind(t.Context(), "UID=admin,OU=Users,DC=example,DC=com", adminApiKey, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, admin.ID, userID)
+	})
+
+	t.Run("rejects invalid credentials", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			dn       string
+			password string
+		}{
+			{"wrong password", "uid=tim,ou=users,dc=example,dc=com", "wrong"},
+			{"password of another user", "uid=craig,ou=users,dc=example,dc=com", timPassword},
+			{"API key of another user", "uid=tim,ou=users,dc=example,dc=com", adminApiKey},
+			{"disabled user", "uid=disabled,ou=users,dc=example,dc=com", disabledPassword},
+			{"wrong base DN", "uid=tim,ou=users,dc=example,dc=org", timPassword},
+			{"unauthenticated bind", "", timPassword},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := service.Bind(t.Context(), tt.dn, tt.password, "192.0.2.1")
+				var invalidCredentialsErr *common.LdapServerInvalidCredentialsError
+				require.ErrorAs(t, err, &invalidCredentialsErr)
+			})
+		}
+	})
+
+	t.Run("audits failed binds", func(t *testing.T) {
+		var auditLog model.AuditLog
+		require.NoError(t, db.
+			Where("event = ? AND user_id = ?", model.AuditLogEventLdapBindFailed, craig.ID).
+			First(&auditLog).
+			Error)
+		assert.Equal(t, "192.0.2.1", auditLog.IpAddress)
+		assert.Equal(t, "uid=craig,ou=users,dc=example,dc=com", auditLog.Data["bindDn"])
+	})
+
+	t.Run("throttles failed binds per IP address", func(t *testing.T) {
+		for range 10 {
+			_, err := service.Bind(t.Context(), "uid=unknown,ou=users,dc=example,dc=com", "wrong", "192.0.2.2")
+			var invalidCredentialsErr *common.LdapServerInvalidCredentialsError
+			require.ErrorAs(t, err, &invalidCredentialsErr)
+		}
+
+		// Even valid credentials are rejected from the same IP address
+		_, err := service.Bind(t.Context(), "uid=tim,ou=users,dc=example,dc=com", timPassword, "192.0.2.2")
+		var tooManyBindsErr *common.LdapServerTooManyBindsError
+		require.ErrorAs(t, err, &tooManyBindsErr)
+
+		_, err = service.Bind(t.Context(), "uid=tim,ou=users,dc=example,dc=com", timPassword, "192.0.2.3")
+		require.NoError(t, err)
+	})
+
+	t.Run("throttles failed binds per user", func(t *testing.T) {
+		for i := range 10 {
+			_, err := service.Bind(t.Context(), "uid=Admin,ou=users,dc=example,dc=com", "wrong", fmt.Sprintf("198.51.100.%d", i))
+			var invalidCredentialsErr *common.LdapServerInvalidCredentialsError
+			require.ErrorAs(t, err, &invalidCredentialsErr)
+		}
+
+		_, err := service.Bind(t.Context(), "uid=admin,ou=users,dc=example,dc=com", "wrong", "198.51.100.100")
+		var tooManyBindsErr *common.LdapServerTooManyBindsError
+		require.ErrorAs(t, err, &tooManyBindsErr)
+	})
+
+	t.Run("allows anonymous users to read the root DSE only", func(t *testing.T) {
+		entries, _, err := service.Search(t.Context(), "", LdapSearchRequest{Filter: "(objectClass=*)"})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, []string{"dc=example,dc=com"}, entries[0].Get("namingContexts"))
+
+		_, _, err = service.Search(t.Context(), "", LdapSearchRequest{
+			BaseDN: "dc=example,dc=com",
+			Scope:  LdapScopeWholeSubtree,
+			Filter: "(objectClass=*)",
+		})
+		var insufficientAccessErr *common.LdapServerInsufficientAccessError
+		require.ErrorAs(t, err, &insufficientAccessErr)
+	})
+
+	t.Run("returns the whole directory to admins", func(t *testing.T) {
+		entries := search(t, admin.ID, "dc=example,dc=com", "(objectClass=*)")
+		assert.Equal(t, []string{
+			"dc=example,dc=com",
+			"ou=users,dc=example,dc=com",
+			"ou=groups,dc=example,dc=com",
+			"uid=admin,ou=users,dc=example,dc=com",
+			"uid=craig,ou=users,dc=example,dc=com",
+			"uid=tim,ou=users,dc=example,dc=com",
+			"cn=designers,ou=groups,dc=example,dc=com",
+			"cn=developers,ou=groups,dc=example,dc=com",
+		}, dns(entries))
+	})
+
+	t.Run("returns only the user and their groups to other users", func(t *testing.T) {
+		entries := search(t, tim.ID, "dc=example,dc=com", "(|(objectClass=inetOrgPerson)(objectClass=groupOfNames))")
+		assert.Equal(t, []string{
+			"uid=tim,ou=users,dc=example,dc=com",
+			"cn=developers,ou=groups,dc=example,dc=com",
+		}, dns(entries))
+	})
+
+	t.Run("supports memberOf and member filters", func(t *testing.T) {
+		entries := search(t, admin.ID, "ou=users,dc=example,dc=com", "(&(objectClass=person)(memberOf=CN=developers,OU=groups,DC=example,DC=com))")
+		assert.Equal(t, []string{"uid=tim,ou=users,dc=example,dc=com"}, dns(entries))
+
+		entries = search(t, admin.ID, "ou=groups,dc=example,dc=com", "(member=uid=craig,ou=users,dc=example,dc=com)", "cn", "member")
+		require.Len(t, entries, 1)
+		assert.Equal(t, []LdapAttribute{
+			{Name: "cn", Values: []string{"designers"}},
+			{Name: "member", Values: []string{"uid=craig,ou=users,dc=example,dc=com"}},
+		}, entries[0].Attributes)
+
+		// Disabled users aren't members of groups
+		entries = search(t, admin.ID, "ou=groups,dc=example,dc=com", "(cn=developers)", "member")
+		require.Len(t, entries, 1)
+		assert.Equal(t, []string{"uid=tim,ou=users,dc=example,dc=com"}, entries[0].Get("member"))
+	})
+
+	t.Run("supports substring and negation filters", func(t *testing.T) {
+		entries := search(t, admin.ID, "ou=users,dc=example,dc=com", "(&(mail=*@EXAMPLE.com)(!(uid=admin))(cn=T*m*k))")
+		assert.Equal(t, []string{"uid=tim,ou=users,dc=example,dc=com"}, dns(entries))
+	})
+
+	t.Run("respects the search scope and size limit", func(t *testing.T) {
+		entries, truncated, err := service.Search(t.Context(), admin.ID, LdapSearchRequest{
+			BaseDN:    "ou=users,dc=example,dc=com",
+			Scope:     LdapScopeSingleLevel,
+			Filter:    "(objectClass=*)",
+			SizeLimit: 2,
+		})
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		assert.Equal(t, []string{
+			"uid=admin,ou=users,dc=example,dc=com",
+			"uid=craig,ou=users,dc=example,dc=com",
+		}, dns(entries))
+	})
+
+	t.Run("returns an error for unknown base DNs", func(t *testing.T) {
+		_, _, err := service.Search(t.Context(), admin.ID, LdapSearchRequest{
+			BaseDN: "ou=people,dc=example,dc=com",
+			Scope:  LdapScopeWholeSubtree,
+			Filter: "(objectClass=*)",
+		})
+		var noSuchObjectErr *common.LdapServerNoSuchObjectError
+		require.ErrorAs(t, err, &noSuchObjectErr)
+	})
+}
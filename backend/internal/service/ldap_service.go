@@ -237,10 +237,13 @@ func (s *LdapService) SyncGroups(ctx context.Context, tx *gorm.DB, client *ldap.
 			continue
 		}
 
-		err = tx.
-			WithContext(ctx).
-			Delete(&model.UserGroup{}, "ldap_id = ?", group.LdapID).
-			Error
+		// Load the group with its users, so that the users who lose access are synced to the provisioning targets
+		groupWithUsers, err := s.groupService.getInternal(ctx, group.ID, tx)
+		if err != nil {
+			return fmt.Errorf("failed to load group '%s': %w", group.Name, err)
+		}
+
+		err = s.groupService.deleteInternal(ctx, groupWithUsers, tx)
 		if err != nil {
 			return fmt.Errorf("failed to delete group '%s': %w", group.Name, err)
 		}
@@ -52,6 +52,8 @@ type OidcService struct {
 	auditLogService    *AuditLogService
 	customClaimService *CustomClaimService
 
+	scimProvisioningService *ScimProvisioningService
+
 	httpClient *http.Client
 	jwkCache   *jwk.Cache
 }
@@ -63,13 +65,15 @@ func NewOidcService(
 	appConfigService *AppConfigService,
 	auditLogService *AuditLogService,
 	customClaimService *CustomClaimService,
+	scimProvisioningService *ScimProvisioningService,
 ) (s *OidcService, err error) {
 	s = &OidcService{
-		db:                 db,
-		jwtService:         jwtService,
-		appConfigService:   appConfigService,
-		auditLogService:    auditLogService,
-		customClaimService: customClaimService,
+		db:                      db,
+		jwtService:              jwtService,
+		appConfigService:        appConfigService,
+		auditLogService:         auditLogService,
+		customClaimService:      customClaimService,
+		scimProvisioningService: scimProvisioningService,
 	}
 
 	// Note: we don't pass the HTTP Client with OTel instrumented to this because requests are always made in background and not tied to a specific trace
@@ -108,7 +112,7 @@ func (s *OidcService) getJWKCache(ctx context.Context) (*jwk.Cache, error) {
 	)
 }
 
-func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClientRequestDto, userID, ipAddress, userAgent string) (string, string, error) {
+func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClientRequestDto, userID, sessionID, ipAddress, userAgent string) (string, string, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -150,6 +154,15 @@ func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClie
 		return "", "", &common.OidcAccessDeniedError{}
 	}
 
+	// Make sure that the way the user authenticated satisfies the client's requirements
+	session, err := s.getSessionInternal(ctx, sessionID, userID, tx)
+	if err != nil {
+		return "", "", err
+	}
+	if !sessionSatisfiesAcrValues(session, input.AcrValues) {
+		return "", "", &common.OidcReauthenticationRequiredError{}
+	}
+
 	// Check if the user has already authorized the client with the given scope
 	hasAuthorizedClient, err := s.hasAuthorizedClientInternal(ctx, input.ClientID, userID, input.Scope, tx)
 	if err != nil {
@@ -165,7 +178,7 @@ func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClie
 	}
 
 	// Create the authorization code
-	code, err := s.createAuthorizationCode(ctx, input.ClientID, userID, input.Scope, input.Nonce, input.CodeChallenge, input.CodeChallengeMethod, tx)
+	code, err := s.createAuthorizationCode(ctx, input.ClientID, userID, input.Scope, input.Nonce, input.CodeChallenge, input.CodeChallengeMethod, session, tx)
 	if err != nil {
 		return "", "", err
 	}
@@ -295,13 +308,15 @@ func (s *OidcService) createTokenFromDeviceCode(ctx context.Context, input dto.O
 		return CreatedTokens{}, err
 	}
 
+	addAuthenticationClaims(userClaims, deviceAuth.Acr, deviceAuth.Amr)
+
 	// Explicitly use the input clientID for the audience claim to ensure consistency
 	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, "")
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, *deviceAuth.UserID, deviceAuth.Scope, tx)
+	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, *deviceAuth.UserID, deviceAuth.Scope, deviceAuth.Acr, deviceAuth.Amr, tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
@@ -367,13 +382,15 @@ func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, inpu
 		return CreatedTokens{}, err
 	}
 
+	addAuthenticationClaims(userClaims, authorizationCodeMetaData.Acr, authorizationCodeMetaData.Amr)
+
 	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, authorizationCodeMetaData.Nonce)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
 	// Generate a refresh token
-	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, authorizationCodeMetaData.UserID, authorizationCodeMetaData.Scope, tx)
+	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, authorizationCodeMetaData.UserID, authorizationCodeMetaData.Scope, authorizationCodeMetaData.Acr, authorizationCodeMetaData.Amr, tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
@@ -456,6 +473,22 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 		return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
 	}
 
+	// Generate a new ID token with the authentication context of the original authorization
+	var idToken string
+	if slices.Contains(strings.Fields(storedRefreshToken.Scope), "openid") {
+		userClaims, err := s.getUserClaimsForClientInternal(ctx, storedRefreshToken.UserID, input.ClientID, tx)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+
+		addAuthenticationClaims(userClaims, storedRefreshToken.Acr, storedRefreshToken.Amr)
+
+		idToken, err = s.jwtService.GenerateIDToken(userClaims, input.ClientID, "")
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
 	// Generate a new access token
 	accessToken, err := s.jwtService.GenerateOAuthAccessToken(storedRefreshToken.User, input.ClientID)
 	if err != nil {
@@ -463,7 +496,7 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 	}
 
 	// Generate a new refresh token and invalidate the old one
-	newRefreshToken, err := s.createRefreshToken(ctx, input.ClientID, storedRefreshToken.UserID, storedRefreshToken.Scope, tx)
+	newRefreshToken, err := s.createRefreshToken(ctx, input.ClientID, storedRefreshToken.UserID, storedRefreshToken.Scope, storedRefreshToken.Acr, storedRefreshToken.Amr, tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
@@ -483,6 +516,7 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 	}
 
 	return CreatedTokens{
+		IdToken:      idToken,
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		ExpiresIn:    time.Hour,
@@ -521,7 +555,7 @@ func (s *OidcService) IntrospectToken(ctx context.Context, creds ClientAuthCrede
 	// Introspect the token
 	switch tokenType {
 	case OAuthAccessTokenJWTType:
-		return s.introspectAccessToken(client.ID, tokenString)
+		return s.introspectAccessToken(ctx, client.ID, tokenString)
 	case OAuthRefreshTokenJWTType:
 		return s.introspectRefreshToken(ctx, client.ID, tokenString)
 	default:
@@ -531,7 +565,7 @@ func (s *OidcService) IntrospectToken(ctx context.Context, creds ClientAuthCrede
 	}
 }
 
-func (s *OidcService) introspectAccessToken(clientID string, tokenString string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
+func (s *OidcService) introspectAccessToken(ctx context.Context, clientID string, tokenString string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
 	token, err := s.jwtService.VerifyOAuthAccessToken(tokenString)
 	if err != nil {
 		// Every failure we get means the token is invalid. Nothing more to do with the error.
@@ -549,6 +583,21 @@ func (s *OidcService) introspectAccessToken(clientID string, tokenString string)
 		return introspectDto, &common.OidcMissingClientCredentialsError{}
 	}
 
+	// Tokens of users that have been disabled or deleted are not active anymore
+	subject, _ := token.Subject()
+	var user model.User
+	err = s.db.
+		WithContext(ctx).
+		Where("id = ?", subject).
+		First(&user).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && user.Disabled) {
+		introspectDto.Active = false
+		return introspectDto, nil
+	} else if err != nil {
+		return introspectDto, err
+	}
+
 	introspectDto.Active = true
 	introspectDto.TokenType = "access_token"
 	introspectDto.Audience = audience
@@ -618,6 +667,11 @@ func (s *OidcService) introspectRefreshToken(ctx context.Context, clientID strin
 		return introspectDto, err
 	}
 
+	if storedRefreshToken.User.Disabled {
+		introspectDto.Active = false
+		return introspectDto, nil
+	}
+
 	introspectDto.Active = true
 	introspectDto.TokenType = "refresh_token"
 	return introspectDto, nil
@@ -671,11 +725,21 @@ func (s *OidcService) ListClients(ctx context.Context, name string, sortedPagina
 
 func (s *OidcService) CreateClient(ctx context.Context, input dto.OidcClientCreateDto, userID string) (model.OidcClient, error) {
 	client := model.OidcClient{
-		CreatedByID: userID,
+		CreatedByID: &userID,
 	}
 	updateOIDCClientModelFromDto(&client, &input)
 
-	err := s.db.
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	err := s.checkForwardAuthHostsInternal(ctx, "", client.ForwardAuthHosts, tx)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	err = tx.
 		WithContext(ctx).
 		Create(&client).
 		Error
@@ -683,6 +747,13 @@ func (s *OidcService) CreateClient(ctx context.Context, input dto.OidcClientCrea
 		return model.OidcClient{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientCreated, oidcClientAuditTarget(client), nil, newOidcClientAuditState(client), tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
 	return client, nil
 }
 
@@ -701,9 +772,18 @@ func (s *OidcService) UpdateClient(ctx context.Context, clientID string, input d
 	if err != nil {
 		return model.OidcClient{}, err
 	}
+	if client.ConfigManaged {
+		return model.OidcClient{}, &common.ConfigManagedError{}
+	}
+	before := newOidcClientAuditState(client)
 
 	updateOIDCClientModelFromDto(&client, &input)
 
+	err = s.checkForwardAuthHostsInternal(ctx, clientID, client.ForwardAuthHosts, tx)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
 	err = tx.
 		WithContext(ctx).
 		Save(&client).
@@ -712,6 +792,8 @@ func (s *OidcService) UpdateClient(ctx context.Context, clientID string, input d
 		return model.OidcClient{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientUpdated, oidcClientAuditTarget(client), before, newOidcClientAuditState(client), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.OidcClient{}, err
@@ -729,6 +811,12 @@ func updateOIDCClientModelFromDto(client *model.OidcClient, input *dto.OidcClien
 	// PKCE is required for public clients
 	client.PkceEnabled = input.IsPublic || input.PkceEnabled
 
+	// Hosts are matched case-insensitively
+	client.ForwardAuthHosts = make(model.UrlList, len(input.ForwardAuthHosts))
+	for i, host := range input.ForwardAuthHosts {
+		client.ForwardAuthHosts[i] = strings.ToLower(host)
+	}
+
 	// Credentials
 	if len(input.Credentials.FederatedIdentities) > 0 {
 		client.Credentials.FederatedIdentities = make([]model.OidcClientFederatedIdentity, len(input.Credentials.FederatedIdentities))
@@ -743,18 +831,99 @@ func updateOIDCClientModelFromDto(client *model.OidcClient, input *dto.OidcClien
 	}
 }
 
+// oidcClientAuditState contains the attributes of an OIDC client that are compared in the audit log
+type oidcClientAuditState struct {
+	Name               string                      `json:"name"`
+	CallbackURLs       model.UrlList               `json:"callbackURLs"`
+	LogoutCallbackURLs model.UrlList               `json:"logoutCallbackURLs"`
+	IsPublic           bool                        `json:"isPublic"`
+	PkceEnabled        bool                        `json:"pkceEnabled"`
+	Credentials        model.OidcClientCredentials `json:"credentials"`
+	ForwardAuthHosts   model.UrlList               `json:"forwardAuthHosts"`
+	HasLogo            bool                        `json:"hasLogo"`
+}
+
+func newOidcClientAuditState(client model.OidcClient) oidcClientAuditState {
+	return oidcClientAuditState{
+		Name:               client.Name,
+		CallbackURLs:       client.CallbackURLs,
+		LogoutCallbackURLs: client.LogoutCallbackURLs,
+		IsPublic:           client.IsPublic,
+		PkceEnabled:        client.PkceEnabled,
+		Credentials:        client.Credentials,
+		ForwardAuthHosts:   client.ForwardAuthHosts,
+		HasLogo:            client.ImageType != nil,
+	}
+}
+
+func oidcClientAuditTarget(client model.OidcClient) AuditTarget {
+	return AuditTarget{Type: model.AuditLogTargetOidcClient, ID: client.ID, Name: client.Name}
+}
+
+// checkForwardAuthHostsInternal makes sure that a host is protected by one client only
+func (s *OidcService) checkForwardAuthHostsInternal(ctx context.Context, clientID string, hosts []string, tx *gorm.DB) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	var clients []model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		Select("id", "forward_auth_hosts").
+		Where("id != ? AND forward_auth_hosts IS NOT NULL", clientID).
+		Find(&clients).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		for _, host := range hosts {
+			if slices.Contains(client.ForwardAuthHosts, host) {
+				return &common.AlreadyInUseError{Property: "Forward auth host " + host}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *OidcService) DeleteClient(ctx context.Context, clientID string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// The client is loaded before it's deleted, because not all databases can return deleted rows
 	var client model.OidcClient
-	err := s.db.
+	err := tx.
 		WithContext(ctx).
 		Where("id = ?", clientID).
-		Delete(&client).
+		Limit(1).
+		Find(&client).
 		Error
 	if err != nil {
 		return err
 	}
+	if client.ID == "" {
+		return tx.Commit().Error
+	}
+	if client.ConfigManaged {
+		return &common.ConfigManagedError{}
+	}
 
-	return nil
+	result := tx.
+		WithContext(ctx).
+		Delete(&client)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientDeleted, oidcClientAuditTarget(client), newOidcClientAuditState(client), nil, tx)
+	}
+
+	return tx.Commit().Error
 }
 
 func (s *OidcService) CreateClientSecret(ctx context.Context, clientID string) (string, error) {
@@ -771,6 +940,9 @@ func (s *OidcService) CreateClientSecret(ctx context.Context, clientID string) (
 	if err != nil {
 		return "", err
 	}
+	if client.ConfigManaged {
+		return "", &common.ConfigManagedError{}
+	}
 
 	clientSecret, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
@@ -791,6 +963,8 @@ func (s *OidcService) CreateClientSecret(ctx context.Context, clientID string) (
 		return "", err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientSecretCreated, oidcClientAuditTarget(client), nil, nil, tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return "", err
@@ -845,6 +1019,8 @@ func (s *OidcService) UpdateClientLogo(ctx context.Context, clientID string, fil
 		return err
 	}
 
+	before := newOidcClientAuditState(client)
+
 	if client.ImageType != nil && fileType != *client.ImageType {
 		oldImagePath := fmt.Sprintf("%s/oidc-client-images/%s.%s", common.EnvConfig.UploadPath, client.ID, *client.ImageType)
 		if err := os.Remove(oldImagePath); err != nil {
@@ -861,6 +1037,8 @@ func (s *OidcService) UpdateClientLogo(ctx context.Context, clientID string, fil
 		return err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientUpdated, oidcClientAuditTarget(client), before, newOidcClientAuditState(client), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return err
@@ -888,6 +1066,8 @@ func (s *OidcService) DeleteClientLogo(ctx context.Context, clientID string) err
 		return errors.New("image not found")
 	}
 
+	before := newOidcClientAuditState(client)
+
 	oldImageType := *client.ImageType
 	client.ImageType = nil
 	err = tx.
@@ -898,6 +1078,8 @@ func (s *OidcService) DeleteClientLogo(ctx context.Context, clientID string) err
 		return err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientUpdated, oidcClientAuditTarget(client), before, newOidcClientAuditState(client), tx)
+
 	imagePath := common.EnvConfig.UploadPath + "/oidc-client-images/" + client.ID + "." + oldImageType
 	if err := os.Remove(imagePath); err != nil {
 		return err
@@ -921,6 +1103,10 @@ func (s *OidcService) UpdateAllowedUserGroups(ctx context.Context, id string, in
 	if err != nil {
 		return model.OidcClient{}, err
 	}
+	if client.ConfigManaged {
+		return model.OidcClient{}, &common.ConfigManagedError{}
+	}
+	originalGroups := client.AllowedUserGroups
 
 	// Fetch the user groups based on UserGroupIDs in input
 	var groups []model.UserGroup
@@ -945,6 +1131,12 @@ func (s *OidcService) UpdateAllowedUserGroups(ctx context.Context, id string, in
 		return model.OidcClient{}, err
 	}
 
+	// The users who gained or lost access have to be synced to the provisioning target of the client
+	err = s.scimProvisioningService.enqueueClientInternal(ctx, client.ID, tx)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
 	// Save the updated client
 	err = tx.
 		WithContext(ctx).
@@ -954,6 +1146,9 @@ func (s *OidcService) UpdateAllowedUserGroups(ctx context.Context, id string, in
 		return model.OidcClient{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOidcClientAllowedGroupsUpdated, oidcClientAuditTarget(client),
+		userGroupNamesAuditState(originalGroups), userGroupNamesAuditState(groups), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.OidcClient{}, err
@@ -1009,7 +1204,7 @@ func (s *OidcService) ValidateEndSession(ctx context.Context, input dto.OidcLogo
 	return callbackURL, nil
 }
 
-func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID string, userID string, scope string, nonce string, codeChallenge string, codeChallengeMethod string, tx *gorm.DB) (string, error) {
+func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID string, userID string, scope string, nonce string, codeChallenge string, codeChallengeMethod string, session model.Session, tx *gorm.DB) (string, error) {
 	randomString, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
 		return "", err
@@ -1026,6 +1221,8 @@ func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID stri
 		Nonce:                     nonce,
 		CodeChallenge:             &codeChallenge,
 		CodeChallengeMethodSha256: &codeChallengeMethodSha256,
+		Acr:                       session.Acr(),
+		Amr:                       strings.Join(session.Amr(), " "),
 	}
 
 	err = tx.
@@ -1039,6 +1236,61 @@ func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID stri
 	return randomString, nil
 }
 
+// getSessionInternal loads the session the user is authenticated with
+// Requests authenticated with an API key don't have a session, in which case an empty session is returned
+func (s *OidcService) getSessionInternal(ctx context.Context, sessionID string, userID string, tx *gorm.DB) (model.Session, error) {
+	var session model.Session
+	if sessionID == "" {
+		return session, nil
+	}
+
+	err := tx.
+		WithContext(ctx).
+		First(&session, "id = ? AND user_id = ?", sessionID, userID).
+		Error
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	return session, nil
+}
+
+// acrLevels ranks the supported acr values from the lowest to the highest assurance
+var acrLevels = map[string]int{
+	model.AcrBasic:   1,
+	model.AcrPasskey: 2,
+}
+
+// sessionSatisfiesAcrValues checks if the session satisfies at least one of the space-separated requested acr values
+// Unknown values are ignored, because the acr_values parameter is only a voluntary request
+func sessionSatisfiesAcrValues(session model.Session, acrValues string) bool {
+	sessionLevel := acrLevels[session.Acr()]
+
+	hasKnownValue := false
+	for _, acr := range strings.Fields(acrValues) {
+		level, ok := acrLevels[acr]
+		if !ok {
+			continue
+		}
+		if sessionLevel >= level {
+			return true
+		}
+		hasKnownValue = true
+	}
+
+	return !hasKnownValue
+}
+
+// addAuthenticationClaims adds the "acr" and "amr" claims to the claims of an ID token
+func addAuthenticationClaims(claims map[string]any, acr string, amr string) {
+	if acr != "" {
+		claims["acr"] = acr
+	}
+	if amr != "" {
+		claims["amr"] = strings.Fields(amr)
+	}
+}
+
 func (s *OidcService) validateCodeVerifier(codeVerifier, codeChallenge string, codeChallengeMethodSha256 bool) bool {
 	if codeVerifier == "" || codeChallenge == "" {
 		return false
@@ -1176,7 +1428,7 @@ func (s *OidcService) CreateDeviceAuthorization(ctx context.Context, input dto.O
 	}, nil
 }
 
-func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, userID string, ipAddress string, userAgent string) error {
+func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, userID string, sessionID string, ipAddress string, userAgent string) error {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -1211,8 +1463,15 @@ func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, use
 		return &common.OidcDeviceCodeExpiredError{}
 	}
 
+	session, err := s.getSessionInternal(ctx, sessionID, userID, tx)
+	if err != nil {
+		return err
+	}
+
 	deviceAuth.UserID = &userID
 	deviceAuth.IsAuthorized = true
+	deviceAuth.Acr = session.Acr()
+	deviceAuth.Amr = strings.Join(session.Amr(), " ")
 
 	if err := tx.WithContext(ctx).Save(&deviceAuth).Error; err != nil {
 		log.Printf("Error saving device auth: %v", err)
@@ -1316,7 +1575,7 @@ func (s *OidcService) ListAuthorizedClients(ctx context.Context, userID string,
 	return authorizedClients, response, err
 }
 
-func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, userID string, scope string, tx *gorm.DB) (string, error) {
+func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, userID string, scope string, acr string, amr string, tx *gorm.DB) (string, error) {
 	refreshToken, err := utils.GenerateRandomAlphanumericString(40)
 	if err != nil {
 		return "", err
@@ -1332,6 +1591,8 @@ func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, u
 		ClientID:  clientID,
 		UserID:    userID,
 		Scope:     scope,
+		Acr:       acr,
+		Amr:       amr,
 	}
 
 	err = tx.
@@ -5,8 +5,10 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +20,8 @@ import (
 
 	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
 )
 
 // generateTestECDSAKey creates an ECDSA key for testing
@@ -161,9 +165,11 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 	}
 
 	// Init the OidcService
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
 	s := &OidcService{
-		db:         db,
-		httpClient: httpClient,
+		db:              db,
+		httpClient:      httpClient,
+		auditLogService: NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil),
 	}
 	s.jwkCache, err = s.getJWKCache(t.Context())
 	require.NoError(t, err)
@@ -363,3 +369,109 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 		})
 	})
 }
+
+func TestSessionSatisfiesAcrValues(t *testing.T) {
+	passkeySession := model.Session{AuthMethod: model.SessionAuthMethodPasskey, UserVerified: true}
+	otpSession := model.Session{AuthMethod: model.SessionAuthMethodOneTimeAccessToken}
+
+	tests := []struct {
+		name      string
+		session   model.Session
+		acrValues string
+		expected  bool
+	}{
+		{"no acr values requested", otpSession, "", true},
+		{"passkey session satisfies passkey", passkeySession, model.AcrPasskey, true},
+		{"passkey session satisfies basic", passkeySession, model.AcrBasic, true},
+		{"one-time token session does not satisfy passkey", otpSession, model.AcrPasskey, false},
+		{"one-time token session satisfies one of the values", otpSession, model.AcrPasskey + " " + model.AcrBasic, true},
+		{"unknown values are ignored", otpSession, "urn:example:unknown", true},
+		{"session without authentication method does not satisfy passkey", model.Session{}, model.AcrPasskey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sessionSatisfiesAcrValues(tt.session, tt.acrValues))
+		})
+	}
+}
+
+func TestOidcService_createTokenFromRefreshToken(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(appConfig, common.EnvConfig.KeysPath))
+	s := &OidcService{
+		db:               db,
+		jwtService:       jwtService,
+		appConfigService: appConfig,
+		auditLogService:  NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, NewWebhookService(db, nil), nil, nil),
+	}
+
+	tim := model.User{Username: "tim", Email: "tim@example.com"}
+	require.NoError(t, db.Create(&tim).Error)
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		Name:         "Wiki",
+		CallbackURLs: []string{"https://wiki.example.com/callback"},
+	}, tim.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&model.UserAuthorizedOidcClient{UserID: tim.ID, ClientID: client.ID, Scope: "openid email"}).Error)
+	require.NoError(t, db.Create(&model.OidcAuthorizationCode{
+		Code:      "code",
+		Scope:     "openid email",
+		ExpiresAt: datatype.DateTime(time.Now().Add(time.Minute)),
+		Acr:       model.AcrPasskey,
+		Amr:       "hwk user",
+		UserID:    tim.ID,
+		ClientID:  client.ID,
+	}).Error)
+
+	// The claims of the ID token, which is verified by the JWT service
+	idTokenClaims := func(t *testing.T, idToken string) map[string]any {
+		t.Helper()
+		_, err := jwtService.VerifyIdToken(idToken, false)
+		require.NoError(t, err)
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(idToken, ".")[1])
+		require.NoError(t, err)
+		var claims map[string]any
+		require.NoError(t, json.Unmarshal(payload, &claims))
+		return claims
+	}
+
+	tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         "code",
+		ClientID:     client.ID,
+		ClientSecret: clientSecret,
+	})
+	require.NoError(t, err)
+	claims := idTokenClaims(t, tokens.IdToken)
+	assert.Equal(t, model.AcrPasskey, claims["acr"])
+	assert.Equal(t, []any{"hwk", "user"}, claims["amr"])
+
+	// The authentication context must survive multiple refreshes
+	for range 2 {
+		tokens, err = s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		claims = idTokenClaims(t, tokens.IdToken)
+		assert.Equal(t, tim.ID, claims["sub"])
+		assert.Equal(t, "tim@example.com", claims["email"])
+		assert.Equal(t, model.AcrPasskey, claims["acr"])
+		assert.Equal(t, []any{"hwk", "user"}, claims["amr"])
+		assert.NotContains(t, claims, "nonce")
+	}
+}
@@ -0,0 +1,704 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+const (
+	// SamlCertificateFile is the path in the data/keys folder where the self-signed certificate for the signing key is stored
+	SamlCertificateFile = "saml_certificate.pem"
+
+	samlCertificateValidity = 20 * 365 * 24 * time.Hour
+)
+
+type SamlService struct {
+	db                 *gorm.DB
+	jwtService         *JwtService
+	appConfigService   *AppConfigService
+	auditLogService    *AuditLogService
+	customClaimService *CustomClaimService
+
+	certificateMu sync.Mutex
+	certificate   *x509.Certificate
+}
+
+func NewSamlService(db *gorm.DB, jwtService *JwtService, appConfigService *AppConfigService, auditLogService *AuditLogService, customClaimService *CustomClaimService) *SamlService {
+	return &SamlService{
+		db:                 db,
+		jwtService:         jwtService,
+		appConfigService:   appConfigService,
+		auditLogService:    auditLogService,
+		customClaimService: customClaimService,
+	}
+}
+
+// GetMetadata returns the metadata XML of the identity provider
+func (s *SamlService) GetMetadata(ctx context.Context) ([]byte, error) {
+	idp, err := s.identityProvider(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := xml.MarshalIndent(idp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	return append([]byte(xml.Header), metadata...), nil
+}
+
+// DecodeSsoRequest reads the AuthnRequest sent with the HTTP-Redirect or HTTP-POST binding and
+// returns it encoded for the HTTP-Redirect binding, so it can be passed on to the sign in page
+func (s *SamlService) DecodeSsoRequest(r *http.Request) (samlRequest string, relayState string, err error) {
+	req, err := saml.NewIdpAuthnRequest(&saml.IdentityProvider{}, r)
+	if err != nil {
+		return "", "", &common.SamlInvalidRequestError{Message: err.Error()}
+	}
+
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = writer.Write(req.RequestBuffer)
+	if err != nil {
+		return "", "", err
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), req.RelayState, nil
+}
+
+// CreateSsoResponse creates the signed SAML response for a SP-initiated or IdP-initiated sign in of the user
+func (s *SamlService) CreateSsoResponse(ctx context.Context, input dto.SamlSsoRequestDto, userID, sessionID, ipAddress, userAgent string) (dto.SamlSsoResponseDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	idp, err := s.identityProvider(ctx, tx)
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	var req *saml.IdpAuthnRequest
+	if input.SamlRequest != "" {
+		req, err = s.parseAuthnRequest(ctx, idp, input.SamlRequest, input.RelayState, ipAddress)
+	} else {
+		req, err = s.newIdpInitiatedRequest(ctx, idp, input.ServiceProviderID, input.RelayState, ipAddress)
+	}
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	var serviceProvider model.SamlServiceProvider
+	err = tx.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		First(&serviceProvider, "entity_id = ?", req.ServiceProviderMetadata.EntityID).
+		Error
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	var user model.User
+	err = tx.
+		WithContext(ctx).
+		Preload("UserGroups").
+		First(&user, "id = ?", userID).
+		Error
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	if !s.IsUserGroupAllowedToSignIn(user, serviceProvider) {
+		return dto.SamlSsoResponseDto{}, &common.SamlAccessDeniedError{}
+	}
+
+	var userSession model.Session
+	err = tx.
+		WithContext(ctx).
+		First(&userSession, "id = ? AND user_id = ?", sessionID, userID).
+		Error
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	session, err := s.samlSessionForUser(ctx, user, userSession, serviceProvider, tx)
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	err = saml.DefaultAssertionMaker{}.MakeAssertion(req, session)
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, fmt.Errorf("failed to create assertion: %w", err)
+	}
+
+	// Use the same authentication context class as in OIDC
+	for i := range req.Assertion.AuthnStatements {
+		req.Assertion.AuthnStatements[i].AuthnContext.AuthnContextClassRef.Value = userSession.Acr()
+	}
+
+	form, err := req.PostBinding()
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, &common.SamlInvalidRequestError{Message: err.Error()}
+	}
+
+	s.auditLogService.Create(ctx, model.AuditLogEventSamlSignIn, ipAddress, userAgent, userID, model.AuditLogData{"clientName": serviceProvider.Name}, tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return dto.SamlSsoResponseDto{}, err
+	}
+
+	return dto.SamlSsoResponseDto{
+		URL:          form.URL,
+		SamlResponse: form.SAMLResponse,
+		RelayState:   form.RelayState,
+	}, nil
+}
+
+// IsUserGroupAllowedToSignIn checks if the user is a member of a user group that is allowed to sign in to the service provider
+func (s *SamlService) IsUserGroupAllowedToSignIn(user model.User, serviceProvider model.SamlServiceProvider) bool {
+	if len(serviceProvider.AllowedUserGroups) == 0 {
+		return true
+	}
+
+	for _, userGroup := range serviceProvider.AllowedUserGroups {
+		for _, userGroupUser := range user.UserGroups {
+			if userGroup.ID == userGroupUser.ID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *SamlService) parseAuthnRequest(ctx context.Context, idp *saml.IdentityProvider, samlRequest, relayState, ipAddress string) (*saml.IdpAuthnRequest, error) {
+	query := url.Values{}
+	query.Set("SAMLRequest", samlRequest)
+	query.Set("RelayState", relayState)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, idp.SSOURL.String()+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = ipAddress
+
+	req, err := saml.NewIdpAuthnRequest(idp, r)
+	if err != nil {
+		return nil, &common.SamlInvalidRequestError{Message: err.Error()}
+	}
+
+	err = req.Validate()
+	if err != nil {
+		return nil, &common.SamlInvalidRequestError{Message: err.Error()}
+	}
+
+	return req, nil
+}
+
+func (s *SamlService) newIdpInitiatedRequest(ctx context.Context, idp *saml.IdentityProvider, serviceProviderID, relayState, ipAddress string) (*saml.IdpAuthnRequest, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, idp.SSOURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = ipAddress
+
+	req := &saml.IdpAuthnRequest{
+		IDP:         idp,
+		HTTPRequest: r,
+		RelayState:  relayState,
+		Now:         saml.TimeNow(),
+	}
+
+	provider := idp.ServiceProviderProvider.(*samlServiceProviderProvider)
+	req.ServiceProviderMetadata, err = provider.getServiceProviderByID(serviceProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// IdP-initiated responses can only be sent with the HTTP-POST binding
+	for _, spssoDescriptor := range req.ServiceProviderMetadata.SPSSODescriptors {
+		for _, endpoint := range spssoDescriptor.AssertionConsumerServices {
+			if endpoint.Binding == saml.HTTPPostBinding {
+				req.ACSEndpoint = &endpoint
+				req.SPSSODescriptor = &spssoDescriptor
+				return req, nil
+			}
+		}
+	}
+
+	return nil, &common.SamlInvalidRequestError{Message: "the service provider has no assertion consumer service with the HTTP-POST binding"}
+}
+
+func (s *SamlService) samlSessionForUser(ctx context.Context, user model.User, userSession model.Session, serviceProvider model.SamlServiceProvider, tx *gorm.DB) (*saml.Session, error) {
+	session := &saml.Session{
+		ID:         userSession.ID,
+		Index:      userSession.ID,
+		CreateTime: userSession.CreatedAt.ToTime(),
+		ExpireTime: userSession.ExpiresAt.ToTime(),
+	}
+
+	switch serviceProvider.NameIDFormat {
+	case model.SamlNameIDFormatEmail:
+		session.NameID = user.Email
+		session.NameIDFormat = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	case model.SamlNameIDFormatUnspecified:
+		session.NameID = user.Username
+		session.NameIDFormat = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	default:
+		session.NameID = user.ID
+		session.NameIDFormat = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	}
+
+	groups := make([]string, len(user.UserGroups))
+	for i, group := range user.UserGroups {
+		groups[i] = group.Name
+	}
+
+	customClaims, err := s.customClaimService.GetCustomClaimsForUserWithUserGroups(ctx, user.ID, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a mapping, the standard attributes and all custom claims are sent
+	if len(serviceProvider.AttributeMapping) == 0 {
+		session.UserName = user.Username
+		session.UserEmail = user.Email
+		session.UserGivenName = user.FirstName
+		session.UserSurname = user.LastName
+		session.UserCommonName = user.FullName()
+		session.Groups = groups
+
+		for _, customClaim := range customClaims {
+			session.CustomAttributes = append(session.CustomAttributes, newSamlAttribute(customClaim.Key, customClaimValues(customClaim.Value)...))
+		}
+
+		return session, nil
+	}
+
+	customClaimsByKey := make(map[string]string, len(customClaims))
+	for _, customClaim := range customClaims {
+		customClaimsByKey[customClaim.Key] = customClaim.Value
+	}
+
+	for _, attribute := range serviceProvider.AttributeMapping {
+		var values []string
+		switch attribute.Value {
+		case "id":
+			values = []string{user.ID}
+		case "username":
+			values = []string{user.Username}
+		case "email":
+			values = []string{user.Email}
+		case "firstName":
+			values = []string{user.FirstName}
+		case "lastName":
+			values = []string{user.LastName}
+		case "name":
+			values = []string{user.FullName()}
+		case "groups":
+			values = groups
+		default:
+			value, ok := customClaimsByKey[attribute.Value]
+			if !ok {
+				continue
+			}
+			values = customClaimValues(value)
+		}
+
+		session.CustomAttributes = append(session.CustomAttributes, newSamlAttribute(attribute.Name, values...))
+	}
+
+	return session, nil
+}
+
+func newSamlAttribute(name string, values ...string) saml.Attribute {
+	attribute := saml.Attribute{
+		Name:       name,
+		NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+		Values:     make([]saml.AttributeValue, len(values)),
+	}
+	for i, value := range values {
+		attribute.Values[i] = saml.AttributeValue{Type: "xs:string", Value: value}
+	}
+	return attribute
+}
+
+// customClaimValues returns the values of a custom claim, which can be a JSON array or a single string
+func customClaimValues(value string) []string {
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err == nil {
+		return values
+	}
+	return []string{value}
+}
+
+func (s *SamlService) identityProvider(ctx context.Context, tx *gorm.DB) (*saml.IdentityProvider, error) {
+	signer, err := s.jwtService.GetSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	var signatureMethod string
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		signatureMethod = dsig.RSASHA256SignatureMethod
+	case *ecdsa.PublicKey:
+		signatureMethod = dsig.ECDSASHA256SignatureMethod
+	default:
+		return nil, fmt.Errorf("keys of type %T can't be used to sign SAML assertions", signer.Public())
+	}
+
+	certificate, err := s.getCertificate(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL, err := url.Parse(common.EnvConfig.AppURL + "/api/saml/metadata")
+	if err != nil {
+		return nil, err
+	}
+	ssoURL, err := url.Parse(common.EnvConfig.AppURL + "/api/saml/sso")
+	if err != nil {
+		return nil, err
+	}
+
+	return &saml.IdentityProvider{
+		Signer:                  signer,
+		Certificate:             certificate,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		SignatureMethod:         signatureMethod,
+		ServiceProviderProvider: &samlServiceProviderProvider{ctx: ctx, tx: tx},
+	}, nil
+}
+
+// getCertificate returns a self-signed certificate for the signing key, as SAML requires the key to be wrapped in a certificate.
+// The certificate is stored so service providers that pin it don't need to be updated after a restart.
+func (s *SamlService) getCertificate(signer crypto.Signer) (*x509.Certificate, error) {
+	s.certificateMu.Lock()
+	defer s.certificateMu.Unlock()
+
+	if s.certificate != nil && publicKeysEqual(s.certificate.PublicKey, signer.Public()) {
+		return s.certificate, nil
+	}
+
+	certificatePath := filepath.Join(common.EnvConfig.KeysPath, SamlCertificateFile)
+	data, err := os.ReadFile(certificatePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read SAML certificate: %w", err)
+	}
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block != nil {
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			// Regenerate the certificate if the signing key has been changed in the meantime
+			if err == nil && publicKeysEqual(certificate.PublicKey, signer.Public()) {
+				s.certificate = certificate
+				return certificate, nil
+			}
+		}
+	}
+
+	certificate, err := generateSamlCertificate(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(certificatePath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for SAML certificate: %w", err)
+	}
+	err = os.WriteFile(certificatePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}), 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save SAML certificate: %w", err)
+	}
+
+	s.certificate = certificate
+	return certificate, nil
+}
+
+func generateSamlCertificate(signer crypto.Signer) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	commonName := common.EnvConfig.AppURL
+	if appURL, err := url.Parse(common.EnvConfig.AppURL); err == nil {
+		commonName = appURL.Hostname()
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(samlCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SAML certificate: %w", err)
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
+
+// samlServiceProviderProvider looks up the metadata of registered service providers
+type samlServiceProviderProvider struct {
+	ctx context.Context
+	tx  *gorm.DB
+}
+
+func (p *samlServiceProviderProvider) GetServiceProvider(_ *http.Request, entityID string) (*saml.EntityDescriptor, error) {
+	return p.getServiceProvider("entity_id = ?", entityID)
+}
+
+func (p *samlServiceProviderProvider) getServiceProviderByID(id string) (*saml.EntityDescriptor, error) {
+	return p.getServiceProvider("id = ?", id)
+}
+
+func (p *samlServiceProviderProvider) getServiceProvider(query string, args ...any) (*saml.EntityDescriptor, error) {
+	var serviceProvider model.SamlServiceProvider
+	err := p.tx.
+		WithContext(p.ctx).
+		First(&serviceProvider, append([]any{query}, args...)...).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+
+	return samlEntityDescriptor(serviceProvider)
+}
+
+// samlEntityDescriptor returns the metadata of the service provider, or builds it from the ACS URL if no metadata was provided
+func samlEntityDescriptor(serviceProvider model.SamlServiceProvider) (*saml.EntityDescriptor, error) {
+	if serviceProvider.Metadata != "" {
+		var metadata saml.EntityDescriptor
+		err := xml.Unmarshal([]byte(serviceProvider.Metadata), &metadata)
+		if err != nil {
+			return nil, &common.SamlInvalidRequestError{Message: "failed to parse the metadata of the service provider"}
+		}
+		// The entity ID of the registration takes precedence
+		metadata.EntityID = serviceProvider.EntityID
+		return &metadata, nil
+	}
+
+	return &saml.EntityDescriptor{
+		EntityID: serviceProvider.EntityID,
+		SPSSODescriptors: []saml.SPSSODescriptor{{
+			AssertionConsumerServices: []saml.IndexedEndpoint{{
+				Binding:   saml.HTTPPostBinding,
+				Location:  serviceProvider.AcsURL,
+				Index:     1,
+				IsDefault: utils.Ptr(true),
+			}},
+		}},
+	}, nil
+}
+
+func (s *SamlService) ListServiceProviders(ctx context.Context, search string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.SamlServiceProvider, utils.PaginationResponse, error) {
+	var serviceProviders []model.SamlServiceProvider
+
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.SamlServiceProvider{})
+
+	if search != "" {
+		query = query.Where("name LIKE ? OR entity_id LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	response, err := utils.PaginateAndSort(sortedPaginationRequest, query, &serviceProviders)
+	return serviceProviders, response, err
+}
+
+func (s *SamlService) GetServiceProvider(ctx context.Context, id string) (model.SamlServiceProvider, error) {
+	return s.getServiceProviderInternal(ctx, id, s.db)
+}
+
+func (s *SamlService) getServiceProviderInternal(ctx context.Context, id string, tx *gorm.DB) (model.SamlServiceProvider, error) {
+	var serviceProvider model.SamlServiceProvider
+	err := tx.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		First(&serviceProvider, "id = ?", id).
+		Error
+	return serviceProvider, err
+}
+
+func (s *SamlService) CreateServiceProvider(ctx context.Context, input dto.SamlServiceProviderCreateDto, userID string) (model.SamlServiceProvider, error) {
+	serviceProvider := model.SamlServiceProvider{
+		CreatedByID: userID,
+	}
+	err := updateSamlServiceProviderModelFromDto(&serviceProvider, &input)
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Create(&serviceProvider).
+		Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return model.SamlServiceProvider{}, &common.AlreadyInUseError{Property: "entity ID"}
+	} else if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	return serviceProvider, nil
+}
+
+func (s *SamlService) UpdateServiceProvider(ctx context.Context, id string, input dto.SamlServiceProviderCreateDto) (model.SamlServiceProvider, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	serviceProvider, err := s.getServiceProviderInternal(ctx, id, tx)
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	err = updateSamlServiceProviderModelFromDto(&serviceProvider, &input)
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Save(&serviceProvider).
+		Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return model.SamlServiceProvider{}, &common.AlreadyInUseError{Property: "entity ID"}
+	} else if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	return serviceProvider, nil
+}
+
+func updateSamlServiceProviderModelFromDto(serviceProvider *model.SamlServiceProvider, input *dto.SamlServiceProviderCreateDto) error {
+	serviceProvider.Name = input.Name
+	serviceProvider.EntityID = input.EntityID
+	serviceProvider.AcsURL = input.AcsURL
+	serviceProvider.Metadata = input.Metadata
+
+	serviceProvider.NameIDFormat = input.NameIDFormat
+	if serviceProvider.NameIDFormat == "" {
+		serviceProvider.NameIDFormat = model.SamlNameIDFormatPersistent
+	}
+
+	serviceProvider.AttributeMapping = make(model.SamlAttributeMapping, len(input.AttributeMapping))
+	for i, attribute := range input.AttributeMapping {
+		serviceProvider.AttributeMapping[i] = model.SamlAttribute{Name: attribute.Name, Value: attribute.Value}
+	}
+
+	// Make sure the metadata can be used before saving it
+	if serviceProvider.Metadata != "" {
+		_, err := samlEntityDescriptor(*serviceProvider)
+		if err != nil {
+			return &common.ValidationError{Message: "The metadata of the service provider is invalid"}
+		}
+	}
+
+	return nil
+}
+
+func (s *SamlService) DeleteServiceProvider(ctx context.Context, id string) error {
+	return s.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.SamlServiceProvider{}).
+		Error
+}
+
+func (s *SamlService) UpdateAllowedUserGroups(ctx context.Context, id string, input dto.SamlUpdateAllowedUserGroupsDto) (model.SamlServiceProvider, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	serviceProvider, err := s.getServiceProviderInternal(ctx, id, tx)
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	var groups []model.UserGroup
+	if len(input.UserGroupIDs) > 0 {
+		err = tx.
+			WithContext(ctx).
+			Where("id IN (?)", input.UserGroupIDs).
+			Find(&groups).
+			Error
+		if err != nil {
+			return model.SamlServiceProvider{}, err
+		}
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Model(&serviceProvider).
+		Association("AllowedUserGroups").
+		Replace(groups)
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.SamlServiceProvider{}, err
+	}
+
+	return serviceProvider, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/url"
+	"testing"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+func TestSamlService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"},
+	})
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(appConfig, common.EnvConfig.KeysPath))
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	service := NewSamlService(db, jwtService, appConfig, auditLogService, NewCustomClaimService(db, auditLogService))
+
+	user := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim", LastName: "Cook"}
+	require.NoError(t, db.Create(&user).Error)
+	group := model.UserGroup{Name: "developers", FriendlyName: "Developers", Users: []model.User{user}}
+	require.NoError(t, db.Create(&group).Error)
+	require.NoError(t, db.Create(&model.CustomClaim{Key: "department", Value: "Engineering", UserID: &user.ID}).Error)
+
+	userSession, err := sessionService.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+	require.NoError(t, err)
+
+	serviceProvider, err := service.CreateServiceProvider(t.Context(), dto.SamlServiceProviderCreateDto{
+		Name:     "Wiki",
+		EntityID: "https://wiki.example.com/saml",
+		AcsURL:   "https://wiki.example.com/saml/acs",
+		AttributeMapping: []dto.SamlAttributeDto{
+			{Name: "mail", Value: "email"},
+			{Name: "memberOf", Value: "groups"},
+			{Name: "department", Value: "department"},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+
+	// The service provider verifies the response with the metadata of the identity provider
+	metadata, err := service.GetMetadata(t.Context())
+	require.NoError(t, err)
+	var idpMetadata saml.EntityDescriptor
+	require.NoError(t, xml.Unmarshal(metadata, &idpMetadata))
+
+	acsURL, _ := url.Parse(serviceProvider.AcsURL)
+	sp := saml.ServiceProvider{
+		EntityID:          serviceProvider.EntityID,
+		AcsURL:            *acsURL,
+		IDPMetadata:       &idpMetadata,
+		AllowIDPInitiated: true,
+	}
+
+	parseResponse := func(t *testing.T, response dto.SamlSsoResponseDto, requestIDs []string) *saml.Assertion {
+		assert.Equal(t, serviceProvider.AcsURL, response.URL)
+		responseXML, err := base64.StdEncoding.DecodeString(response.SamlResponse)
+		require.NoError(t, err)
+		assertion, err := sp.ParseXMLResponse(responseXML, requestIDs, *acsURL)
+		require.NoError(t, err)
+		return assertion
+	}
+
+	attributeValues := func(assertion *saml.Assertion, name string) []string {
+		var values []string
+		for _, attribute := range assertion.AttributeStatements[0].Attributes {
+			if attribute.Name == name {
+				for _, value := range attribute.Values {
+					values = append(values, value.Value)
+				}
+			}
+		}
+		return values
+	}
+
+	t.Run("creates a signed response for IdP-initiated sign ins", func(t *testing.T) {
+		response, err := service.CreateSsoResponse(t.Context(), dto.SamlSsoRequestDto{ServiceProviderID: serviceProvider.ID, RelayState: "/home"}, user.ID, userSession.ID, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+		assert.Equal(t, "/home", response.RelayState)
+
+		assertion := parseResponse(t, response, []string{""})
+		assert.Equal(t, user.ID, assertion.Subject.NameID.Value)
+		assert.Equal(t, model.AcrPasskey, assertion.AuthnStatements[0].AuthnContext.AuthnContextClassRef.Value)
+		assert.Equal(t, []string{"tim@example.com"}, attributeValues(assertion, "mail"))
+		assert.Equal(t, []string{"developers"}, attributeValues(assertion, "memberOf"))
+		assert.Equal(t, []string{"Engineering"}, attributeValues(assertion, "department"))
+	})
+
+	t.Run("creates a signed response for SP-initiated sign ins", func(t *testing.T) {
+		ssoURL, _ := url.Parse(common.EnvConfig.AppURL + "/api/saml/sso")
+		authnRequest, err := sp.MakeAuthenticationRequest(ssoURL.String(), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+		require.NoError(t, err)
+		redirectURL, err := authnRequest.Redirect("state", &sp)
+		require.NoError(t, err)
+
+		response, err := service.CreateSsoResponse(t.Context(), dto.SamlSsoRequestDto{
+			SamlRequest: redirectURL.Query().Get("SAMLRequest"),
+			RelayState:  redirectURL.Query().Get("RelayState"),
+		}, user.ID, userSession.ID, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		parseResponse(t, response, []string{authnRequest.ID})
+	})
+
+	t.Run("rejects users that aren't in an allowed user group", func(t *testing.T) {
+		otherGroup := model.UserGroup{Name: "admins", FriendlyName: "Admins"}
+		require.NoError(t, db.Create(&otherGroup).Error)
+		_, err := service.UpdateAllowedUserGroups(t.Context(), serviceProvider.ID, dto.SamlUpdateAllowedUserGroupsDto{UserGroupIDs: []string{otherGroup.ID}})
+		require.NoError(t, err)
+
+		_, err = service.CreateSsoResponse(t.Context(), dto.SamlSsoRequestDto{ServiceProviderID: serviceProvider.ID}, user.ID, userSession.ID, "127.0.0.1", "test-agent")
+		require.ErrorIs(t, err, &common.SamlAccessDeniedError{})
+	})
+}
@@ -0,0 +1,628 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+const (
+	// scimProvisioningBatchSize is the maximum number of queued users that are synced per run
+	scimProvisioningBatchSize = 100
+	// scimProvisioningMaxBackoff is the maximum delay between two attempts to sync a user
+	scimProvisioningMaxBackoff = 6 * time.Hour
+)
+
+// ScimProvisioningService pushes the users that are allowed to use an OIDC client to the SCIM server of the client.
+// Every change that affects a user is added to a persistent queue, which is processed by a background job.
+type ScimProvisioningService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewScimProvisioningService(db *gorm.DB, httpClient *http.Client) *ScimProvisioningService {
+	return &ScimProvisioningService{db: db, httpClient: httpClient}
+}
+
+func (s *ScimProvisioningService) GetTarget(ctx context.Context, clientID string) (dto.ScimProvisioningTargetDto, error) {
+	var target model.ScimProvisioningTarget
+	err := s.db.
+		WithContext(ctx).
+		Where("oidc_client_id = ?", clientID).
+		First(&target).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	return s.targetToDto(ctx, target)
+}
+
+// UpdateTarget creates or updates the provisioning target of a client and queues all users to be synced
+func (s *ScimProvisioningService) UpdateTarget(ctx context.Context, clientID string, input dto.ScimProvisioningTargetUpdateDto) (dto.ScimProvisioningTargetDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var client model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		Where("id = ?", clientID).
+		First(&client).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	var target model.ScimProvisioningTarget
+	err = tx.
+		WithContext(ctx).
+		Where("oidc_client_id = ?", clientID).
+		First(&target).
+		Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	target.OidcClientID = clientID
+	target.Endpoint = strings.TrimSuffix(input.Endpoint, "/")
+	target.Enabled = input.Enabled
+	if input.Token != "" {
+		target.Token = input.Token
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Save(&target).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	err = s.enqueueTargetInternal(ctx, target.ID, tx)
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	return s.targetToDto(ctx, target)
+}
+
+func (s *ScimProvisioningService) DeleteTarget(ctx context.Context, clientID string) error {
+	result := s.db.
+		WithContext(ctx).
+		Where("oidc_client_id = ?", clientID).
+		Delete(&model.ScimProvisioningTarget{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SyncTarget queues all users to be synced to the provisioning target of a client
+func (s *ScimProvisioningService) SyncTarget(ctx context.Context, clientID string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var target model.ScimProvisioningTarget
+	err := tx.
+		WithContext(ctx).
+		Where("oidc_client_id = ?", clientID).
+		First(&target).
+		Error
+	if err != nil {
+		return err
+	}
+
+	err = s.enqueueTargetInternal(ctx, target.ID, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (s *ScimProvisioningService) targetToDto(ctx context.Context, target model.ScimProvisioningTarget) (dto.ScimProvisioningTargetDto, error) {
+	targetDto := dto.ScimProvisioningTargetDto{
+		ID:            target.ID,
+		Endpoint:      target.Endpoint,
+		Enabled:       target.Enabled,
+		LastSyncedAt:  target.LastSyncedAt,
+		LastSyncError: target.LastSyncError,
+	}
+
+	err := s.db.
+		WithContext(ctx).
+		Model(&model.ScimProvisioningJob{}).
+		Where("scim_provisioning_target_id = ?", target.ID).
+		Count(&targetDto.PendingUsers).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Model(&model.ScimProvisioningJob{}).
+		Where("scim_provisioning_target_id = ? AND attempts > 0", target.ID).
+		Count(&targetDto.FailedUsers).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Model(&model.ScimProvisionedUser{}).
+		Where("scim_provisioning_target_id = ? AND active = ?", target.ID, true).
+		Count(&targetDto.ProvisionedUsers).
+		Error
+	if err != nil {
+		return dto.ScimProvisioningTargetDto{}, err
+	}
+
+	return targetDto, nil
+}
+
+// enqueueUsersInternal queues users to be synced to all provisioning targets.
+// It must be called whenever the profile of a user or their group memberships change.
+func (s *ScimProvisioningService) enqueueUsersInternal(ctx context.Context, userIDs []string, tx *gorm.DB) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var targetIDs []string
+	err := tx.
+		WithContext(ctx).
+		Model(&model.ScimProvisioningTarget{}).
+		Pluck("id", &targetIDs).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load SCIM provisioning targets: %w", err)
+	}
+
+	for _, targetID := range targetIDs {
+		err = s.enqueueInternal(ctx, targetID, userIDs, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueClientInternal queues all users to be synced to the provisioning target of a client, if it has one.
+// It must be called whenever the allowed user groups of the client change.
+func (s *ScimProvisioningService) enqueueClientInternal(ctx context.Context, clientID string, tx *gorm.DB) error {
+	var targetIDs []string
+	err := tx.
+		WithContext(ctx).
+		Model(&model.ScimProvisioningTarget{}).
+		Where("oidc_client_id = ?", clientID).
+		Pluck("id", &targetIDs).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load SCIM provisioning target: %w", err)
+	}
+
+	for _, targetID := range targetIDs {
+		err = s.enqueueTargetInternal(ctx, targetID, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueTargetInternal queues all users, including the provisioned users that have been deleted since, to be synced to a target
+func (s *ScimProvisioningService) enqueueTargetInternal(ctx context.Context, targetID string, tx *gorm.DB) error {
+	var userIDs []string
+	err := tx.
+		WithContext(ctx).
+		Model(&model.User{}).
+		Pluck("id", &userIDs).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	var provisionedUserIDs []string
+	err = tx.
+		WithContext(ctx).
+		Model(&model.ScimProvisionedUser{}).
+		Where("scim_provisioning_target_id = ?", targetID).
+		Pluck("user_id", &provisionedUserIDs).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load provisioned users: %w", err)
+	}
+
+	for _, userID := range provisionedUserIDs {
+		if !slices.Contains(userIDs, userID) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return s.enqueueInternal(ctx, targetID, userIDs, tx)
+}
+
+func (s *ScimProvisioningService) enqueueInternal(ctx context.Context, targetID string, userIDs []string, tx *gorm.DB) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := datatype.DateTime(time.Now())
+	jobs := make([]model.ScimProvisioningJob, len(userIDs))
+	for i, userID := range userIDs {
+		jobs[i] = model.ScimProvisioningJob{
+			ScimProvisioningTargetID: targetID,
+			UserID:                   userID,
+			NextAttemptAt:            now,
+		}
+	}
+
+	// If a user is already queued, the job is reset so that it's retried immediately
+	err := tx.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "scim_provisioning_target_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"attempts":        0,
+				"next_attempt_at": now,
+				"last_error":      nil,
+			}),
+		}).
+		CreateInBatches(&jobs, 100).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to queue users for SCIM provisioning: %w", err)
+	}
+	return nil
+}
+
+// ProcessQueue syncs the queued users whose next attempt is due.
+// Failed syncs are retried with an exponential backoff.
+func (s *ScimProvisioningService) ProcessQueue(ctx context.Context) error {
+	var jobs []model.ScimProvisioningJob
+	err := s.db.
+		WithContext(ctx).
+		Select("scim_provisioning_jobs.*").
+		Joins("JOIN scim_provisioning_targets ON scim_provisioning_targets.id = scim_provisioning_jobs.scim_provisioning_target_id").
+		Where("scim_provisioning_targets.enabled = ? AND scim_provisioning_jobs.next_attempt_at <= ?", true, datatype.DateTime(time.Now())).
+		Order("scim_provisioning_jobs.next_attempt_at").
+		Limit(scimProvisioningBatchSize).
+		Find(&jobs).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load SCIM provisioning queue: %w", err)
+	}
+
+	targets := make(map[string]model.ScimProvisioningTarget)
+	for _, job := range jobs {
+		target, ok := targets[job.ScimProvisioningTargetID]
+		if !ok {
+			err = s.db.
+				WithContext(ctx).
+				Where("id = ?", job.ScimProvisioningTargetID).
+				First(&target).
+				Error
+			if err != nil {
+				return fmt.Errorf("failed to load SCIM provisioning target: %w", err)
+			}
+			targets[target.ID] = target
+		}
+
+		syncErr := s.syncUser(ctx, target, job.UserID)
+		if syncErr != nil {
+			slog.WarnContext(ctx, "Failed to sync user to SCIM provisioning target",
+				slog.String("user", job.UserID),
+				slog.String("endpoint", target.Endpoint),
+				slog.Any("error", syncErr),
+			)
+		}
+
+		err = s.completeJob(ctx, job, syncErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeJob removes a successful job from the queue or schedules the next attempt of a failed job
+func (s *ScimProvisioningService) completeJob(ctx context.Context, job model.ScimProvisioningJob, syncErr error) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	now := datatype.DateTime(time.Now())
+	if syncErr == nil {
+		// The job is only deleted if it hasn't been queued again while the user was synced
+		err := tx.
+			WithContext(ctx).
+			Where("id = ? AND next_attempt_at = ?", job.ID, job.NextAttemptAt).
+			Delete(&model.ScimProvisioningJob{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to remove SCIM provisioning job: %w", err)
+		}
+
+		err = tx.
+			WithContext(ctx).
+			Model(&model.ScimProvisioningTarget{}).
+			Where("id = ?", job.ScimProvisioningTargetID).
+			Updates(map[string]any{"last_synced_at": now, "last_sync_error": nil}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to update SCIM provisioning target: %w", err)
+		}
+	} else {
+		errorMessage := syncErr.Error()
+		backoff := min(time.Minute<<min(job.Attempts, 16), scimProvisioningMaxBackoff)
+
+		err := tx.
+			WithContext(ctx).
+			Model(&model.ScimProvisioningJob{}).
+			Where("id = ? AND next_attempt_at = ?", job.ID, job.NextAttemptAt).
+			Updates(map[string]any{
+				"attempts":        job.Attempts + 1,
+				"next_attempt_at": datatype.DateTime(time.Now().Add(backoff)),
+				"last_error":      errorMessage,
+			}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to update SCIM provisioning job: %w", err)
+		}
+
+		err = tx.
+			WithContext(ctx).
+			Model(&model.ScimProvisioningTarget{}).
+			Where("id = ?", job.ScimProvisioningTargetID).
+			Update("last_sync_error", errorMessage).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to update SCIM provisioning target: %w", err)
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// syncUser creates or updates the user on the SCIM server if they are allowed to use the client, otherwise it deactivates them
+func (s *ScimProvisioningService) syncUser(ctx context.Context, target model.ScimProvisioningTarget, userID string) error {
+	var client model.OidcClient
+	err := s.db.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		Where("id = ?", target.OidcClientID).
+		First(&client).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load client: %w", err)
+	}
+
+	var user model.User
+	err = s.db.
+		WithContext(ctx).
+		Preload("UserGroups").
+		Where("id = ?", userID).
+		First(&user).
+		Error
+	userExists := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	var provisionedUser model.ScimProvisionedUser
+	err = s.db.
+		WithContext(ctx).
+		Where("scim_provisioning_target_id = ? AND user_id = ?", target.ID, userID).
+		First(&provisionedUser).
+		Error
+	isProvisioned := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load provisioned user: %w", err)
+	}
+
+	if userExists && !user.Disabled && isUserAllowedToUseClient(user, client) {
+		return s.pushUser(ctx, target, user, provisionedUser, isProvisioned)
+	}
+
+	if !isProvisioned {
+		return nil
+	}
+	return s.deactivateUser(ctx, target, provisionedUser, userExists)
+}
+
+func (s *ScimProvisioningService) pushUser(ctx context.Context, target model.ScimProvisioningTarget, user model.User, provisionedUser model.ScimProvisionedUser, isProvisioned bool) error {
+	active := true
+	scimUser := dto.ScimUserCreateDto{
+		Schemas:     []string{dto.ScimSchemaUser},
+		ExternalID:  user.ID,
+		UserName:    user.Username,
+		Name:        dto.ScimNameDto{Formatted: user.FullName(), GivenName: user.FirstName, FamilyName: user.LastName},
+		DisplayName: user.FullName(),
+		Emails:      []dto.ScimEmailDto{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+	}
+
+	remoteID := provisionedUser.RemoteID
+	if isProvisioned {
+		err := s.request(ctx, target, http.MethodPut, "/Users/"+url.PathEscape(remoteID), scimUser, nil)
+		var requestErr *scimRequestError
+		if errors.As(err, &requestErr) && requestErr.statusCode == http.StatusNotFound {
+			// The user has been deleted on the SCIM server, so we create them again
+			isProvisioned = false
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !isProvisioned {
+		var createdUser dto.ScimUserDto
+		err := s.request(ctx, target, http.MethodPost, "/Users", scimUser, &createdUser)
+		var requestErr *scimRequestError
+		if errors.As(err, &requestErr) && requestErr.statusCode == http.StatusConflict {
+			// The user already exists on the SCIM server, so we link and update them
+			createdUser.ID, err = s.findRemoteUserID(ctx, target, user.Username)
+			if err != nil {
+				return err
+			}
+			err = s.request(ctx, target, http.MethodPut, "/Users/"+url.PathEscape(createdUser.ID), scimUser, nil)
+		}
+		if err != nil {
+			return err
+		}
+		remoteID = createdUser.ID
+	}
+
+	return s.db.
+		WithContext(ctx).
+		Save(&model.ScimProvisionedUser{
+			ScimProvisioningTargetID: target.ID,
+			UserID:                   user.ID,
+			RemoteID:                 remoteID,
+			Active:                   true,
+		}).
+		Error
+}
+
+func (s *ScimProvisioningService) deactivateUser(ctx context.Context, target model.ScimProvisioningTarget, provisionedUser model.ScimProvisionedUser, userExists bool) error {
+	if provisionedUser.Active {
+		patch := dto.ScimPatchRequestDto{
+			Schemas:    []string{dto.ScimSchemaPatchOp},
+			Operations: []dto.ScimPatchOperationDto{{Op: "replace", Path: "active", Value: json.RawMessage("false")}},
+		}
+		err := s.request(ctx, target, http.MethodPatch, "/Users/"+url.PathEscape(provisionedUser.RemoteID), patch, nil)
+		var requestErr *scimRequestError
+		if err != nil && (!errors.As(err, &requestErr) || requestErr.statusCode != http.StatusNotFound) {
+			return err
+		}
+	}
+
+	// Deleted users are forgotten once they are deactivated
+	if !userExists {
+		return s.db.
+			WithContext(ctx).
+			Delete(&provisionedUser).
+			Error
+	}
+
+	provisionedUser.Active = false
+	return s.db.
+		WithContext(ctx).
+		Save(&provisionedUser).
+		Error
+}
+
+func (s *ScimProvisioningService) findRemoteUserID(ctx context.Context, target model.ScimProvisioningTarget, username string) (string, error) {
+	filter := url.Values{}
+	filter.Set("filter", fmt.Sprintf("userName eq %q", username))
+
+	var list dto.ScimListResponseDto[dto.ScimUserDto]
+	err := s.request(ctx, target, http.MethodGet, "/Users?"+filter.Encode(), nil, &list)
+	if err != nil {
+		return "", err
+	}
+	if len(list.Resources) == 0 {
+		return "", fmt.Errorf("user '%s' already exists on the SCIM server but can't be found", username)
+	}
+	return list.Resources[0].ID, nil
+}
+
+// scimRequestError is returned if the SCIM server responds with an error status code
+type scimRequestError struct {
+	statusCode int
+	detail     string
+}
+
+func (e *scimRequestError) Error() string {
+	return fmt.Sprintf("SCIM server responded with status %d: %s", e.statusCode, e.detail)
+}
+
+func (s *ScimProvisioningService) request(ctx context.Context, target model.ScimProvisioningTarget, method string, path string, body any, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.Endpoint+path, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+target.Token)
+	req.Header.Set("Accept", "application/scim+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/scim+json")
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to SCIM server: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var scimErr dto.ScimErrorDto
+		responseBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		if json.Unmarshal(responseBody, &scimErr) != nil || scimErr.Detail == "" {
+			scimErr.Detail = http.StatusText(res.StatusCode)
+		}
+		return &scimRequestError{statusCode: res.StatusCode, detail: scimErr.Detail}
+	}
+
+	if result != nil {
+		err = json.NewDecoder(res.Body).Decode(result)
+		if err != nil {
+			return fmt.Errorf("failed to decode response of SCIM server: %w", err)
+		}
+	}
+	return nil
+}
+
+// isUserAllowedToUseClient checks if the user is in one of the allowed user groups of the client, if it restricts them
+func isUserAllowedToUseClient(user model.User, client model.OidcClient) bool {
+	if len(client.AllowedUserGroups) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(client.AllowedUserGroups, func(allowedGroup model.UserGroup) bool {
+		return slices.ContainsFunc(user.UserGroups, func(group model.UserGroup) bool {
+			return group.ID == allowedGroup.ID
+		})
+	})
+}
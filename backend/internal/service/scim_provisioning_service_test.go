@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+// fakeScimServer records the requests it receives and responds like a SCIM server
+type fakeScimServer struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []map[string]any
+	fail     bool
+}
+
+func (f *fakeScimServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"detail":"database is down"}`))
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	var decoded map[string]any
+	_ = json.Unmarshal(body, &decoded)
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.bodies = append(f.bodies, decoded)
+
+	w.Header().Set("Content-Type", "application/scim+json")
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"remote-1"}`))
+		return
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func (f *fakeScimServer) lastRequest() (string, map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return "", nil
+	}
+	return f.requests[len(f.requests)-1], f.bodies[len(f.bodies)-1]
+}
+
+func TestScimProvisioningService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	scimServer := &fakeScimServer{}
+	server := httptest.NewServer(scimServer)
+	t.Cleanup(server.Close)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration:     model.AppConfigVariable{Value: "60"},
+		AllowOwnAccountEdit: model.AppConfigVariable{Value: "true"},
+	})
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	service := NewScimProvisioningService(db, server.Client())
+	userService := NewUserService(db, nil, auditLogService, nil, appConfig, sessionService, service, webhookService)
+	userGroupService := NewUserGroupService(db, appConfig, service, webhookService, auditLogService)
+
+	user := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim", LastName: "Cook"}
+	require.NoError(t, db.Create(&user).Error)
+	group := model.UserGroup{Name: "developers", FriendlyName: "Developers"}
+	require.NoError(t, db.Create(&group).Error)
+	client := model.OidcClient{Name: "Wiki", AllowedUserGroups: []model.UserGroup{group}}
+	require.NoError(t, db.Create(&client).Error)
+
+	target, err := service.UpdateTarget(t.Context(), client.ID, dto.ScimProvisioningTargetUpdateDto{
+		Endpoint: server.URL + "/scim/v2/",
+		Token:    "secret-token",
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, target.PendingUsers)
+
+	t.Run("doesn't provision users that aren't allowed to use the client", func(t *testing.T) {
+		require.NoError(t, service.ProcessQueue(t.Context()))
+		request, _ := scimServer.lastRequest()
+		assert.Empty(t, request)
+
+		target, err := service.GetTarget(t.Context(), client.ID)
+		require.NoError(t, err)
+		assert.Zero(t, target.PendingUsers)
+		assert.Zero(t, target.ProvisionedUsers)
+	})
+
+	t.Run("creates users when they are added to an allowed group", func(t *testing.T) {
+		_, err := userGroupService.UpdateUsers(t.Context(), group.ID, []string{user.ID})
+		require.NoError(t, err)
+		require.NoError(t, service.ProcessQueue(t.Context()))
+
+		request, body := scimServer.lastRequest()
+		assert.Equal(t, "POST /scim/v2/Users", request)
+		assert.Equal(t, "tim", body["userName"])
+		assert.Equal(t, user.ID, body["externalId"])
+
+		target, err := service.GetTarget(t.Context(), client.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, target.ProvisionedUsers)
+		assert.NotNil(t, target.LastSyncedAt)
+	})
+
+	t.Run("updates users when their profile changes", func(t *testing.T) {
+		_, err := userService.UpdateUser(t.Context(), user.ID, dto.UserCreateDto{Username: "tim", Email: "tim@example.com", FirstName: "Timothy", LastName: "Cook"}, false, false)
+		require.NoError(t, err)
+		require.NoError(t, service.ProcessQueue(t.Context()))
+
+		request, body := scimServer.lastRequest()
+		assert.Equal(t, "PUT /scim/v2/Users/remote-1", request)
+		assert.Equal(t, "Timothy", body["name"].(map[string]any)["givenName"])
+	})
+
+	t.Run("retries failed syncs later", func(t *testing.T) {
+		scimServer.fail = true
+		t.Cleanup(func() { scimServer.fail = false })
+
+		require.NoError(t, service.SyncTarget(t.Context(), client.ID))
+		require.NoError(t, service.ProcessQueue(t.Context()))
+
+		target, err := service.GetTarget(t.Context(), client.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, target.FailedUsers)
+		require.NotNil(t, target.LastSyncError)
+		assert.Contains(t, *target.LastSyncError, "database is down")
+
+		// The next attempt isn't due yet
+		scimServer.fail = false
+		require.NoError(t, service.ProcessQueue(t.Context()))
+		target, err = service.GetTarget(t.Context(), client.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, target.PendingUsers)
+	})
+
+	t.Run("deactivates users when they are removed from an allowed group", func(t *testing.T) {
+		_, err := userGroupService.UpdateUsers(t.Context(), group.ID, []string{})
+		require.NoError(t, err)
+		require.NoError(t, service.ProcessQueue(t.Context()))
+
+		request, body := scimServer.lastRequest()
+		assert.Equal(t, "PATCH /scim/v2/Users/remote-1", request)
+		assert.Equal(t, false, body["Operations"].([]any)[0].(map[string]any)["value"])
+
+		target, err := service.GetTarget(t.Context(), client.ID)
+		require.NoError(t, err)
+		assert.Zero(t, target.PendingUsers)
+		assert.Zero(t, target.ProvisionedUsers)
+		assert.Nil(t, target.LastSyncError)
+	})
+}
@@ -0,0 +1,949 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+)
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 1000
+)
+
+// ScimService implements a SCIM 2.0 server (RFC 7643 and RFC 7644) on top of the user and user group services.
+// Users and groups that are provisioned over SCIM are marked with a SCIM external ID and can't be edited in the UI.
+// The changes are audited as admin events of the actor of the context, which is the owner of the API key.
+type ScimService struct {
+	db               *gorm.DB
+	userService      *UserService
+	userGroupService *UserGroupService
+	auditLogService  *AuditLogService
+}
+
+func NewScimService(db *gorm.DB, userService *UserService, userGroupService *UserGroupService, auditLogService *AuditLogService) *ScimService {
+	return &ScimService{db: db, userService: userService, userGroupService: userGroupService, auditLogService: auditLogService}
+}
+
+// scimFilterAttribute is a SCIM attribute that can be used in filters
+type scimFilterAttribute struct {
+	column    string
+	caseExact bool
+}
+
+var scimUserFilterAttributes = map[string]scimFilterAttribute{
+	"username":        {column: "username"},
+	"externalid":      {column: "scim_external_id", caseExact: true},
+	"emails":          {column: "email"},
+	"emails.value":    {column: "email"},
+	"name.givenname":  {column: "first_name"},
+	"name.familyname": {column: "last_name"},
+}
+
+var scimGroupFilterAttributes = map[string]scimFilterAttribute{
+	"displayname": {column: "friendly_name"},
+	"externalid":  {column: "scim_external_id", caseExact: true},
+}
+
+func (s *ScimService) ListUsers(ctx context.Context, input dto.ScimListRequestDto) (dto.ScimListResponseDto[dto.ScimUserDto], error) {
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.User{}).
+		Preload("UserGroups")
+
+	query, err := applyScimFilter(query, input.Filter, scimUserFilterAttributes)
+	if err != nil {
+		return dto.ScimListResponseDto[dto.ScimUserDto]{}, err
+	}
+
+	var users []model.User
+	totalResults, startIndex, err := scimPaginate(query, input, &users)
+	if err != nil {
+		return dto.ScimListResponseDto[dto.ScimUserDto]{}, err
+	}
+
+	response := dto.ScimListResponseDto[dto.ScimUserDto]{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    make([]dto.ScimUserDto, len(users)),
+	}
+	for i, user := range users {
+		response.Resources[i] = scimUserFromModel(user)
+	}
+	return response, nil
+}
+
+func (s *ScimService) GetUser(ctx context.Context, id string) (dto.ScimUserDto, error) {
+	user, err := s.userService.GetUser(ctx, id)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+	return scimUserFromModel(user), nil
+}
+
+func (s *ScimService) CreateUser(ctx context.Context, input dto.ScimUserCreateDto) (dto.ScimUserDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	state := scimUserStateFromDto(input)
+	user, err := s.userService.createUserInternal(ctx, state.toUserCreateDto(false, nil), true, tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	// New users are always created enabled, so we disable them afterwards if needed
+	if !state.active {
+		user, err = s.userService.updateUserInternal(ctx, user.ID, state.toUserCreateDto(false, nil), false, true, tx)
+		if err != nil {
+			return dto.ScimUserDto{}, err
+		}
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserCreated, userAuditTarget(user), nil, userAuditState(user), tx)
+
+	return s.commitAndGetUser(ctx, user.ID, tx)
+}
+
+func (s *ScimService) ReplaceUser(ctx context.Context, id string, input dto.ScimUserCreateDto) (dto.ScimUserDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	user, err := s.userService.getUserInternal(ctx, id, tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	err = s.updateUserInternal(ctx, user, scimUserStateFromDto(input), tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	return s.commitAndGetUser(ctx, id, tx)
+}
+
+func (s *ScimService) PatchUser(ctx context.Context, id string, input dto.ScimPatchRequestDto) (dto.ScimUserDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	user, err := s.userService.getUserInternal(ctx, id, tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	state := scimUserStateFromModel(user)
+	for _, operation := range input.Operations {
+		err = state.applyPatchOperation(operation)
+		if err != nil {
+			return dto.ScimUserDto{}, err
+		}
+	}
+
+	if state.userName == "" || state.email == "" {
+		return dto.ScimUserDto{}, &common.ScimInvalidPatchError{Message: "userName and emails can't be removed"}
+	}
+
+	err = s.updateUserInternal(ctx, user, state, tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	return s.commitAndGetUser(ctx, id, tx)
+}
+
+func (s *ScimService) DeleteUser(ctx context.Context, id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.userService.getUserInternal(ctx, id, tx)
+		if err != nil {
+			return err
+		}
+		if user.ScimExternalID == nil {
+			return &common.ScimResourceNotProvisionedError{}
+		}
+
+		err = s.userService.deleteUserInternal(ctx, id, true, tx)
+		if err != nil {
+			return err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserDeleted, userAuditTarget(user), userAuditState(user), nil, tx)
+		return nil
+	})
+}
+
+func (s *ScimService) updateUserInternal(ctx context.Context, user model.User, state scimUserState, tx *gorm.DB) error {
+	// Users that weren't provisioned over SCIM must not be taken over by the provisioning system
+	if user.ScimExternalID == nil {
+		return &common.ScimResourceNotProvisionedError{}
+	}
+
+	updatedUser, err := s.userService.updateUserInternal(ctx, user.ID, state.toUserCreateDto(user.IsAdmin, user.Locale), false, true, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserUpdated, userAuditTarget(updatedUser), userAuditState(user), userAuditState(updatedUser), tx)
+	return nil
+}
+
+func (s *ScimService) commitAndGetUser(ctx context.Context, id string, tx *gorm.DB) (dto.ScimUserDto, error) {
+	user, err := s.userService.getUserInternal(ctx, id, tx)
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return dto.ScimUserDto{}, err
+	}
+
+	return scimUserFromModel(user), nil
+}
+
+func (s *ScimService) ListGroups(ctx context.Context, input dto.ScimListRequestDto) (dto.ScimListResponseDto[dto.ScimGroupDto], error) {
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.UserGroup{}).
+		Preload("Users")
+
+	query, err := applyScimFilter(query, input.Filter, scimGroupFilterAttributes)
+	if err != nil {
+		return dto.ScimListResponseDto[dto.ScimGroupDto]{}, err
+	}
+
+	var groups []model.UserGroup
+	totalResults, startIndex, err := scimPaginate(query, input, &groups)
+	if err != nil {
+		return dto.ScimListResponseDto[dto.ScimGroupDto]{}, err
+	}
+
+	response := dto.ScimListResponseDto[dto.ScimGroupDto]{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(groups),
+		Resources:    make([]dto.ScimGroupDto, len(groups)),
+	}
+	for i, group := range groups {
+		response.Resources[i] = scimGroupFromModel(group)
+	}
+	return response, nil
+}
+
+func (s *ScimService) GetGroup(ctx context.Context, id string) (dto.ScimGroupDto, error) {
+	group, err := s.userGroupService.Get(ctx, id)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+	return scimGroupFromModel(group), nil
+}
+
+func (s *ScimService) CreateGroup(ctx context.Context, input dto.ScimGroupCreateDto) (dto.ScimGroupDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	state := scimGroupStateFromDto(input)
+	group, err := s.userGroupService.createInternal(ctx, state.toUserGroupCreateDto(), tx)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupCreated, userGroupAuditTarget(group), nil, userGroupAuditState(group), tx)
+
+	if len(state.memberIDs) > 0 {
+		group, err = s.userGroupService.updateUsersInternal(ctx, group.ID, state.memberIDs, tx)
+		if err != nil {
+			return dto.ScimGroupDto{}, err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupMembersUpdated, userGroupAuditTarget(group),
+			usernamesAuditState(nil), usernamesAuditState(group.Users), tx)
+	}
+
+	return s.commitAndGetGroup(ctx, group.ID, tx)
+}
+
+func (s *ScimService) ReplaceGroup(ctx context.Context, id string, input dto.ScimGroupCreateDto) (dto.ScimGroupDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	err := s.updateGroupInternal(ctx, id, scimGroupStateFromDto(input), tx)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	return s.commitAndGetGroup(ctx, id, tx)
+}
+
+func (s *ScimService) PatchGroup(ctx context.Context, id string, input dto.ScimPatchRequestDto) (dto.ScimGroupDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	group, err := s.userGroupService.getInternal(ctx, id, tx)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	state := scimGroupStateFromModel(group)
+	for _, operation := range input.Operations {
+		err = state.applyPatchOperation(operation)
+		if err != nil {
+			return dto.ScimGroupDto{}, err
+		}
+	}
+
+	if state.displayName == "" {
+		return dto.ScimGroupDto{}, &common.ScimInvalidPatchError{Message: "displayName can't be removed"}
+	}
+
+	err = s.updateGroupInternal(ctx, id, state, tx)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	return s.commitAndGetGroup(ctx, id, tx)
+}
+
+func (s *ScimService) DeleteGroup(ctx context.Context, id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		group, err := s.userGroupService.getInternal(ctx, id, tx)
+		if err != nil {
+			return err
+		}
+		if group.ScimExternalID == nil {
+			return &common.ScimResourceNotProvisionedError{}
+		}
+
+		err = s.userGroupService.deleteInternal(ctx, group, tx)
+		if err != nil {
+			return err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupDeleted, userGroupAuditTarget(group), userGroupAuditState(group), nil, tx)
+		return nil
+	})
+}
+
+func (s *ScimService) updateGroupInternal(ctx context.Context, id string, state scimGroupState, tx *gorm.DB) error {
+	originalGroup, err := s.userGroupService.getInternal(ctx, id, tx)
+	if err != nil {
+		return err
+	}
+	// Groups that weren't provisioned over SCIM must not be taken over by the provisioning system
+	if originalGroup.ScimExternalID == nil {
+		return &common.ScimResourceNotProvisionedError{}
+	}
+
+	group, err := s.userGroupService.updateInternal(ctx, id, state.toUserGroupCreateDto(), true, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupUpdated, userGroupAuditTarget(group), userGroupAuditState(originalGroup), userGroupAuditState(group), tx)
+
+	group, err = s.userGroupService.updateUsersInternal(ctx, id, state.memberIDs, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupMembersUpdated, userGroupAuditTarget(group),
+		usernamesAuditState(originalGroup.Users), usernamesAuditState(group.Users), tx)
+	return nil
+}
+
+func (s *ScimService) commitAndGetGroup(ctx context.Context, id string, tx *gorm.DB) (dto.ScimGroupDto, error) {
+	group, err := s.userGroupService.getInternal(ctx, id, tx)
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return dto.ScimGroupDto{}, err
+	}
+
+	return scimGroupFromModel(group), nil
+}
+
+// scimUserState contains the SCIM attributes of a user that are stored by Pocket ID
+type scimUserState struct {
+	externalID string
+	userName   string
+	givenName  string
+	familyName string
+	email      string
+	active     bool
+}
+
+func scimUserStateFromDto(input dto.ScimUserCreateDto) scimUserState {
+	state := scimUserState{
+		externalID: input.ExternalID,
+		userName:   input.UserName,
+		givenName:  input.Name.GivenName,
+		familyName: input.Name.FamilyName,
+		email:      scimPrimaryEmail(input.Emails),
+		active:     input.Active == nil || *input.Active,
+	}
+	return state
+}
+
+func scimUserStateFromModel(user model.User) scimUserState {
+	state := scimUserState{
+		userName:   user.Username,
+		givenName:  user.FirstName,
+		familyName: user.LastName,
+		email:      user.Email,
+		active:     !user.Disabled,
+	}
+	if user.ScimExternalID != nil {
+		state.externalID = *user.ScimExternalID
+	}
+	return state
+}
+
+func (u scimUserState) toUserCreateDto(isAdmin bool, locale *string) dto.UserCreateDto {
+	// Usernames in Pocket ID are always lowercase
+	username := strings.ToLower(u.userName)
+
+	// The first name is required, so we fall back to the username if the provisioning system doesn't send it
+	firstName := u.givenName
+	if firstName == "" {
+		firstName = username
+	}
+
+	return dto.UserCreateDto{
+		Username:       username,
+		Email:          u.email,
+		FirstName:      firstName,
+		LastName:       u.familyName,
+		IsAdmin:        isAdmin,
+		Locale:         locale,
+		Disabled:       !u.active,
+		ScimExternalID: &u.externalID,
+	}
+}
+
+func (u *scimUserState) applyPatchOperation(operation dto.ScimPatchOperationDto) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return &common.ScimInvalidPatchError{Message: fmt.Sprintf("unsupported operation '%s'", operation.Op)}
+	}
+
+	// Without a path the value contains the attributes to add or replace
+	if operation.Path == "" {
+		if op == "remove" {
+			return &common.ScimInvalidPatchError{Message: "a path is required to remove attributes"}
+		}
+
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return &common.ScimInvalidPatchError{Message: "the value must be an object if no path is set"}
+		}
+		for path, value := range attributes {
+			if err := u.applyPatchValue(op, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return u.applyPatchValue(op, operation.Path, operation.Value)
+}
+
+func (u *scimUserState) applyPatchValue(op string, path string, value json.RawMessage) error {
+	path = strings.ToLower(strings.TrimPrefix(path, dto.ScimSchemaUser+":"))
+	remove := op == "remove"
+
+	var err error
+	switch {
+	case path == "username":
+		u.userName, err = scimPatchString(value, remove)
+	case path == "externalid":
+		u.externalID, err = scimPatchString(value, remove)
+	case path == "name.givenname":
+		u.givenName, err = scimPatchString(value, remove)
+	case path == "name.familyname":
+		u.familyName, err = scimPatchString(value, remove)
+	case path == "name":
+		var name dto.ScimNameDto
+		if !remove {
+			err = json.Unmarshal(value, &name)
+		}
+		u.givenName, u.familyName = name.GivenName, name.FamilyName
+	case path == "active":
+		if !remove {
+			u.active, err = scimPatchBool(value)
+		}
+	case path == "emails":
+		var emails []dto.ScimEmailDto
+		if !remove {
+			err = json.Unmarshal(value, &emails)
+		}
+		u.email = scimPrimaryEmail(emails)
+	case path == "emails.value" || (strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value")):
+		u.email, err = scimPatchString(value, remove)
+	default:
+		// Attributes that Pocket ID doesn't store, like phone numbers or the enterprise extension, are ignored
+		return nil
+	}
+
+	if err != nil {
+		return &common.ScimInvalidPatchError{Message: fmt.Sprintf("invalid value for '%s'", path)}
+	}
+	return nil
+}
+
+// scimGroupState contains the SCIM attributes of a group that are stored by Pocket ID
+type scimGroupState struct {
+	externalID  string
+	displayName string
+	memberIDs   []string
+}
+
+func scimGroupStateFromDto(input dto.ScimGroupCreateDto) scimGroupState {
+	state := scimGroupState{
+		externalID:  input.ExternalID,
+		displayName: input.DisplayName,
+		memberIDs:   make([]string, 0, len(input.Members)),
+	}
+	for _, member := range input.Members {
+		state.memberIDs = append(state.memberIDs, member.Value)
+	}
+	return state
+}
+
+func scimGroupStateFromModel(group model.UserGroup) scimGroupState {
+	state := scimGroupState{
+		displayName: group.FriendlyName,
+		memberIDs:   make([]string, 0, len(group.Users)),
+	}
+	if group.ScimExternalID != nil {
+		state.externalID = *group.ScimExternalID
+	}
+	for _, user := range group.Users {
+		state.memberIDs = append(state.memberIDs, user.ID)
+	}
+	return state
+}
+
+var scimGroupNameInvalidCharsRegex = regexp.MustCompile(`[^a-z0-9_]`)
+
+func (g scimGroupState) toUserGroupCreateDto() dto.UserGroupCreateDto {
+	return dto.UserGroupCreateDto{
+		FriendlyName: g.displayName,
+		// The name is derived from the display name the same way the admin UI does it
+		Name:           scimGroupNameInvalidCharsRegex.ReplaceAllString(strings.ToLower(g.displayName), "_"),
+		ScimExternalID: &g.externalID,
+	}
+}
+
+var scimMemberValuePathRegex = regexp.MustCompile(`(?i)^members\[value eq "([^"]+)"\]$`)
+
+func (g *scimGroupState) applyPatchOperation(operation dto.ScimPatchOperationDto) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return &common.ScimInvalidPatchError{Message: fmt.Sprintf("unsupported operation '%s'", operation.Op)}
+	}
+
+	if operation.Path == "" {
+		if op == "remove" {
+			return &common.ScimInvalidPatchError{Message: "a path is required to remove attributes"}
+		}
+
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return &common.ScimInvalidPatchError{Message: "the value must be an object if no path is set"}
+		}
+		for path, value := range attributes {
+			if err := g.applyPatchValue(op, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Members can be removed with a value filter, e.g. members[value eq "id"]
+	if match := scimMemberValuePathRegex.FindStringSubmatch(operation.Path); match != nil {
+		if op != "remove" {
+			return &common.ScimInvalidPatchError{Message: "value filters are only supported to remove members"}
+		}
+		g.memberIDs = slices.DeleteFunc(g.memberIDs, func(id string) bool { return id == match[1] })
+		return nil
+	}
+
+	return g.applyPatchValue(op, operation.Path, operation.Value)
+}
+
+func (g *scimGroupState) applyPatchValue(op string, path string, value json.RawMessage) error {
+	path = strings.ToLower(strings.TrimPrefix(path, dto.ScimSchemaGroup+":"))
+	remove := op == "remove"
+
+	var err error
+	switch path {
+	case "displayname":
+		g.displayName, err = scimPatchString(value, remove)
+	case "externalid":
+		g.externalID, err = scimPatchString(value, remove)
+	case "members":
+		var members []dto.ScimMemberDto
+		if len(value) > 0 && string(value) != "null" {
+			err = json.Unmarshal(value, &members)
+		}
+		if err != nil {
+			break
+		}
+
+		switch {
+		case op == "replace":
+			g.memberIDs = g.memberIDs[:0]
+			fallthrough
+		case op == "add":
+			for _, member := range members {
+				if !slices.Contains(g.memberIDs, member.Value) {
+					g.memberIDs = append(g.memberIDs, member.Value)
+				}
+			}
+		case len(members) == 0:
+			// Removing the members without a value removes all members
+			g.memberIDs = g.memberIDs[:0]
+		default:
+			g.memberIDs = slices.DeleteFunc(g.memberIDs, func(id string) bool {
+				return slices.ContainsFunc(members, func(member dto.ScimMemberDto) bool { return member.Value == id })
+			})
+		}
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return &common.ScimInvalidPatchError{Message: fmt.Sprintf("invalid value for '%s'", path)}
+	}
+	return nil
+}
+
+func scimUserFromModel(user model.User) dto.ScimUserDto {
+	scimUser := dto.ScimUserDto{
+		Schemas:  []string{dto.ScimSchemaUser},
+		ID:       user.ID,
+		UserName: user.Username,
+		Name: dto.ScimNameDto{
+			Formatted:  user.FullName(),
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		},
+		DisplayName: user.FullName(),
+		Emails:      []dto.ScimEmailDto{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      !user.Disabled,
+		Groups:      make([]dto.ScimMemberDto, len(user.UserGroups)),
+		Meta:        scimMeta("User", user.Base),
+	}
+	if user.ScimExternalID != nil {
+		scimUser.ExternalID = *user.ScimExternalID
+	}
+	for i, group := range user.UserGroups {
+		scimUser.Groups[i] = dto.ScimMemberDto{
+			Value:   group.ID,
+			Display: group.FriendlyName,
+			Ref:     scimLocation("Group", group.ID),
+		}
+	}
+	return scimUser
+}
+
+func scimGroupFromModel(group model.UserGroup) dto.ScimGroupDto {
+	scimGroup := dto.ScimGroupDto{
+		Schemas:     []string{dto.ScimSchemaGroup},
+		ID:          group.ID,
+		DisplayName: group.FriendlyName,
+		Members:     make([]dto.ScimMemberDto, len(group.Users)),
+		Meta:        scimMeta("Group", group.Base),
+	}
+	if group.ScimExternalID != nil {
+		scimGroup.ExternalID = *group.ScimExternalID
+	}
+	for i, user := range group.Users {
+		scimGroup.Members[i] = dto.ScimMemberDto{
+			Value:   user.ID,
+			Display: user.Username,
+			Ref:     scimLocation("User", user.ID),
+		}
+	}
+	return scimGroup
+}
+
+func scimMeta(resourceType string, base model.Base) dto.ScimMetaDto {
+	created := time.Time(base.CreatedAt)
+	return dto.ScimMetaDto{
+		ResourceType: resourceType,
+		Created:      &created,
+		Location:     scimLocation(resourceType, base.ID),
+	}
+}
+
+func scimLocation(resourceType string, id string) string {
+	return common.EnvConfig.AppURL + "/scim/v2/" + resourceType + "s/" + id
+}
+
+func scimPrimaryEmail(emails []dto.ScimEmailDto) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func scimPatchString(value json.RawMessage, remove bool) (string, error) {
+	if remove {
+		return "", nil
+	}
+	var s string
+	err := json.Unmarshal(value, &s)
+	return s, err
+}
+
+// scimPatchBool parses a boolean value, which some clients like Microsoft Entra ID send as a string
+func scimPatchBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, errors.New("invalid boolean")
+	}
+}
+
+// scimPaginate loads the page of the query that is requested with the 1-based startIndex and count parameters
+func scimPaginate[T any](query *gorm.DB, input dto.ScimListRequestDto, result *[]T) (totalResults int64, startIndex int, err error) {
+	startIndex = max(input.StartIndex, 1)
+
+	count := scimDefaultCount
+	if input.Count != nil {
+		count = min(max(*input.Count, 0), scimMaxCount)
+	}
+
+	err = query.Count(&totalResults).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if count > 0 {
+		err = query.
+			Order("created_at, id").
+			Offset(startIndex - 1).
+			Limit(count).
+			Find(result).
+			Error
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return totalResults, startIndex, nil
+}
+
+var scimFilterExpressionRegex = regexp.MustCompile(`(?i)^([a-z.]+)\s+(eq|ne|co|sw|ew|pr)(?:\s+(.+))?$`)
+
+// applyScimFilter applies a SCIM filter to a query. Only expressions with the operators eq, ne, co, sw, ew and pr
+// that are combined with "and" are supported, which covers the filters that provisioning clients send.
+func applyScimFilter(query *gorm.DB, filter string, attributes map[string]scimFilterAttribute) (*gorm.DB, error) {
+	for _, expression := range splitScimFilter(filter) {
+		match := scimFilterExpressionRegex.FindStringSubmatch(expression)
+		if match == nil {
+			return nil, &common.ScimInvalidFilterError{Message: fmt.Sprintf("unsupported expression '%s'", expression)}
+		}
+
+		attribute, ok := attributes[strings.ToLower(match[1])]
+		if !ok {
+			return nil, &common.ScimInvalidFilterError{Message: fmt.Sprintf("unsupported attribute '%s'", match[1])}
+		}
+
+		operator := strings.ToLower(match[2])
+		if operator == "pr" {
+			query = query.Where(attribute.column + " IS NOT NULL AND " + attribute.column + " <> ''")
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal([]byte(match[3]), &value); err != nil {
+			return nil, &common.ScimInvalidFilterError{Message: fmt.Sprintf("the value of '%s' must be a string", match[1])}
+		}
+
+		column, placeholder := attribute.column, "?"
+		if !attribute.caseExact {
+			column, placeholder = "LOWER("+column+")", "LOWER(?)"
+		}
+
+		switch operator {
+		case "eq":
+			query = query.Where(column+" = "+placeholder, value)
+		case "ne":
+			query = query.Where("("+attribute.column+" IS NULL OR "+column+" <> "+placeholder+")", value)
+		case "co":
+			query = query.Where(column+" LIKE "+placeholder, "%"+value+"%")
+		case "sw":
+			query = query.Where(column+" LIKE "+placeholder, value+"%")
+		case "ew":
+			query = query.Where(column+" LIKE "+placeholder, "%"+value)
+		}
+	}
+
+	return query, nil
+}
+
+// splitScimFilter splits a filter into the expressions that are combined with "and", ignoring quoted strings
+func splitScimFilter(filter string) []string {
+	var expressions []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(filter); i++ {
+		switch {
+		case filter[i] == '\\' && inQuotes:
+			i++
+		case filter[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && i+5 <= len(filter) && strings.EqualFold(filter[i:i+5], " and "):
+			expressions = append(expressions, strings.TrimSpace(filter[start:i]))
+			start = i + 5
+			i += 4
+		}
+	}
+
+	if last := strings.TrimSpace(filter[start:]); last != "" {
+		expressions = append(expressions, last)
+	}
+	return expressions
+}
+
+func (s *ScimService) GetServiceProviderConfig() map[string]any {
+	return map[string]any{
+		"schemas":          []string{dto.ScimSchemaServiceProviderConfig},
+		"documentationUri": "https://pocket-id.org/docs",
+		"patch":            map[string]any{"supported": true},
+		"bulk":             map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]any{"supported": true, "maxResults": scimMaxCount},
+		"changePassword":   map[string]any{"supported": false},
+		"sort":             map[string]any{"supported": false},
+		"etag":             map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "API key",
+			"description": "Authentication with an API key of an admin, sent as bearer token or in the X-API-KEY header",
+		}},
+		"meta": map[string]any{"resourceType": "ServiceProviderConfig", "location": common.EnvConfig.AppURL + "/scim/v2/ServiceProviderConfig"},
+	}
+}
+
+func (s *ScimService) GetResourceTypes() []map[string]any {
+	resourceType := func(name, endpoint, schema string) map[string]any {
+		return map[string]any{
+			"schemas":  []string{dto.ScimSchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta":     map[string]any{"resourceType": "ResourceType", "location": common.EnvConfig.AppURL + "/scim/v2/ResourceTypes/" + name},
+		}
+	}
+
+	return []map[string]any{
+		resourceType("User", "/Users", dto.ScimSchemaUser),
+		resourceType("Group", "/Groups", dto.ScimSchemaGroup),
+	}
+}
+
+func (s *ScimService) GetSchemas() []map[string]any {
+	attribute := func(name, attributeType string, required bool, mutability, uniqueness string, multiValued bool, subAttributes ...map[string]any) map[string]any {
+		a := map[string]any{
+			"name":        name,
+			"type":        attributeType,
+			"multiValued": multiValued,
+			"required":    required,
+			"caseExact":   false,
+			"mutability":  mutability,
+			"returned":    "default",
+			"uniqueness":  uniqueness,
+		}
+		if len(subAttributes) > 0 {
+			a["subAttributes"] = subAttributes
+		}
+		return a
+	}
+	schema := func(id, name string, attributes ...map[string]any) map[string]any {
+		return map[string]any{
+			"schemas":    []string{dto.ScimSchemaSchema},
+			"id":         id,
+			"name":       name,
+			"attributes": attributes,
+			"meta":       map[string]any{"resourceType": "Schema", "location": common.EnvConfig.AppURL + "/scim/v2/Schemas/" + id},
+		}
+	}
+	member := func(mutability string) []map[string]any {
+		return []map[string]any{
+			attribute("value", "string", true, mutability, "none", false),
+			attribute("display", "string", false, "readOnly", "none", false),
+			attribute("$ref", "reference", false, "readOnly", "none", false),
+		}
+	}
+
+	return []map[string]any{
+		schema(dto.ScimSchemaUser, "User",
+			attribute("userName", "string", true, "readWrite", "server", false),
+			attribute("externalId", "string", false, "readWrite", "none", false),
+			attribute("name", "complex", false, "readWrite", "none", false,
+				attribute("givenName", "string", false, "readWrite", "none", false),
+				attribute("familyName", "string", false, "readWrite", "none", false),
+			),
+			attribute("displayName", "string", false, "readOnly", "none", false),
+			attribute("emails", "complex", true, "readWrite", "server", true,
+				attribute("value", "string", true, "readWrite", "server", false),
+				attribute("type", "string", false, "readWrite", "none", false),
+				attribute("primary", "boolean", false, "readWrite", "none", false),
+			),
+			attribute("active", "boolean", false, "readWrite", "none", false),
+			attribute("groups", "complex", false, "readOnly", "none", true, member("readOnly")...),
+		),
+		schema(dto.ScimSchemaGroup, "Group",
+			attribute("displayName", "string", true, "readWrite", "server", false),
+			attribute("externalId", "string", false, "readWrite", "none", false),
+			attribute("members", "complex", false, "readWrite", "none", true, member("immutable")...),
+		),
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+func TestScimService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration:     model.AppConfigVariable{Value: "60"},
+		AllowOwnAccountEdit: model.AppConfigVariable{Value: "true"},
+	})
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	userService := NewUserService(db, nil, auditLogService, nil, appConfig, sessionService, NewScimProvisioningService(db, nil), webhookService)
+	userGroupService := NewUserGroupService(db, appConfig, NewScimProvisioningService(db, nil), webhookService, auditLogService)
+	service := NewScimService(db, userService, userGroupService, auditLogService)
+
+	patch := func(op, path string, value any) dto.ScimPatchRequestDto {
+		rawValue, err := json.Marshal(value)
+		require.NoError(t, err)
+		return dto.ScimPatchRequestDto{
+			Schemas:    []string{dto.ScimSchemaPatchOp},
+			Operations: []dto.ScimPatchOperationDto{{Op: op, Path: path, Value: rawValue}},
+		}
+	}
+
+	user, err := service.CreateUser(t.Context(), dto.ScimUserCreateDto{
+		ExternalID: "hr-1",
+		UserName:   "Tim.Cook",
+		Name:       dto.ScimNameDto{GivenName: "Tim", FamilyName: "Cook"},
+		Emails:     []dto.ScimEmailDto{{Value: "old@example.com"}, {Value: "tim@example.com", Primary: true}},
+	})
+	require.NoError(t, err)
+
+	t.Run("creates externally managed users", func(t *testing.T) {
+		assert.Equal(t, "tim.cook", user.UserName)
+		assert.Equal(t, "tim@example.com", user.Emails[0].Value)
+		assert.True(t, user.Active)
+
+		// Users provisioned over SCIM can't be edited outside of SCIM
+		_, err := userService.UpdateUser(t.Context(), user.ID, dto.UserCreateDto{Username: "tim", Email: "tim@example.com", FirstName: "Timothy"}, false, false)
+		require.NoError(t, err)
+		dbUser, err := userService.GetUser(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Tim", dbUser.FirstName)
+		var updateErr *common.ScimUserUpdateError
+		require.ErrorAs(t, userService.DeleteUser(t.Context(), user.ID, false), &updateErr)
+	})
+
+	t.Run("filters and paginates users", func(t *testing.T) {
+		craig, err := service.CreateUser(t.Context(), dto.ScimUserCreateDto{
+			UserName: "craig",
+			Emails:   []dto.ScimEmailDto{{Value: "craig@example.com"}},
+		})
+		require.NoError(t, err)
+		// The users are listed by their creation time, which is stored with a precision of one second
+		err = db.Model(&model.User{}).
+			Where("id = ?", craig.ID).
+			Update("created_at", datatype.DateTime(time.Now().Add(time.Minute))).
+			Error
+		require.NoError(t, err)
+
+		list, err := service.ListUsers(t.Context(), dto.ScimListRequestDto{Filter: `userName eq "TIM.COOK"`})
+		require.NoError(t, err)
+		require.Len(t, list.Resources, 1)
+		assert.Equal(t, user.ID, list.Resources[0].ID)
+
+		list, err = service.ListUsers(t.Context(), dto.ScimListRequestDto{Filter: `externalId eq "hr-1" and emails.value ew "@example.com"`})
+		require.NoError(t, err)
+		assert.Len(t, list.Resources, 1)
+
+		count := 1
+		list, err = service.ListUsers(t.Context(), dto.ScimListRequestDto{StartIndex: 2, Count: &count})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, list.TotalResults)
+		assert.Equal(t, 2, list.StartIndex)
+		require.Len(t, list.Resources, 1)
+		assert.Equal(t, craig.ID, list.Resources[0].ID)
+
+		_, err = service.ListUsers(t.Context(), dto.ScimListRequestDto{Filter: `password eq "secret"`})
+		var filterErr *common.ScimInvalidFilterError
+		require.ErrorAs(t, err, &filterErr)
+	})
+
+	t.Run("patches users", func(t *testing.T) {
+		patched, err := service.PatchUser(t.Context(), user.ID, patch("Replace", "", map[string]any{
+			"name.givenName": "Timothy",
+			"active":         "False",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "Timothy", patched.Name.GivenName)
+		assert.False(t, patched.Active)
+
+		patched, err = service.PatchUser(t.Context(), user.ID, patch("replace", `emails[type eq "work"].value`, "timothy@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, "timothy@example.com", patched.Emails[0].Value)
+		assert.Equal(t, "hr-1", patched.ExternalID)
+	})
+
+	t.Run("manages group members", func(t *testing.T) {
+		group, err := service.CreateGroup(t.Context(), dto.ScimGroupCreateDto{
+			ExternalID:  "hr-group-1",
+			DisplayName: "Sales Team",
+			Members:     []dto.ScimMemberDto{{Value: user.ID}},
+		})
+		require.NoError(t, err)
+		assert.Len(t, group.Members, 1)
+
+		dbGroup, err := userGroupService.Get(t.Context(), group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "sales_team", dbGroup.Name)
+
+		group, err = service.PatchGroup(t.Context(), group.ID, patch("remove", `members[value eq "`+user.ID+`"]`, nil))
+		require.NoError(t, err)
+		assert.Empty(t, group.Members)
+
+		group, err = service.PatchGroup(t.Context(), group.ID, patch("add", "members", []dto.ScimMemberDto{{Value: user.ID}}))
+		require.NoError(t, err)
+		assert.Len(t, group.Members, 1)
+
+		_, err = userGroupService.Update(t.Context(), group.ID, dto.UserGroupCreateDto{Name: "sales", FriendlyName: "Sales"})
+		var updateErr *common.ScimUserGroupUpdateError
+		require.ErrorAs(t, err, &updateErr)
+
+		require.NoError(t, service.DeleteGroup(t.Context(), group.ID))
+	})
+
+	t.Run("doesn't take over users and groups that weren't provisioned over SCIM", func(t *testing.T) {
+		manualUser := model.User{Username: "craig.federighi", Email: "craig.federighi@example.com", FirstName: "Craig"}
+		require.NoError(t, db.Create(&manualUser).Error)
+		manualGroup := model.UserGroup{Name: "designers", FriendlyName: "Designers"}
+		require.NoError(t, db.Create(&manualGroup).Error)
+
+		var notProvisionedErr *common.ScimResourceNotProvisionedError
+		_, err := service.ReplaceUser(t.Context(), manualUser.ID, dto.ScimUserCreateDto{
+			UserName: "craig.federighi",
+			Emails:   []dto.ScimEmailDto{{Value: "craig.federighi@example.com"}},
+		})
+		require.ErrorAs(t, err, &notProvisionedErr)
+		_, err = service.PatchUser(t.Context(), manualUser.ID, patch("replace", "name.givenName", "Hair Force One"))
+		require.ErrorAs(t, err, &notProvisionedErr)
+		_, err = service.ReplaceGroup(t.Context(), manualGroup.ID, dto.ScimGroupCreateDto{DisplayName: "Designers"})
+		require.ErrorAs(t, err, &notProvisionedErr)
+		_, err = service.PatchGroup(t.Context(), manualGroup.ID, patch("add", "members", []dto.ScimMemberDto{{Value: user.ID}}))
+		require.ErrorAs(t, err, &notProvisionedErr)
+		require.ErrorAs(t, service.DeleteUser(t.Context(), manualUser.ID), &notProvisionedErr)
+		require.ErrorAs(t, service.DeleteGroup(t.Context(), manualGroup.ID), &notProvisionedErr)
+
+		// They can still be edited by admins
+		updatedUser, err := userService.UpdateUser(t.Context(), manualUser.ID, dto.UserCreateDto{
+			Username:  "craig.federighi",
+			Email:     "craig.federighi@example.com",
+			FirstName: "Craig",
+			LastName:  "Federighi",
+		}, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Federighi", updatedUser.LastName)
+		assert.Nil(t, updatedUser.ScimExternalID)
+
+		updatedGroup, err := userGroupService.Update(t.Context(), manualGroup.ID, dto.UserGroupCreateDto{Name: "design", FriendlyName: "Design"})
+		require.NoError(t, err)
+		assert.Equal(t, "design", updatedGroup.Name)
+		assert.Nil(t, updatedGroup.ScimExternalID)
+	})
+}
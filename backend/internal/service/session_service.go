@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// sessionLastSeenInterval is the minimum interval between updates of the last seen time of a session
+// This avoids writing to the database on every authenticated request
+const sessionLastSeenInterval = time.Minute
+
+type SessionService struct {
+	db               *gorm.DB
+	appConfigService *AppConfigService
+	geoliteService   *GeoLiteService
+}
+
+func NewSessionService(db *gorm.DB, appConfigService *AppConfigService, geoliteService *GeoLiteService) *SessionService {
+	return &SessionService{db: db, appConfigService: appConfigService, geoliteService: geoliteService}
+}
+
+// Create creates a new session for the given user, recording how the user authenticated
+func (s *SessionService) Create(ctx context.Context, user model.User, authMethod string, userVerified bool, ipAddress, userAgent string, tx *gorm.DB) (model.Session, error) {
+	country, city, err := s.geoliteService.GetLocationByIP(ipAddress)
+	if err != nil {
+		log.Printf("Failed to get IP location: %v", err)
+	}
+
+	now := time.Now()
+	session := model.Session{
+		UserID:       user.ID,
+		IpAddress:    ipAddress,
+		Country:      country,
+		City:         city,
+		UserAgent:    userAgent,
+		LastSeenAt:   datatype.DateTime(now),
+		ExpiresAt:    datatype.DateTime(now.Add(s.appConfigService.GetDbConfig().SessionDurationForUser(user.IsAdmin))),
+		AuthMethod:   authMethod,
+		UserVerified: userVerified,
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Create(&session).
+		Error
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	return session, nil
+}
+
+// ValidateSession checks that the session exists, belongs to the user and isn't expired or idle, and updates its last seen time
+// The returned boolean is true if the last seen time was updated, which means the session was renewed
+func (s *SessionService) ValidateSession(ctx context.Context, sessionID string, user model.User) (model.Session, bool, error) {
+	now := time.Now()
+
+	var session model.Session
+	err := s.db.
+		WithContext(ctx).
+		Where("id = ? AND user_id = ? AND expires_at > ?", sessionID, user.ID, datatype.DateTime(now)).
+		First(&session).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Session{}, false, &common.SessionNotFoundError{}
+		}
+		return model.Session{}, false, err
+	}
+
+	// Check if the session has been inactive for too long
+	idleTimeout := s.appConfigService.GetDbConfig().SessionIdleTimeoutForUser(user.IsAdmin)
+	if idleTimeout > 0 && now.Sub(session.LastSeenAt.ToTime()) > idleTimeout {
+		return model.Session{}, false, &common.SessionNotFoundError{}
+	}
+
+	renewed := false
+	if now.Sub(session.LastSeenAt.ToTime()) >= sessionLastSeenInterval {
+		session.LastSeenAt = datatype.DateTime(now)
+		err = s.db.
+			WithContext(ctx).
+			Model(&model.Session{}).
+			Where("id = ?", session.ID).
+			Update("last_seen_at", session.LastSeenAt).
+			Error
+		if err != nil {
+			log.Printf("Failed to update last seen time of session: %v", err)
+		} else {
+			renewed = true
+		}
+	}
+
+	return session, renewed, nil
+}
+
+// AccessTokenExpiration returns the time at which an access token for the session should expire
+// If an idle timeout is configured, the token is short-lived and gets renewed while the session is in use
+func (s *SessionService) AccessTokenExpiration(session model.Session, isAdmin bool) time.Time {
+	expiration := session.ExpiresAt.ToTime()
+
+	idleTimeout := s.appConfigService.GetDbConfig().SessionIdleTimeoutForUser(isAdmin)
+	if idleTimeout > 0 {
+		idleExpiration := time.Now().Add(idleTimeout)
+		if idleExpiration.Before(expiration) {
+			expiration = idleExpiration
+		}
+	}
+
+	return expiration
+}
+
+// ListSessionsForUser retrieves all active sessions of a given user
+func (s *SessionService) ListSessionsForUser(ctx context.Context, userID string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.Session, utils.PaginationResponse, error) {
+	var sessions []model.Session
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.Session{}).
+		Where("user_id = ? AND expires_at > ?", userID, datatype.DateTime(time.Now()))
+
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &sessions)
+	return sessions, pagination, err
+}
+
+// ListAllSessions retrieves the active sessions of all users
+func (s *SessionService) ListAllSessions(ctx context.Context, sortedPaginationRequest utils.SortedPaginationRequest, filters dto.SessionFilterDto) ([]model.Session, utils.PaginationResponse, error) {
+	var sessions []model.Session
+	query := s.db.
+		WithContext(ctx).
+		Preload("User").
+		Model(&model.Session{}).
+		Where("expires_at > ?", datatype.DateTime(time.Now()))
+
+	if filters.UserID != "" {
+		query = query.Where("user_id = ?", filters.UserID)
+	}
+
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &sessions)
+	return sessions, pagination, err
+}
+
+// RevokeSession deletes a session of the given user
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	result := s.db.
+		WithContext(ctx).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Delete(&model.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &common.SessionNotFoundError{}
+	}
+
+	return nil
+}
+
+// RevokeAllSessionsForUser deletes all sessions of the given user, optionally keeping the session with the ID in exceptSessionID
+func (s *SessionService) RevokeAllSessionsForUser(ctx context.Context, userID, exceptSessionID string) error {
+	return s.revokeAllSessionsForUserInternal(ctx, userID, exceptSessionID, s.db)
+}
+
+func (s *SessionService) revokeAllSessionsForUserInternal(ctx context.Context, userID, exceptSessionID string, tx *gorm.DB) error {
+	query := tx.
+		WithContext(ctx).
+		Where("user_id = ?", userID)
+	if exceptSessionID != "" {
+		query = query.Where("id != ?", exceptSessionID)
+	}
+
+	return query.Delete(&model.Session{}).Error
+}
+
+// CheckSudoMode returns an error if the action requires sudo mode and the user hasn't re-authenticated recently enough in the session
+func (s *SessionService) CheckSudoMode(session model.Session, action string) error {
+	config := s.appConfigService.GetDbConfig()
+	if !config.IsSudoModeRequired(action) {
+		return nil
+	}
+
+	if time.Since(session.LastAuthenticatedWithPasskeyAt()) > config.SudoModeDuration.AsDurationMinutes() {
+		return &common.ReauthenticationRequiredError{}
+	}
+
+	return nil
+}
+
+// CheckSudoModeForApiKey returns an error if the action requires sudo mode, because requests authenticated with an API key can't be elevated
+func (s *SessionService) CheckSudoModeForApiKey(action string) error {
+	if s.appConfigService.GetDbConfig().IsSudoModeRequired(action) {
+		return &common.SudoModeApiKeyError{}
+	}
+
+	return nil
+}
+
+func (s *SessionService) elevateSessionInternal(ctx context.Context, sessionID string, userID string, tx *gorm.DB) error {
+	result := tx.
+		WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Update("elevated_at", datatype.DateTime(time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &common.SessionNotFoundError{}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+func TestSessionService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	service := NewSessionService(db, appConfig, &GeoLiteService{disableUpdater: true})
+
+	user := model.User{Username: "test", Email: "test@example.com", FirstName: "Test"}
+	require.NoError(t, db.Create(&user).Error)
+
+	t.Run("creates and validates a session", func(t *testing.T) {
+		session, err := service.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+		assert.Equal(t, "Internal Network", session.Country)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt.ToTime(), time.Minute)
+
+		_, _, err = service.ValidateSession(t.Context(), session.ID, user)
+		require.NoError(t, err)
+
+		// The session must belong to the user
+		_, _, err = service.ValidateSession(t.Context(), session.ID, model.User{Base: model.Base{ID: "other-user"}})
+		require.ErrorIs(t, err, &common.SessionNotFoundError{})
+	})
+
+	t.Run("rejects expired sessions", func(t *testing.T) {
+		session, err := service.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+
+		err = db.Model(&session).Update("expires_at", datatype.DateTime(time.Now().Add(-time.Minute))).Error
+		require.NoError(t, err)
+
+		_, _, err = service.ValidateSession(t.Context(), session.ID, user)
+		require.ErrorIs(t, err, &common.SessionNotFoundError{})
+	})
+
+	t.Run("rejects idle sessions and renews active ones", func(t *testing.T) {
+		appConfig.GetDbConfig().SessionIdleTimeout = model.AppConfigVariable{Value: "30"}
+		defer func() {
+			appConfig.GetDbConfig().SessionIdleTimeout = model.AppConfigVariable{}
+		}()
+
+		session, err := service.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+
+		// The access token is limited by the idle timeout
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), service.AccessTokenExpiration(session, false), time.Minute)
+
+		// A session that was last seen 10 minutes ago is still active and gets renewed
+		err = db.Model(&session).Update("last_seen_at", datatype.DateTime(time.Now().Add(-10*time.Minute))).Error
+		require.NoError(t, err)
+		_, renewed, err := service.ValidateSession(t.Context(), session.ID, user)
+		require.NoError(t, err)
+		assert.True(t, renewed)
+
+		// A session that was last seen 40 minutes ago has been idle for too long
+		err = db.Model(&session).Update("last_seen_at", datatype.DateTime(time.Now().Add(-40*time.Minute))).Error
+		require.NoError(t, err)
+		_, _, err = service.ValidateSession(t.Context(), session.ID, user)
+		require.ErrorIs(t, err, &common.SessionNotFoundError{})
+	})
+
+	t.Run("revokes sessions", func(t *testing.T) {
+		current, err := service.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+		other, err := service.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+
+		// Sign out everywhere except the current session
+		err = service.RevokeAllSessionsForUser(t.Context(), user.ID, current.ID)
+		require.NoError(t, err)
+
+		_, _, err = service.ValidateSession(t.Context(), other.ID, user)
+		require.ErrorIs(t, err, &common.SessionNotFoundError{})
+		_, _, err = service.ValidateSession(t.Context(), current.ID, user)
+		require.NoError(t, err)
+
+		err = service.RevokeSession(t.Context(), user.ID, current.ID)
+		require.NoError(t, err)
+		err = service.RevokeSession(t.Context(), user.ID, current.ID)
+		require.ErrorIs(t, err, &common.SessionNotFoundError{})
+	})
+
+	t.Run("requires a recent passkey authentication in sudo mode", func(t *testing.T) {
+		appConfig.GetDbConfig().SudoModeDuration = model.AppConfigVariable{Value: "5"}
+		appConfig.GetDbConfig().SudoModeActions = model.AppConfigVariable{Value: model.SudoModeActionUsers}
+		defer func() {
+			appConfig.GetDbConfig().SudoModeDuration = model.AppConfigVariable{}
+			appConfig.GetDbConfig().SudoModeActions = model.AppConfigVariable{}
+		}()
+
+		session, err := service.Create(t.Context(), user, model.SessionAuthMethodOneTimeAccessToken, false, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+
+		// Actions that aren't protected are always allowed
+		require.NoError(t, service.CheckSudoMode(session, model.SudoModeActionAppConfig))
+		require.ErrorIs(t, service.CheckSudoMode(session, model.SudoModeActionUsers), &common.ReauthenticationRequiredError{})
+
+		err = service.elevateSessionInternal(t.Context(), session.ID, user.ID, db)
+		require.NoError(t, err)
+		session, _, err = service.ValidateSession(t.Context(), session.ID, user)
+		require.NoError(t, err)
+		require.NoError(t, service.CheckSudoMode(session, model.SudoModeActionUsers))
+
+		// The elevation expires after the configured duration
+		elevatedAt := datatype.DateTime(time.Now().Add(-10 * time.Minute))
+		session.ElevatedAt = &elevatedAt
+		require.ErrorIs(t, service.CheckSudoMode(session, model.SudoModeActionUsers), &common.ReauthenticationRequiredError{})
+	})
+}
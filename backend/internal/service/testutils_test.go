@@ -1,8 +1,10 @@
 package service
 
 import (
+	"database/sql"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -10,39 +12,38 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
 	"github.com/glebarez/sqlite"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
+	mysqlMigrate "github.com/golang-migrate/migrate/v4/database/mysql"
 	sqliteMigrate "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	"github.com/pocket-id/pocket-id/backend/resources"
 )
 
+// newDatabaseForTest returns a new database for the test, which is an in-memory SQLite database by default.
+// If TEST_MYSQL_CONNECTION_STRING is set, a database is created on that MySQL or MariaDB server instead.
 func newDatabaseForTest(t *testing.T) *gorm.DB {
 	t.Helper()
 
-	// Get a name for this in-memory database that is specific to the test
+	// Get a name for this database that is specific to the test
 	dbName := utils.CreateSha256Hash(t.Name())
 
+	if connectionString := os.Getenv("TEST_MYSQL_CONNECTION_STRING"); connectionString != "" {
+		return newMysqlDatabaseForTest(t, connectionString, "pocket_id_test_"+dbName[:16])
+	}
+
 	// Connect to a new in-memory SQL database
 	db, err := gorm.Open(
 		sqlite.Open("file:"+dbName+"?mode=memory&cache=shared"),
-		&gorm.Config{
-			TranslateError: true,
-			Logger: logger.New(
-				testLoggerAdapter{t: t},
-				logger.Config{
-					SlowThreshold:             200 * time.Millisecond,
-					LogLevel:                  logger.Info,
-					IgnoreRecordNotFoundError: false,
-					ParameterizedQueries:      false,
-					Colorful:                  false,
-				},
-			),
-		})
+		newGormConfigForTest(t),
+	)
 	require.NoError(t, err, "Failed to connect to test database")
 
 	// Perform migrations with the embedded migrations
@@ -57,9 +58,88 @@ func newDatabaseForTest(t *testing.T) *gorm.DB {
 	err = m.Up()
 	require.NoError(t, err, "Failed to perform migrations")
 
+	utils.EnableAfterCommitHooks(db)
+
+	return db
+}
+
+func newMysqlDatabaseForTest(t *testing.T, connectionString string, dbName string) *gorm.DB {
+	t.Helper()
+
+	// The models store times depending on the database provider
+	originalDbProvider := common.EnvConfig.DbProvider
+	common.EnvConfig.DbProvider = common.DbProviderMysql
+	t.Cleanup(func() {
+		common.EnvConfig.DbProvider = originalDbProvider
+	})
+
+	config, err := mysqlDriver.ParseDSN(connectionString)
+	require.NoError(t, err, "Failed to parse TEST_MYSQL_CONNECTION_STRING")
+
+	// Create an empty database for the test
+	server, err := sql.Open("mysql", config.FormatDSN())
+	require.NoError(t, err, "Failed to connect to MySQL server")
+	defer server.Close()
+	_, err = server.Exec("DROP DATABASE IF EXISTS " + dbName)
+	require.NoError(t, err, "Failed to drop test database")
+	_, err = server.Exec("CREATE DATABASE " + dbName + " CHARACTER SET utf8mb4 COLLATE utf8mb4_bin")
+	require.NoError(t, err, "Failed to create test database")
+	t.Cleanup(func() {
+		server, err := sql.Open("mysql", config.FormatDSN())
+		if err == nil {
+			_, _ = server.Exec("DROP DATABASE IF EXISTS " + dbName)
+			_ = server.Close()
+		}
+	})
+
+	// Use the same settings as bootstrap.ConnectDatabase
+	config.DBName = dbName
+	config.ParseTime = true
+	config.MultiStatements = true
+	if config.Params == nil {
+		config.Params = make(map[string]string)
+	}
+	config.Params["sql_mode"] = "CONCAT(@@sql_mode, ',ANSI_QUOTES,PIPES_AS_CONCAT')"
+
+	db, err := gorm.Open(mysql.Open(config.FormatDSN()), newGormConfigForTest(t))
+	require.NoError(t, err, "Failed to connect to test database")
+
+	// Perform migrations with the embedded migrations
+	sqlDB, err := db.DB()
+	require.NoError(t, err, "Failed to get sql.DB")
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	driver, err := mysqlMigrate.WithInstance(sqlDB, &mysqlMigrate.Config{})
+	require.NoError(t, err, "Failed to create migration driver")
+	source, err := iofs.New(resources.FS, "migrations/mysql")
+	require.NoError(t, err, "Failed to create embedded migration source")
+	m, err := migrate.NewWithInstance("iofs", source, "pocket-id", driver)
+	require.NoError(t, err, "Failed to create migration instance")
+	err = m.Up()
+	require.NoError(t, err, "Failed to perform migrations")
+
+	utils.EnableAfterCommitHooks(db)
+
 	return db
 }
 
+func newGormConfigForTest(t *testing.T) *gorm.Config {
+	return &gorm.Config{
+		TranslateError: true,
+		Logger: logger.New(
+			testLoggerAdapter{t: t},
+			logger.Config{
+				SlowThreshold:             200 * time.Millisecond,
+				LogLevel:                  logger.Info,
+				IgnoreRecordNotFoundError: false,
+				ParameterizedQueries:      false,
+				Colorful:                  false,
+			},
+		),
+	}
+}
+
 // Implements gorm's logger.Writer interface
 type testLoggerAdapter struct {
 	t *testing.T
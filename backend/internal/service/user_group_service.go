@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 
 	"gorm.io/gorm"
 
@@ -13,12 +14,15 @@ import (
 )
 
 type UserGroupService struct {
-	db               *gorm.DB
-	appConfigService *AppConfigService
+	db                      *gorm.DB
+	appConfigService        *AppConfigService
+	scimProvisioningService *ScimProvisioningService
+	webhookService          *WebhookService
+	auditLogService         *AuditLogService
 }
 
-func NewUserGroupService(db *gorm.DB, appConfigService *AppConfigService) *UserGroupService {
-	return &UserGroupService{db: db, appConfigService: appConfigService}
+func NewUserGroupService(db *gorm.DB, appConfigService *AppConfigService, scimProvisioningService *ScimProvisioningService, webhookService *WebhookService, auditLogService *AuditLogService) *UserGroupService {
+	return &UserGroupService{db: db, appConfigService: appConfigService, scimProvisioningService: scimProvisioningService, webhookService: webhookService, auditLogService: auditLogService}
 }
 
 func (s *UserGroupService) List(ctx context.Context, name string, sortedPaginationRequest utils.SortedPaginationRequest) (groups []model.UserGroup, response utils.PaginationResponse, err error) {
@@ -68,34 +72,76 @@ func (s *UserGroupService) Delete(ctx context.Context, id string) error {
 		tx.Rollback()
 	}()
 
-	var group model.UserGroup
-	err := tx.
-		WithContext(ctx).
-		Where("id = ?", id).
-		First(&group).
-		Error
+	group, err := s.getInternal(ctx, id, tx)
 	if err != nil {
 		return err
 	}
 
-	// Disallow deleting the group if it is an LDAP group and LDAP is enabled
+	// Disallow deleting the group if it is an LDAP group and LDAP is enabled, or if it is provisioned over SCIM
 	if group.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
 		return &common.LdapUserGroupUpdateError{}
 	}
+	if group.ScimExternalID != nil {
+		return &common.ScimUserGroupUpdateError{}
+	}
 
-	err = tx.
-		WithContext(ctx).
-		Delete(&group).
-		Error
+	err = s.deleteInternal(ctx, group, tx)
 	if err != nil {
 		return err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupDeleted, userGroupAuditTarget(group), userGroupAuditState(group), nil, tx)
+
 	return tx.Commit().Error
 }
 
+// deleteInternal deletes a group with its users loaded, and syncs the users who lose access with it
+func (s *UserGroupService) deleteInternal(ctx context.Context, group model.UserGroup, tx *gorm.DB) error {
+	// Groups that are managed by the declarative configuration file can't be deleted, not even by a sync
+	if group.ConfigManaged {
+		return &common.ConfigManagedError{}
+	}
+
+	userIDs := make([]string, len(group.Users))
+	for i, user := range group.Users {
+		userIDs[i] = user.ID
+	}
+
+	err := s.scimProvisioningService.enqueueUsersInternal(ctx, userIDs, tx)
+	if err != nil {
+		return err
+	}
+
+	err = s.webhookService.enqueueGroupMembershipChangedInternal(ctx, group, nil, userIDs, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.
+		WithContext(ctx).
+		Delete(&group).
+		Error
+}
+
 func (s *UserGroupService) Create(ctx context.Context, input dto.UserGroupCreateDto) (group model.UserGroup, err error) {
-	return s.createInternal(ctx, input, s.db)
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	group, err = s.createInternal(ctx, input, tx)
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupCreated, userGroupAuditTarget(group), nil, userGroupAuditState(group), tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
+	return group, nil
 }
 
 func (s *UserGroupService) createInternal(ctx context.Context, input dto.UserGroupCreateDto, tx *gorm.DB) (group model.UserGroup, err error) {
@@ -107,6 +153,7 @@ func (s *UserGroupService) createInternal(ctx context.Context, input dto.UserGro
 	if input.LdapID != "" {
 		group.LdapID = &input.LdapID
 	}
+	group.ScimExternalID = input.ScimExternalID
 
 	err = tx.
 		WithContext(ctx).
@@ -128,11 +175,18 @@ func (s *UserGroupService) Update(ctx context.Context, id string, input dto.User
 		tx.Rollback()
 	}()
 
+	originalGroup, err := s.getInternal(ctx, id, tx)
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
 	group, err = s.updateInternal(ctx, id, input, false, tx)
 	if err != nil {
 		return model.UserGroup{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupUpdated, userGroupAuditTarget(group), userGroupAuditState(originalGroup), userGroupAuditState(group), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.UserGroup{}, err
@@ -141,19 +195,29 @@ func (s *UserGroupService) Update(ctx context.Context, id string, input dto.User
 	return group, nil
 }
 
-func (s *UserGroupService) updateInternal(ctx context.Context, id string, input dto.UserGroupCreateDto, isLdapSync bool, tx *gorm.DB) (group model.UserGroup, err error) {
+func (s *UserGroupService) updateInternal(ctx context.Context, id string, input dto.UserGroupCreateDto, isExternalSync bool, tx *gorm.DB) (group model.UserGroup, err error) {
 	group, err = s.getInternal(ctx, id, tx)
 	if err != nil {
 		return model.UserGroup{}, err
 	}
 
-	// Disallow updating the group if it is an LDAP group and LDAP is enabled
-	if !isLdapSync && group.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
+	// Disallow updating the group if it is an LDAP group and LDAP is enabled, or if it is provisioned over SCIM
+	if !isExternalSync && group.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
 		return model.UserGroup{}, &common.LdapUserGroupUpdateError{}
 	}
+	if !isExternalSync && group.ScimExternalID != nil {
+		return model.UserGroup{}, &common.ScimUserGroupUpdateError{}
+	}
+	// The names of groups that are managed by the declarative configuration file can't be changed, not even by a sync
+	if group.ConfigManaged && (input.Name != group.Name || input.FriendlyName != group.FriendlyName) {
+		return model.UserGroup{}, &common.ConfigManagedError{}
+	}
 
 	group.Name = input.Name
 	group.FriendlyName = input.FriendlyName
+	if isExternalSync && input.ScimExternalID != nil {
+		group.ScimExternalID = input.ScimExternalID
+	}
 
 	err = tx.
 		WithContext(ctx).
@@ -174,11 +238,19 @@ func (s *UserGroupService) UpdateUsers(ctx context.Context, id string, userIds [
 		tx.Rollback()
 	}()
 
+	originalGroup, err := s.getInternal(ctx, id, tx)
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
 	group, err = s.updateUsersInternal(ctx, id, userIds, tx)
 	if err != nil {
 		return model.UserGroup{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupMembersUpdated, userGroupAuditTarget(group),
+		usernamesAuditState(originalGroup.Users), usernamesAuditState(group.Users), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.UserGroup{}, err
@@ -206,6 +278,20 @@ func (s *UserGroupService) updateUsersInternal(ctx context.Context, id string, u
 		}
 	}
 
+	// Sync the users that are added to or removed from the group to the provisioning targets
+	var addedUserIDs, removedUserIDs []string
+	for _, user := range group.Users {
+		if !slices.ContainsFunc(users, func(u model.User) bool { return u.ID == user.ID }) {
+			removedUserIDs = append(removedUserIDs, user.ID)
+		}
+	}
+	for _, user := range users {
+		if !slices.ContainsFunc(group.Users, func(u model.User) bool { return u.ID == user.ID }) {
+			addedUserIDs = append(addedUserIDs, user.ID)
+		}
+	}
+	changedUserIDs := slices.Concat(addedUserIDs, removedUserIDs)
+
 	// Replace the current users with the new set of users
 	err = tx.
 		WithContext(ctx).
@@ -216,6 +302,16 @@ func (s *UserGroupService) updateUsersInternal(ctx context.Context, id string, u
 		return model.UserGroup{}, err
 	}
 
+	err = s.scimProvisioningService.enqueueUsersInternal(ctx, changedUserIDs, tx)
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
+	err = s.webhookService.enqueueGroupMembershipChangedInternal(ctx, group, addedUserIDs, removedUserIDs, tx)
+	if err != nil {
+		return model.UserGroup{}, err
+	}
+
 	// Save the updated group
 	err = tx.
 		WithContext(ctx).
@@ -252,3 +348,25 @@ func (s *UserGroupService) GetUserCountOfGroup(ctx context.Context, id string) (
 		Count()
 	return count, nil
 }
+
+func userGroupAuditTarget(group model.UserGroup) AuditTarget {
+	return AuditTarget{Type: model.AuditLogTargetUserGroup, ID: group.ID, Name: group.Name}
+}
+
+// userGroupAuditState returns the attributes of the group that are compared in the audit log
+func userGroupAuditState(group model.UserGroup) dto.UserGroupCreateDto {
+	return dto.UserGroupCreateDto{
+		FriendlyName: group.FriendlyName,
+		Name:         group.Name,
+	}
+}
+
+// usernamesAuditState returns the sorted usernames of the users to compare group memberships in the audit log
+func usernamesAuditState(users []model.User) map[string][]string {
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		usernames[i] = user.Username
+	}
+	slices.Sort(usernames)
+	return map[string][]string{"users": usernames}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,10 +31,14 @@ type UserService struct {
 	auditLogService  *AuditLogService
 	emailService     *EmailService
 	appConfigService *AppConfigService
+	sessionService   *SessionService
+
+	scimProvisioningService *ScimProvisioningService
+	webhookService          *WebhookService
 }
 
-func NewUserService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, emailService *EmailService, appConfigService *AppConfigService) *UserService {
-	return &UserService{db: db, jwtService: jwtService, auditLogService: auditLogService, emailService: emailService, appConfigService: appConfigService}
+func NewUserService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, emailService *EmailService, appConfigService *AppConfigService, sessionService *SessionService, scimProvisioningService *ScimProvisioningService, webhookService *WebhookService) *UserService {
+	return &UserService{db: db, jwtService: jwtService, auditLogService: auditLogService, emailService: emailService, appConfigService: appConfigService, sessionService: sessionService, scimProvisioningService: scimProvisioningService, webhookService: webhookService}
 }
 
 func (s *UserService) ListUsers(ctx context.Context, searchTerm string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.User, utils.PaginationResponse, error) {
@@ -174,13 +179,29 @@ func (s *UserService) UpdateProfilePicture(userID string, file io.Reader) error
 	return nil
 }
 
-func (s *UserService) DeleteUser(ctx context.Context, userID string, allowLdapDelete bool) error {
+func (s *UserService) DeleteUser(ctx context.Context, userID string, allowExternalDelete bool) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		return s.deleteUserInternal(ctx, userID, allowLdapDelete, tx)
+		var user model.User
+		err := tx.
+			WithContext(ctx).
+			Where("id = ?", userID).
+			First(&user).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to load user to delete: %w", err)
+		}
+
+		err = s.deleteUserInternal(ctx, userID, allowExternalDelete, tx)
+		if err != nil {
+			return err
+		}
+
+		s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserDeleted, userAuditTarget(user), userAuditState(user), nil, tx)
+		return nil
 	})
 }
 
-func (s *UserService) deleteUserInternal(ctx context.Context, userID string, allowLdapDelete bool, tx *gorm.DB) error {
+func (s *UserService) deleteUserInternal(ctx context.Context, userID string, allowExternalDelete bool, tx *gorm.DB) error {
 	var user model.User
 
 	err := tx.
@@ -192,9 +213,14 @@ func (s *UserService) deleteUserInternal(ctx context.Context, userID string, all
 		return fmt.Errorf("failed to load user to delete: %w", err)
 	}
 
-	// Disallow deleting the user if it is an LDAP user, LDAP is enabled, and the user is not disabled
-	if !allowLdapDelete && !user.Disabled && user.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
-		return &common.LdapUserUpdateError{}
+	// Disallow deleting the user if it is managed by LDAP or SCIM and the user is not disabled
+	if !allowExternalDelete && !user.Disabled {
+		if user.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue() {
+			return &common.LdapUserUpdateError{}
+		}
+		if user.ScimExternalID != nil {
+			return &common.ScimUserUpdateError{}
+		}
 	}
 
 	// Delete the profile picture
@@ -204,6 +230,18 @@ func (s *UserService) deleteUserInternal(ctx context.Context, userID string, all
 		return err
 	}
 
+	// Revoke everything that grants access explicitly, in case foreign keys aren't enforced by the database
+	err = s.revokeUserAccessInternal(ctx, userID, tx)
+	if err != nil {
+		return err
+	}
+
+	// Deactivate the user in the applications they were provisioned to
+	err = s.scimProvisioningService.enqueueUsersInternal(ctx, []string{userID}, tx)
+	if err != nil {
+		return err
+	}
+
 	err = tx.WithContext(ctx).Delete(&user).Error
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
@@ -223,6 +261,8 @@ func (s *UserService) CreateUser(ctx context.Context, input dto.UserCreateDto) (
 		return model.User{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserCreated, userAuditTarget(user), nil, userAuditState(user), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.User{}, err
@@ -231,7 +271,7 @@ func (s *UserService) CreateUser(ctx context.Context, input dto.UserCreateDto) (
 	return user, nil
 }
 
-func (s *UserService) createUserInternal(ctx context.Context, input dto.UserCreateDto, isLdapSync bool, tx *gorm.DB) (model.User, error) {
+func (s *UserService) createUserInternal(ctx context.Context, input dto.UserCreateDto, isExternalSync bool, tx *gorm.DB) (model.User, error) {
 	user := model.User{
 		FirstName: input.FirstName,
 		LastName:  input.LastName,
@@ -243,11 +283,12 @@ func (s *UserService) createUserInternal(ctx context.Context, input dto.UserCrea
 	if input.LdapID != "" {
 		user.LdapID = &input.LdapID
 	}
+	user.ScimExternalID = input.ScimExternalID
 
 	err := tx.WithContext(ctx).Create(&user).Error
 	if errors.Is(err, gorm.ErrDuplicatedKey) {
-		// Do not follow this path if we're using LDAP, as we don't want to roll-back the transaction here
-		if !isLdapSync {
+		// Do not follow this path if we're syncing from LDAP or SCIM, as we don't want to roll-back the transaction here
+		if !isExternalSync {
 			tx.Rollback()
 			// If we are here, the transaction is already aborted due to an error, so we pass s.db
 			err = s.checkDuplicatedFields(ctx, user, s.db)
@@ -259,20 +300,43 @@ func (s *UserService) createUserInternal(ctx context.Context, input dto.UserCrea
 	} else if err != nil {
 		return model.User{}, err
 	}
+
+	err = s.scimProvisioningService.enqueueUsersInternal(ctx, []string{user.ID}, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = s.webhookService.enqueueUserCreatedInternal(ctx, user, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	return user, nil
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, userID string, updatedUser dto.UserCreateDto, updateOwnUser bool, isLdapSync bool) (model.User, error) {
+func (s *UserService) UpdateUser(ctx context.Context, userID string, updatedUser dto.UserCreateDto, updateOwnUser bool, isExternalSync bool) (model.User, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
 	}()
 
-	user, err := s.updateUserInternal(ctx, userID, updatedUser, updateOwnUser, isLdapSync, tx)
+	var originalUser model.User
+	err := tx.
+		WithContext(ctx).
+		Where("id = ?", userID).
+		First(&originalUser).
+		Error
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user, err := s.updateUserInternal(ctx, userID, updatedUser, updateOwnUser, isExternalSync, tx)
 	if err != nil {
 		return model.User{}, err
 	}
 
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserUpdated, userAuditTarget(user), userAuditState(originalUser), userAuditState(user), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.User{}, err
@@ -281,7 +345,7 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, updatedUser
 	return user, nil
 }
 
-func (s *UserService) updateUserInternal(ctx context.Context, userID string, updatedUser dto.UserCreateDto, updateOwnUser bool, isLdapSync bool, tx *gorm.DB) (model.User, error) {
+func (s *UserService) updateUserInternal(ctx context.Context, userID string, updatedUser dto.UserCreateDto, updateOwnUser bool, isExternalSync bool, tx *gorm.DB) (model.User, error) {
 	var user model.User
 	err := tx.
 		WithContext(ctx).
@@ -291,13 +355,14 @@ func (s *UserService) updateUserInternal(ctx context.Context, userID string, upd
 	if err != nil {
 		return model.User{}, err
 	}
+	originalUser := user
 
-	// Check if this is an LDAP user and LDAP is enabled
-	isLdapUser := user.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue()
+	// Check if this is an LDAP user and LDAP is enabled, or a user provisioned over SCIM
+	isExternalUser := (user.LdapID != nil && s.appConfigService.GetDbConfig().LdapEnabled.IsTrue()) || user.ScimExternalID != nil
 	allowOwnAccountEdit := s.appConfigService.GetDbConfig().AllowOwnAccountEdit.IsTrue()
 
-	// For LDAP users or if own account editing is not allowed, only allow updating the locale unless it's an LDAP sync
-	if !isLdapSync && (isLdapUser || (!allowOwnAccountEdit && !updateOwnUser)) {
+	// For external users or if own account editing is not allowed, only allow updating the locale unless it's a sync from LDAP or SCIM
+	if !isExternalSync && (isExternalUser || (!allowOwnAccountEdit && !updateOwnUser)) {
 		user.Locale = updatedUser.Locale
 	} else {
 		user.FirstName = updatedUser.FirstName
@@ -307,8 +372,17 @@ func (s *UserService) updateUserInternal(ctx context.Context, userID string, upd
 		user.Locale = updatedUser.Locale
 		if !updateOwnUser {
 			user.IsAdmin = updatedUser.IsAdmin
+			if updatedUser.Disabled && !user.Disabled {
+				err = s.revokeDisabledUserAccessInternal(ctx, userID, tx)
+				if err != nil {
+					return model.User{}, err
+				}
+			}
 			user.Disabled = updatedUser.Disabled
 		}
+		if isExternalSync && updatedUser.ScimExternalID != nil {
+			user.ScimExternalID = updatedUser.ScimExternalID
+		}
 	}
 
 	err = tx.
@@ -316,8 +390,8 @@ func (s *UserService) updateUserInternal(ctx context.Context, userID string, upd
 		Save(&user).
 		Error
 	if errors.Is(err, gorm.ErrDuplicatedKey) {
-		// Do not follow this path if we're using LDAP, as we don't want to roll-back the transaction here
-		if !isLdapSync {
+		// Do not follow this path if we're syncing from LDAP or SCIM, as we don't want to roll-back the transaction here
+		if !isExternalSync {
 			tx.Rollback()
 			// If we are here, the transaction is already aborted due to an error, so we pass s.db
 			err = s.checkDuplicatedFields(ctx, user, s.db)
@@ -330,6 +404,15 @@ func (s *UserService) updateUserInternal(ctx context.Context, userID string, upd
 		return user, err
 	}
 
+	// Only sync the user to the provisioning targets if an attribute that is sent over SCIM has changed
+	if user.Username != originalUser.Username || user.Email != originalUser.Email || user.FirstName != originalUser.FirstName ||
+		user.LastName != originalUser.LastName || user.Disabled != originalUser.Disabled {
+		err = s.scimProvisioningService.enqueueUsersInternal(ctx, []string{user.ID}, tx)
+		if err != nil {
+			return user, err
+		}
+	}
+
 	return user, nil
 }
 
@@ -339,7 +422,18 @@ func (s *UserService) RequestOneTimeAccessEmailAsAdmin(ctx context.Context, user
 		return &common.OneTimeAccessDisabledError{}
 	}
 
-	return s.requestOneTimeAccessEmailInternal(ctx, userID, "", expiration)
+	err := s.requestOneTimeAccessEmailInternal(ctx, userID, "", expiration)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOneTimeAccessTokenCreated, userAuditTarget(user), nil, nil, s.db)
+
+	return nil
 }
 
 func (s *UserService) RequestOneTimeAccessEmailAsUnauthenticatedUser(ctx context.Context, userID, redirectPath string) error {
@@ -416,7 +510,29 @@ func (s *UserService) requestOneTimeAccessEmailInternal(ctx context.Context, use
 }
 
 func (s *UserService) CreateOneTimeAccessToken(ctx context.Context, userID string, expiresAt time.Time) (string, error) {
-	return s.createOneTimeAccessTokenInternal(ctx, userID, expiresAt, s.db)
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	user, err := s.getUserInternal(ctx, userID, tx)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.createOneTimeAccessTokenInternal(ctx, userID, expiresAt, tx)
+	if err != nil {
+		return "", err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventOneTimeAccessTokenCreated, userAuditTarget(user), nil, nil, tx)
+
+	err = tx.Commit().Error
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
 }
 
 func (s *UserService) createOneTimeAccessTokenInternal(ctx context.Context, userID string, expiresAt time.Time, tx *gorm.DB) (string, error) {
@@ -450,7 +566,12 @@ func (s *UserService) ExchangeOneTimeAccessToken(ctx context.Context, token stri
 		}
 		return model.User{}, "", err
 	}
-	accessToken, err := s.jwtService.GenerateAccessToken(oneTimeAccessToken.User)
+	session, err := s.sessionService.Create(ctx, oneTimeAccessToken.User, model.SessionAuthMethodOneTimeAccessToken, false, ipAddress, userAgent, tx)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(oneTimeAccessToken.User, session.ID, s.sessionService.AccessTokenExpiration(session, oneTimeAccessToken.User.IsAdmin))
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -499,6 +620,20 @@ func (s *UserService) UpdateUserGroups(ctx context.Context, id string, userGroup
 		}
 	}
 
+	originalGroups := user.UserGroups
+
+	var joinedGroups, leftGroups []model.UserGroup
+	for _, group := range groups {
+		if !slices.ContainsFunc(user.UserGroups, func(g model.UserGroup) bool { return g.ID == group.ID }) {
+			joinedGroups = append(joinedGroups, group)
+		}
+	}
+	for _, group := range user.UserGroups {
+		if !slices.ContainsFunc(groups, func(g model.UserGroup) bool { return g.ID == group.ID }) {
+			leftGroups = append(leftGroups, group)
+		}
+	}
+
 	// Replace the current groups with the new set of groups
 	err = tx.
 		WithContext(ctx).
@@ -509,12 +644,25 @@ func (s *UserService) UpdateUserGroups(ctx context.Context, id string, userGroup
 		return model.User{}, err
 	}
 
+	err = s.webhookService.enqueueUserGroupsChangedInternal(ctx, user.ID, joinedGroups, leftGroups, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	// Save the updated user
 	err = tx.WithContext(ctx).Save(&user).Error
 	if err != nil {
 		return model.User{}, err
 	}
 
+	err = s.scimProvisioningService.enqueueUsersInternal(ctx, []string{user.ID}, tx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	s.auditLogService.CreateAdminEvent(ctx, model.AuditLogEventUserGroupsUpdated, userAuditTarget(user),
+		userGroupNamesAuditState(originalGroups), userGroupNamesAuditState(groups), tx)
+
 	err = tx.Commit().Error
 	if err != nil {
 		return model.User{}, err
@@ -523,7 +671,7 @@ func (s *UserService) UpdateUserGroups(ctx context.Context, id string, userGroup
 	return user, nil
 }
 
-func (s *UserService) SetupInitialAdmin(ctx context.Context) (model.User, string, error) {
+func (s *UserService) SetupInitialAdmin(ctx context.Context, ipAddress, userAgent string) (model.User, string, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -553,7 +701,12 @@ func (s *UserService) SetupInitialAdmin(ctx context.Context) (model.User, string
 		return model.User{}, "", &common.SetupAlreadyCompletedError{}
 	}
 
-	token, err := s.jwtService.GenerateAccessToken(user)
+	session, err := s.sessionService.Create(ctx, user, model.SessionAuthMethodSetup, false, ipAddress, userAgent, tx)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	token, err := s.jwtService.GenerateAccessToken(user, session.ID, s.sessionService.AccessTokenExpiration(session, user.IsAdmin))
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -622,12 +775,57 @@ func (s *UserService) ResetProfilePicture(userID string) error {
 }
 
 func (s *UserService) disableUserInternal(ctx context.Context, userID string, tx *gorm.DB) error {
-	return tx.
+	err := tx.
 		WithContext(ctx).
 		Model(&model.User{}).
 		Where("id = ?", userID).
 		Update("disabled", true).
 		Error
+	if err != nil {
+		return err
+	}
+
+	return s.revokeDisabledUserAccessInternal(ctx, userID, tx)
+}
+
+// revokeDisabledUserAccessInternal revokes the access of a user that is being disabled and records it in the audit log
+func (s *UserService) revokeDisabledUserAccessInternal(ctx context.Context, userID string, tx *gorm.DB) error {
+	err := s.revokeUserAccessInternal(ctx, userID, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.Create(ctx, model.AuditLogEventUserDisabled, "", "", userID, model.AuditLogData{}, tx)
+
+	return nil
+}
+
+// revokeUserAccessInternal deletes all sessions, refresh tokens, pending codes and API keys of the user
+func (s *UserService) revokeUserAccessInternal(ctx context.Context, userID string, tx *gorm.DB) error {
+	err := s.sessionService.revokeAllSessionsForUserInternal(ctx, userID, "", tx)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	toDelete := []any{
+		&model.OidcRefreshToken{},
+		&model.OidcAuthorizationCode{},
+		&model.OidcDeviceCode{},
+		&model.OneTimeAccessToken{},
+		&model.ApiKey{},
+	}
+	for _, m := range toDelete {
+		err = tx.
+			WithContext(ctx).
+			Where("user_id = ?", userID).
+			Delete(m).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to revoke %T: %w", m, err)
+		}
+	}
+
+	return nil
 }
 
 func NewOneTimeAccessToken(userID string, expiresAt time.Time) (*model.OneTimeAccessToken, error) {
@@ -650,3 +848,30 @@ func NewOneTimeAccessToken(userID string, expiresAt time.Time) (*model.OneTimeAc
 
 	return o, nil
 }
+
+func userAuditTarget(user model.User) AuditTarget {
+	return AuditTarget{Type: model.AuditLogTargetUser, ID: user.ID, Name: user.Username}
+}
+
+// userAuditState returns the attributes of the user that are compared in the audit log
+func userAuditState(user model.User) dto.UserCreateDto {
+	return dto.UserCreateDto{
+		Username:  user.Username,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		IsAdmin:   user.IsAdmin,
+		Locale:    user.Locale,
+		Disabled:  user.Disabled,
+	}
+}
+
+// userGroupNamesAuditState returns the sorted names of the groups to compare group memberships in the audit log
+func userGroupNamesAuditState(groups []model.UserGroup) map[string][]string {
+	names := make([]string, len(groups))
+	for i, group := range groups {
+		names[i] = group.Name
+	}
+	slices.Sort(names)
+	return map[string][]string{"userGroups": names}
+}
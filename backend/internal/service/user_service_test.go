@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+func TestUserService_DisableUserRevokesAccess(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration:     model.AppConfigVariable{Value: "60"},
+		AllowOwnAccountEdit: model.AppConfigVariable{Value: "true"},
+	})
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	service := NewUserService(db, nil, auditLogService, nil, appConfig, sessionService, NewScimProvisioningService(db, nil), webhookService)
+
+	user := model.User{Username: "test", Email: "test@example.com", FirstName: "Test"}
+	require.NoError(t, db.Create(&user).Error)
+	client := model.OidcClient{Name: "Test"}
+	require.NoError(t, db.Create(&client).Error)
+
+	expiresAt := datatype.DateTime(time.Now().Add(time.Hour))
+	_, err := sessionService.Create(t.Context(), user, model.SessionAuthMethodPasskey, true, "127.0.0.1", "test-agent", db)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&model.OidcRefreshToken{Token: "rt", ExpiresAt: expiresAt, UserID: user.ID, ClientID: client.ID}).Error)
+	require.NoError(t, db.Create(&model.OidcAuthorizationCode{Code: "code", ExpiresAt: expiresAt, UserID: user.ID, ClientID: client.ID}).Error)
+	require.NoError(t, db.Create(&model.OneTimeAccessToken{Token: "ott", ExpiresAt: expiresAt, UserID: user.ID}).Error)
+	require.NoError(t, db.Create(&model.ApiKey{Name: "key", Key: "hash", ExpiresAt: expiresAt, UserID: user.ID}).Error)
+
+	_, err = service.UpdateUser(t.Context(), user.ID, dto.UserCreateDto{
+		Username:  user.Username,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		Disabled:  true,
+	}, false, false)
+	require.NoError(t, err)
+
+	for _, m := range []any{&model.Session{}, &model.OidcRefreshToken{}, &model.OidcAuthorizationCode{}, &model.OneTimeAccessToken{}, &model.ApiKey{}} {
+		var count int64
+		require.NoError(t, db.Model(m).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Zerof(t, count, "%T should have been revoked", m)
+	}
+
+	var auditLog model.AuditLog
+	require.NoError(t, db.Where("user_id = ? AND event = ?", user.ID, model.AuditLogEventUserDisabled).First(&auditLog).Error)
+
+	// The event isn't triggered by a request, so it has no IP address
+	var withoutIpAddress int64
+	require.NoError(t, db.Model(&model.AuditLog{}).Where("id = ? AND ip_address IS NULL", auditLog.ID).Count(&withoutIpAddress).Error)
+	assert.EqualValues(t, 1, withoutIpAddress)
+}
+
+func TestUserService_UpdateUserCreatesAuditLog(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration:     model.AppConfigVariable{Value: "60"},
+		AllowOwnAccountEdit: model.AppConfigVariable{Value: "true"},
+	})
+	geoliteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoliteService)
+	webhookService := NewWebhookService(db, nil)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoliteService, webhookService, nil, nil)
+	service := NewUserService(db, nil, auditLogService, nil, appConfig, sessionService, NewScimProvisioningService(db, nil), webhookService)
+
+	admin := model.User{Username: "admin", Email: "admin@example.com", FirstName: "Ada", IsAdmin: true}
+	require.NoError(t, db.Create(&admin).Error)
+	user := model.User{Username: "tim", Email: "tim@example.com", FirstName: "Tim"}
+	require.NoError(t, db.Create(&user).Error)
+
+	ctx := ContextWithAuditActor(t.Context(), AuditActor{UserID: admin.ID, IpAddress: "127.0.0.1", UserAgent: "test-agent"})
+	_, err := service.UpdateUser(ctx, user.ID, dto.UserCreateDto{
+		Username:  user.Username,
+		Email:     "tim@apple.com",
+		FirstName: user.FirstName,
+		IsAdmin:   true,
+	}, false, false)
+	require.NoError(t, err)
+
+	var auditLog model.AuditLog
+	require.NoError(t, db.Where("event = ?", model.AuditLogEventUserUpdated).First(&auditLog).Error)
+	assert.Equal(t, admin.ID, auditLog.UserID)
+	assert.Equal(t, "127.0.0.1", auditLog.IpAddress)
+	assert.Equal(t, model.AuditLogTargetUser, auditLog.Data["targetType"])
+	assert.Equal(t, user.ID, auditLog.Data["targetId"])
+	assert.Equal(t, "tim", auditLog.Data["targetName"])
+	assert.JSONEq(t, `{
+		"email": {"before": "tim@example.com", "after": "tim@apple.com"},
+		"isAdmin": {"before": false, "after": true}
+	}`, auditLog.Data["changes"])
+}
@@ -22,9 +22,10 @@ type WebAuthnService struct {
 	jwtService       *JwtService
 	auditLogService  *AuditLogService
 	appConfigService *AppConfigService
+	sessionService   *SessionService
 }
 
-func NewWebAuthnService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, appConfigService *AppConfigService) *WebAuthnService {
+func NewWebAuthnService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, appConfigService *AppConfigService, sessionService *SessionService) *WebAuthnService {
 	webauthnConfig := &webauthn.Config{
 		RPDisplayName: appConfigService.GetDbConfig().AppName.Value,
 		RPID:          utils.GetHostnameFromURL(common.EnvConfig.AppURL),
@@ -52,6 +53,7 @@ func NewWebAuthnService(db *gorm.DB, jwtService *JwtService, auditLogService *Au
 		jwtService:       jwtService,
 		auditLogService:  auditLogService,
 		appConfigService: appConfigService,
+		sessionService:   sessionService,
 	}
 }
 
@@ -210,7 +212,9 @@ func (s *WebAuthnService) BeginLogin(ctx context.Context) (*model.PublicKeyCrede
 	}, nil
 }
 
-func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, credentialAssertionData *protocol.ParsedCredentialAssertionData, ipAddress, userAgent string) (model.User, string, error) {
+// VerifyLogin signs the user in with a passkey and creates a new session.
+// If the user was already signed in, e.g. because a client requested a stronger authentication, the session of previousAccessToken is revoked.
+func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, credentialAssertionData *protocol.ParsedCredentialAssertionData, previousAccessToken, ipAddress, userAgent string) (model.User, string, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -225,13 +229,15 @@ func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, cre
 		return model.User{}, "", err
 	}
 
+	// The user verification is enforced, as the passkey acr of the session depends on it
 	session := webauthn.SessionData{
-		Challenge: storedSession.Challenge,
-		Expires:   storedSession.ExpiresAt.ToTime(),
+		Challenge:        storedSession.Challenge,
+		Expires:          storedSession.ExpiresAt.ToTime(),
+		UserVerification: protocol.UserVerificationRequirement(storedSession.UserVerification),
 	}
 
 	var user *model.User
-	_, err = s.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+	credential, err := s.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
 		innerErr := tx.
 			WithContext(ctx).
 			Preload("Credentials").
@@ -251,11 +257,23 @@ func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, cre
 		return model.User{}, "", &common.UserDisabledError{}
 	}
 
-	token, err := s.jwtService.GenerateAccessToken(*user)
+	userSession, err := s.sessionService.Create(ctx, *user, model.SessionAuthMethodPasskey, credential.Flags.UserVerified, ipAddress, userAgent, tx)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	token, err := s.jwtService.GenerateAccessToken(*user, userSession.ID, s.sessionService.AccessTokenExpiration(userSession, user.IsAdmin))
 	if err != nil {
 		return model.User{}, "", err
 	}
 
+	if previousAccessToken != "" {
+		err = s.revokePreviousSessionInternal(ctx, previousAccessToken, user.ID, tx)
+		if err != nil {
+			return model.User{}, "", err
+		}
+	}
+
 	s.auditLogService.CreateNewSignInWithEmail(ctx, ipAddress, userAgent, user.ID, tx)
 
 	err = tx.Commit().Error
@@ -266,6 +284,83 @@ func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, cre
 	return *user, token, nil
 }
 
+// revokePreviousSessionInternal revokes the session of an access token if it belongs to the given user.
+// Invalid or expired tokens are ignored, as their sessions can't be used anymore anyway.
+func (s *WebAuthnService) revokePreviousSessionInternal(ctx context.Context, accessToken string, userID string, tx *gorm.DB) error {
+	token, err := s.jwtService.VerifyAccessToken(accessToken)
+	if err != nil {
+		return nil
+	}
+	subject, ok := token.Subject()
+	if !ok || subject != userID {
+		return nil
+	}
+	sessionID, err := GetSessionID(token)
+	if err != nil {
+		return nil
+	}
+
+	return tx.
+		WithContext(ctx).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Delete(&model.Session{}).
+		Error
+}
+
+// VerifyReauthentication verifies a fresh passkey assertion of the signed in user and elevates the session to sudo mode
+func (s *WebAuthnService) VerifyReauthentication(ctx context.Context, webauthnSessionID string, credentialAssertionData *protocol.ParsedCredentialAssertionData, userID, userSessionID, ipAddress, userAgent string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var storedSession model.WebauthnSession
+	err := tx.
+		WithContext(ctx).
+		First(&storedSession, "id = ?", webauthnSessionID).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// The user verification is enforced, as a mere presence check must not grant sudo mode
+	session := webauthn.SessionData{
+		Challenge:        storedSession.Challenge,
+		Expires:          storedSession.ExpiresAt.ToTime(),
+		UserVerification: protocol.UserVerificationRequirement(storedSession.UserVerification),
+	}
+
+	_, err = s.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		// Only passkeys of the signed in user are accepted
+		if string(userHandle) != userID {
+			return nil, &common.NotSignedInError{}
+		}
+
+		var user model.User
+		innerErr := tx.
+			WithContext(ctx).
+			Preload("Credentials").
+			First(&user, "id = ?", userID).
+			Error
+		if innerErr != nil {
+			return nil, innerErr
+		}
+		return &user, nil
+	}, session, credentialAssertionData)
+	if err != nil {
+		return err
+	}
+
+	err = s.sessionService.elevateSessionInternal(ctx, userSessionID, userID, tx)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.Create(ctx, model.AuditLogEventSudoModeElevation, ipAddress, userAgent, userID, model.AuditLogData{}, tx)
+
+	return tx.Commit().Error
+}
+
 func (s *WebAuthnService) ListCredentials(ctx context.Context, userID string) ([]model.WebauthnCredential, error) {
 	var credentials []model.WebauthnCredential
 	err := s.db.
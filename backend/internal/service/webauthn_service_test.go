@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+func TestWebAuthnServiceRevokePreviousSession(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	originalKeysPath := common.EnvConfig.KeysPath
+	common.EnvConfig.KeysPath = t.TempDir()
+	t.Cleanup(func() {
+		common.EnvConfig.KeysPath = originalKeysPath
+	})
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"},
+	})
+	jwtService := &JwtService{}
+	require.NoError(t, jwtService.init(appConfig, common.EnvConfig.KeysPath))
+	sessionService := NewSessionService(db, appConfig, &GeoLiteService{disableUpdater: true})
+	service := &WebAuthnService{db: db, jwtService: jwtService, sessionService: sessionService}
+
+	tim := model.User{Username: "tim", Email: "tim@example.com"}
+	require.NoError(t, db.Create(&tim).Error)
+	craig := model.User{Username: "craig", Email: "craig@example.com"}
+	require.NoError(t, db.Create(&craig).Error)
+
+	createToken := func(user model.User) (model.Session, string) {
+		session, err := sessionService.Create(t.Context(), user, model.SessionAuthMethodOneTimeAccessToken, false, "127.0.0.1", "test-agent", db)
+		require.NoError(t, err)
+		token, err := jwtService.GenerateAccessToken(user, session.ID, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		return session, token
+	}
+	sessionExists := func(sessionID string) bool {
+		var count int64
+		require.NoError(t, db.Model(&model.Session{}).Where("id = ?", sessionID).Count(&count).Error)
+		return count > 0
+	}
+
+	t.Run("revokes the session of the same user", func(t *testing.T) {
+		session, token := createToken(tim)
+
+		require.NoError(t, service.revokePreviousSessionInternal(t.Context(), token, tim.ID, db))
+		assert.False(t, sessionExists(session.ID))
+	})
+
+	t.Run("keeps the session of another user", func(t *testing.T) {
+		session, token := createToken(craig)
+
+		require.NoError(t, service.revokePreviousSessionInternal(t.Context(), token, tim.ID, db))
+		assert.True(t, sessionExists(session.ID))
+	})
+
+	t.Run("ignores invalid tokens", func(t *testing.T) {
+		require.NoError(t, service.revokePreviousSessionInternal(t.Context(), "invalid", tim.ID, db))
+	})
+}
+
+func TestWebAuthnServiceVerifyReauthentication(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{
+		AppName:         model.AppConfigVariable{Value: "Pocket ID"},
+		SessionDuration: model.AppConfigVariable{Value: "60"},
+	})
+	geoLiteService := &GeoLiteService{disableUpdater: true}
+	sessionService := NewSessionService(db, appConfig, geoLiteService)
+	auditLogService := NewAuditLogService(db, appConfig, nil, geoLiteService, NewWebhookService(db, nil), nil, nil)
+	service := NewWebAuthnService(db, &JwtService{}, auditLogService, appConfig, sessionService)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: privateKey.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: privateKey.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	user := model.User{Username: "tim", Email: "tim@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	credential := model.WebauthnCredential{
+		Name:         "Passkey",
+		CredentialID: []byte("test-credential"),
+		PublicKey:    publicKey,
+		UserID:       user.ID,
+	}
+	require.NoError(t, db.Create(&credential).Error)
+
+	userSession, err := sessionService.Create(t.Context(), user, model.SessionAuthMethodPasskey, false, "127.0.0.1", "test-agent", db)
+	require.NoError(t, err)
+
+	// createAssertion starts a re-authentication and returns a signed assertion with the given authenticator flags
+	createAssertion := func(flags protocol.AuthenticatorFlags) (string, *protocol.ParsedCredentialAssertionData) {
+		challenge, err := utils.GenerateRandomAlphanumericString(32)
+		require.NoError(t, err)
+		webauthnSession := model.WebauthnSession{
+			Challenge:        challenge,
+			ExpiresAt:        datatype.DateTime(time.Now().Add(time.Minute)),
+			UserVerification: string(protocol.VerificationRequired),
+		}
+		require.NoError(t, db.Create(&webauthnSession).Error)
+
+		clientDataJSON, err := json.Marshal(protocol.CollectedClientData{
+			Type:      protocol.AssertCeremony,
+			Challenge: webauthnSession.Challenge,
+			Origin:    common.EnvConfig.AppURL,
+		})
+		require.NoError(t, err)
+
+		rpIDHash := sha256.Sum256([]byte(utils.GetHostnameFromURL(common.EnvConfig.AppURL)))
+		authenticatorData := append(rpIDHash[:], byte(flags))
+		authenticatorData = binary.BigEndian.AppendUint32(authenticatorData, 1)
+
+		clientDataHash := sha256.Sum256(clientDataJSON)
+		digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+		signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+		require.NoError(t, err)
+
+		encode := base64.RawURLEncoding.EncodeToString
+		body, err := json.Marshal(map[string]any{
+			"id":    encode(credential.CredentialID),
+			"rawId": encode(credential.CredentialID),
+			"type":  "public-key",
+			"response": map[string]string{
+				"clientDataJSON":    encode(clientDataJSON),
+				"authenticatorData": encode(authenticatorData),
+				"signature":         encode(signature),
+				"userHandle":        encode([]byte(user.ID)),
+			},
+		})
+		require.NoError(t, err)
+
+		assertion, err := protocol.ParseCredentialRequestResponseBytes(body)
+		require.NoError(t, err)
+		return webauthnSession.ID, assertion
+	}
+	isElevated := func() bool {
+		var session model.Session
+		require.NoError(t, db.First(&session, "id = ?", userSession.ID).Error)
+		return session.ElevatedAt != nil
+	}
+
+	t.Run("rejects an assertion without user verification", func(t *testing.T) {
+		webauthnSessionID, assertion := createAssertion(protocol.FlagUserPresent)
+
+		err := service.VerifyReauthentication(t.Context(), webauthnSessionID, assertion, user.ID, userSession.ID, "127.0.0.1", "test-agent")
+		require.Error(t, err)
+		assert.False(t, isElevated())
+	})
+
+	t.Run("elevates the session with user verification", func(t *testing.T) {
+		webauthnSessionID, assertion := createAssertion(protocol.FlagUserPresent | protocol.FlagUserVerified)
+
+		err := service.VerifyReauthentication(t.Context(), webauthnSessionID, assertion, user.ID, userSession.ID, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+		assert.True(t, isElevated())
+	})
+}
@@ -0,0 +1,552 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+const (
+	// webhookBatchSize is the maximum number of deliveries that are sent per run
+	webhookBatchSize = 100
+	// webhookMaxAttempts is the number of attempts after which a delivery is marked as failed
+	webhookMaxAttempts = 10
+	// webhookMaxBackoff is the maximum delay between two attempts of a delivery
+	webhookMaxBackoff = 6 * time.Hour
+	// webhookTimeout is the time a webhook has to respond to a delivery
+	webhookTimeout = 10 * time.Second
+	// webhookClaimDuration is the time a delivery is reserved for the run that sends it.
+	// If the run is interrupted, e.g. by a restart, the delivery is sent again afterwards.
+	webhookClaimDuration = webhookTimeout + time.Minute
+)
+
+// WebhookService notifies webhooks about identity events.
+// Events are stored as deliveries in a persistent queue, which is processed by a background job.
+type WebhookService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewWebhookService(db *gorm.DB, httpClient *http.Client) *WebhookService {
+	return &WebhookService{db: db, httpClient: httpClient}
+}
+
+// webhookPayload is the JSON body that is sent to the webhooks
+type webhookPayload struct {
+	// ID identifies the event, it's the same for all deliveries and replays of the event
+	ID        string             `json:"id"`
+	Event     model.WebhookEvent `json:"event"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Data      map[string]any     `json:"data"`
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.Webhook, utils.PaginationResponse, error) {
+	query := s.db.
+		WithContext(ctx).
+		Model(&model.Webhook{})
+
+	var webhooks []model.Webhook
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &webhooks)
+	if err != nil {
+		return nil, utils.PaginationResponse{}, err
+	}
+
+	return webhooks, pagination, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (model.Webhook, error) {
+	var webhook model.Webhook
+	err := s.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&webhook).
+		Error
+	return webhook, err
+}
+
+// CreateWebhook creates a webhook with a new secret, which is only returned once
+func (s *WebhookService) CreateWebhook(ctx context.Context, input dto.WebhookCreateDto) (model.Webhook, string, error) {
+	secret, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return model.Webhook{}, "", err
+	}
+
+	webhook := model.Webhook{Secret: secret}
+	err = updateWebhookModelFromDto(&webhook, input)
+	if err != nil {
+		return model.Webhook{}, "", err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Create(&webhook).
+		Error
+	if err != nil {
+		return model.Webhook{}, "", err
+	}
+
+	return webhook, secret, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, input dto.WebhookCreateDto) (model.Webhook, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var webhook model.Webhook
+	err := tx.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&webhook).
+		Error
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	err = updateWebhookModelFromDto(&webhook, input)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Save(&webhook).
+		Error
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func updateWebhookModelFromDto(webhook *model.Webhook, input dto.WebhookCreateDto) error {
+	events := make(model.WebhookEvents, 0, len(input.Events))
+	for _, event := range input.Events {
+		webhookEvent := model.WebhookEvent(event)
+		if !slices.Contains(model.AllWebhookEvents, webhookEvent) {
+			return &common.ValidationError{Message: "Unknown webhook event: " + event}
+		}
+		if !slices.Contains(events, webhookEvent) {
+			events = append(events, webhookEvent)
+		}
+	}
+
+	webhook.Name = input.Name
+	webhook.URL = input.URL
+	webhook.Events = events
+	webhook.Enabled = input.Enabled
+	return nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	result := s.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.Webhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RegenerateSecret replaces the secret of a webhook and returns the new secret
+func (s *WebhookService) RegenerateSecret(ctx context.Context, id string) (string, error) {
+	secret, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return "", err
+	}
+
+	result := s.db.
+		WithContext(ctx).
+		Model(&model.Webhook{}).
+		Where("id = ?", id).
+		Update("secret", secret)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return secret, nil
+}
+
+// ListDeliveries returns the delivery log of a webhook, the newest deliveries first unless sorted otherwise
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.WebhookDelivery, utils.PaginationResponse, error) {
+	if sortedPaginationRequest.Sort.Column == "" {
+		sortedPaginationRequest.Sort.Column = "createdAt"
+		sortedPaginationRequest.Sort.Direction = "desc"
+	}
+
+	query := s.db.
+		WithContext(ctx).
+		Where("webhook_id = ?", webhookID).
+		Model(&model.WebhookDelivery{})
+
+	var deliveries []model.WebhookDelivery
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &deliveries)
+	if err != nil {
+		return nil, utils.PaginationResponse{}, err
+	}
+
+	return deliveries, pagination, nil
+}
+
+// ReplayDelivery queues the payload of a delivery to be sent to the webhook again.
+// The replay is a new delivery, so that the log of the original delivery is kept.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, webhookID string, deliveryID string) (model.WebhookDelivery, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var delivery model.WebhookDelivery
+	err := tx.
+		WithContext(ctx).
+		Where("id = ? AND webhook_id = ?", deliveryID, webhookID).
+		First(&delivery).
+		Error
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	now := datatype.DateTime(time.Now())
+	replay := model.WebhookDelivery{
+		WebhookID:     delivery.WebhookID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        model.WebhookDeliveryStatusPending,
+		NextAttemptAt: &now,
+	}
+	err = tx.
+		WithContext(ctx).
+		Create(&replay).
+		Error
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	return replay, nil
+}
+
+// enqueueEventInternal queues a delivery of the event for every enabled webhook that subscribed to it
+func (s *WebhookService) enqueueEventInternal(ctx context.Context, event model.WebhookEvent, data map[string]any, tx *gorm.DB) error {
+	var webhooks []model.Webhook
+	err := tx.
+		WithContext(ctx).
+		Select("id", "events").
+		Where("enabled = ?", true).
+		Find(&webhooks).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	webhooks = slices.DeleteFunc(webhooks, func(webhook model.Webhook) bool {
+		return !slices.Contains(webhook.Events, event)
+	})
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		ID:        uuid.NewString(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	now := datatype.DateTime(time.Now())
+	deliveries := make([]model.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryStatusPending,
+			NextAttemptAt: &now,
+		}
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Create(&deliveries).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// enqueueAuditLogInternal queues the webhook event that corresponds to an audit log event, if there is one
+func (s *WebhookService) enqueueAuditLogInternal(ctx context.Context, auditLog model.AuditLog, tx *gorm.DB) error {
+	data := map[string]any{
+		"userId":    auditLog.UserID,
+		"ipAddress": auditLog.IpAddress,
+		"userAgent": auditLog.UserAgent,
+		"country":   auditLog.Country,
+		"city":      auditLog.City,
+	}
+	for key, value := range auditLog.Data {
+		data[key] = value
+	}
+
+	var event model.WebhookEvent
+	switch auditLog.Event {
+	case model.AuditLogEventSignIn, model.AuditLogEventOneTimeAccessTokenSignIn, model.AuditLogEventIdentityProviderSignIn:
+		event = model.WebhookEventSignIn
+		data["method"] = auditLog.Event
+	case model.AuditLogEventNewClientAuthorization, model.AuditLogEventNewDeviceCodeAuthorization:
+		event = model.WebhookEventNewClientAuthorization
+	case model.AuditLogEventUserDisabled:
+		event = model.WebhookEventUserDisabled
+	default:
+		return nil
+	}
+
+	return s.enqueueEventInternal(ctx, event, data, tx)
+}
+
+// enqueueUserCreatedInternal queues the event for a user that has been created
+func (s *WebhookService) enqueueUserCreatedInternal(ctx context.Context, user model.User, tx *gorm.DB) error {
+	return s.enqueueEventInternal(ctx, model.WebhookEventUserCreated, map[string]any{
+		"userId":    user.ID,
+		"username":  user.Username,
+		"email":     user.Email,
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+		"isAdmin":   user.IsAdmin,
+	}, tx)
+}
+
+// enqueueGroupMembershipChangedInternal queues the event for a user group whose members changed.
+// Nothing is queued if no users were added or removed.
+func (s *WebhookService) enqueueGroupMembershipChangedInternal(ctx context.Context, group model.UserGroup, addedUserIDs []string, removedUserIDs []string, tx *gorm.DB) error {
+	if len(addedUserIDs) == 0 && len(removedUserIDs) == 0 {
+		return nil
+	}
+
+	// Empty lists are sent as arrays instead of null
+	if addedUserIDs == nil {
+		addedUserIDs = []string{}
+	}
+	if removedUserIDs == nil {
+		removedUserIDs = []string{}
+	}
+
+	return s.enqueueEventInternal(ctx, model.WebhookEventUserGroupMembershipChanged, map[string]any{
+		"userGroupId":    group.ID,
+		"userGroupName":  group.Name,
+		"addedUserIds":   addedUserIDs,
+		"removedUserIds": removedUserIDs,
+	}, tx)
+}
+
+// enqueueUserGroupsChangedInternal queues the membership event for every group that a user joined or left
+func (s *WebhookService) enqueueUserGroupsChangedInternal(ctx context.Context, userID string, joinedGroups []model.UserGroup, leftGroups []model.UserGroup, tx *gorm.DB) error {
+	for _, group := range joinedGroups {
+		err := s.enqueueGroupMembershipChangedInternal(ctx, group, []string{userID}, nil, tx)
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range leftGroups {
+		err := s.enqueueGroupMembershipChangedInternal(ctx, group, nil, []string{userID}, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessQueue sends the pending deliveries whose next attempt is due.
+// Failed deliveries are retried with an exponential backoff until they reach the maximum number of attempts.
+func (s *WebhookService) ProcessQueue(ctx context.Context) error {
+	var deliveries []model.WebhookDelivery
+	err := s.db.
+		WithContext(ctx).
+		Select("webhook_deliveries.*").
+		Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+		Where("webhooks.enabled = ? AND webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", true, model.WebhookDeliveryStatusPending, datatype.DateTime(time.Now())).
+		Order("webhook_deliveries.next_attempt_at").
+		Limit(webhookBatchSize).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+
+	webhooks := make(map[string]model.Webhook)
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			err = s.db.
+				WithContext(ctx).
+				Where("id = ?", delivery.WebhookID).
+				First(&webhook).
+				Error
+			if err != nil {
+				return fmt.Errorf("failed to load webhook: %w", err)
+			}
+			webhooks[webhook.ID] = webhook
+		}
+
+		// Another run may have sent the delivery in the meantime
+		claimed, err := s.claimDelivery(ctx, delivery)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		statusCode, sendErr := s.send(ctx, webhook, delivery)
+		if sendErr != nil {
+			slog.WarnContext(ctx, "Failed to deliver webhook",
+				slog.String("webhook", webhook.Name),
+				slog.String("event", string(delivery.Event)),
+				slog.Any("error", sendErr),
+			)
+		}
+
+		err = s.completeDelivery(ctx, delivery, statusCode, sendErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// claimDelivery marks a due delivery as in flight by moving its next attempt into the future, so that it isn't sent twice.
+// It returns false if the delivery was already claimed by another run.
+func (s *WebhookService) claimDelivery(ctx context.Context, delivery model.WebhookDelivery) (bool, error) {
+	now := time.Now()
+	result := s.db.
+		WithContext(ctx).
+		Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, model.WebhookDeliveryStatusPending, datatype.DateTime(now)).
+		Update("next_attempt_at", datatype.DateTime(now.Add(webhookClaimDuration)))
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// completeDelivery records the result of an attempt and schedules the next attempt of a failed delivery
+func (s *WebhookService) completeDelivery(ctx context.Context, delivery model.WebhookDelivery, statusCode int, sendErr error) error {
+	now := datatype.DateTime(time.Now())
+	attempts := delivery.Attempts + 1
+
+	updates := map[string]any{
+		"attempts":             attempts,
+		"last_attempt_at":      now,
+		"response_status_code": nil,
+		"last_error":           nil,
+		"next_attempt_at":      nil,
+	}
+	if statusCode != 0 {
+		updates["response_status_code"] = statusCode
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = model.WebhookDeliveryStatusSucceeded
+	case attempts >= webhookMaxAttempts:
+		updates["status"] = model.WebhookDeliveryStatusFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		backoff := min(time.Minute<<min(delivery.Attempts, 16), webhookMaxBackoff)
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = datatype.DateTime(time.Now().Add(backoff))
+	}
+
+	err := s.db.
+		WithContext(ctx).
+		Model(&model.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(updates).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// send posts the payload of a delivery to the webhook and returns the status code of the response.
+// The payload is signed with the secret of the webhook: the X-Pocket-ID-Signature header contains
+// the hex encoded HMAC-SHA256 of "<X-Pocket-ID-Timestamp>.<body>".
+func (s *WebhookService) send(ctx context.Context, webhook model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Pocket ID")
+	req.Header.Set("X-Pocket-ID-Event", string(delivery.Event))
+	req.Header.Set("X-Pocket-ID-Delivery", delivery.ID)
+	req.Header.Set("X-Pocket-ID-Timestamp", timestamp)
+	req.Header.Set("X-Pocket-ID-Signature", "sha256="+signWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d: %s", res.StatusCode, truncate(string(body), 200))
+	}
+
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<20))
+	return res.StatusCode, nil
+}
+
+func signWebhookPayload(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
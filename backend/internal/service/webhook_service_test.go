@@ -0,0 +1,219 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+func TestWebhookService(t *testing.T) {
+	db := newDatabaseForTest(t)
+
+	var secret string
+	statusCode := http.StatusOK
+	var received []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		signature := "sha256=" + signWebhookPayload(secret, r.Header.Get("X-Pocket-ID-Timestamp"), string(body))
+		assert.Equal(t, signature, r.Header.Get("X-Pocket-ID-Signature"))
+
+		var payload map[string]any
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, payload["event"], r.Header.Get("X-Pocket-ID-Event"))
+		received = append(received, payload)
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	appConfig := NewTestAppConfigService(&model.AppConfig{})
+	service := NewWebhookService(db, server.Client())
+	auditLogService := NewAuditLogService(db, appConfig, nil, &GeoLiteService{disableUpdater: true}, service, nil, nil)
+	scimProvisioningService := NewScimProvisioningService(db, nil)
+	userService := NewUserService(db, nil, auditLogService, nil, appConfig, nil, scimProvisioningService, service)
+	userGroupService := NewUserGroupService(db, appConfig, scimProvisioningService, service, auditLogService)
+
+	webhook, secret, err := service.CreateWebhook(t.Context(), dto.WebhookCreateDto{
+		Name:    "SIEM",
+		URL:     server.URL,
+		Events:  []string{string(model.WebhookEventUserCreated), string(model.WebhookEventUserGroupMembershipChanged)},
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	_, _, err = service.CreateWebhook(t.Context(), dto.WebhookCreateDto{
+		Name:    "Disabled",
+		URL:     server.URL,
+		Events:  []string{string(model.WebhookEventUserCreated)},
+		Enabled: false,
+	})
+	require.NoError(t, err)
+
+	listDeliveries := func(t *testing.T) []model.WebhookDelivery {
+		t.Helper()
+		deliveries, _, err := service.ListDeliveries(t.Context(), webhook.ID, utils.SortedPaginationRequest{})
+		require.NoError(t, err)
+		return deliveries
+	}
+
+	// Makes the pending deliveries due and processes the queue
+	processQueue := func(t *testing.T) {
+		t.Helper()
+		require.NoError(t, db.Model(&model.WebhookDelivery{}).
+			Where("status = ?", model.WebhookDeliveryStatusPending).
+			Update("next_attempt_at", datatype.DateTime(time.Now().Add(-time.Second))).
+			Error)
+		require.NoError(t, service.ProcessQueue(t.Context()))
+	}
+
+	t.Run("rejects unknown events", func(t *testing.T) {
+		_, _, err := service.CreateWebhook(t.Context(), dto.WebhookCreateDto{
+			Name:   "Invalid",
+			URL:    server.URL,
+			Events: []string{"UNKNOWN"},
+		})
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	var user model.User
+	t.Run("enqueues subscribed events of enabled webhooks", func(t *testing.T) {
+		user, err = userService.CreateUser(t.Context(), dto.UserCreateDto{Username: "tim", Email: "tim@example.com", FirstName: "Tim"})
+		require.NoError(t, err)
+		group, err := userGroupService.Create(t.Context(), dto.UserGroupCreateDto{Name: "developers", FriendlyName: "Developers"})
+		require.NoError(t, err)
+		_, err = userGroupService.UpdateUsers(t.Context(), group.ID, []string{user.ID})
+		require.NoError(t, err)
+
+		var count int64
+		require.NoError(t, db.Model(&model.WebhookDelivery{}).Count(&count).Error)
+		assert.EqualValues(t, 2, count)
+
+		deliveries := listDeliveries(t)
+		require.Len(t, deliveries, 2)
+		for _, delivery := range deliveries {
+			assert.Equal(t, model.WebhookDeliveryStatusPending, delivery.Status)
+		}
+	})
+
+	t.Run("sends signed payloads", func(t *testing.T) {
+		processQueue(t)
+
+		require.Len(t, received, 2)
+		events := []any{received[0]["event"], received[1]["event"]}
+		assert.ElementsMatch(t, []any{string(model.WebhookEventUserCreated), string(model.WebhookEventUserGroupMembershipChanged)}, events)
+
+		for _, payload := range received {
+			if payload["event"] == string(model.WebhookEventUserGroupMembershipChanged) {
+				data := payload["data"].(map[string]any)
+				assert.Equal(t, []any{user.ID}, data["addedUserIds"])
+				assert.Empty(t, data["removedUserIds"])
+			}
+		}
+
+		for _, delivery := range listDeliveries(t) {
+			assert.Equal(t, model.WebhookDeliveryStatusSucceeded, delivery.Status)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Nil(t, delivery.NextAttemptAt)
+		}
+	})
+
+	t.Run("retries failed deliveries with backoff", func(t *testing.T) {
+		received = nil
+		statusCode = http.StatusInternalServerError
+		t.Cleanup(func() { statusCode = http.StatusOK })
+
+		_, err := userService.CreateUser(t.Context(), dto.UserCreateDto{Username: "craig", Email: "craig@example.com", FirstName: "Craig"})
+		require.NoError(t, err)
+		processQueue(t)
+
+		var delivery model.WebhookDelivery
+		require.NoError(t, db.Where("status = ?", model.WebhookDeliveryStatusPending).First(&delivery).Error)
+		assert.Equal(t, 1, delivery.Attempts)
+		require.NotNil(t, delivery.ResponseStatusCode)
+		assert.Equal(t, http.StatusInternalServerError, *delivery.ResponseStatusCode)
+		require.NotNil(t, delivery.LastError)
+		require.NotNil(t, delivery.NextAttemptAt)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttemptAt.ToTime(), 5*time.Second)
+
+		// The delivery isn't due yet
+		require.NoError(t, service.ProcessQueue(t.Context()))
+		assert.Len(t, received, 1)
+
+		for range webhookMaxAttempts - 1 {
+			processQueue(t)
+		}
+		var failed model.WebhookDelivery
+		require.NoError(t, db.Where("id = ?", delivery.ID).First(&failed).Error)
+		assert.Equal(t, model.WebhookDeliveryStatusFailed, failed.Status)
+		assert.Equal(t, webhookMaxAttempts, failed.Attempts)
+		assert.Nil(t, failed.NextAttemptAt)
+		assert.Len(t, received, webhookMaxAttempts)
+	})
+
+	t.Run("sends claimed deliveries only once", func(t *testing.T) {
+		received = nil
+
+		_, err := userService.CreateUser(t.Context(), dto.UserCreateDto{Username: "lisa", Email: "lisa@example.com", FirstName: "Lisa"})
+		require.NoError(t, err)
+		require.NoError(t, db.Model(&model.WebhookDelivery{}).
+			Where("status = ?", model.WebhookDeliveryStatusPending).
+			Update("next_attempt_at", datatype.DateTime(time.Now().Add(-time.Second))).
+			Error)
+		var delivery model.WebhookDelivery
+		require.NoError(t, db.Where("status = ?", model.WebhookDeliveryStatusPending).First(&delivery).Error)
+
+		// Another run claims the delivery first
+		claimed, err := service.claimDelivery(t.Context(), delivery)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, err = service.claimDelivery(t.Context(), delivery)
+		require.NoError(t, err)
+		assert.False(t, claimed)
+
+		require.NoError(t, service.ProcessQueue(t.Context()))
+		assert.Empty(t, received)
+
+		var inFlight model.WebhookDelivery
+		require.NoError(t, db.Where("id = ?", delivery.ID).First(&inFlight).Error)
+		assert.Equal(t, model.WebhookDeliveryStatusPending, inFlight.Status)
+		assert.Equal(t, 0, inFlight.Attempts)
+
+		processQueue(t)
+		assert.Len(t, received, 1)
+	})
+
+	t.Run("replays deliveries", func(t *testing.T) {
+		received = nil
+
+		var failed model.WebhookDelivery
+		require.NoError(t, db.Where("status = ?", model.WebhookDeliveryStatusFailed).First(&failed).Error)
+
+		replayed, err := service.ReplayDelivery(t.Context(), webhook.ID, failed.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, failed.ID, replayed.ID)
+		assert.Equal(t, failed.Payload, replayed.Payload)
+
+		processQueue(t)
+		require.Len(t, received, 1)
+		var delivered model.WebhookDelivery
+		require.NoError(t, db.Where("id = ?", replayed.ID).First(&delivered).Error)
+		assert.Equal(t, model.WebhookDeliveryStatusSucceeded, delivered.Status)
+	})
+}
@@ -11,3 +11,11 @@ func AddAccessTokenCookie(c *gin.Context, maxAgeInSeconds int, token string) {
 func AddSessionIdCookie(c *gin.Context, maxAgeInSeconds int, sessionID string) {
 	c.SetCookie(SessionIdCookieName, sessionID, maxAgeInSeconds, "/", "", true, true)
 }
+
+func AddForwardAuthCookie(c *gin.Context, maxAgeInSeconds int, token string) {
+	c.SetCookie(ForwardAuthCookieName, token, maxAgeInSeconds, "/", "", true, true)
+}
+
+func AddIdentityProviderStateCookie(c *gin.Context, maxAgeInSeconds int, state string) {
+	c.SetCookie(IdentityProviderStateCookieName, state, maxAgeInSeconds, "/", "", true, true)
+}
@@ -8,10 +8,14 @@ import (
 
 var AccessTokenCookieName = "__Host-access_token"
 var SessionIdCookieName = "__Host-session"
+var ForwardAuthCookieName = "__Host-pocket_id_forward_auth"
+var IdentityProviderStateCookieName = "__Host-identity_provider_state"
 
 func init() {
 	if strings.HasPrefix(common.EnvConfig.AppURL, "http://") {
 		AccessTokenCookieName = "access_token"
 		SessionIdCookieName = "session"
+		ForwardAuthCookieName = "pocket_id_forward_auth"
+		IdentityProviderStateCookieName = "identity_provider_state"
 	}
 }
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// EnableAfterCommitHooks wraps the connection pool of db so that functions can be registered with AfterCommit
+// on the transactions started from it
+func EnableAfterCommitHooks(db *gorm.DB) {
+	sqlDB, ok := db.ConnPool.(*sql.DB)
+	if !ok {
+		return
+	}
+
+	pool := &afterCommitConnPool{DB: sqlDB}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+}
+
+// AfterCommit runs fn once the transaction tx is committed, and never if it is rolled back.
+// If tx is not a transaction, or after-commit hooks aren't enabled on the database, fn is run immediately.
+func AfterCommit(tx *gorm.DB, fn func()) {
+	committer, ok := tx.Statement.ConnPool.(*afterCommitTx)
+	if !ok {
+		fn()
+		return
+	}
+
+	committer.mu.Lock()
+	defer committer.mu.Unlock()
+	committer.hooks = append(committer.hooks, fn)
+}
+
+type afterCommitConnPool struct {
+	*sql.DB
+}
+
+// BeginTx shadows sql.DB's method so gorm uses it as a gorm.ConnPoolBeginner
+func (p *afterCommitConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &afterCommitTx{Tx: tx, db: p.DB}, nil
+}
+
+func (p *afterCommitConnPool) GetDBConn() (*sql.DB, error) { return p.DB, nil }
+
+type afterCommitTx struct {
+	*sql.Tx
+	db    *sql.DB
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (t *afterCommitTx) Commit() error {
+	err := t.Tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	hooks := t.hooks
+	t.hooks = nil
+	t.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
+
+func (t *afterCommitTx) GetDBConn() (*sql.DB, error) { return t.db, nil }
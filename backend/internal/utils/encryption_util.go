@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// The encrypted format starts with a header that consists of the magic bytes, the format version and the salt of the
+// key derivation. The plaintext is then split into chunks that are sealed with AES-256-GCM one by one. The nonce of a
+// chunk is its counter plus a flag that marks the final chunk, so that reordered, removed and truncated chunks are
+// detected. Every chunk authenticates the header as additional data, which binds it to the version and, through the
+// salt, to its file.
+const (
+	encryptionMagic          = "PIDENC1\n"
+	encryptionVersion        = 1
+	encryptionSaltSize       = 16
+	encryptionHeaderSize     = len(encryptionMagic) + 1 + encryptionSaltSize
+	encryptionChunkSize      = 64 * 1024
+	encryptionFinalChunkFlag = 1
+)
+
+// ErrWrongPassphrase is returned if encrypted data can't be decrypted, either because the passphrase is wrong or
+// because the data was modified
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+
+// IsEncrypted reports whether the data starts with the header of data encrypted by NewEncryptingWriter
+func IsEncrypted(header []byte) bool {
+	return bytes.HasPrefix(header, []byte(encryptionMagic))
+}
+
+// EncryptionHeaderSize is the number of bytes IsEncrypted needs
+const EncryptionHeaderSize = len(encryptionMagic)
+
+// NewEncryptingWriter returns a writer that encrypts everything written to it with a key derived from the passphrase.
+// Close must be called to write the final chunk, it doesn't close the underlying writer.
+func NewEncryptingWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	salt := make([]byte, encryptionSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newEncryptionAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, encryptionHeaderSize)
+	header = append(header, encryptionMagic...)
+	header = append(header, encryptionVersion)
+	header = append(header, salt...)
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, encryptionChunkSize)}, nil
+}
+
+type encryptingWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, as the final chunk must be marked as such
+		if len(e.buf) == encryptionChunkSize {
+			err := e.seal(false)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(e.buf[len(e.buf):encryptionChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (e *encryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptingWriter) seal(final bool) error {
+	ciphertext := e.aead.Seal(nil, encryptionNonce(e.counter, final), e.buf, e.header)
+	_, err := e.w.Write(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// NewDecryptingReader returns a reader that decrypts data encrypted by NewEncryptingWriter.
+// Reading returns ErrWrongPassphrase if the passphrase is wrong or the data was modified or truncated.
+func NewDecryptingReader(r io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, encryptionHeaderSize)
+	_, err := io.ReadFull(r, header)
+	if err != nil || !IsEncrypted(header) {
+		return nil, errors.New("the data isn't encrypted")
+	}
+	if version := header[len(encryptionMagic)]; version != encryptionVersion {
+		return nil, fmt.Errorf("unsupported encryption format version %d", version)
+	}
+
+	aead, err := newEncryptionAEAD(passphrase, header[len(encryptionMagic)+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{r: bufio.NewReader(r), aead: aead, header: header}, nil
+}
+
+type decryptingReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	done    bool
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		err := d.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptingReader) open() error {
+	ciphertext := make([]byte, encryptionChunkSize+d.aead.Overhead())
+	n, err := io.ReadFull(d.r, ciphertext)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	// The chunk is the final one if there's no more data after it
+	_, peekErr := d.r.Peek(1)
+	final := errors.Is(peekErr, io.EOF)
+
+	plaintext, err := d.aead.Open(nil, encryptionNonce(d.counter, final), ciphertext[:n], d.header)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+
+	d.counter++
+	d.buf = plaintext
+	d.done = final
+	return nil
+}
+
+func newEncryptionAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("the passphrase must not be empty")
+	}
+
+	key := argon2.IDKey([]byte(passphrase), salt, 3, 64*1024, 4, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptionNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = encryptionFinalChunkFlag
+	}
+	return nonce
+}
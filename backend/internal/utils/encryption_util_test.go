@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptForTest(t *testing.T, plaintext []byte, passphrase string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewEncryptingWriter(&buf, passphrase)
+	require.NoError(t, err)
+	_, err = writer.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func decryptForTest(encrypted []byte, passphrase string) ([]byte, error) {
+	reader, err := NewDecryptingReader(bytes.NewReader(encrypted), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestEncryption(t *testing.T) {
+	t.Run("round-trips data of any size", func(t *testing.T) {
+		for _, size := range []int{0, 1, encryptionChunkSize, encryptionChunkSize + 1, 3 * encryptionChunkSize} {
+			plaintext := make([]byte, size)
+			_, err := rand.Read(plaintext)
+			require.NoError(t, err)
+
+			encrypted := encryptForTest(t, plaintext, "correct horse battery staple")
+			assert.True(t, IsEncrypted(encrypted))
+
+			decrypted, err := decryptForTest(encrypted, "correct horse battery staple")
+			require.NoError(t, err, "size %d", size)
+			assert.Equal(t, plaintext, decrypted, "size %d", size)
+		}
+	})
+
+	plaintext := bytes.Repeat([]byte("pocket-id"), encryptionChunkSize/4)
+	encrypted := encryptForTest(t, plaintext, "correct horse battery staple")
+
+	t.Run("rejects wrong passphrases", func(t *testing.T) {
+		_, err := decryptForTest(encrypted, "wrong")
+		require.ErrorIs(t, err, ErrWrongPassphrase)
+	})
+
+	// The ciphertext of the chunks of the encrypted data
+	chunksOf := func(encrypted []byte) [][]byte {
+		var chunks [][]byte
+		for rest := encrypted[encryptionHeaderSize:]; len(rest) > 0; {
+			n := min(len(rest), encryptionChunkSize+16)
+			chunks = append(chunks, rest[:n])
+			rest = rest[n:]
+		}
+		return chunks
+	}
+	largeEncrypted := encryptForTest(t, bytes.Repeat([]byte("pocket-id"), encryptionChunkSize/3), "correct horse battery staple")
+	largeChunks := chunksOf(largeEncrypted)
+	require.Len(t, largeChunks, 3)
+	withChunks := func(chunks ...[]byte) []byte {
+		return bytes.Join(append([][]byte{largeEncrypted[:encryptionHeaderSize]}, chunks...), nil)
+	}
+
+	t.Run("detects truncated data", func(t *testing.T) {
+		for name, truncated := range map[string][]byte{
+			"final chunk removed":  withChunks(largeChunks[0], largeChunks[1]),
+			"final chunk cut":      largeEncrypted[:len(largeEncrypted)-1],
+			"middle chunk cut":     largeEncrypted[:encryptionHeaderSize+encryptionChunkSize],
+			"middle chunk removed": withChunks(largeChunks[0], largeChunks[2]),
+		} {
+			_, err := decryptForTest(truncated, "correct horse battery staple")
+			require.ErrorIs(t, err, ErrWrongPassphrase, name)
+		}
+	})
+
+	t.Run("detects reordered chunks", func(t *testing.T) {
+		_, err := decryptForTest(withChunks(largeChunks[1], largeChunks[0], largeChunks[2]), "correct horse battery staple")
+		require.ErrorIs(t, err, ErrWrongPassphrase)
+	})
+
+	t.Run("detects chunks of other files", func(t *testing.T) {
+		// The other file has the same plaintext and passphrase, but a different salt
+		otherEncrypted := encryptForTest(t, bytes.Repeat([]byte("pocket-id"), encryptionChunkSize/3), "correct horse battery staple")
+		otherChunks := chunksOf(otherEncrypted)
+
+		_, err := decryptForTest(withChunks(largeChunks[0], otherChunks[1], largeChunks[2]), "correct horse battery staple")
+		require.ErrorIs(t, err, ErrWrongPassphrase)
+	})
+
+	t.Run("rejects other format versions", func(t *testing.T) {
+		modified := bytes.Clone(encrypted)
+		modified[len(encryptionMagic)] = encryptionVersion + 1
+		_, err := decryptForTest(modified, "correct horse battery staple")
+		require.ErrorContains(t, err, "unsupported encryption format version")
+	})
+
+	t.Run("detects modified data", func(t *testing.T) {
+		modified := bytes.Clone(encrypted)
+		modified[len(modified)-20] ^= 0xff
+		_, err := decryptForTest(modified, "correct horse battery staple")
+		require.ErrorIs(t, err, ErrWrongPassphrase)
+	})
+
+	t.Run("isn't confused by unencrypted data", func(t *testing.T) {
+		assert.False(t, IsEncrypted(plaintext))
+		_, err := decryptForTest(plaintext, "correct horse battery staple")
+		require.Error(t, err)
+	})
+}
@@ -26,6 +26,7 @@ func NewServiceRunner(services ...Service) *ServiceRunner {
 }
 
 // Run all background services
+// If a service fails, the other services are stopped by canceling their context
 func (r *ServiceRunner) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -51,6 +52,7 @@ func (r *ServiceRunner) Run(ctx context.Context) error {
 		err := <-errCh
 		if err != nil {
 			errs = append(errs, err)
+			cancel()
 		}
 	}
 
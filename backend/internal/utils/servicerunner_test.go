@@ -122,4 +122,35 @@ func TestServiceRunner_Run(t *testing.T) {
 		require.ErrorIs(t, err, err1)
 		require.ErrorIs(t, err, err2)
 	})
+
+	t.Run("service with error stops the other services", func(t *testing.T) {
+		expectedErr := errors.New("service failed")
+		errorService := func(ctx context.Context) error {
+			return expectedErr
+		}
+
+		// Create a service that waits until context is canceled
+		waitingService := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		runner := NewServiceRunner(errorService, waitingService)
+
+		// Run the services with a timeout that the test doesn't reach
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		errCh := make(chan error)
+		go func() {
+			errCh <- runner.Run(ctx)
+		}()
+
+		select {
+		case err := <-errCh:
+			require.ErrorIs(t, err, expectedErr)
+		case <-time.After(time.Second):
+			t.Fatal("the waiting service wasn't stopped")
+		}
+	})
 }
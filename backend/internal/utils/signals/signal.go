@@ -38,3 +38,31 @@ func SignalContext(parentCtx context.Context) context.Context {
 
 	return ctx
 }
+
+// ReloadSignal returns a channel that receives a value whenever the application receives SIGHUP, which asks it to
+// reload its configuration. The channel is closed when the context is canceled.
+func ReloadSignal(ctx context.Context) <-chan struct{} {
+	reloadCh := make(chan struct{})
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		defer close(reloadCh)
+		defer signal.Stop(sigCh)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				select {
+				case reloadCh <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return reloadCh
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// CertificateReloader serves a TLS certificate from files and reloads it when the files change on disk,
+// e.g. when they're renewed by certbot or cert-manager
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	cert atomic.Pointer[tls.Certificate]
+	// lastFiles identifies the versions of the files that were loaded, it's only accessed by Run
+	lastFiles string
+}
+
+// NewCertificateReloader loads the certificate and its key, which must be valid
+func NewCertificateReloader(certFile string, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile, interval: 10 * time.Second}
+	_, err := r.reloadIfChanged()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, it's used as tls.Config.GetCertificate
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Run checks the files for changes until the context is canceled
+func (r *CertificateReloader) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			reloaded, err := r.reloadIfChanged()
+			if err != nil {
+				// Keep serving the previous certificate, the files may be in the middle of being replaced
+				log.Printf("[WARN] Failed to reload TLS certificate: %v", err)
+			} else if reloaded {
+				log.Printf("Reloaded TLS certificate from %s", r.certFile)
+			}
+		}
+	}
+}
+
+func (r *CertificateReloader) reloadIfChanged() (bool, error) {
+	files, err := fileVersions(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if files == r.lastFiles {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.cert.Store(&cert)
+	r.lastFiles = files
+	return true, nil
+}
+
+// fileVersions returns a string that changes when one of the files is modified or replaced
+func fileVersions(paths ...string) (string, error) {
+	var versions string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		versions += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return versions, nil
+}
+
+// LoadCertPool loads the PEM-encoded certificates in a file, e.g. the CAs that client certificates are verified with
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("the file doesn't contain PEM-encoded certificates")
+	}
+	return pool, nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeTestCertificate(t, certFile, keyFile, "first.example.com")
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "first.example.com", servedCommonName(t, reloader))
+
+	t.Run("keeps the certificate if the files didn't change", func(t *testing.T) {
+		reloaded, err := reloader.reloadIfChanged()
+		require.NoError(t, err)
+		assert.False(t, reloaded)
+	})
+
+	t.Run("keeps the certificate if the new files are invalid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0600))
+
+		_, err := reloader.reloadIfChanged()
+		require.Error(t, err)
+		assert.Equal(t, "first.example.com", servedCommonName(t, reloader))
+	})
+
+	t.Run("reloads the certificate when the files change", func(t *testing.T) {
+		writeTestCertificate(t, certFile, keyFile, "second.example.com")
+
+		reloaded, err := reloader.reloadIfChanged()
+		require.NoError(t, err)
+		assert.True(t, reloaded)
+		assert.Equal(t, "second.example.com", servedCommonName(t, reloader))
+	})
+
+	t.Run("rejects invalid files at startup", func(t *testing.T) {
+		_, err := NewCertificateReloader(filepath.Join(dir, "missing.crt"), keyFile)
+		require.Error(t, err)
+	})
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "ca.crt")
+	writeTestCertificate(t, certFile, filepath.Join(dir, "ca.key"), "ca.example.com")
+
+	pool, err := LoadCertPool(certFile)
+	require.NoError(t, err)
+	assert.NotNil(t, pool)
+
+	invalidFile := filepath.Join(dir, "invalid.crt")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("not a certificate"), 0600))
+	_, err = LoadCertPool(invalidFile)
+	require.Error(t, err)
+}
+
+func writeTestCertificate(t *testing.T, certFile string, keyFile string, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func servedCommonName(t *testing.T, reloader *CertificateReloader) string {
+	t.Helper()
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
@@ -0,0 +1,31 @@
+DROP TABLE audit_log_checkpoints;
+DROP TABLE audit_log_chain_links;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+DROP TABLE identity_provider_login_states;
+DROP TABLE user_identities;
+DROP TABLE identity_providers;
+DROP TABLE forward_auth_codes;
+DROP TABLE app_passwords;
+DROP TABLE scim_provisioning_jobs;
+DROP TABLE scim_provisioned_users;
+DROP TABLE scim_provisioning_targets;
+DROP TABLE saml_service_providers_allowed_user_groups;
+DROP TABLE saml_service_providers;
+DROP TABLE sessions;
+DROP TABLE oidc_device_codes;
+DROP TABLE oidc_refresh_tokens;
+DROP TABLE api_keys;
+DROP TABLE webauthn_sessions;
+DROP TABLE webauthn_credentials;
+DROP TABLE oidc_clients_allowed_user_groups;
+DROP TABLE user_groups_users;
+DROP TABLE user_authorized_oidc_clients;
+DROP TABLE one_time_access_tokens;
+DROP TABLE oidc_authorization_codes;
+DROP TABLE oidc_clients;
+DROP TABLE custom_claims;
+DROP TABLE audit_logs;
+DROP TABLE users;
+DROP TABLE user_groups;
+DROP TABLE app_config_variables;
@@ -0,0 +1,404 @@
+-- The MySQL schema starts with the schema of the other providers at the time MySQL support was added.
+-- All tables use a binary collation, so that values are compared case-sensitively like with Postgres.
+
+CREATE TABLE app_config_variables
+(
+    `key` VARCHAR(100) NOT NULL PRIMARY KEY,
+    value TEXT         NOT NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE user_groups
+(
+    id               CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at       DATETIME(6),
+    friendly_name    VARCHAR(255) NOT NULL,
+    name             VARCHAR(255) NOT NULL UNIQUE,
+    ldap_id          VARCHAR(255) UNIQUE,
+    scim_external_id TEXT
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE users
+(
+    id               CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at       DATETIME(6),
+    username         VARCHAR(255) NOT NULL UNIQUE,
+    email            VARCHAR(255) NOT NULL UNIQUE,
+    first_name       VARCHAR(100),
+    last_name        VARCHAR(100),
+    is_admin         BOOLEAN      NOT NULL DEFAULT FALSE,
+    ldap_id          VARCHAR(255) UNIQUE,
+    locale           TEXT,
+    disabled         BOOLEAN      NOT NULL DEFAULT FALSE,
+    scim_external_id TEXT
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- Unlike with the other providers, the client name isn't indexed, because MariaDB doesn't support functional indexes
+CREATE TABLE audit_logs
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at DATETIME(6),
+    event      VARCHAR(100) NOT NULL,
+    ip_address VARCHAR(45),
+    data       JSON         NOT NULL,
+    user_id    CHAR(36),
+    user_agent TEXT,
+    country    VARCHAR(100),
+    city       VARCHAR(100),
+    INDEX idx_audit_logs_event (event),
+    INDEX idx_audit_logs_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE custom_claims
+(
+    id            CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at    DATETIME(6),
+    `key`         VARCHAR(255) NOT NULL,
+    value         TEXT         NOT NULL,
+    user_id       CHAR(36),
+    user_group_id CHAR(36),
+    CONSTRAINT custom_claims_unique UNIQUE (`key`, user_id, user_group_id),
+    CHECK (user_id IS NOT NULL OR user_group_id IS NOT NULL),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_group_id) REFERENCES user_groups (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE oidc_clients
+(
+    id                   CHAR(36) NOT NULL PRIMARY KEY,
+    created_at           DATETIME(6),
+    name                 VARCHAR(255),
+    secret               TEXT,
+    callback_urls        JSON,
+    logout_callback_urls JSON,
+    image_type           VARCHAR(10),
+    created_by_id        CHAR(36),
+    is_public            BOOLEAN DEFAULT FALSE,
+    pkce_enabled         BOOLEAN DEFAULT FALSE,
+    credentials          JSON,
+    forward_auth_hosts   JSON,
+    FOREIGN KEY (created_by_id) REFERENCES users (id) ON DELETE SET NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE oidc_authorization_codes
+(
+    id                           CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at                   DATETIME(6),
+    code                         VARCHAR(255) NOT NULL UNIQUE,
+    scope                        TEXT         NOT NULL,
+    nonce                        VARCHAR(255),
+    expires_at                   DATETIME(6)  NOT NULL,
+    user_id                      CHAR(36)     NOT NULL,
+    client_id                    CHAR(36)     NOT NULL,
+    code_challenge               VARCHAR(255),
+    code_challenge_method_sha256 BOOLEAN,
+    acr                          VARCHAR(255) NOT NULL DEFAULT '',
+    amr                          VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE one_time_access_tokens
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at DATETIME(6),
+    token      VARCHAR(255) NOT NULL UNIQUE,
+    expires_at DATETIME(6)  NOT NULL,
+    user_id    CHAR(36)     NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE user_authorized_oidc_clients
+(
+    scope     VARCHAR(255),
+    user_id   CHAR(36) NOT NULL,
+    client_id CHAR(36) NOT NULL,
+    PRIMARY KEY (user_id, client_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE user_groups_users
+(
+    user_id       CHAR(36) NOT NULL,
+    user_group_id CHAR(36) NOT NULL,
+    PRIMARY KEY (user_id, user_group_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_group_id) REFERENCES user_groups (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE oidc_clients_allowed_user_groups
+(
+    user_group_id  CHAR(36) NOT NULL,
+    oidc_client_id CHAR(36) NOT NULL,
+    PRIMARY KEY (oidc_client_id, user_group_id),
+    FOREIGN KEY (user_group_id) REFERENCES user_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (oidc_client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE webauthn_credentials
+(
+    id               CHAR(36)        NOT NULL PRIMARY KEY,
+    created_at       DATETIME(6),
+    name             VARCHAR(255)    NOT NULL,
+    credential_id    VARBINARY(1024) NOT NULL UNIQUE,
+    public_key       BLOB            NOT NULL,
+    attestation_type VARCHAR(20)     NOT NULL,
+    transport        JSON            NOT NULL,
+    user_id          CHAR(36),
+    backup_eligible  BOOLEAN         NOT NULL DEFAULT FALSE,
+    backup_state     BOOLEAN         NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE webauthn_sessions
+(
+    id                CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at        DATETIME(6),
+    challenge         VARCHAR(255) NOT NULL UNIQUE,
+    expires_at        DATETIME(6)  NOT NULL,
+    user_verification VARCHAR(255) NOT NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE api_keys
+(
+    id                    CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at            DATETIME(6),
+    name                  VARCHAR(255) NOT NULL,
+    `key`                 VARCHAR(255) NOT NULL UNIQUE,
+    description           TEXT,
+    expires_at            DATETIME(6)  NOT NULL,
+    last_used_at          DATETIME(6),
+    expiration_email_sent BOOLEAN      NOT NULL DEFAULT FALSE,
+    user_id               CHAR(36),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE oidc_refresh_tokens
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at DATETIME(6),
+    token      VARCHAR(255) NOT NULL UNIQUE,
+    expires_at DATETIME(6)  NOT NULL,
+    scope      TEXT         NOT NULL,
+    user_id    CHAR(36)     NOT NULL,
+    client_id  CHAR(36)     NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE oidc_device_codes
+(
+    id            CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at    DATETIME(6),
+    device_code   VARCHAR(255) NOT NULL UNIQUE,
+    user_code     VARCHAR(255) NOT NULL UNIQUE,
+    scope         TEXT         NOT NULL,
+    expires_at    DATETIME(6)  NOT NULL,
+    is_authorized BOOLEAN      NOT NULL DEFAULT FALSE,
+    user_id       CHAR(36),
+    client_id     CHAR(36)     NOT NULL,
+    acr           VARCHAR(255) NOT NULL DEFAULT '',
+    amr           VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE sessions
+(
+    id            CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at    DATETIME(6),
+    user_id       CHAR(36)     NOT NULL,
+    ip_address    TEXT,
+    country       TEXT,
+    city          TEXT,
+    user_agent    TEXT,
+    last_seen_at  DATETIME(6)  NOT NULL,
+    expires_at    DATETIME(6)  NOT NULL,
+    auth_method   VARCHAR(255) NOT NULL DEFAULT '',
+    user_verified BOOLEAN      NOT NULL DEFAULT FALSE,
+    elevated_at   DATETIME(6),
+    INDEX idx_sessions_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE saml_service_providers
+(
+    id                CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at        DATETIME(6),
+    name              VARCHAR(255) NOT NULL,
+    entity_id         VARCHAR(512) NOT NULL UNIQUE,
+    acs_url           TEXT,
+    metadata          MEDIUMTEXT,
+    name_id_format    VARCHAR(20)  NOT NULL DEFAULT 'persistent',
+    attribute_mapping JSON,
+    created_by_id     CHAR(36),
+    FOREIGN KEY (created_by_id) REFERENCES users (id) ON DELETE SET NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE saml_service_providers_allowed_user_groups
+(
+    user_group_id            CHAR(36) NOT NULL,
+    saml_service_provider_id CHAR(36) NOT NULL,
+    PRIMARY KEY (saml_service_provider_id, user_group_id),
+    FOREIGN KEY (user_group_id) REFERENCES user_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (saml_service_provider_id) REFERENCES saml_service_providers (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE scim_provisioning_targets
+(
+    id              CHAR(36) NOT NULL PRIMARY KEY,
+    created_at      DATETIME(6),
+    oidc_client_id  CHAR(36) NOT NULL UNIQUE,
+    endpoint        TEXT     NOT NULL,
+    token           TEXT     NOT NULL,
+    enabled         BOOLEAN  NOT NULL DEFAULT TRUE,
+    last_synced_at  DATETIME(6),
+    last_sync_error TEXT,
+    FOREIGN KEY (oidc_client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE scim_provisioned_users
+(
+    scim_provisioning_target_id CHAR(36) NOT NULL,
+    user_id                     CHAR(36) NOT NULL,
+    remote_id                   TEXT     NOT NULL,
+    active                      BOOLEAN  NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (scim_provisioning_target_id, user_id),
+    FOREIGN KEY (scim_provisioning_target_id) REFERENCES scim_provisioning_targets (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE scim_provisioning_jobs
+(
+    id                          CHAR(36)    NOT NULL PRIMARY KEY,
+    created_at                  DATETIME(6),
+    scim_provisioning_target_id CHAR(36)    NOT NULL,
+    user_id                     CHAR(36)    NOT NULL,
+    attempts                    INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at             DATETIME(6) NOT NULL,
+    last_error                  TEXT,
+    UNIQUE (scim_provisioning_target_id, user_id),
+    INDEX idx_scim_provisioning_jobs_next_attempt_at (next_attempt_at),
+    FOREIGN KEY (scim_provisioning_target_id) REFERENCES scim_provisioning_targets (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE app_passwords
+(
+    id           CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at   DATETIME(6),
+    name         TEXT         NOT NULL,
+    password     VARCHAR(255) NOT NULL UNIQUE,
+    last_used_at DATETIME(6),
+    user_id      CHAR(36)     NOT NULL,
+    INDEX idx_app_passwords_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE forward_auth_codes
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at DATETIME(6),
+    code       VARCHAR(255) NOT NULL UNIQUE,
+    host       TEXT         NOT NULL,
+    expires_at DATETIME(6)  NOT NULL,
+    session_id CHAR(36)     NOT NULL,
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE identity_providers
+(
+    id                  CHAR(36) NOT NULL PRIMARY KEY,
+    created_at          DATETIME(6),
+    name                TEXT     NOT NULL,
+    issuer              TEXT     NOT NULL,
+    client_id           TEXT     NOT NULL,
+    client_secret       TEXT     NOT NULL,
+    scopes              TEXT     NOT NULL,
+    enabled             BOOLEAN  NOT NULL DEFAULT TRUE,
+    link_by_email       BOOLEAN  NOT NULL DEFAULT FALSE,
+    allow_user_creation BOOLEAN  NOT NULL DEFAULT FALSE,
+    groups_claim        TEXT,
+    group_mappings      JSON
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE user_identities
+(
+    id                   CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at           DATETIME(6),
+    subject              VARCHAR(255) NOT NULL,
+    email                TEXT,
+    identity_provider_id CHAR(36)     NOT NULL,
+    user_id              CHAR(36)     NOT NULL,
+    UNIQUE (identity_provider_id, subject),
+    UNIQUE (identity_provider_id, user_id),
+    INDEX idx_user_identities_user_id (user_id),
+    FOREIGN KEY (identity_provider_id) REFERENCES identity_providers (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE identity_provider_login_states
+(
+    id                   CHAR(36)     NOT NULL PRIMARY KEY,
+    created_at           DATETIME(6),
+    state                VARCHAR(255) NOT NULL UNIQUE,
+    nonce                TEXT         NOT NULL,
+    code_verifier        TEXT         NOT NULL,
+    redirect_path        TEXT,
+    expires_at           DATETIME(6)  NOT NULL,
+    identity_provider_id CHAR(36)     NOT NULL,
+    user_id              CHAR(36),
+    FOREIGN KEY (identity_provider_id) REFERENCES identity_providers (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE webhooks
+(
+    id         CHAR(36) NOT NULL PRIMARY KEY,
+    created_at DATETIME(6),
+    name       TEXT     NOT NULL,
+    url        TEXT     NOT NULL,
+    secret     TEXT     NOT NULL,
+    events     JSON     NOT NULL,
+    enabled    BOOLEAN  NOT NULL DEFAULT TRUE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE webhook_deliveries
+(
+    id                   CHAR(36)    NOT NULL PRIMARY KEY,
+    created_at           DATETIME(6),
+    webhook_id           CHAR(36)    NOT NULL,
+    event                TEXT        NOT NULL,
+    payload              MEDIUMTEXT  NOT NULL,
+    status               VARCHAR(20) NOT NULL,
+    attempts             INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at      DATETIME(6),
+    last_attempt_at      DATETIME(6),
+    response_status_code INTEGER,
+    last_error           TEXT,
+    INDEX idx_webhook_deliveries_webhook_id (webhook_id),
+    INDEX idx_webhook_deliveries_next_attempt_at (next_attempt_at),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- The links don't reference the audit logs with a foreign key, because they must outlive purged audit logs
+-- and reveal audit logs that were deleted from the database directly
+CREATE TABLE audit_log_chain_links
+(
+    sequence            BIGINT   NOT NULL PRIMARY KEY,
+    audit_log_id        CHAR(36) UNIQUE,
+    user_id             CHAR(36),
+    entry_hash          TEXT     NOT NULL,
+    hash                TEXT     NOT NULL,
+    purge_checkpoint_id CHAR(36),
+    INDEX idx_audit_log_chain_links_purge_checkpoint_id (purge_checkpoint_id)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE audit_log_checkpoints
+(
+    id           CHAR(36) NOT NULL PRIMARY KEY,
+    created_at   DATETIME(6),
+    number       BIGINT   NOT NULL UNIQUE,
+    sequence     BIGINT   NOT NULL,
+    hash         TEXT     NOT NULL,
+    purge_digest TEXT     NOT NULL,
+    signature    TEXT     NOT NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
@@ -0,0 +1,14 @@
+-- The head is a single row with the last link of the chain, which is locked when a link is appended.
+-- It's created here, so that it also exists while the chain is empty.
+CREATE TABLE audit_log_chain_heads
+(
+    id       BIGINT  NOT NULL PRIMARY KEY CHECK (id = 1),
+    sequence BIGINT  NOT NULL,
+    hash     TEXT    NOT NULL
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+INSERT INTO audit_log_chain_heads (id, sequence, hash)
+SELECT 1,
+       COALESCE(MAX(sequence), 0),
+       COALESCE((SELECT hash FROM audit_log_chain_links ORDER BY sequence DESC LIMIT 1), '')
+FROM audit_log_chain_links;
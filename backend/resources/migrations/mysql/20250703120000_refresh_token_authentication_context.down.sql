@@ -0,0 +1,2 @@
+ALTER TABLE oidc_refresh_tokens DROP COLUMN amr;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN acr;
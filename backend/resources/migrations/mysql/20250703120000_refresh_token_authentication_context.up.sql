@@ -0,0 +1,2 @@
+ALTER TABLE oidc_refresh_tokens ADD COLUMN acr VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE oidc_refresh_tokens ADD COLUMN amr VARCHAR(255) NOT NULL DEFAULT '';
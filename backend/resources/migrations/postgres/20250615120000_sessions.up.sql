@@ -0,0 +1,14 @@
+CREATE TABLE sessions
+(
+    id           UUID        NOT NULL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    user_id      UUID        NOT NULL REFERENCES users ON DELETE CASCADE,
+    ip_address   TEXT,
+    country      TEXT,
+    city         TEXT,
+    user_agent   TEXT,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,2 @@
+-- This fails if there are audit logs without an IP address, which must be deleted first
+ALTER TABLE audit_logs ALTER COLUMN ip_address SET NOT NULL;
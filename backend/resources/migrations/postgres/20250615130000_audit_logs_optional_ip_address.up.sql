@@ -0,0 +1,2 @@
+-- Events that aren't triggered by a request, such as those of the LDAP sync, don't have an IP address
+ALTER TABLE audit_logs ALTER COLUMN ip_address DROP NOT NULL;
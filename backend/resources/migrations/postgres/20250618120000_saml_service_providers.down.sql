@@ -0,0 +1,2 @@
+DROP TABLE saml_service_providers_allowed_user_groups;
+DROP TABLE saml_service_providers;
@@ -0,0 +1,19 @@
+CREATE TABLE saml_service_providers
+(
+    id                UUID         NOT NULL PRIMARY KEY,
+    created_at        TIMESTAMPTZ,
+    name              VARCHAR(255) NOT NULL,
+    entity_id         TEXT         NOT NULL UNIQUE,
+    acs_url           TEXT,
+    metadata          TEXT,
+    name_id_format    VARCHAR(20)  NOT NULL DEFAULT 'persistent',
+    attribute_mapping JSONB,
+    created_by_id     UUID REFERENCES users ON DELETE SET NULL
+);
+
+CREATE TABLE saml_service_providers_allowed_user_groups
+(
+    user_group_id            UUID NOT NULL REFERENCES user_groups ON DELETE CASCADE,
+    saml_service_provider_id UUID NOT NULL REFERENCES saml_service_providers ON DELETE CASCADE,
+    PRIMARY KEY (saml_service_provider_id, user_group_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user_groups DROP COLUMN scim_external_id;
+ALTER TABLE users DROP COLUMN scim_external_id;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN scim_external_id TEXT;
+ALTER TABLE user_groups ADD COLUMN scim_external_id TEXT;
@@ -0,0 +1,3 @@
+DROP TABLE scim_provisioning_jobs;
+DROP TABLE scim_provisioned_users;
+DROP TABLE scim_provisioning_targets;
@@ -0,0 +1,34 @@
+CREATE TABLE scim_provisioning_targets
+(
+    id              UUID        NOT NULL PRIMARY KEY,
+    created_at      TIMESTAMPTZ,
+    oidc_client_id  UUID        NOT NULL UNIQUE REFERENCES oidc_clients ON DELETE CASCADE,
+    endpoint        TEXT        NOT NULL,
+    token           TEXT        NOT NULL,
+    enabled         BOOLEAN     NOT NULL DEFAULT TRUE,
+    last_synced_at  TIMESTAMPTZ,
+    last_sync_error TEXT
+);
+
+CREATE TABLE scim_provisioned_users
+(
+    scim_provisioning_target_id UUID    NOT NULL REFERENCES scim_provisioning_targets ON DELETE CASCADE,
+    user_id                     UUID    NOT NULL,
+    remote_id                   TEXT    NOT NULL,
+    active                      BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (scim_provisioning_target_id, user_id)
+);
+
+CREATE TABLE scim_provisioning_jobs
+(
+    id                          UUID        NOT NULL PRIMARY KEY,
+    created_at                  TIMESTAMPTZ,
+    scim_provisioning_target_id UUID        NOT NULL REFERENCES scim_provisioning_targets ON DELETE CASCADE,
+    user_id                     UUID        NOT NULL,
+    attempts                    INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at             TIMESTAMPTZ NOT NULL,
+    last_error                  TEXT,
+    UNIQUE (scim_provisioning_target_id, user_id)
+);
+
+CREATE INDEX idx_scim_provisioning_jobs_next_attempt_at ON scim_provisioning_jobs (next_attempt_at);
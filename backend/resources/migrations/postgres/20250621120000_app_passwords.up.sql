@@ -0,0 +1,11 @@
+CREATE TABLE app_passwords
+(
+    id           UUID        NOT NULL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    name         TEXT        NOT NULL,
+    password     TEXT        NOT NULL UNIQUE,
+    last_used_at TIMESTAMPTZ,
+    user_id      UUID        NOT NULL REFERENCES users ON DELETE CASCADE
+);
+
+CREATE INDEX idx_app_passwords_user_id ON app_passwords (user_id);
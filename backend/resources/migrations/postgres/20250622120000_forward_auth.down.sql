@@ -0,0 +1,2 @@
+DROP TABLE forward_auth_codes;
+ALTER TABLE oidc_clients DROP COLUMN forward_auth_hosts;
@@ -0,0 +1,11 @@
+ALTER TABLE oidc_clients ADD COLUMN forward_auth_hosts JSONB;
+
+CREATE TABLE forward_auth_codes
+(
+    id         UUID        NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    code       TEXT        NOT NULL UNIQUE,
+    host       TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    session_id UUID        NOT NULL REFERENCES sessions ON DELETE CASCADE
+);
@@ -0,0 +1,28 @@
+CREATE TABLE webhooks
+(
+    id         UUID        NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    name       TEXT        NOT NULL,
+    url        TEXT        NOT NULL,
+    secret     TEXT        NOT NULL,
+    events     JSONB       NOT NULL,
+    enabled    BOOLEAN     NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id                   UUID        NOT NULL PRIMARY KEY,
+    created_at           TIMESTAMPTZ,
+    webhook_id           UUID        NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event                TEXT        NOT NULL,
+    payload              TEXT        NOT NULL,
+    status               TEXT        NOT NULL,
+    attempts             INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at      TIMESTAMPTZ,
+    last_attempt_at      TIMESTAMPTZ,
+    response_status_code INTEGER,
+    last_error           TEXT
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
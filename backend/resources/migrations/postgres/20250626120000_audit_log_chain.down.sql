@@ -0,0 +1,2 @@
+DROP TABLE audit_log_checkpoints;
+DROP TABLE audit_log_chain_links;
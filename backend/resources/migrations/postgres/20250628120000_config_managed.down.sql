@@ -0,0 +1,3 @@
+ALTER TABLE app_config_variables DROP COLUMN config_managed;
+ALTER TABLE user_groups DROP COLUMN config_managed;
+ALTER TABLE oidc_clients DROP COLUMN config_managed;
@@ -0,0 +1,14 @@
+CREATE TABLE sessions
+(
+    id           TEXT     NOT NULL PRIMARY KEY,
+    created_at   DATETIME,
+    user_id      TEXT     NOT NULL REFERENCES users ON DELETE CASCADE,
+    ip_address   TEXT,
+    country      TEXT,
+    city         TEXT,
+    user_agent   TEXT,
+    last_seen_at DATETIME NOT NULL,
+    expires_at   DATETIME NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
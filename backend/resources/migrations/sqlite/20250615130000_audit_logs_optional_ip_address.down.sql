@@ -0,0 +1,24 @@
+CREATE TABLE audit_logs_old
+(
+    id         TEXT NOT NULL PRIMARY KEY,
+    created_at DATETIME,
+    event      TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    data       BLOB NOT NULL,
+    user_id    TEXT REFERENCES users,
+    country    TEXT,
+    city       TEXT
+);
+
+-- Before, audit logs without an IP address stored an empty string
+INSERT INTO audit_logs_old (id, created_at, event, ip_address, user_agent, data, user_id, country, city)
+SELECT id, created_at, event, COALESCE(ip_address, ''), user_agent, data, user_id, country, city
+FROM audit_logs;
+
+DROP TABLE audit_logs;
+ALTER TABLE audit_logs_old RENAME TO audit_logs;
+
+CREATE INDEX idx_audit_logs_event ON audit_logs(event);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX idx_audit_logs_client_name ON audit_logs((json_extract(data, '$.clientName')));
@@ -0,0 +1,25 @@
+-- Events that aren't triggered by a request, such as those of the LDAP sync, don't have an IP address.
+-- SQLite can't drop the NOT NULL constraint of a column, so the table is recreated.
+CREATE TABLE audit_logs_new
+(
+    id         TEXT NOT NULL PRIMARY KEY,
+    created_at DATETIME,
+    event      TEXT NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT NOT NULL,
+    data       BLOB NOT NULL,
+    user_id    TEXT REFERENCES users,
+    country    TEXT,
+    city       TEXT
+);
+
+INSERT INTO audit_logs_new (id, created_at, event, ip_address, user_agent, data, user_id, country, city)
+SELECT id, created_at, event, NULLIF(ip_address, ''), user_agent, data, user_id, country, city
+FROM audit_logs;
+
+DROP TABLE audit_logs;
+ALTER TABLE audit_logs_new RENAME TO audit_logs;
+
+CREATE INDEX idx_audit_logs_event ON audit_logs(event);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX idx_audit_logs_client_name ON audit_logs((json_extract(data, '$.clientName')));
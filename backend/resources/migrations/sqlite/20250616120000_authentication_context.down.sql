@@ -0,0 +1,8 @@
+ALTER TABLE oidc_device_codes DROP COLUMN amr;
+ALTER TABLE oidc_device_codes DROP COLUMN acr;
+
+ALTER TABLE oidc_authorization_codes DROP COLUMN amr;
+ALTER TABLE oidc_authorization_codes DROP COLUMN acr;
+
+ALTER TABLE sessions DROP COLUMN user_verified;
+ALTER TABLE sessions DROP COLUMN auth_method;
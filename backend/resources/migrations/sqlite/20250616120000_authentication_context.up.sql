@@ -0,0 +1,8 @@
+ALTER TABLE sessions ADD COLUMN auth_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN user_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE oidc_authorization_codes ADD COLUMN acr TEXT NOT NULL DEFAULT '';
+ALTER TABLE oidc_authorization_codes ADD COLUMN amr TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE oidc_device_codes ADD COLUMN acr TEXT NOT NULL DEFAULT '';
+ALTER TABLE oidc_device_codes ADD COLUMN amr TEXT NOT NULL DEFAULT '';
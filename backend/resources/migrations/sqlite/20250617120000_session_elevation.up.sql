@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN elevated_at DATETIME;
@@ -0,0 +1,21 @@
+CREATE TABLE saml_service_providers
+(
+    id                TEXT NOT NULL PRIMARY KEY,
+    created_at        DATETIME,
+    name              TEXT NOT NULL,
+    entity_id         TEXT NOT NULL UNIQUE,
+    acs_url           TEXT,
+    metadata          TEXT,
+    name_id_format    TEXT NOT NULL DEFAULT 'persistent',
+    attribute_mapping BLOB,
+    created_by_id     TEXT REFERENCES users ON DELETE SET NULL
+);
+
+CREATE TABLE saml_service_providers_allowed_user_groups
+(
+    user_group_id            TEXT NOT NULL,
+    saml_service_provider_id TEXT NOT NULL,
+    PRIMARY KEY (saml_service_provider_id, user_group_id),
+    FOREIGN KEY (saml_service_provider_id) REFERENCES saml_service_providers (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_group_id) REFERENCES user_groups (id) ON DELETE CASCADE
+);
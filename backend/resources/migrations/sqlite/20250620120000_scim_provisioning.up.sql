@@ -0,0 +1,34 @@
+CREATE TABLE scim_provisioning_targets
+(
+    id              TEXT    NOT NULL PRIMARY KEY,
+    created_at      DATETIME,
+    oidc_client_id  TEXT    NOT NULL UNIQUE REFERENCES oidc_clients ON DELETE CASCADE,
+    endpoint        TEXT    NOT NULL,
+    token           TEXT    NOT NULL,
+    enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    last_synced_at  DATETIME,
+    last_sync_error TEXT
+);
+
+CREATE TABLE scim_provisioned_users
+(
+    scim_provisioning_target_id TEXT    NOT NULL REFERENCES scim_provisioning_targets ON DELETE CASCADE,
+    user_id                     TEXT    NOT NULL,
+    remote_id                   TEXT    NOT NULL,
+    active                      BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (scim_provisioning_target_id, user_id)
+);
+
+CREATE TABLE scim_provisioning_jobs
+(
+    id                          TEXT     NOT NULL PRIMARY KEY,
+    created_at                  DATETIME,
+    scim_provisioning_target_id TEXT     NOT NULL REFERENCES scim_provisioning_targets ON DELETE CASCADE,
+    user_id                     TEXT     NOT NULL,
+    attempts                    INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at             DATETIME NOT NULL,
+    last_error                  TEXT,
+    UNIQUE (scim_provisioning_target_id, user_id)
+);
+
+CREATE INDEX idx_scim_provisioning_jobs_next_attempt_at ON scim_provisioning_jobs (next_attempt_at);
@@ -0,0 +1,11 @@
+ALTER TABLE oidc_clients ADD COLUMN forward_auth_hosts TEXT;
+
+CREATE TABLE forward_auth_codes
+(
+    id         TEXT     NOT NULL PRIMARY KEY,
+    created_at DATETIME,
+    code       TEXT     NOT NULL UNIQUE,
+    host       TEXT     NOT NULL,
+    expires_at DATETIME NOT NULL,
+    session_id TEXT     NOT NULL REFERENCES sessions ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TABLE identity_provider_login_states;
+DROP TABLE user_identities;
+DROP TABLE identity_providers;
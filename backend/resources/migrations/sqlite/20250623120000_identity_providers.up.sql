@@ -0,0 +1,42 @@
+CREATE TABLE identity_providers
+(
+    id                  TEXT     NOT NULL PRIMARY KEY,
+    created_at          DATETIME,
+    name                TEXT     NOT NULL,
+    issuer              TEXT     NOT NULL,
+    client_id           TEXT     NOT NULL,
+    client_secret       TEXT     NOT NULL,
+    scopes              TEXT     NOT NULL,
+    enabled             BOOLEAN  NOT NULL DEFAULT TRUE,
+    link_by_email       BOOLEAN  NOT NULL DEFAULT FALSE,
+    allow_user_creation BOOLEAN  NOT NULL DEFAULT FALSE,
+    groups_claim        TEXT,
+    group_mappings      TEXT
+);
+
+CREATE TABLE user_identities
+(
+    id                   TEXT     NOT NULL PRIMARY KEY,
+    created_at           DATETIME,
+    subject              TEXT     NOT NULL,
+    email                TEXT,
+    identity_provider_id TEXT     NOT NULL REFERENCES identity_providers ON DELETE CASCADE,
+    user_id              TEXT     NOT NULL REFERENCES users ON DELETE CASCADE,
+    UNIQUE (identity_provider_id, subject),
+    UNIQUE (identity_provider_id, user_id)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE identity_provider_login_states
+(
+    id                   TEXT     NOT NULL PRIMARY KEY,
+    created_at           DATETIME,
+    state                TEXT     NOT NULL UNIQUE,
+    nonce                TEXT     NOT NULL,
+    code_verifier        TEXT     NOT NULL,
+    redirect_path        TEXT,
+    expires_at           DATETIME NOT NULL,
+    identity_provider_id TEXT     NOT NULL REFERENCES identity_providers ON DELETE CASCADE,
+    user_id              TEXT REFERENCES users ON DELETE CASCADE
+);
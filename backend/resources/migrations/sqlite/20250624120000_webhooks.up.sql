@@ -0,0 +1,28 @@
+CREATE TABLE webhooks
+(
+    id         TEXT     NOT NULL PRIMARY KEY,
+    created_at DATETIME,
+    name       TEXT     NOT NULL,
+    url        TEXT     NOT NULL,
+    secret     TEXT     NOT NULL,
+    events     TEXT     NOT NULL,
+    enabled    BOOLEAN  NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id                   TEXT     NOT NULL PRIMARY KEY,
+    created_at           DATETIME,
+    webhook_id           TEXT     NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event                TEXT     NOT NULL,
+    payload              TEXT     NOT NULL,
+    status               TEXT     NOT NULL,
+    attempts             INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at      DATETIME,
+    last_attempt_at      DATETIME,
+    response_status_code INTEGER,
+    last_error           TEXT
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
@@ -0,0 +1,24 @@
+-- The links don't reference the audit logs with a foreign key, because they must outlive purged audit logs
+-- and reveal audit logs that were deleted from the database directly
+CREATE TABLE audit_log_chain_links
+(
+    sequence            INTEGER NOT NULL PRIMARY KEY,
+    audit_log_id        TEXT UNIQUE,
+    user_id             TEXT,
+    entry_hash          TEXT    NOT NULL,
+    hash                TEXT    NOT NULL,
+    purge_checkpoint_id TEXT
+);
+
+CREATE INDEX idx_audit_log_chain_links_purge_checkpoint_id ON audit_log_chain_links (purge_checkpoint_id);
+
+CREATE TABLE audit_log_checkpoints
+(
+    id           TEXT     NOT NULL PRIMARY KEY,
+    created_at   DATETIME,
+    number       INTEGER  NOT NULL UNIQUE,
+    sequence     INTEGER  NOT NULL,
+    hash         TEXT     NOT NULL,
+    purge_digest TEXT     NOT NULL,
+    signature    TEXT     NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE oidc_clients ADD COLUMN config_managed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_groups ADD COLUMN config_managed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE app_config_variables ADD COLUMN config_managed BOOLEAN NOT NULL DEFAULT FALSE;
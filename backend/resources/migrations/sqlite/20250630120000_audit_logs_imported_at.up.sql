@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD COLUMN imported_at DATETIME;
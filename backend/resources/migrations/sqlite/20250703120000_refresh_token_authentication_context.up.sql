@@ -0,0 +1,2 @@
+ALTER TABLE oidc_refresh_tokens ADD COLUMN acr TEXT NOT NULL DEFAULT '';
+ALTER TABLE oidc_refresh_tokens ADD COLUMN amr TEXT NOT NULL DEFAULT '';
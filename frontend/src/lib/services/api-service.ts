@@ -1,4 +1,5 @@
-import axios from 'axios';
+import { startAuthentication } from '@simplewebauthn/browser';
+import axios, { type AxiosError, type InternalAxiosRequestConfig } from 'axios';
 
 abstract class APIService {
 	api = axios.create({
@@ -9,6 +10,24 @@ abstract class APIService {
 		if (typeof process !== 'undefined' && process?.env?.DEVELOPMENT_BACKEND_URL) {
 			this.api.defaults.baseURL = process.env.DEVELOPMENT_BACKEND_URL;
 		}
+
+		// Sensitive actions may require a recent passkey authentication (sudo mode).
+		// In that case, ask the user for their passkey and retry the request once.
+		this.api.interceptors.response.use(undefined, async (error: AxiosError) => {
+			const config = error.config as
+				| (InternalAxiosRequestConfig & { reauthenticated?: boolean })
+				| undefined;
+			if (error.response?.status !== 428 || !config || config.reauthenticated) {
+				throw error;
+			}
+
+			const loginOptions = (await this.api.get('/webauthn/login/start')).data;
+			const authResponse = await startAuthentication({ optionsJSON: loginOptions });
+			await this.api.post('/webauthn/reauthenticate', authResponse);
+
+			config.reauthenticated = true;
+			return this.api.request(config);
+		});
 	}
 }
 
@@ -0,0 +1,22 @@
+import type {
+	AppPassword,
+	AppPasswordCreate,
+	AppPasswordResponse
+} from '$lib/types/app-password.type';
+import APIService from './api-service';
+
+export default class AppPasswordService extends APIService {
+	async list() {
+		const res = await this.api.get('/app-passwords');
+		return res.data as AppPassword[];
+	}
+
+	async create(data: AppPasswordCreate): Promise<AppPasswordResponse> {
+		const res = await this.api.post('/app-passwords', data);
+		return res.data as AppPasswordResponse;
+	}
+
+	async remove(id: string): Promise<void> {
+		await this.api.delete(`/app-passwords/${id}`);
+	}
+}
@@ -1,4 +1,4 @@
-import type { AuditLog, AuditLogFilter } from '$lib/types/audit-log.type';
+import type { AuditLog, AuditLogExportFormat, AuditLogFilter } from '$lib/types/audit-log.type';
 import type { Paginated, SearchPaginationSortRequest } from '$lib/types/pagination.type';
 import APIService from './api-service';
 
@@ -14,12 +14,22 @@ class AuditLogService extends APIService {
 		const res = await this.api.get('/audit-logs/all', {
 			params: {
 				...options,
-				filters
+				filters: this.toRequestFilters(filters)
 			}
 		});
 		return res.data as Paginated<AuditLog>;
 	}
 
+	exportUrl(format: AuditLogExportFormat, filters?: AuditLogFilter) {
+		return this.api.getUri({
+			url: '/audit-logs/export',
+			params: {
+				format,
+				filters: this.toRequestFilters(filters)
+			}
+		});
+	}
+
 	async listClientNames() {
 		const res = await this.api.get<string[]>('/audit-logs/filters/client-names');
 		return res.data;
@@ -29,6 +39,16 @@ class AuditLogService extends APIService {
 		const res = await this.api.get<Record<string, string>>('/audit-logs/filters/users');
 		return res.data;
 	}
+
+	// The dates are sent as the start and the end of the selected days in the local time zone
+	private toRequestFilters(filters?: AuditLogFilter) {
+		if (!filters) return undefined;
+		return {
+			...filters,
+			from: filters.from ? new Date(`${filters.from}T00:00:00`).toISOString() : '',
+			to: filters.to ? new Date(`${filters.to}T23:59:59.999`).toISOString() : ''
+		};
+	}
 }
 
 export default AuditLogService;
@@ -0,0 +1,9 @@
+import type { ForwardAuthAuthorizeResponse } from '$lib/types/forward-auth.type';
+import APIService from './api-service';
+
+export default class ForwardAuthService extends APIService {
+	async authorize(redirectUrl: string) {
+		const res = await this.api.post('/forward-auth/authorize', { redirectUrl });
+		return res.data as ForwardAuthAuthorizeResponse;
+	}
+}
@@ -0,0 +1,47 @@
+import type {
+	IdentityProvider,
+	IdentityProviderCreate,
+	IdentityProviderMinimal,
+	UserIdentity
+} from '$lib/types/identity-provider.type';
+import APIService from './api-service';
+
+export default class IdentityProviderService extends APIService {
+	async listEnabled() {
+		const res = await this.api.get('/identity-providers/enabled');
+		return res.data as IdentityProviderMinimal[];
+	}
+
+	async list() {
+		const res = await this.api.get('/identity-providers');
+		return res.data as IdentityProvider[];
+	}
+
+	async get(id: string) {
+		const res = await this.api.get(`/identity-providers/${id}`);
+		return res.data as IdentityProvider;
+	}
+
+	async create(provider: IdentityProviderCreate) {
+		const res = await this.api.post('/identity-providers', provider);
+		return res.data as IdentityProvider;
+	}
+
+	async update(id: string, provider: IdentityProviderCreate) {
+		const res = await this.api.put(`/identity-providers/${id}`, provider);
+		return res.data as IdentityProvider;
+	}
+
+	async remove(id: string) {
+		await this.api.delete(`/identity-providers/${id}`);
+	}
+
+	async listUserIdentities() {
+		const res = await this.api.get('/user-identities');
+		return res.data as UserIdentity[];
+	}
+
+	async removeUserIdentity(id: string) {
+		await this.api.delete(`/user-identities/${id}`);
+	}
+}
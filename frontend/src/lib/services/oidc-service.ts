@@ -8,6 +8,10 @@ import type {
 	OidcDeviceCodeInfo
 } from '$lib/types/oidc.type';
 import type { Paginated, SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type {
+	ScimProvisioningTarget,
+	ScimProvisioningTargetUpdate
+} from '$lib/types/scim-provisioning.type';
 import APIService from './api-service';
 
 class OidcService extends APIService {
@@ -17,7 +21,8 @@ class OidcService extends APIService {
 		callbackURL: string,
 		nonce?: string,
 		codeChallenge?: string,
-		codeChallengeMethod?: string
+		codeChallengeMethod?: string,
+		acrValues?: string
 	) {
 		const res = await this.api.post('/oidc/authorize', {
 			scope,
@@ -25,7 +30,8 @@ class OidcService extends APIService {
 			callbackURL,
 			clientId,
 			codeChallenge,
-			codeChallengeMethod
+			codeChallengeMethod,
+			acrValues
 		});
 
 		return res.data as AuthorizeResponse;
@@ -110,6 +116,23 @@ class OidcService extends APIService {
 		});
 		return response.data;
 	}
+
+	async getScimProvisioningTarget(clientId: string) {
+		return (await this.api.get(`/oidc/clients/${clientId}/scim`)).data as ScimProvisioningTarget;
+	}
+
+	async updateScimProvisioningTarget(clientId: string, target: ScimProvisioningTargetUpdate) {
+		return (await this.api.put(`/oidc/clients/${clientId}/scim`, target))
+			.data as ScimProvisioningTarget;
+	}
+
+	async removeScimProvisioningTarget(clientId: string) {
+		await this.api.delete(`/oidc/clients/${clientId}/scim`);
+	}
+
+	async syncScimProvisioningTarget(clientId: string) {
+		await this.api.post(`/oidc/clients/${clientId}/scim/sync`);
+	}
 }
 
 export default OidcService;
@@ -0,0 +1,55 @@
+import type { Paginated, SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type {
+	SamlServiceProvider,
+	SamlServiceProviderCreate,
+	SamlServiceProviderWithAllowedUserGroups,
+	SamlSsoResponse
+} from '$lib/types/saml.type';
+import APIService from './api-service';
+
+class SamlService extends APIService {
+	async createSsoResponse(samlRequest?: string, serviceProviderId?: string, relayState?: string) {
+		const res = await this.api.post('/saml/sso/response', {
+			samlRequest,
+			serviceProviderId,
+			relayState
+		});
+
+		return res.data as SamlSsoResponse;
+	}
+
+	async listServiceProviders(options?: SearchPaginationSortRequest) {
+		const res = await this.api.get('/saml/service-providers', {
+			params: options
+		});
+		return res.data as Paginated<SamlServiceProvider>;
+	}
+
+	async getServiceProvider(id: string) {
+		const res = await this.api.get(`/saml/service-providers/${id}`);
+		return res.data as SamlServiceProviderWithAllowedUserGroups;
+	}
+
+	async createServiceProvider(serviceProvider: SamlServiceProviderCreate) {
+		const res = await this.api.post('/saml/service-providers', serviceProvider);
+		return res.data as SamlServiceProviderWithAllowedUserGroups;
+	}
+
+	async updateServiceProvider(id: string, serviceProvider: SamlServiceProviderCreate) {
+		const res = await this.api.put(`/saml/service-providers/${id}`, serviceProvider);
+		return res.data as SamlServiceProviderWithAllowedUserGroups;
+	}
+
+	async removeServiceProvider(id: string) {
+		await this.api.delete(`/saml/service-providers/${id}`);
+	}
+
+	async updateAllowedUserGroups(id: string, userGroupIds: string[]) {
+		const res = await this.api.put(`/saml/service-providers/${id}/allowed-user-groups`, {
+			userGroupIds
+		});
+		return res.data as SamlServiceProviderWithAllowedUserGroups;
+	}
+}
+
+export default SamlService;
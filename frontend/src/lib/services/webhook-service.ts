@@ -0,0 +1,53 @@
+import type { Paginated, SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type {
+	Webhook,
+	WebhookCreate,
+	WebhookDelivery,
+	WebhookResponse
+} from '$lib/types/webhook.type';
+import APIService from './api-service';
+
+export default class WebhookService extends APIService {
+	async list(options?: SearchPaginationSortRequest) {
+		const res = await this.api.get('/webhooks', {
+			params: options
+		});
+		return res.data as Paginated<Webhook>;
+	}
+
+	async get(id: string) {
+		const res = await this.api.get(`/webhooks/${id}`);
+		return res.data as Webhook;
+	}
+
+	async create(webhook: WebhookCreate) {
+		const res = await this.api.post('/webhooks', webhook);
+		return res.data as WebhookResponse;
+	}
+
+	async update(id: string, webhook: WebhookCreate) {
+		const res = await this.api.put(`/webhooks/${id}`, webhook);
+		return res.data as Webhook;
+	}
+
+	async remove(id: string) {
+		await this.api.delete(`/webhooks/${id}`);
+	}
+
+	async regenerateSecret(id: string) {
+		const res = await this.api.post(`/webhooks/${id}/secret`);
+		return res.data.secret as string;
+	}
+
+	async listDeliveries(id: string, options?: SearchPaginationSortRequest) {
+		const res = await this.api.get(`/webhooks/${id}/deliveries`, {
+			params: options
+		});
+		return res.data as Paginated<WebhookDelivery>;
+	}
+
+	async replayDelivery(id: string, deliveryId: string) {
+		const res = await this.api.post(`/webhooks/${id}/deliveries/${deliveryId}/replay`);
+		return res.data as WebhookDelivery;
+	}
+}
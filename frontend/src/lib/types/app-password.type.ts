@@ -0,0 +1,15 @@
+export type AppPassword = {
+	id: string;
+	name: string;
+	lastUsedAt?: string;
+	createdAt: string;
+};
+
+export type AppPasswordCreate = {
+	name: string;
+};
+
+export type AppPasswordResponse = {
+	appPassword: AppPassword;
+	password: string;
+};
@@ -11,6 +11,13 @@ export type AppConfig = {
 export type AllAppConfig = AppConfig & {
 	// General
 	sessionDuration: number;
+	sessionIdleTimeout: number;
+	// If empty, the values of all users are used for admins
+	adminSessionDuration: number | '';
+	adminSessionIdleTimeout: number | '';
+	sudoModeDuration: number;
+	// Comma-separated list of the actions that require a recent re-authentication
+	sudoModeActions: string;
 	emailsVerified: boolean;
 	// Email
 	smtpHost: string;
@@ -15,4 +15,12 @@ export type AuditLogFilter = {
 	userId: string;
 	event: string;
 	clientName: string;
+	// Dates in the format YYYY-MM-DD
+	from: string;
+	to: string;
+	ipAddress: string;
+	country: string;
+	search: string;
 };
+
+export type AuditLogExportFormat = 'csv' | 'jsonl';
@@ -0,0 +1,3 @@
+export type ForwardAuthAuthorizeResponse = {
+	callbackUrl: string;
+};
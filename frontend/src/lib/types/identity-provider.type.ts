@@ -0,0 +1,33 @@
+export type IdentityProviderMinimal = {
+	id: string;
+	name: string;
+};
+
+export type IdentityProviderGroupMapping = {
+	value: string;
+	userGroupId: string;
+};
+
+export type IdentityProvider = IdentityProviderMinimal & {
+	issuer: string;
+	clientId: string;
+	scopes: string;
+	enabled: boolean;
+	linkByEmail: boolean;
+	allowUserCreation: boolean;
+	groupsClaim?: string;
+	groupMappings: IdentityProviderGroupMapping[];
+	createdAt: string;
+};
+
+export type IdentityProviderCreate = Omit<IdentityProvider, 'id' | 'createdAt' | 'groupsClaim'> & {
+	clientSecret: string;
+	groupsClaim: string;
+};
+
+export type UserIdentity = {
+	id: string;
+	email?: string;
+	identityProvider: IdentityProviderMinimal;
+	createdAt: string;
+};
@@ -20,9 +20,11 @@ export type OidcClientCredentials = {
 export type OidcClient = OidcClientMetaData & {
 	callbackURLs: string[];
 	logoutCallbackURLs: string[];
+	forwardAuthHosts: string[];
 	isPublic: boolean;
 	pkceEnabled: boolean;
 	credentials?: OidcClientCredentials;
+	configManaged: boolean;
 };
 
 export type OidcClientWithAllowedUserGroups = OidcClient & {
@@ -33,7 +35,7 @@ export type OidcClientWithAllowedUserGroupsCount = OidcClient & {
 	allowedUserGroupsCount: number;
 };
 
-export type OidcClientCreate = Omit<OidcClient, 'id' | 'logoURL' | 'hasLogo'>;
+export type OidcClientCreate = Omit<OidcClient, 'id' | 'logoURL' | 'hasLogo' | 'configManaged'>;
 
 export type OidcClientCreateWithLogo = OidcClientCreate & {
 	logo: File | null | undefined;
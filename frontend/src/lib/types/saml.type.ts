@@ -0,0 +1,31 @@
+import type { UserGroupWithUserCount } from './user-group.type';
+
+export type SamlNameIdFormat = 'persistent' | 'email' | 'unspecified';
+
+export type SamlAttribute = {
+	name: string;
+	value: string;
+};
+
+export type SamlServiceProvider = {
+	id: string;
+	name: string;
+	entityId: string;
+	acsUrl: string;
+	metadata: string;
+	nameIdFormat: SamlNameIdFormat;
+	attributeMapping: SamlAttribute[];
+	createdAt: string;
+};
+
+export type SamlServiceProviderWithAllowedUserGroups = SamlServiceProvider & {
+	allowedUserGroups: UserGroupWithUserCount[];
+};
+
+export type SamlServiceProviderCreate = Omit<SamlServiceProvider, 'id' | 'createdAt'>;
+
+export type SamlSsoResponse = {
+	url: string;
+	samlResponse: string;
+	relayState: string;
+};
@@ -0,0 +1,16 @@
+export type ScimProvisioningTarget = {
+	id: string;
+	endpoint: string;
+	enabled: boolean;
+	lastSyncedAt?: string;
+	lastSyncError?: string;
+	pendingUsers: number;
+	failedUsers: number;
+	provisionedUsers: number;
+};
+
+export type ScimProvisioningTargetUpdate = {
+	endpoint: string;
+	token: string;
+	enabled: boolean;
+};
@@ -8,6 +8,8 @@ export type UserGroup = {
 	createdAt: string;
 	customClaims: CustomClaim[];
 	ldapId?: string;
+	scimExternalId?: string;
+	configManaged: boolean;
 };
 
 export type UserGroupWithUsers = UserGroup & {
@@ -18,4 +20,5 @@ export type UserGroupWithUserCount = UserGroup & {
 	userCount: number;
 };
 
-export type UserGroupCreate = Pick<UserGroup, 'friendlyName' | 'name' | 'ldapId'>;
+export type UserGroupCreate = Pick<UserGroup, 'friendlyName' | 'name' | 'ldapId'> &
+	Partial<Pick<UserGroup, 'scimExternalId' | 'configManaged'>>;
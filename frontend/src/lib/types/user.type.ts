@@ -13,7 +13,8 @@ export type User = {
 	customClaims: CustomClaim[];
 	locale?: Locale;
 	ldapId?: string;
+	scimExternalId?: string;
 	disabled?: boolean;
 };
 
-export type UserCreate = Omit<User, 'id' | 'customClaims' | 'ldapId' | 'userGroups'>;
+export type UserCreate = Omit<User, 'id' | 'customClaims' | 'ldapId' | 'scimExternalId' | 'userGroups'>;
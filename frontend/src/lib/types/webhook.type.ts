@@ -0,0 +1,37 @@
+export type WebhookEvent =
+	| 'SIGN_IN'
+	| 'NEW_CLIENT_AUTHORIZATION'
+	| 'USER_CREATED'
+	| 'USER_DISABLED'
+	| 'USER_GROUP_MEMBERSHIP_CHANGED';
+
+export type Webhook = {
+	id: string;
+	name: string;
+	url: string;
+	events: WebhookEvent[];
+	enabled: boolean;
+	createdAt: string;
+};
+
+export type WebhookCreate = Omit<Webhook, 'id' | 'createdAt'>;
+
+export type WebhookResponse = {
+	webhook: Webhook;
+	secret: string;
+};
+
+export type WebhookDeliveryStatus = 'pending' | 'succeeded' | 'failed';
+
+export type WebhookDelivery = {
+	id: string;
+	event: WebhookEvent;
+	payload: string;
+	status: WebhookDeliveryStatus;
+	attempts: number;
+	nextAttemptAt?: string;
+	lastAttemptAt?: string;
+	responseStatusCode?: number;
+	lastError?: string;
+	createdAt: string;
+};
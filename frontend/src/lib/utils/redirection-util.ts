@@ -8,7 +8,7 @@ export function getAuthRedirectPath(path: string, user: User | null) {
 
 	const isUnauthenticatedOnlyPath =
 		path == '/login' || path.startsWith('/login/') || path == '/lc' || path.startsWith('/lc/');
-	const isPublicPath = ['/authorize', '/device', '/health', '/healthz'].includes(path);
+	const isPublicPath = ['/authorize', '/device', '/saml/sso', '/forward-auth', '/health', '/healthz'].includes(path);
 	const isAdminPath = path == '/settings/admin' || path.startsWith('/settings/admin/');
 
 	if (!isUnauthenticatedOnlyPath && !isPublicPath && !isSignedIn) {
@@ -0,0 +1,13 @@
+import IdentityProviderService from '$lib/services/identity-provider-service';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ url }) => {
+	const identityProviderService = new IdentityProviderService();
+	const identityProviders = await identityProviderService.listEnabled().catch(() => []);
+
+	return {
+		identityProviders,
+		// Set by the identity provider callback if signing in failed
+		error: url.searchParams.get('error') || undefined
+	};
+};
@@ -0,0 +1,9 @@
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ url }) => {
+	return {
+		samlRequest: url.searchParams.get('SAMLRequest') || undefined,
+		serviceProviderId: url.searchParams.get('sp') || undefined,
+		relayState: url.searchParams.get('RelayState') || undefined
+	};
+};
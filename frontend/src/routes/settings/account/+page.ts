@@ -1,3 +1,5 @@
+import AppPasswordService from '$lib/services/app-password-service';
+import IdentityProviderService from '$lib/services/identity-provider-service';
 import UserService from '$lib/services/user-service';
 import WebAuthnService from '$lib/services/webauthn-service';
 import type { PageLoad } from './$types';
@@ -5,14 +7,22 @@ import type { PageLoad } from './$types';
 export const load: PageLoad = async () => {
 	const webauthnService = new WebAuthnService();
 	const userService = new UserService();
+	const appPasswordService = new AppPasswordService();
+	const identityProviderService = new IdentityProviderService();
 
-	const [account, passkeys] = await Promise.all([
+	const [account, passkeys, appPasswords, identityProviders, userIdentities] = await Promise.all([
 		userService.getCurrent(),
-		webauthnService.listCredentials()
+		webauthnService.listCredentials(),
+		appPasswordService.list(),
+		identityProviderService.listEnabled(),
+		identityProviderService.listUserIdentities()
 	]);
 
 	return {
 		account,
-		passkeys
+		passkeys,
+		appPasswords,
+		identityProviders,
+		userIdentities
 	};
 };
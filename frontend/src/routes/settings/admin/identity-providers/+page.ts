@@ -0,0 +1,8 @@
+import IdentityProviderService from '$lib/services/identity-provider-service';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async () => {
+	const identityProviderService = new IdentityProviderService();
+	const identityProviders = await identityProviderService.list();
+	return { identityProviders };
+};
@@ -0,0 +1,7 @@
+import IdentityProviderService from '$lib/services/identity-provider-service';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ params }) => {
+	const identityProviderService = new IdentityProviderService();
+	return await identityProviderService.get(params.id);
+};
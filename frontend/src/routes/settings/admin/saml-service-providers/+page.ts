@@ -0,0 +1,18 @@
+import SamlService from '$lib/services/saml-service';
+import type { SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async () => {
+	const samlService = new SamlService();
+
+	const serviceProvidersRequestOptions: SearchPaginationSortRequest = {
+		sort: {
+			column: 'name',
+			direction: 'asc'
+		}
+	};
+
+	const serviceProviders = await samlService.listServiceProviders(serviceProvidersRequestOptions);
+
+	return { serviceProviders, serviceProvidersRequestOptions };
+};
@@ -0,0 +1,7 @@
+import SamlService from '$lib/services/saml-service';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ params }) => {
+	const samlService = new SamlService();
+	return await samlService.getServiceProvider(params.id);
+};
@@ -0,0 +1,18 @@
+import WebhookService from '$lib/services/webhook-service';
+import type { SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async () => {
+	const webhookService = new WebhookService();
+
+	const webhooksRequestOptions: SearchPaginationSortRequest = {
+		sort: {
+			column: 'name',
+			direction: 'asc' as const
+		}
+	};
+
+	const webhooks = await webhookService.list(webhooksRequestOptions);
+
+	return { webhooks, webhooksRequestOptions };
+};
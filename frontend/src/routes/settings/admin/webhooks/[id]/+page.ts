@@ -0,0 +1,21 @@
+import WebhookService from '$lib/services/webhook-service';
+import type { SearchPaginationSortRequest } from '$lib/types/pagination.type';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ params }) => {
+	const webhookService = new WebhookService();
+
+	const deliveriesRequestOptions: SearchPaginationSortRequest = {
+		sort: {
+			column: 'createdAt',
+			direction: 'desc' as const
+		}
+	};
+
+	const [webhook, deliveries] = await Promise.all([
+		webhookService.get(params.id),
+		webhookService.listDeliveries(params.id, deliveriesRequestOptions)
+	]);
+
+	return { webhook, deliveries, deliveriesRequestOptions };
+};